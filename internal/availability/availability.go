@@ -0,0 +1,148 @@
+package availability
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/engineer"
+)
+
+// ErrInvalidQuery - returned when a slot query has no duration, a negative travel buffer or an empty
+// date range
+var ErrInvalidQuery = errors.New("availability query needs a positive duration, a buffer of zero or more and From before To")
+
+// MaxRange - the longest date range a single query may search
+const MaxRange = 62 * 24 * time.Hour
+
+// Service - combines engineer working hours, bookings and absences to find free time
+type Service struct {
+	Bookings  *booking.BookService
+	Engineers *engineer.Service
+}
+
+// Query - what a dispatcher is looking for. Skill, Manufacturer and EngineerIDs are optional filters,
+// TravelBuffer is kept free either side of every existing booking.
+type Query struct {
+	From         time.Time
+	To           time.Time
+	Duration     time.Duration
+	TravelBuffer time.Duration
+	Skill        string
+	Manufacturer string
+	EngineerIDs  []uint
+}
+
+// Slot - a free window in an engineer's day long enough for the requested duration. The work can
+// start anywhere between Start and LatestStart.
+type Slot struct {
+	EngineerID   uint      `json:"engineerId"`
+	EngineerName string    `json:"engineerName"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	LatestStart  time.Time `json:"latestStart"`
+}
+
+// AvailabilityService - the interface for our availability service
+type AvailabilityService interface {
+	FreeSlots(query Query) ([]Slot, error)
+}
+
+// NewService - returns a pointer to a new availability service
+func NewService(bookings *booking.BookService, engineers *engineer.Service) *Service {
+	return &Service{
+		Bookings:  bookings,
+		Engineers: engineers,
+	}
+}
+
+// FreeSlots - returns every free window matching the query, grouped by engineer and ordered by start
+func (s *Service) FreeSlots(query Query) ([]Slot, error) {
+	var slots []Slot
+	if query.Duration <= 0 || query.TravelBuffer < 0 || !query.From.Before(query.To) || query.To.Sub(query.From) > MaxRange {
+		return slots, ErrInvalidQuery
+	}
+
+	engineers, err := s.candidates(query)
+	if err != nil {
+		return slots, err
+	}
+
+	for _, e := range engineers {
+		busy, err := s.busy(e.ID, query)
+		if err != nil {
+			return slots, err
+		}
+		start, end, days, loc := e.WorkingHours()
+		for _, shift := range workingIntervals(start, end, days, loc, query.From, query.To) {
+			for _, free := range subtract(shift, busy) {
+				if free.End.Sub(free.Start) < query.Duration {
+					continue
+				}
+				slots = append(slots, Slot{
+					EngineerID:   e.ID,
+					EngineerName: e.Name,
+					Start:        free.Start,
+					End:          free.End,
+					LatestStart:  free.End.Add(-query.Duration),
+				})
+			}
+		}
+	}
+	return slots, nil
+}
+
+// candidates - the engineers matching the query's engineer, skill and manufacturer filters
+func (s *Service) candidates(query Query) ([]engineer.Engineer, error) {
+	var engineers []engineer.Engineer
+	var err error
+	if len(query.EngineerIDs) > 0 {
+		engineers, err = s.Engineers.GetEngineers(query.EngineerIDs)
+	} else {
+		engineers, err = s.Engineers.GetAllEngineers()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []engineer.Engineer
+	for _, e := range engineers {
+		if query.Skill != "" && !e.HasSkill(query.Skill) {
+			continue
+		}
+		if query.Manufacturer != "" && !e.ServicesManufacturer(query.Manufacturer) {
+			continue
+		}
+		matched = append(matched, e)
+	}
+	return matched, nil
+}
+
+// busy - an engineer's bookings, padded by the travel buffer, and absences around the query window
+func (s *Service) busy(engineerID uint, query Query) ([]interval, error) {
+	from, to := query.From.Add(-query.TravelBuffer), query.To.Add(query.TravelBuffer)
+
+	bookings, err := s.Bookings.GetEngineerOccurrences(engineerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	absences, err := s.Engineers.GetAbsences(engineerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	busy := make([]interval, 0, len(bookings)+len(absences))
+	for _, b := range bookings {
+		if !b.Status.Active() {
+			continue
+		}
+		busy = append(busy, interval{
+			Start: b.StartDateTime.Add(-query.TravelBuffer),
+			End:   b.EndDateTime.Add(query.TravelBuffer),
+		})
+	}
+	for _, a := range absences {
+		busy = append(busy, interval{Start: a.Start, End: a.End})
+	}
+	return busy, nil
+}
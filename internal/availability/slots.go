@@ -0,0 +1,73 @@
+package availability
+
+import (
+	"sort"
+	"time"
+)
+
+// interval - a half-open [Start, End) span of time
+type interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// subtract - removes the busy intervals from the free interval, returning what is left in order
+func subtract(free interval, busy []interval) []interval {
+	sort.Slice(busy, func(i, j int) bool { return busy[i].Start.Before(busy[j].Start) })
+
+	var remaining []interval
+	cursor := free.Start
+	for _, b := range busy {
+		if !b.End.After(cursor) || !b.Start.Before(free.End) {
+			continue
+		}
+		if b.Start.After(cursor) {
+			remaining = append(remaining, interval{Start: cursor, End: b.Start})
+		}
+		cursor = b.End
+		if !cursor.Before(free.End) {
+			return remaining
+		}
+	}
+	return append(remaining, interval{Start: cursor, End: free.End})
+}
+
+// workingIntervals - expands a working pattern into concrete intervals overlapping [from, to)
+func workingIntervals(start, end string, days []string, loc *time.Location, from, to time.Time) []interval {
+	startClock, err := time.Parse("15:04", start)
+	if err != nil {
+		return nil
+	}
+	endClock, err := time.Parse("15:04", end)
+	if err != nil {
+		return nil
+	}
+	workDays := make(map[string]bool, len(days))
+	for _, day := range days {
+		workDays[day] = true
+	}
+
+	var intervals []interval
+	local := from.In(loc)
+	// start the day before so a window opening mid-shift still picks up that shift
+	day := time.Date(local.Year(), local.Month(), local.Day()-1, 0, 0, 0, 0, loc)
+	for ; day.Before(to); day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc) {
+		if !workDays[day.Weekday().String()[:3]] {
+			continue
+		}
+		shift := interval{
+			Start: time.Date(day.Year(), day.Month(), day.Day(), startClock.Hour(), startClock.Minute(), 0, 0, loc),
+			End:   time.Date(day.Year(), day.Month(), day.Day(), endClock.Hour(), endClock.Minute(), 0, 0, loc),
+		}
+		if shift.Start.Before(from) {
+			shift.Start = from
+		}
+		if shift.End.After(to) {
+			shift.End = to
+		}
+		if shift.Start.Before(shift.End) {
+			intervals = append(intervals, shift)
+		}
+	}
+	return intervals
+}
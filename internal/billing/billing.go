@@ -0,0 +1,485 @@
+package billing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/Open-FiSE/go-rest-api/internal/document"
+	"github.com/Open-FiSE/go-rest-api/internal/inventory"
+	"github.com/Open-FiSE/go-rest-api/internal/timesheet"
+	"github.com/jinzhu/gorm"
+)
+
+// Kind - whether a billing document is a quote or an invoice
+type Kind string
+
+// kinds of billing document
+const (
+	KindQuote   Kind = "quote"
+	KindInvoice Kind = "invoice"
+)
+
+// Status - whether a quote or invoice stands
+type Status string
+
+// statuses of quotes and invoices
+const (
+	StatusIssued Status = "issued"
+	StatusVoid   Status = "void"
+)
+
+// LineKind - what a line of a quote or invoice charges for
+type LineKind string
+
+// kinds of line
+const (
+	LineLabour LineKind = "labour"
+	LinePart   LineKind = "part"
+	LineWork   LineKind = "work"
+)
+
+// errors returned by the billing service
+var (
+	ErrInvalidIssuedBy = errors.New("quote or invoice IssuedBy is required")
+	ErrNoCustomer      = errors.New("booking has no customer to bill")
+	ErrNotCompleted    = errors.New("booking must be completed before it is invoiced")
+	ErrAlreadyInvoiced = errors.New("booking has already been invoiced, void the invoice before issuing another")
+	ErrNothingToBill   = errors.New("booking has no approved time, parts used or fixed-price work to bill")
+	ErrMissingRate     = errors.New("price list has no labour rate")
+	ErrInvalidVoid     = errors.New("only an issued quote or invoice can be voided, by a named person with a reason")
+	ErrNotInvoice      = errors.New("only invoices can be exported as UBL")
+)
+
+// Seller - the business quotes and invoices are issued by
+type Seller struct {
+	Name    string
+	Address []string
+	// Country - the ISO 3166-1 alpha-2 country code of the seller's address
+	Country   string
+	TaxNumber string
+}
+
+// Service - the struct for the billing service, which prices completed bookings from their approved
+// time, the parts used and fixed-price work, and issues numbered quotes and invoices for them
+type Service struct {
+	DB         *gorm.DB
+	Bookings   *booking.BookService
+	Customers  *customer.Service
+	Inventory  *inventory.Service
+	Timesheets *timesheet.Service
+	Documents  *document.Service
+	// Dir - the directory quote and invoice PDFs are written to
+	Dir    string
+	Seller Seller
+	// Currency - the ISO 4217 code all prices are in
+	Currency string
+	// PaymentDays - how long a customer has to pay an invoice, and how long a quote stands
+	PaymentDays int
+}
+
+// BillingService - the interface for our billing service
+type BillingService interface {
+	GetInvoice(ID uint) (Invoice, error)
+	GetInvoices(kind Kind, customerID uint) ([]Invoice, error)
+	GetBookingInvoices(bookingID uint) ([]Invoice, error)
+	GetIssued(kind Kind, from, to time.Time) ([]Invoice, error)
+	Quote(bookingID uint, issuedBy string) (Invoice, error)
+	Invoice(bookingID uint, issuedBy string) (Invoice, error)
+	Void(ID uint, voidedBy string, reason string) (Invoice, error)
+	GetRates() (Rates, error)
+	SetLabourRate(rate LabourRate) (LabourRate, error)
+	SetWorkPrice(price WorkPrice) (WorkPrice, error)
+	SetTaxRule(rule TaxRule) (TaxRule, error)
+	DeleteTaxRule(ID uint) error
+	GetSequences() ([]Sequence, error)
+	SetSequence(kind Kind, newSequence Sequence) (Sequence, error)
+}
+
+// NewService - takes in the services a quote or invoice is drawn from and the directory to write them
+// to & returns a pointer to a new billing service
+func NewService(db *gorm.DB, bookings *booking.BookService, customers *customer.Service, inventory *inventory.Service,
+	timesheets *timesheet.Service, documents *document.Service, dir string) *Service {
+	return &Service{
+		DB:          db,
+		Bookings:    bookings,
+		Customers:   customers,
+		Inventory:   inventory,
+		Timesheets:  timesheets,
+		Documents:   documents,
+		Dir:         dir,
+		Seller:      Seller{Name: "Open-FiSE Field Service", Country: "IE"},
+		Currency:    "EUR",
+		PaymentDays: 30,
+	}
+}
+
+// Invoice - a quote or invoice for the work done on a booking. Amounts are in the minor unit of
+// Currency (cents) and are fixed when it is issued, later changes to the price list leave it as it
+// was. A quote's DueAt is the date it stands until.
+type Invoice struct {
+	gorm.Model
+	Kind       Kind               `gorm:"index" json:"kind"`
+	Number     string             `gorm:"unique_index" json:"number"`
+	Status     Status             `gorm:"default:'issued'" json:"status"`
+	BookingID  uint               `gorm:"index" json:"bookingId"`
+	JobID      *uint              `json:"jobId"`
+	CustomerID uint               `gorm:"index" json:"customerId"`
+	Customer   *customer.Customer `gorm:"association_autoupdate:false;association_autocreate:false;association_save_reference:false" json:"customer,omitempty"`
+	Reference  string             `json:"reference"`
+	Currency   string             `json:"currency"`
+	IssuedBy   string             `json:"issuedBy"`
+	IssuedAt   time.Time          `gorm:"index" json:"issuedAt"`
+	DueAt      time.Time          `json:"dueAt"`
+	Subtotal   int64              `json:"subtotal"`
+	Tax        int64              `json:"tax"`
+	Total      int64              `json:"total"`
+	DocumentID *uint              `json:"documentId"`
+	VoidedBy   string             `json:"voidedBy"`
+	VoidedAt   *time.Time         `json:"voidedAt"`
+	VoidReason string             `json:"voidReason"`
+	Lines      []Line             `json:"lines"`
+}
+
+// Line - one charge on a quote or invoice. Net is Quantity at UnitPrice, and Tax is charged on it at
+// TaxRate percent.
+type Line struct {
+	gorm.Model
+	InvoiceID   uint     `json:"invoiceId"`
+	Kind        LineKind `json:"kind"`
+	Description string   `json:"description"`
+	Quantity    float64  `json:"quantity"`
+	Unit        string   `json:"unit"`
+	UnitPrice   int64    `json:"unitPrice"`
+	TaxRate     float64  `json:"taxRate"`
+	Net         int64    `json:"net"`
+	Tax         int64    `json:"tax"`
+}
+
+// TableName - the table quote and invoice lines are stored in
+func (Line) TableName() string {
+	return "invoice_lines"
+}
+
+// GetInvoice - retrieves a quote or invoice and its lines by ID
+func (s *Service) GetInvoice(ID uint) (Invoice, error) {
+	var invoice Invoice
+	if result := s.DB.Preload("Customer").Preload("Lines", byID).First(&invoice, ID); result.Error != nil {
+		return Invoice{}, result.Error
+	}
+	return invoice, nil
+}
+
+// GetInvoices - retrieves quotes and invoices, newest first, only those of a kind when kind is not
+// empty and only the customer's when customerID is not zero
+func (s *Service) GetInvoices(kind Kind, customerID uint) ([]Invoice, error) {
+	var invoices []Invoice
+	query := s.DB
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if customerID != 0 {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	if result := query.Order("issued_at DESC, id DESC").Find(&invoices); result.Error != nil {
+		return invoices, result.Error
+	}
+	return invoices, nil
+}
+
+// GetBookingInvoices - retrieves the quotes and invoices issued for a booking, oldest first
+func (s *Service) GetBookingInvoices(bookingID uint) ([]Invoice, error) {
+	var invoices []Invoice
+	if result := s.DB.Preload("Lines", byID).Where("booking_id = ?", bookingID).Order("id").Find(&invoices); result.Error != nil {
+		return invoices, result.Error
+	}
+	return invoices, nil
+}
+
+// GetIssued - retrieves the quotes or invoices issued from one time up to another, oldest first, with
+// their customers and lines
+func (s *Service) GetIssued(kind Kind, from, to time.Time) ([]Invoice, error) {
+	var invoices []Invoice
+	if result := s.DB.Preload("Customer").Preload("Lines", byID).
+		Where("kind = ? AND issued_at >= ? AND issued_at < ?", kind, from, to).
+		Order("issued_at, id").Find(&invoices); result.Error != nil {
+		return invoices, result.Error
+	}
+	return invoices, nil
+}
+
+// Quote - prices the work recorded on a booking so far and issues a numbered quote for it. Any
+// number of quotes can be issued for a booking as the work changes.
+func (s *Service) Quote(bookingID uint, issuedBy string) (Invoice, error) {
+	return s.issue(KindQuote, bookingID, issuedBy)
+}
+
+// Invoice - prices a completed booking from its approved time, the parts used and fixed-price work
+// and issues a numbered invoice for it. A booking has one invoice standing at a time.
+func (s *Service) Invoice(bookingID uint, issuedBy string) (Invoice, error) {
+	return s.issue(KindInvoice, bookingID, issuedBy)
+}
+
+// Void - withdraws an issued quote or invoice, which keeps its number. A booking whose invoice is
+// voided can be invoiced again.
+func (s *Service) Void(ID uint, voidedBy string, reason string) (Invoice, error) {
+	if strings.TrimSpace(voidedBy) == "" || strings.TrimSpace(reason) == "" {
+		return Invoice{}, ErrInvalidVoid
+	}
+	invoice, err := s.GetInvoice(ID)
+	if err != nil {
+		return Invoice{}, err
+	}
+	now := time.Now().UTC()
+	result := s.DB.Model(&Invoice{}).Where("id = ? AND status = ?", ID, StatusIssued).
+		Updates(map[string]interface{}{"status": StatusVoid, "voided_by": voidedBy, "voided_at": now, "void_reason": reason})
+	if result.Error != nil {
+		return Invoice{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return Invoice{}, ErrInvalidVoid
+	}
+	invoice.Status, invoice.VoidedBy, invoice.VoidedAt, invoice.VoidReason = StatusVoid, voidedBy, &now, reason
+	return invoice, nil
+}
+
+// issue - prices a booking, numbers the quote or invoice from its sequence and saves it in one
+// transaction, then files its PDF as a document on the booking
+func (s *Service) issue(kind Kind, bookingID uint, issuedBy string) (Invoice, error) {
+	if strings.TrimSpace(issuedBy) == "" {
+		return Invoice{}, ErrInvalidIssuedBy
+	}
+	b, err := s.Bookings.GetBooking(bookingID)
+	if err != nil {
+		return Invoice{}, err
+	}
+	if b.CustomerID == nil {
+		return Invoice{}, ErrNoCustomer
+	}
+	if kind == KindInvoice && b.Status != booking.StatusCompleted {
+		return Invoice{}, ErrNotCompleted
+	}
+	lines, err := s.price(kind, b)
+	if err != nil {
+		return Invoice{}, err
+	}
+	if len(lines) == 0 {
+		return Invoice{}, ErrNothingToBill
+	}
+
+	now := time.Now().UTC()
+	invoice := Invoice{
+		Kind:       kind,
+		Status:     StatusIssued,
+		BookingID:  b.ID,
+		JobID:      b.JobID,
+		CustomerID: *b.CustomerID,
+		Reference:  b.Summary,
+		Currency:   s.Currency,
+		IssuedBy:   issuedBy,
+		IssuedAt:   now,
+		DueAt:      now.AddDate(0, 0, s.PaymentDays),
+		Lines:      lines,
+	}
+	if b.Job != nil && b.Job.Reference != "" {
+		invoice.Reference = b.Job.Reference
+	}
+	invoice.Subtotal, invoice.Tax = totals(lines)
+	invoice.Total = invoice.Subtotal + invoice.Tax
+
+	tx := s.DB.Begin()
+	// taking the number first locks the kind's sequence row until commit, so two invoices for the
+	// same booking cannot both find no standing invoice
+	if invoice.Number, err = nextNumber(tx, kind); err != nil {
+		tx.Rollback()
+		return Invoice{}, err
+	}
+	if kind == KindInvoice {
+		var standing int
+		if result := tx.Model(&Invoice{}).Where("booking_id = ? AND kind = ? AND status = ?", b.ID, KindInvoice, StatusIssued).
+			Count(&standing); result.Error != nil {
+			tx.Rollback()
+			return Invoice{}, result.Error
+		}
+		if standing > 0 {
+			tx.Rollback()
+			return Invoice{}, ErrAlreadyInvoiced
+		}
+	}
+	if result := tx.Create(&invoice); result.Error != nil {
+		tx.Rollback()
+		return Invoice{}, result.Error
+	}
+	if result := tx.Commit(); result.Error != nil {
+		return Invoice{}, result.Error
+	}
+
+	invoice.Customer = b.Customer
+	doc, err := s.file(invoice)
+	if err != nil {
+		return Invoice{}, err
+	}
+	if result := s.DB.Model(&invoice).UpdateColumn("document_id", doc.ID); result.Error != nil {
+		return Invoice{}, result.Error
+	}
+	invoice.DocumentID = &doc.ID
+	return invoice, nil
+}
+
+// price - the lines a booking is billed for: labour for each type of time recorded against it, the
+// completed equipment on its job with a fixed price for its work type, and the parts used during
+// it. A quote counts all the time recorded so far, an invoice only time on approved timesheets. Work
+// time is not charged when every completed item on the job is fixed price, as the prices cover it.
+func (s *Service) price(kind Kind, b booking.Booking) ([]Line, error) {
+	rates, err := s.GetRates()
+	if err != nil {
+		return nil, err
+	}
+	var lines []Line
+
+	fixedOnly := false
+	if b.JobID != nil {
+		job, err := s.Customers.GetJob(*b.JobID)
+		if err != nil {
+			return nil, err
+		}
+		completed := 0
+		for _, item := range job.Equipment {
+			if item.Status != customer.EquipmentCompleted {
+				continue
+			}
+			completed++
+			price, ok := rates.work(item.WorkType)
+			if !ok {
+				continue
+			}
+			description := price.Description
+			if description == "" {
+				description = capitalise(string(item.WorkType))
+			}
+			if instrument := strings.TrimSpace(fmt.Sprintf("%s %s %s", item.Manufacturer, item.InstrumentModel, item.SerialNo)); instrument != "" {
+				description += " - " + instrument
+			}
+			lines = append(lines, Line{Kind: LineWork, Description: description, Quantity: 1, Unit: "each", UnitPrice: price.Price})
+		}
+		fixedOnly = completed > 0 && len(lines) == completed
+	}
+
+	var entries []timesheet.Entry
+	if kind == KindInvoice {
+		entries, err = s.Timesheets.GetApprovedEntries(b.ID)
+	} else {
+		entries, err = s.Timesheets.GetEntries(b.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	minutes := map[timesheet.EntryType]int{}
+	for _, entry := range entries {
+		if !entry.Running() {
+			minutes[entry.Type] += entry.Minutes
+		}
+	}
+	for _, entryType := range []timesheet.EntryType{timesheet.EntryTravel, timesheet.EntryWork, timesheet.EntryWait} {
+		if minutes[entryType] == 0 || (entryType == timesheet.EntryWork && fixedOnly) {
+			continue
+		}
+		rate, ok := rates.labour(entryType)
+		if !ok {
+			return nil, fmt.Errorf("%w for %s time", ErrMissingRate, entryType)
+		}
+		lines = append(lines, Line{
+			Kind:        LineLabour,
+			Description: capitalise(string(entryType)) + " time",
+			Quantity:    hours(minutes[entryType]),
+			Unit:        "hour",
+			UnitPrice:   rate.HourlyRate,
+		})
+	}
+
+	if b.JobID != nil {
+		usage, err := s.Inventory.GetUsage(*b.JobID)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range usage {
+			if u.BookingID == nil || *u.BookingID != b.ID {
+				continue
+			}
+			line := Line{Kind: LinePart, Description: fmt.Sprintf("Part %d", u.PartID), Quantity: float64(u.Quantity), Unit: "each", UnitPrice: u.UnitPrice}
+			if u.Part != nil {
+				line.Description, line.Unit = u.Part.PartNo+" "+u.Part.Name, u.Part.Unit
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	rates.charge(lines, *b.CustomerID)
+	return lines, nil
+}
+
+// charge - sets the tax rate of each line billed to a customer and works out its net and tax, each
+// rounded to the nearest minor unit
+func (r Rates) charge(lines []Line, customerID uint) {
+	for i := range lines {
+		lines[i].TaxRate = r.tax(lines[i].Kind, customerID)
+		lines[i].Net = int64(math.Round(lines[i].Quantity * float64(lines[i].UnitPrice)))
+		lines[i].Tax = int64(math.Round(float64(lines[i].Net) * lines[i].TaxRate / 100))
+	}
+}
+
+// totals - the sum of the net amounts and of the tax of the lines, tax being rounded line by line
+func totals(lines []Line) (subtotal int64, tax int64) {
+	for _, line := range lines {
+		subtotal += line.Net
+		tax += line.Tax
+	}
+	return subtotal, tax
+}
+
+// hours - minutes of time as hours, to two decimal places
+func hours(minutes int) float64 {
+	return math.Round(float64(minutes)/60*100) / 100
+}
+
+// file - writes the quote or invoice PDF to the billing directory and records it as a document on the
+// booking
+func (s *Service) file(invoice Invoice) (document.Document, error) {
+	data := Render(invoice, s.Seller).Bytes()
+	if err := os.MkdirAll(s.Dir, os.ModePerm); err != nil {
+		return document.Document{}, err
+	}
+	title := invoice.Number + ".pdf"
+	path := filepath.Join(s.Dir, title)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return document.Document{}, err
+	}
+	sum := sha256.Sum256(data)
+	return s.Documents.PostDocument(document.Document{
+		Path:      path,
+		Title:     title,
+		Version:   1.0,
+		Author:    invoice.IssuedBy,
+		Hash:      hex.EncodeToString(sum[:]),
+		BookingID: &invoice.BookingID,
+	})
+}
+
+func byID(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
+
+func capitalise(text string) string {
+	if text == "" {
+		return text
+	}
+	return strings.ToUpper(text[:1]) + text[1:]
+}
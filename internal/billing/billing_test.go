@@ -0,0 +1,182 @@
+package billing
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+)
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+func TestRatesTax(t *testing.T) {
+	rules := []TaxRule{
+		{Name: "standard", Percent: 23},
+		{Name: "reduced labour", Kind: LineLabour, Percent: 13.5},
+		{Name: "exempt customer", CustomerID: uintPtr(7), Percent: 0},
+		{Name: "exempt customer parts", CustomerID: uintPtr(7), Kind: LinePart, Percent: 9},
+	}
+	reversed := make([]TaxRule, len(rules))
+	for i, rule := range rules {
+		reversed[len(rules)-1-i] = rule
+	}
+
+	tests := []struct {
+		name       string
+		kind       LineKind
+		customerID uint
+		want       float64
+	}{
+		{"rule with neither", LinePart, 1, 23},
+		{"kind of line", LineLabour, 1, 13.5},
+		{"customer before kind of line", LineLabour, 7, 0},
+		{"customer and kind of line", LinePart, 7, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, r := range []Rates{{Tax: rules}, {Tax: reversed}} {
+				if got := r.tax(tt.kind, tt.customerID); got != tt.want {
+					t.Errorf("tax(%s, %d) = %v, want %v", tt.kind, tt.customerID, got, tt.want)
+				}
+			}
+		})
+	}
+
+	if got := (Rates{}).tax(LineWork, 1); got != 0 {
+		t.Errorf("tax with no rules = %v, want 0", got)
+	}
+}
+
+func TestCharge(t *testing.T) {
+	rates := Rates{Tax: []TaxRule{{Percent: 23}, {Kind: LineLabour, Percent: 13.5}, {Kind: LineWork, Percent: 0}}}
+	tests := []struct {
+		name     string
+		line     Line
+		net, tax int64
+		rate     float64
+	}{
+		{"whole quantity", Line{Kind: LinePart, Quantity: 3, UnitPrice: 1999}, 5997, 1379, 23},
+		{"tax rounds up from a half", Line{Kind: LinePart, Quantity: 1, UnitPrice: 150}, 150, 35, 23},
+		{"tax rounds down", Line{Kind: LinePart, Quantity: 1, UnitPrice: 1000}, 1000, 230, 23},
+		{"part hours", Line{Kind: LineLabour, Quantity: hours(20), UnitPrice: 4500}, 1485, 200, 13.5},
+		{"net rounds to the cent", Line{Kind: LineLabour, Quantity: hours(50), UnitPrice: 6050}, 5022, 678, 13.5},
+		{"untaxed", Line{Kind: LineWork, Quantity: 1, UnitPrice: 12000}, 12000, 0, 0},
+		{"free", Line{Kind: LinePart, Quantity: 2, UnitPrice: 0}, 0, 0, 23},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := []Line{tt.line}
+			rates.charge(lines, 1)
+			if lines[0].Net != tt.net || lines[0].Tax != tt.tax || lines[0].TaxRate != tt.rate {
+				t.Errorf("charged net %d, tax %d at %v%%, want net %d, tax %d at %v%%",
+					lines[0].Net, lines[0].Tax, lines[0].TaxRate, tt.net, tt.tax, tt.rate)
+			}
+		})
+	}
+}
+
+// TestTotals - tax is rounded on each line and then summed, so the total tax can differ from the
+// tax on the subtotal
+func TestTotals(t *testing.T) {
+	tests := []struct {
+		name          string
+		lines         []Line
+		subtotal, tax int64
+	}{
+		{"none", nil, 0, 0},
+		{"one", []Line{{Net: 1000, Tax: 230}}, 1000, 230},
+		{"rounded per line", []Line{{Net: 150, Tax: 35}, {Net: 150, Tax: 35}, {Net: 150, Tax: 35}}, 450, 105},
+		{"mixed rates", []Line{{Net: 5997, Tax: 1379}, {Net: 1485, Tax: 200}, {Net: 12000, Tax: 0}}, 19482, 1579},
+	}
+	for _, tt := range tests {
+		subtotal, tax := totals(tt.lines)
+		if subtotal != tt.subtotal || tax != tt.tax {
+			t.Errorf("%s: totals = %d, %d, want %d, %d", tt.name, subtotal, tax, tt.subtotal, tt.tax)
+		}
+	}
+}
+
+func TestHours(t *testing.T) {
+	tests := []struct {
+		minutes int
+		want    float64
+	}{
+		{0, 0},
+		{15, 0.25},
+		{20, 0.33},
+		{50, 0.83},
+		{90, 1.5},
+		{481, 8.02},
+	}
+	for _, tt := range tests {
+		if got := hours(tt.minutes); got != tt.want {
+			t.Errorf("hours(%d) = %v, want %v", tt.minutes, got, tt.want)
+		}
+	}
+}
+
+func TestSequenceFormat(t *testing.T) {
+	tests := []struct {
+		seq    Sequence
+		number int
+		want   string
+	}{
+		{Sequence{Prefix: "INV-", Digits: 6}, 42, "INV-000042"},
+		{Sequence{Prefix: "Q-", Digits: 2}, 1234, "Q-1234"},
+		{Sequence{Digits: 0}, 7, "7"},
+	}
+	for _, tt := range tests {
+		if got := tt.seq.Format(tt.number); got != tt.want {
+			t.Errorf("%+v.Format(%d) = %q, want %q", tt.seq, tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestAmount(t *testing.T) {
+	tests := []struct {
+		value int64
+		want  string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{123450, "1234.50"},
+		{-1999, "-19.99"},
+	}
+	for _, tt := range tests {
+		if got := amount(tt.value); got != tt.want {
+			t.Errorf("amount(%d) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	issued := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	invoices := []Invoice{{
+		Kind: KindInvoice, Status: StatusIssued, Number: "INV-000001", Currency: "EUR",
+		IssuedAt: issued, DueAt: issued.AddDate(0, 0, 30), Reference: "JOB-7",
+		Lines: []Line{
+			{Kind: LineLabour, Description: "Work time", Quantity: 1.5, Unit: "hour", UnitPrice: 6000, TaxRate: 13.5, Net: 9000, Tax: 1215},
+			{Kind: LinePart, Description: "Seal, 40mm", Quantity: 2, Unit: "each", UnitPrice: 450, TaxRate: 23, Net: 900, Tax: 207},
+		},
+	}}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, invoices); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || len(records[0]) != len(csvHeader) {
+		t.Fatalf("got %d records of %d columns", len(records), len(records[0]))
+	}
+	want := []string{"INV-000001", "invoice", "issued", "2026-03-02", "2026-04-01", "", "JOB-7", "EUR",
+		"2", "part", "Seal, 40mm", "2", "each", "4.50", "9.00", "23", "2.07", "11.07"}
+	for i, value := range want {
+		if records[2][i] != value {
+			t.Errorf("%s = %q, want %q", csvHeader[i], records[2][i], value)
+		}
+	}
+}
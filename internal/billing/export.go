@@ -0,0 +1,244 @@
+package billing
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvHeader - the columns of the CSV export, one row per line of each quote or invoice
+var csvHeader = []string{
+	"number", "kind", "status", "issued", "due", "customer", "reference", "currency",
+	"line", "kind of line", "description", "quantity", "unit", "unit price", "net", "tax rate", "tax", "gross",
+}
+
+// WriteCSV - writes quotes or invoices as CSV for import into an accounting system, one row for each
+// of their lines with amounts as decimals
+func WriteCSV(w io.Writer, invoices []Invoice) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, invoice := range invoices {
+		customerName := ""
+		if invoice.Customer != nil {
+			customerName = invoice.Customer.Name
+		}
+		for i, line := range invoice.Lines {
+			record := []string{
+				invoice.Number,
+				string(invoice.Kind),
+				string(invoice.Status),
+				invoice.IssuedAt.Format("2006-01-02"),
+				invoice.DueAt.Format("2006-01-02"),
+				customerName,
+				invoice.Reference,
+				invoice.Currency,
+				strconv.Itoa(i + 1),
+				string(line.Kind),
+				line.Description,
+				strconv.FormatFloat(line.Quantity, 'f', -1, 64),
+				line.Unit,
+				amount(line.UnitPrice),
+				amount(line.Net),
+				strconv.FormatFloat(line.TaxRate, 'f', -1, 64),
+				amount(line.Tax),
+				amount(line.Net + line.Tax),
+			}
+			if err := out.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// UBL 2.1 namespaces
+const (
+	ublInvoice   = "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+	ublAggregate = "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+	ublBasic     = "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+)
+
+type ublDocument struct {
+	XMLName              xml.Name         `xml:"Invoice"`
+	Xmlns                string           `xml:"xmlns,attr"`
+	Cac                  string           `xml:"xmlns:cac,attr"`
+	Cbc                  string           `xml:"xmlns:cbc,attr"`
+	UBLVersionID         string           `xml:"cbc:UBLVersionID"`
+	CustomizationID      string           `xml:"cbc:CustomizationID"`
+	ID                   string           `xml:"cbc:ID"`
+	IssueDate            string           `xml:"cbc:IssueDate"`
+	DueDate              string           `xml:"cbc:DueDate"`
+	InvoiceTypeCode      string           `xml:"cbc:InvoiceTypeCode"`
+	DocumentCurrencyCode string           `xml:"cbc:DocumentCurrencyCode"`
+	BuyerReference       string           `xml:"cbc:BuyerReference,omitempty"`
+	Supplier             ublParty         `xml:"cac:AccountingSupplierParty>cac:Party"`
+	Customer             ublParty         `xml:"cac:AccountingCustomerParty>cac:Party"`
+	TaxTotal             ublTaxTotal      `xml:"cac:TaxTotal"`
+	MonetaryTotal        ublMonetaryTotal `xml:"cac:LegalMonetaryTotal"`
+	Lines                []ublLine        `xml:"cac:InvoiceLine"`
+}
+
+type ublParty struct {
+	Name      string      `xml:"cac:PartyName>cbc:Name"`
+	Address   ublAddress  `xml:"cac:PostalAddress"`
+	TaxScheme *ublCompany `xml:"cac:PartyTaxScheme,omitempty"`
+	LegalName string      `xml:"cac:PartyLegalEntity>cbc:RegistrationName"`
+}
+
+type ublAddress struct {
+	Lines   []string    `xml:"cac:AddressLine>cbc:Line"`
+	Country *ublCountry `xml:"cac:Country,omitempty"`
+}
+
+type ublCountry struct {
+	Code string `xml:"cbc:IdentificationCode"`
+}
+
+type ublCompany struct {
+	CompanyID string `xml:"cbc:CompanyID"`
+	TaxScheme string `xml:"cac:TaxScheme>cbc:ID"`
+}
+
+type ublAmount struct {
+	Currency string `xml:"currencyID,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type ublTaxTotal struct {
+	TaxAmount ublAmount        `xml:"cbc:TaxAmount"`
+	Subtotals []ublTaxSubtotal `xml:"cac:TaxSubtotal"`
+}
+
+type ublTaxSubtotal struct {
+	TaxableAmount ublAmount      `xml:"cbc:TaxableAmount"`
+	TaxAmount     ublAmount      `xml:"cbc:TaxAmount"`
+	Category      ublTaxCategory `xml:"cac:TaxCategory"`
+}
+
+type ublTaxCategory struct {
+	ID        string `xml:"cbc:ID"`
+	Percent   string `xml:"cbc:Percent"`
+	TaxScheme string `xml:"cac:TaxScheme>cbc:ID"`
+}
+
+type ublMonetaryTotal struct {
+	LineExtensionAmount ublAmount `xml:"cbc:LineExtensionAmount"`
+	TaxExclusiveAmount  ublAmount `xml:"cbc:TaxExclusiveAmount"`
+	TaxInclusiveAmount  ublAmount `xml:"cbc:TaxInclusiveAmount"`
+	PayableAmount       ublAmount `xml:"cbc:PayableAmount"`
+}
+
+type ublLine struct {
+	ID                  string         `xml:"cbc:ID"`
+	Quantity            ublQuantity    `xml:"cbc:InvoicedQuantity"`
+	LineExtensionAmount ublAmount      `xml:"cbc:LineExtensionAmount"`
+	Name                string         `xml:"cac:Item>cbc:Name"`
+	TaxCategory         ublTaxCategory `xml:"cac:Item>cac:ClassifiedTaxCategory"`
+	Price               ublAmount      `xml:"cac:Price>cbc:PriceAmount"`
+}
+
+type ublQuantity struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// UBL - an invoice as a UBL 2.1 (EN 16931) XML document for e-invoicing and accounting systems. Lines
+// are grouped into a tax subtotal for each rate charged, standard rated (S) or zero rated (Z).
+func UBL(invoice Invoice, seller Seller) ([]byte, error) {
+	if invoice.Kind != KindInvoice {
+		return nil, ErrNotInvoice
+	}
+	money := func(value int64) ublAmount {
+		return ublAmount{Currency: invoice.Currency, Value: amount(value)}
+	}
+
+	doc := ublDocument{
+		Xmlns:                ublInvoice,
+		Cac:                  ublAggregate,
+		Cbc:                  ublBasic,
+		UBLVersionID:         "2.1",
+		CustomizationID:      "urn:cen.eu:en16931:2017",
+		ID:                   invoice.Number,
+		IssueDate:            invoice.IssuedAt.Format("2006-01-02"),
+		DueDate:              invoice.DueAt.Format("2006-01-02"),
+		InvoiceTypeCode:      "380",
+		DocumentCurrencyCode: invoice.Currency,
+		BuyerReference:       invoice.Reference,
+		Supplier: ublParty{
+			Name:      seller.Name,
+			Address:   ublAddress{Lines: seller.Address},
+			LegalName: seller.Name,
+		},
+		MonetaryTotal: ublMonetaryTotal{
+			LineExtensionAmount: money(invoice.Subtotal),
+			TaxExclusiveAmount:  money(invoice.Subtotal),
+			TaxInclusiveAmount:  money(invoice.Total),
+			PayableAmount:       money(invoice.Total),
+		},
+	}
+	if seller.Country != "" {
+		doc.Supplier.Address.Country = &ublCountry{Code: seller.Country}
+	}
+	if seller.TaxNumber != "" {
+		doc.Supplier.TaxScheme = &ublCompany{CompanyID: seller.TaxNumber, TaxScheme: "VAT"}
+	}
+	if c := invoice.Customer; c != nil {
+		doc.Customer = ublParty{Name: c.Name, LegalName: c.Name}
+		for _, line := range strings.Split(c.BillingAddress, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				doc.Customer.Address.Lines = append(doc.Customer.Address.Lines, line)
+			}
+		}
+	}
+
+	doc.TaxTotal.TaxAmount = money(invoice.Tax)
+	// net and tax totals for each rate, in the order the rates first appear
+	var rates []float64
+	nets, taxes := map[float64]int64{}, map[float64]int64{}
+	for i, line := range invoice.Lines {
+		if _, ok := nets[line.TaxRate]; !ok {
+			rates = append(rates, line.TaxRate)
+		}
+		nets[line.TaxRate] += line.Net
+		taxes[line.TaxRate] += line.Tax
+
+		unitCode := "C62"
+		if line.Kind == LineLabour {
+			unitCode = "HUR"
+		}
+		doc.Lines = append(doc.Lines, ublLine{
+			ID:                  strconv.Itoa(i + 1),
+			Quantity:            ublQuantity{UnitCode: unitCode, Value: strconv.FormatFloat(line.Quantity, 'f', -1, 64)},
+			LineExtensionAmount: money(line.Net),
+			Name:                line.Description,
+			TaxCategory:         taxCategory(line.TaxRate),
+			Price:               money(line.UnitPrice),
+		})
+	}
+	for _, rate := range rates {
+		doc.TaxTotal.Subtotals = append(doc.TaxTotal.Subtotals, ublTaxSubtotal{
+			TaxableAmount: money(nets[rate]),
+			TaxAmount:     money(taxes[rate]),
+			Category:      taxCategory(rate),
+		})
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+func taxCategory(percent float64) ublTaxCategory {
+	id := "S"
+	if percent == 0 {
+		id = "Z"
+	}
+	return ublTaxCategory{ID: id, Percent: strconv.FormatFloat(percent, 'f', -1, 64), TaxScheme: "VAT"}
+}
@@ -0,0 +1,186 @@
+package billing
+
+import (
+	"errors"
+
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/Open-FiSE/go-rest-api/internal/timesheet"
+	"github.com/jinzhu/gorm"
+)
+
+// errors returned when setting the price list
+var (
+	ErrInvalidLabourRate = errors.New("labour rate needs a Type of travel, work or wait and an HourlyRate of zero or more")
+	ErrInvalidWorkPrice  = errors.New("work price needs a known WorkType and a Price of zero or more")
+	ErrInvalidTaxRule    = errors.New("tax rule needs a Percent from 0 to 100 and a Kind of labour, part, work or none")
+)
+
+// LabourRate - the hourly rate charged for a type of time
+type LabourRate struct {
+	gorm.Model
+	Type       timesheet.EntryType `gorm:"unique_index" json:"type"`
+	HourlyRate int64               `json:"hourlyRate"`
+}
+
+// WorkPrice - the fixed price charged for each completed item of equipment of a work type
+type WorkPrice struct {
+	gorm.Model
+	WorkType    customer.WorkType `gorm:"unique_index" json:"workType"`
+	Description string            `json:"description"`
+	Price       int64             `json:"price"`
+}
+
+// TaxRule - the tax charged on a kind of line, for one customer when CustomerID is set. A rule with
+// no Kind covers every kind of line. The most specific rule applies: one for the customer and the
+// kind of line, then the customer, then the kind of line, then the rule with neither. Lines no rule
+// covers are not taxed.
+type TaxRule struct {
+	gorm.Model
+	Name       string   `json:"name"`
+	Kind       LineKind `json:"kind"`
+	CustomerID *uint    `json:"customerId"`
+	Percent    float64  `json:"percent"`
+}
+
+// Rates - the price list: labour rates, fixed prices for work types and the tax rules
+type Rates struct {
+	Labour []LabourRate `json:"labour"`
+	Work   []WorkPrice  `json:"work"`
+	Tax    []TaxRule    `json:"tax"`
+}
+
+// GetRates - retrieves the price list
+func (s *Service) GetRates() (Rates, error) {
+	var rates Rates
+	if result := s.DB.Order("type").Find(&rates.Labour); result.Error != nil {
+		return Rates{}, result.Error
+	}
+	if result := s.DB.Order("work_type").Find(&rates.Work); result.Error != nil {
+		return Rates{}, result.Error
+	}
+	if result := s.DB.Order("id").Find(&rates.Tax); result.Error != nil {
+		return Rates{}, result.Error
+	}
+	return rates, nil
+}
+
+// SetLabourRate - sets the hourly rate for a type of time
+func (s *Service) SetLabourRate(rate LabourRate) (LabourRate, error) {
+	switch rate.Type {
+	case timesheet.EntryTravel, timesheet.EntryWork, timesheet.EntryWait:
+	default:
+		return LabourRate{}, ErrInvalidLabourRate
+	}
+	if rate.HourlyRate < 0 {
+		return LabourRate{}, ErrInvalidLabourRate
+	}
+	var existing LabourRate
+	if result := s.DB.Where(LabourRate{Type: rate.Type}).FirstOrInit(&existing); result.Error != nil {
+		return LabourRate{}, result.Error
+	}
+	existing.HourlyRate = rate.HourlyRate
+	if result := s.DB.Save(&existing); result.Error != nil {
+		return LabourRate{}, result.Error
+	}
+	return existing, nil
+}
+
+// SetWorkPrice - sets the fixed price for a work type
+func (s *Service) SetWorkPrice(price WorkPrice) (WorkPrice, error) {
+	switch price.WorkType {
+	case customer.WorkCalibration, customer.WorkRepair, customer.WorkMaintenance, customer.WorkInstallation,
+		customer.WorkInspection, customer.WorkOther:
+	default:
+		return WorkPrice{}, ErrInvalidWorkPrice
+	}
+	if price.Price < 0 {
+		return WorkPrice{}, ErrInvalidWorkPrice
+	}
+	var existing WorkPrice
+	if result := s.DB.Where(WorkPrice{WorkType: price.WorkType}).FirstOrInit(&existing); result.Error != nil {
+		return WorkPrice{}, result.Error
+	}
+	existing.Description, existing.Price = price.Description, price.Price
+	if result := s.DB.Save(&existing); result.Error != nil {
+		return WorkPrice{}, result.Error
+	}
+	return existing, nil
+}
+
+// SetTaxRule - sets the tax rule for a kind of line and customer, replacing the rule already set for
+// them
+func (s *Service) SetTaxRule(rule TaxRule) (TaxRule, error) {
+	switch rule.Kind {
+	case "", LineLabour, LinePart, LineWork:
+	default:
+		return TaxRule{}, ErrInvalidTaxRule
+	}
+	if rule.Percent < 0 || rule.Percent > 100 {
+		return TaxRule{}, ErrInvalidTaxRule
+	}
+	var existing TaxRule
+	query := s.DB.Where("kind = ?", rule.Kind)
+	if rule.CustomerID != nil {
+		query = query.Where("customer_id = ?", *rule.CustomerID)
+	} else {
+		query = query.Where("customer_id IS NULL")
+	}
+	if result := query.First(&existing); result.Error != nil && !gorm.IsRecordNotFoundError(result.Error) {
+		return TaxRule{}, result.Error
+	}
+	existing.Name, existing.Kind, existing.CustomerID, existing.Percent = rule.Name, rule.Kind, rule.CustomerID, rule.Percent
+	if result := s.DB.Save(&existing); result.Error != nil {
+		return TaxRule{}, result.Error
+	}
+	return existing, nil
+}
+
+// DeleteTaxRule - removes a tax rule by ID
+func (s *Service) DeleteTaxRule(ID uint) error {
+	if result := s.DB.Delete(&TaxRule{}, ID); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (r Rates) labour(entryType timesheet.EntryType) (LabourRate, bool) {
+	for _, rate := range r.Labour {
+		if rate.Type == entryType {
+			return rate, true
+		}
+	}
+	return LabourRate{}, false
+}
+
+func (r Rates) work(workType customer.WorkType) (WorkPrice, bool) {
+	for _, price := range r.Work {
+		if price.WorkType == workType {
+			return price, true
+		}
+	}
+	return WorkPrice{}, false
+}
+
+// tax - the percentage of tax charged on a kind of line billed to a customer
+func (r Rates) tax(kind LineKind, customerID uint) float64 {
+	best, percent := -1, 0.0
+	for _, rule := range r.Tax {
+		score := 0
+		if rule.CustomerID != nil {
+			if *rule.CustomerID != customerID {
+				continue
+			}
+			score += 2
+		}
+		if rule.Kind != "" {
+			if rule.Kind != kind {
+				continue
+			}
+			score++
+		}
+		if score > best {
+			best, percent = score, rule.Percent
+		}
+	}
+	return percent
+}
@@ -0,0 +1,188 @@
+package billing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Open-FiSE/go-rest-api/internal/pdf"
+)
+
+// page layout, in points
+const (
+	margin     = 50.0
+	lineHeight = 14.0
+	bodySize   = 10.0
+	tableSize  = 9.0
+)
+
+// line table columns: heading and right edge, the description is left aligned at the margin
+var columns = []struct {
+	heading string
+	right   float64
+}{
+	{"Qty", 350},
+	{"Unit price", 420},
+	{"Tax", 465},
+	{"Amount", pdf.PageWidth - margin},
+}
+
+// descriptionWidth - the width descriptions wrap at, clear of the quantity column
+const descriptionWidth = 250.0
+
+// sheet - writes a quote or invoice top to bottom, starting new pages as it fills them
+type sheet struct {
+	doc     *pdf.Document
+	page    *pdf.Page
+	y       float64
+	invoice Invoice
+	seller  Seller
+}
+
+// Render - lays a quote or invoice out as a PDF document
+func Render(invoice Invoice, seller Seller) *pdf.Document {
+	s := &sheet{doc: pdf.New(title(invoice) + " " + invoice.Number), invoice: invoice, seller: seller}
+	s.newPage()
+
+	s.text(pdf.Bold, "Bill to")
+	if c := invoice.Customer; c != nil {
+		s.text(pdf.Regular, c.Name)
+		for _, line := range strings.Split(c.BillingAddress, "\n") {
+			s.text(pdf.Regular, strings.TrimSpace(line))
+		}
+	}
+	s.y -= lineHeight / 2
+	if invoice.Reference != "" {
+		s.text(pdf.Regular, "Reference: "+invoice.Reference)
+	}
+	s.text(pdf.Regular, fmt.Sprintf("Booking: %d", invoice.BookingID))
+	s.y -= lineHeight
+
+	s.tableHeader()
+	for _, line := range invoice.Lines {
+		s.row(line)
+	}
+
+	s.y -= lineHeight / 2
+	s.ensure(4 * lineHeight)
+	s.total("Subtotal", invoice.Subtotal, pdf.Regular)
+	s.total("Tax", invoice.Tax, pdf.Regular)
+	s.total("Total "+invoice.Currency, invoice.Total, pdf.Bold)
+
+	s.y -= lineHeight
+	if invoice.Kind == KindQuote {
+		s.text(pdf.Regular, "This quote is valid until "+invoice.DueAt.Format("2 January 2006")+".")
+	} else {
+		s.text(pdf.Regular, "Payment is due by "+invoice.DueAt.Format("2 January 2006")+". Please quote "+invoice.Number+" with your payment.")
+	}
+	if invoice.Status == StatusVoid {
+		s.y -= lineHeight
+		s.text(pdf.Bold, "VOID: "+invoice.VoidReason)
+	}
+	return s.doc
+}
+
+// newPage - starts a page with the letterhead and the document's number and dates
+func (s *sheet) newPage() {
+	s.page = s.doc.AddPage()
+	y := pdf.PageHeight - margin
+	s.page.Text(margin, y, pdf.Bold, 16, s.seller.Name)
+	for _, line := range s.seller.Address {
+		y -= lineHeight
+		s.page.Text(margin, y, pdf.Regular, bodySize, line)
+	}
+	if s.seller.TaxNumber != "" {
+		y -= lineHeight
+		s.page.Text(margin, y, pdf.Regular, bodySize, "VAT number: "+s.seller.TaxNumber)
+	}
+	y -= 2 * lineHeight
+	s.page.Text(margin, y, pdf.Bold, 20, title(s.invoice))
+	details := []string{
+		"Number: " + s.invoice.Number,
+		"Date: " + s.invoice.IssuedAt.Format("2 January 2006"),
+	}
+	for i, detail := range details {
+		s.right(pdf.PageWidth-margin, y-float64(i)*lineHeight, pdf.Regular, bodySize, detail)
+	}
+	y -= float64(len(details)) * lineHeight
+	s.page.Line(margin, y, pdf.PageWidth-margin, y, 1)
+	s.y = y - 1.5*lineHeight
+}
+
+// ensure - starts a new page unless height points fit above the bottom margin
+func (s *sheet) ensure(height float64) {
+	if s.y-height < margin {
+		s.newPage()
+	}
+}
+
+func (s *sheet) text(font pdf.Font, text string) {
+	if text == "" {
+		return
+	}
+	for _, line := range pdf.Wrap(text, font, bodySize, pdf.PageWidth-2*margin) {
+		s.ensure(lineHeight)
+		s.page.Text(margin, s.y, font, bodySize, line)
+		s.y -= lineHeight
+	}
+}
+
+// right - writes text ending at x
+func (s *sheet) right(x, y float64, font pdf.Font, size float64, text string) {
+	s.page.Text(x-pdf.TextWidth(text, font, size), y, font, size, text)
+}
+
+func (s *sheet) tableHeader() {
+	s.ensure(2 * lineHeight)
+	s.page.Rect(margin, s.y-4, pdf.PageWidth-2*margin, lineHeight, 0.88)
+	s.page.Text(margin+2, s.y, pdf.Bold, tableSize, "Description")
+	for _, col := range columns {
+		s.right(col.right-2, s.y, pdf.Bold, tableSize, col.heading)
+	}
+	s.y -= lineHeight
+}
+
+func (s *sheet) row(line Line) {
+	descriptions := pdf.Wrap(line.Description, pdf.Regular, tableSize, descriptionWidth)
+	// a continued table repeats its header at the top of the next page
+	if s.y-float64(len(descriptions))*lineHeight < margin {
+		s.newPage()
+		s.tableHeader()
+	}
+	quantity := strconv.FormatFloat(line.Quantity, 'f', -1, 64)
+	if line.Unit != "" && line.Unit != "each" {
+		quantity += " " + line.Unit
+	}
+	cells := []string{quantity, amount(line.UnitPrice), strconv.FormatFloat(line.TaxRate, 'f', -1, 64) + "%", amount(line.Net)}
+	for i, cell := range cells {
+		s.right(columns[i].right-2, s.y, pdf.Regular, tableSize, cell)
+	}
+	for _, description := range descriptions {
+		s.page.Text(margin+2, s.y, pdf.Regular, tableSize, description)
+		s.y -= lineHeight
+	}
+	s.page.Line(margin, s.y+lineHeight-4, pdf.PageWidth-margin, s.y+lineHeight-4, 0.3)
+}
+
+func (s *sheet) total(label string, value int64, font pdf.Font) {
+	s.right(columns[2].right-2, s.y, font, bodySize, label)
+	s.right(columns[3].right-2, s.y, font, bodySize, amount(value))
+	s.y -= lineHeight
+}
+
+// title - what a quote or invoice is headed
+func title(invoice Invoice) string {
+	if invoice.Kind == KindQuote {
+		return "Quote"
+	}
+	return "Invoice"
+}
+
+// amount - formats an amount in minor units as a decimal ("1234.50")
+func amount(value int64) string {
+	sign := ""
+	if value < 0 {
+		sign, value = "-", -value
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, value/100, value%100)
+}
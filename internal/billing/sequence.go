@@ -0,0 +1,115 @@
+package billing
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrInvalidSequence - returned when a sequence is not for quotes or invoices, or would reuse numbers
+var ErrInvalidSequence = errors.New("sequence must be for quote or invoice and can only move forward")
+
+// Sequence - the numbering of quotes or invoices. Numbers are taken in the transaction that saves the
+// quote or invoice, so every number is used once and none are skipped.
+type Sequence struct {
+	gorm.Model
+	Kind   Kind   `gorm:"unique_index" json:"kind"`
+	Prefix string `json:"prefix"`
+	// NextNumber - the number the next quote or invoice takes
+	NextNumber int `json:"nextNumber"`
+	// Digits - the number is padded with zeros to this many digits
+	Digits int `json:"digits"`
+}
+
+// TableName - the table sequences are stored in
+func (Sequence) TableName() string {
+	return "billing_sequences"
+}
+
+// default numbering of sequences that have not been set
+var defaultPrefixes = map[Kind]string{KindQuote: "Q-", KindInvoice: "INV-"}
+
+const defaultDigits = 6
+
+// Format - the document number a sequence gives a number
+func (seq Sequence) Format(number int) string {
+	return fmt.Sprintf("%s%0*d", seq.Prefix, seq.Digits, number)
+}
+
+// GetSequences - retrieves the quote and invoice sequences
+func (s *Service) GetSequences() ([]Sequence, error) {
+	var sequences []Sequence
+	for _, kind := range []Kind{KindQuote, KindInvoice} {
+		seq, err := sequence(s.DB, kind)
+		if err != nil {
+			return sequences, err
+		}
+		sequences = append(sequences, seq)
+	}
+	return sequences, nil
+}
+
+// SetSequence - changes the prefix, padding or next number of a sequence, leaving those not given as
+// they are. The next number cannot be moved back, so numbers already issued are never reused.
+func (s *Service) SetSequence(kind Kind, newSequence Sequence) (Sequence, error) {
+	if _, ok := defaultPrefixes[kind]; !ok || newSequence.Digits < 0 || newSequence.Digits > 12 {
+		return Sequence{}, ErrInvalidSequence
+	}
+	seq, err := sequence(s.DB, kind)
+	if err != nil {
+		return Sequence{}, err
+	}
+	updates := map[string]interface{}{}
+	if newSequence.Prefix != "" {
+		updates["prefix"] = newSequence.Prefix
+	}
+	if newSequence.Digits != 0 {
+		updates["digits"] = newSequence.Digits
+	}
+	query := s.DB.Model(&Sequence{}).Where("id = ?", seq.ID)
+	if newSequence.NextNumber != 0 {
+		// guarded so a number taken since the sequence was read is not handed out again
+		query = query.Where("next_number <= ?", newSequence.NextNumber)
+		updates["next_number"] = newSequence.NextNumber
+	}
+	if len(updates) == 0 {
+		return seq, nil
+	}
+	result := query.Updates(updates)
+	if result.Error != nil {
+		return Sequence{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return Sequence{}, ErrInvalidSequence
+	}
+	return sequence(s.DB, kind)
+}
+
+// sequence - retrieves a sequence, starting it from one when it has not been used
+func sequence(db *gorm.DB, kind Kind) (Sequence, error) {
+	var seq Sequence
+	if result := db.Where(Sequence{Kind: kind}).
+		Attrs(Sequence{Prefix: defaultPrefixes[kind], NextNumber: 1, Digits: defaultDigits}).
+		FirstOrCreate(&seq); result.Error != nil {
+		return Sequence{}, result.Error
+	}
+	return seq, nil
+}
+
+// nextNumber - takes the next number from a sequence. Incrementing the sequence first holds its row
+// until tx ends, so concurrent quotes or invoices are numbered one after the other.
+func nextNumber(tx *gorm.DB, kind Kind) (string, error) {
+	if _, err := sequence(tx, kind); err != nil {
+		return "", err
+	}
+	if result := tx.Model(&Sequence{}).Where("kind = ?", kind).
+		UpdateColumn("next_number", gorm.Expr("next_number + 1")); result.Error != nil {
+		return "", result.Error
+	}
+	var seq Sequence
+	if result := tx.Where("kind = ?", kind).First(&seq); result.Error != nil {
+		return "", result.Error
+	}
+	return seq.Format(seq.NextNumber - 1), nil
+}
@@ -1,25 +1,25 @@
 package booking
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/Open-FiSE/go-rest-api/internal/engineer"
+	"github.com/Open-FiSE/go-rest-api/internal/events"
 	"github.com/jinzhu/gorm"
 )
 
 type BookService struct {
 	DB *gorm.DB
+	// Events - where changes to bookings are published, none when nil
+	Events *events.Broker
+	// pending - the changes made within Transaction, published once it commits
+	pending *[]bookingChange
 }
 
-// Job has 1-1 with booking, Job can consists of 0-* equipment
-type Job struct {
-	ID              uint
-	SerialNo        string
-	InstrumentModel string
-	Manufacturer    string
-	Customer        Customer `gorm:"foreignKey:ID"`
-}
-
-// Booking has one Job association and one Customer
+// Booking - a visit booked for a customer, optionally against one of their jobs
 type Booking struct {
 	gorm.Model
 	// bookingNo uint
@@ -28,30 +28,78 @@ type Booking struct {
 	Location      string
 	StartDateTime time.Time
 	EndDateTime   time.Time
-	Customer      Customer `gorm:"foreignKey:ID"` //Embeded Type
-	Job           Job      `gorm:"foreignKey:ID"` //Embeded Type
-}
-
-// Customer may have 0-* bookings
-type Customer struct {
-	ID      int
-	Name    string
-	Booking []Booking `gorm:"foreignKey:ID"`
+	// the customer, job, site and contact are referenced by ID, posting a booking never creates or
+	// edits them. A booking at a site takes the site's address as its Location and its TimeZone.
+	CustomerID *uint
+	Customer   *customer.Customer `gorm:"association_autoupdate:false;association_autocreate:false;association_save_reference:false"`
+	JobID      *uint
+	Job        *customer.Job `gorm:"association_autoupdate:false;association_autocreate:false;association_save_reference:false"`
+	SiteID     *uint
+	Site       *customer.Site `gorm:"association_autoupdate:false;association_autocreate:false;association_save_reference:false"`
+	ContactID  *uint
+	Contact    *customer.Contact `gorm:"association_autoupdate:false;association_autocreate:false;association_save_reference:false"`
+	// engineers are assigned by reference only, posting a booking never creates or edits an engineer
+	Engineers []engineer.Engineer `gorm:"many2many:booking_engineers;association_autoupdate:false;association_autocreate:false"`
+	// the instruments worked on during the visit, assigned by reference like engineers. A booking for a
+	// job on a registered instrument includes that instrument.
+	Instruments []customer.Instrument `gorm:"many2many:booking_instruments;association_autoupdate:false;association_autocreate:false"`
+	// a recurring booking repeats from StartDateTime by an RFC 5545 RRULE ("FREQ=MONTHLY;INTERVAL=6"),
+	// skipping its ExceptionDates
+	RRule          string `gorm:"column:r_rule"`
+	ExceptionDates []ExceptionDate
+	// an occurrence edited on its own is saved as a booking pointing back at its series, with
+	// RecurrenceID holding the start time the occurrence originally had
+	SeriesID     uint
+	RecurrenceID *time.Time
+	// the iCalendar UID of a booking imported from a calendar, used to spot repeat imports
+	UID string `gorm:"index"`
+	// Status only changes through TransitionStatus, which records each change in the StatusChange history
+	Status Status `gorm:"default:'requested';index"`
+	// times are stored in UTC. TimeZone is the site's IANA zone, recurring bookings repeat at the
+	// same wall-clock time in it, and LocalStart/LocalEnd carry site wall-clock times ("2026-03-29T09:00")
+	// in and out of the API
+	TimeZone   string
+	LocalStart string `gorm:"-"`
+	LocalEnd   string `gorm:"-"`
+	// set when the customer signs the booking off, after which it can no longer be changed
+	SignedAt *time.Time
 }
 
 // BookingService - the interface for our boooking service
 type BookingService interface {
 	GetBooking(ID uint) (Booking, error)
-	PostBooking(booking Booking) (Booking, error)
-	UpdateBooking(ID uint, newBooking Booking) (Booking error)
+	PostBooking(booking Booking, override bool) (Booking, error)
+	CheckBooking(booking Booking, override bool) (Booking, error)
+	UpdateBooking(ID uint, newBooking Booking, override bool) (Booking, error)
+	ReplaceBooking(ID uint, newBooking Booking, override bool) (Booking, error)
+	Transaction(fn func(bookings *BookService) error) error
 	DeleteBookings(ID uint) error
-	GetAllBookings() ([]Booking, error)
+	GetAllBookings(statuses ...Status) ([]Booking, error)
+	AssignEngineers(ID uint, engineerIDs []uint, override bool) (Booking, error)
+	GetBookingsByEngineer(engineerID uint, from, to time.Time) ([]Booking, error)
+	GetEngineerOccurrences(engineerID uint, from, to time.Time) ([]Booking, error)
+	GetBookingsByCustomer(customerID uint, from, to time.Time) ([]Booking, error)
+	GetUnassignedBookings(from, to time.Time) ([]Booking, error)
+	AssignInstruments(ID uint, instrumentIDs []uint) (Booking, error)
+	GetBookingsByInstrument(instrumentID uint) ([]Booking, error)
+	GetBookingByUID(UID string) (Booking, error)
+	GetOccurrences(from, to time.Time) ([]Booking, error)
+	UpdateOccurrence(ID uint, occurrence time.Time, scope Scope, newBooking Booking, override bool) (Booking, error)
+	CheckOccurrence(master Booking, occurrence time.Time, newBooking Booking, override bool) (Booking, error)
+	DeleteOccurrence(ID uint, occurrence time.Time, scope Scope) error
+	SetExceptionDates(ID uint, dates []time.Time) error
+	TransitionStatus(ID uint, to Status, changedBy string, reason string) (Booking, error)
+	GetStatusHistory(ID uint) ([]StatusChange, error)
+	GetSignOff(bookingID uint) (SignOff, error)
+	SignOffBooking(ID uint, signOff SignOff, changedBy string) (Booking, error)
 }
 
-// NewService - takes in a pointer to the DB & returns a pointer to a new booking service
-func NewService(db *gorm.DB) *BookService {
+// NewService - takes in a pointer to the DB and the broker changes are published to & returns a
+// pointer to a new booking service
+func NewService(db *gorm.DB, broker *events.Broker) *BookService {
 	return &BookService{
-		DB: db,
+		DB:     db,
+		Events: broker,
 	}
 }
 
@@ -59,47 +107,539 @@ func NewService(db *gorm.DB) *BookService {
 func (s *BookService) GetBooking(ID uint) (Booking, error) {
 	var booking Booking // define a new booking variable
 	// retireive the 1st booking from the DB with the passed in Id & populate the booking var with the result obj
-	if result := s.DB.First(&booking, ID); result.Error != nil {
+	if result := s.DB.Preload("Customer").Preload("Job").Preload("Site").Preload("Contact").Preload("Engineers").Preload("Instruments").Preload("ExceptionDates").First(&booking, ID); result.Error != nil {
+		return Booking{}, result.Error
+	}
+	return booking, nil
+}
+
+// GetBookingByUID - retrieves the series or single booking imported with the iCalendar UID
+func (s *BookService) GetBookingByUID(UID string) (Booking, error) {
+	var booking Booking
+	if result := s.DB.Preload("Customer").Preload("Job").Preload("Site").Preload("Contact").Preload("Engineers").Preload("Instruments").Preload("ExceptionDates").
+		Where("uid = ? AND recurrence_id IS NULL", UID).First(&booking); result.Error != nil {
 		return Booking{}, result.Error
 	}
 	return booking, nil
 }
 
-func (s *BookService) PostBooking(booking Booking) (Booking, error) {
+// PostBooking - adds a new booking, rejecting it with a *ConflictError if it overlaps an existing
+// booking unless override is set. A new booking is never signed off or part of a series, whatever
+// the request says.
+func (s *BookService) PostBooking(booking Booking, override bool) (Booking, error) {
+	return s.create(newBooking(booking), override)
+}
+
+// CheckBooking - validates a new booking as PostBooking does without saving it, returning the booking
+// as it would be saved
+func (s *BookService) CheckBooking(booking Booking, override bool) (Booking, error) {
+	return s.validate(newBooking(booking), override)
+}
+
+// newBooking - clears what a new booking can never be given: its identity, sign-off and series
+func newBooking(booking Booking) Booking {
+	booking.Model = gorm.Model{}
+	booking.SignedAt = nil
+	booking.SeriesID, booking.RecurrenceID = 0, nil
+	return booking
+}
+
+// create - validates and saves a new booking as given, including the series fields of an occurrence
+// detached from its series
+func (s *BookService) create(booking Booking, override bool) (Booking, error) {
+	booking, err := s.validate(booking, override)
+	if err != nil {
+		return Booking{}, err
+	}
 	if result := s.DB.Save(&booking); result.Error != nil {
 		return Booking{}, result.Error
 	}
+	s.publish(events.Created, booking.ID)
+	return booking, nil
+}
+
+// validate - checks a new booking's references, times, status and recurrence rule and that it does
+// not overlap other bookings unless override is set, returning it with its references resolved,
+// its times normalised and its status defaulted
+func (s *BookService) validate(booking Booking, override bool) (Booking, error) {
+	engineers, err := s.resolveEngineers(booking.Engineers)
+	if err != nil {
+		return Booking{}, err
+	}
+	booking.Engineers = engineers
+	if err := s.resolveReferences(&booking); err != nil {
+		return Booking{}, err
+	}
+	if err := s.resolveInstruments(&booking); err != nil {
+		return Booking{}, err
+	}
+	if err := normaliseTimes(&booking, ""); err != nil {
+		return Booking{}, err
+	}
+	// new bookings start out requested, or confirmed when the slot has already been agreed
+	switch booking.Status {
+	case "":
+		booking.Status = StatusRequested
+	case StatusRequested, StatusConfirmed:
+	default:
+		return Booking{}, fmt.Errorf("%w: a new booking must be requested or confirmed", ErrInvalidStatus)
+	}
+	if booking.RRule != "" {
+		if _, err := booking.Rule(); err != nil {
+			return Booking{}, err
+		}
+	}
+	if err := s.checkConflicts(booking); err != nil {
+		var conflict *ConflictError
+		if !override || !errors.As(err, &conflict) {
+			return Booking{}, err
+		}
+	}
 	return booking, nil
 }
 
 // UpdateDocument - updates a booking by ID with new document info
-func (s *BookService) UpdateBooking(ID uint, newBooking Booking) (Booking, error) {
+func (s *BookService) UpdateBooking(ID uint, newBooking Booking, override bool) (Booking, error) {
+	return s.update(ID, newBooking, override, false)
+}
+
+// ReplaceBooking - updates a booking by ID as UpdateBooking does, except that its description,
+// recurrence rule and, unless it is at a site, location are replaced when newBooking has none, as
+// when a calendar client rewrites the booking's event without them
+func (s *BookService) ReplaceBooking(ID uint, newBooking Booking, override bool) (Booking, error) {
+	return s.update(ID, newBooking, override, true)
+}
+
+// update - updates a booking by ID, leaving the fields newBooking does not set as they are unless
+// replace is set
+func (s *BookService) update(ID uint, newBooking Booking, override bool, replace bool) (Booking, error) {
 	booking, err := s.GetBooking(ID)
 	if err != nil {
 		return Booking{}, err
 	}
+	if err := booking.editable(); err != nil {
+		return Booking{}, err
+	}
+	if newBooking.RRule != "" {
+		if _, err := newBooking.Rule(); err != nil {
+			return Booking{}, err
+		}
+	}
+	var jobInstrument *uint
+	if newBooking.CustomerID != nil || newBooking.JobID != nil || newBooking.SiteID != nil || newBooking.ContactID != nil {
+		refs := applyChanges(booking, newBooking)
+		// a booking moved to another site takes that site's address and zone unless new ones are given
+		if newBooking.SiteID != nil {
+			refs.Location, refs.TimeZone = newBooking.Location, newBooking.TimeZone
+		}
+		if err := s.resolveReferences(&refs); err != nil {
+			return Booking{}, err
+		}
+		newBooking.CustomerID, newBooking.SiteID = refs.CustomerID, refs.SiteID
+		newBooking.Location, newBooking.TimeZone = refs.Location, refs.TimeZone
+		// a booking moved to a job on a registered instrument picks up the instrument
+		if newBooking.JobID != nil && refs.Job.InstrumentID != nil {
+			jobInstrument = refs.Job.InstrumentID
+		}
+	}
+	if err := normaliseTimes(&newBooking, booking.TimeZone); err != nil {
+		return Booking{}, err
+	}
+	// gorm ignores zero values on Updates, so check conflicts against the merged booking window
+	merged := applyChanges(booking, newBooking)
+	replaced := map[string]interface{}{}
+	if replace {
+		merged.Description, merged.RRule = newBooking.Description, newBooking.RRule
+		replaced["description"], replaced["r_rule"] = merged.Description, merged.RRule
+		if merged.SiteID == nil {
+			merged.Location = newBooking.Location
+			replaced["location"] = merged.Location
+		}
+	}
+	if err := s.checkConflicts(merged); err != nil {
+		var conflict *ConflictError
+		if !override || !errors.As(err, &conflict) {
+			return Booking{}, err
+		}
+	}
+	// engineers and instruments are (re)assigned through AssignEngineers and AssignInstruments, exceptions through DeleteOccurrence and
+	// status through TransitionStatus, never as a side effect of an update
+	newBooking.Engineers = nil
+	newBooking.Instruments = nil
+	newBooking.ExceptionDates = nil
+	newBooking.Status = ""
+	newBooking.SignedAt = nil
+	// nor does an update move the booking in or out of a series, or change its identity
+	newBooking.Model = gorm.Model{}
+	newBooking.SeriesID, newBooking.RecurrenceID, newBooking.UID = 0, nil, ""
+	newBooking.Customer, newBooking.Job, newBooking.Site, newBooking.Contact = nil, nil, nil, nil
 	if result := s.DB.Model(&booking).Updates(newBooking); result.Error != nil {
 		return Booking{}, result.Error
 	}
+	if len(replaced) > 0 {
+		if result := s.DB.Model(&booking).Updates(replaced); result.Error != nil {
+			return Booking{}, result.Error
+		}
+	}
+	if jobInstrument != nil && !containsID(instrumentIDs(booking.Instruments), *jobInstrument) {
+		instrument := customer.Instrument{Model: gorm.Model{ID: *jobInstrument}}
+		if err := s.DB.Model(&booking).Association("Instruments").Append(instrument).Error; err != nil {
+			return Booking{}, err
+		}
+	}
+	s.publish(events.Updated, ID)
 	// return booking once it has been updated by gorm.
 	return booking, nil
 }
 
 // DeleteBooking - deletes a booking from the database by ID
 func (s *BookService) DeleteBooking(ID uint) error {
+	if err := s.checkEditable(ID); err != nil {
+		return err
+	}
 	// pass in empty comment obj and ID of booking to delete
 	if result := s.DB.Delete(&Booking{}, ID); result.Error != nil {
 		return result.Error
 	}
+	s.publish(events.Deleted, ID)
 	// if ID passed in is successfully deleted, return nil
 	return nil
 }
 
-// GetAllBookings() - retrieves all bookings from the database
-func (s *BookService) GetAllBookings() ([]Booking, error) {
+// GetAllBookings() - retrieves all bookings from the database, only those in the given statuses when
+// any are passed
+func (s *BookService) GetAllBookings(statuses ...Status) ([]Booking, error) {
 	var bookings []Booking
-	if result := s.DB.Find(&bookings); result.Error != nil {
+	query := s.DB.Preload("Customer").Preload("Job").Preload("Site").Preload("Contact").Preload("Engineers").Preload("Instruments").Preload("ExceptionDates")
+	if len(statuses) > 0 {
+		query = query.Where("status IN (?)", statuses)
+	}
+	if result := query.Find(&bookings); result.Error != nil {
 		return bookings, result.Error
 	}
 	return bookings, nil
 }
+
+// AssignEngineers - replaces the engineers assigned to a booking, rejecting the assignment with a
+// *ConflictError if any of the engineers are already booked in that window unless override is set
+func (s *BookService) AssignEngineers(ID uint, engineerIDs []uint, override bool) (Booking, error) {
+	booking, err := s.GetBooking(ID)
+	if err != nil {
+		return Booking{}, err
+	}
+	if err := booking.editable(); err != nil {
+		return Booking{}, err
+	}
+	engineers := make([]engineer.Engineer, len(engineerIDs))
+	for i, engineerID := range engineerIDs {
+		engineers[i].ID = engineerID
+	}
+	if engineers, err = s.resolveEngineers(engineers); err != nil {
+		return Booking{}, err
+	}
+
+	// only the engineers' schedules matter here, the location was checked when the booking was saved
+	probe := booking
+	probe.Location = ""
+	probe.Engineers = engineers
+	if err := s.checkConflicts(probe); err != nil {
+		var conflict *ConflictError
+		if !override || !errors.As(err, &conflict) {
+			return Booking{}, err
+		}
+	}
+
+	if err := s.DB.Model(&booking).Association("Engineers").Replace(engineers).Error; err != nil {
+		return Booking{}, err
+	}
+	if err := s.touch(ID); err != nil {
+		return Booking{}, err
+	}
+	return s.GetBooking(ID)
+}
+
+// AssignInstruments - replaces the instruments worked on during a booking, which must all belong to
+// the booking's customer
+func (s *BookService) AssignInstruments(ID uint, instrumentIDs []uint) (Booking, error) {
+	booking, err := s.GetBooking(ID)
+	if err != nil {
+		return Booking{}, err
+	}
+	if err := booking.editable(); err != nil {
+		return Booking{}, err
+	}
+	booking.Instruments = make([]customer.Instrument, len(instrumentIDs))
+	for i, instrumentID := range instrumentIDs {
+		booking.Instruments[i].ID = instrumentID
+	}
+	booking.Job = nil
+	if err := s.resolveInstruments(&booking); err != nil {
+		return Booking{}, err
+	}
+	if err := s.DB.Model(&booking).Association("Instruments").Replace(booking.Instruments).Error; err != nil {
+		return Booking{}, err
+	}
+	if err := s.touch(ID); err != nil {
+		return Booking{}, err
+	}
+	return s.GetBooking(ID)
+}
+
+// touch - marks a booking as updated when only its join tables changed, so clients syncing changes
+// since its last update pick the change up
+func (s *BookService) touch(ID uint) error {
+	if err := s.DB.Model(&Booking{}).Where("id = ?", ID).UpdateColumn("updated_at", time.Now().UTC()).Error; err != nil {
+		return err
+	}
+	s.publish(events.Updated, ID)
+	return nil
+}
+
+// GetBookingsByInstrument - retrieves every visit to an instrument, booked for it directly or for a job
+// raised on it or listing it as equipment, ordered by start time
+func (s *BookService) GetBookingsByInstrument(instrumentID uint) ([]Booking, error) {
+	var bookings []Booking
+	query := s.DB.Preload("Customer").Preload("Job").Preload("Site").Preload("Contact").Preload("Engineers").Preload("Instruments").Preload("ExceptionDates").
+		Where("id IN (SELECT booking_id FROM booking_instruments WHERE instrument_id = ?) OR "+
+			"job_id IN (SELECT id FROM jobs WHERE instrument_id = ?) OR "+
+			"job_id IN (SELECT job_id FROM equipment WHERE instrument_id = ? AND deleted_at IS NULL)",
+			instrumentID, instrumentID, instrumentID)
+	if result := query.Order("start_date_time").Find(&bookings); result.Error != nil {
+		return bookings, result.Error
+	}
+	return bookings, nil
+}
+
+// GetBookingsByEngineer - retrieves an engineer's schedule ordered by start time. A zero from or to
+// leaves that end of the window open.
+func (s *BookService) GetBookingsByEngineer(engineerID uint, from, to time.Time) ([]Booking, error) {
+	var bookings []Booking
+	query := s.DB.Preload("Customer").Preload("Job").Preload("Site").Preload("Contact").Preload("Engineers").Preload("Instruments").Preload("ExceptionDates").
+		Joins("JOIN booking_engineers ON booking_engineers.booking_id = bookings.id").
+		Where("booking_engineers.engineer_id = ?", engineerID)
+	if !from.IsZero() {
+		query = query.Where("bookings.end_date_time > ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("bookings.start_date_time < ?", to)
+	}
+	if result := query.Order("bookings.start_date_time").Find(&bookings); result.Error != nil {
+		return bookings, result.Error
+	}
+	return bookings, nil
+}
+
+// GetBookingsByCustomer - retrieves a customer's bookings ordered by start time. A zero from or to
+// leaves that end of the window open.
+func (s *BookService) GetBookingsByCustomer(customerID uint, from, to time.Time) ([]Booking, error) {
+	var bookings []Booking
+	query := s.DB.Preload("Customer").Preload("Job").Preload("Site").Preload("Contact").Preload("Engineers").Preload("Instruments").Preload("ExceptionDates").
+		Where("customer_id = ?", customerID)
+	if !from.IsZero() {
+		query = query.Where("end_date_time > ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("start_date_time < ?", to)
+	}
+	if result := query.Order("start_date_time").Find(&bookings); result.Error != nil {
+		return bookings, result.Error
+	}
+	return bookings, nil
+}
+
+// GetUnassignedBookings - retrieves the one-off bookings starting in [from, to) that are still to be
+// confirmed or carried out and have no engineers assigned, ordered by start time
+func (s *BookService) GetUnassignedBookings(from, to time.Time) ([]Booking, error) {
+	var bookings []Booking
+	if result := s.DB.Preload("Customer").Preload("Job").Preload("Site").Preload("Contact").Preload("Instruments").
+		Where("start_date_time >= ? AND start_date_time < ?", from, to).
+		Where("status IN (?) AND r_rule = ''", []Status{StatusRequested, StatusConfirmed}).
+		Where("id NOT IN (SELECT booking_id FROM booking_engineers)").
+		Order("start_date_time").Find(&bookings); result.Error != nil {
+		return bookings, result.Error
+	}
+	return bookings, nil
+}
+
+// applyChanges - overlays the non-zero scheduling fields of changes onto booking, the way gorm's
+// Updates will when it saves them
+func applyChanges(booking Booking, changes Booking) Booking {
+	if changes.Summary != "" {
+		booking.Summary = changes.Summary
+	}
+	if changes.Description != "" {
+		booking.Description = changes.Description
+	}
+	if changes.Location != "" {
+		booking.Location = changes.Location
+	}
+	if !changes.StartDateTime.IsZero() {
+		booking.StartDateTime = changes.StartDateTime
+	}
+	if !changes.EndDateTime.IsZero() {
+		booking.EndDateTime = changes.EndDateTime
+	}
+	if changes.RRule != "" {
+		booking.RRule = changes.RRule
+	}
+	if changes.TimeZone != "" {
+		booking.TimeZone = changes.TimeZone
+	}
+	if changes.CustomerID != nil {
+		booking.CustomerID = changes.CustomerID
+	}
+	if changes.JobID != nil {
+		booking.JobID = changes.JobID
+	}
+	if changes.SiteID != nil {
+		booking.SiteID = changes.SiteID
+	}
+	if changes.ContactID != nil {
+		booking.ContactID = changes.ContactID
+	}
+	return booking
+}
+
+// resolveEngineers - loads the referenced engineers, returning ErrEngineerNotFound if any are missing
+func (s *BookService) resolveEngineers(refs []engineer.Engineer) ([]engineer.Engineer, error) {
+	var engineers []engineer.Engineer
+	if len(refs) == 0 {
+		return engineers, nil
+	}
+	IDs := make([]uint, len(refs))
+	for i, ref := range refs {
+		IDs[i] = ref.ID
+	}
+	engineers, err := engineer.NewService(s.DB).GetEngineers(IDs)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return engineers, ErrEngineerNotFound
+	}
+	return engineers, err
+}
+
+// resolveReferences - loads the booking's customer, job, site and contact, accepting them as
+// {"ID": n} objects as well as by their IDs. A booking for a job takes the job's customer and site,
+// a booking at a site takes the site's customer, and they may not name others. A booking at a site
+// with no contact given gets the site's on-site contact.
+func (s *BookService) resolveReferences(booking *Booking) error {
+	customers := customer.NewService(s.DB)
+	if booking.JobID == nil && booking.Job != nil && booking.Job.ID != 0 {
+		booking.JobID = &booking.Job.ID
+	}
+	if booking.CustomerID == nil && booking.Customer != nil && booking.Customer.ID != 0 {
+		booking.CustomerID = &booking.Customer.ID
+	}
+	if booking.SiteID == nil && booking.Site != nil && booking.Site.ID != 0 {
+		booking.SiteID = &booking.Site.ID
+	}
+	if booking.ContactID == nil && booking.Contact != nil && booking.Contact.ID != 0 {
+		booking.ContactID = &booking.Contact.ID
+	}
+	booking.Customer, booking.Job, booking.Site, booking.Contact = nil, nil, nil, nil
+
+	if booking.JobID != nil {
+		job, err := customers.GetJob(*booking.JobID)
+		if gorm.IsRecordNotFoundError(err) {
+			return ErrJobNotFound
+		} else if err != nil {
+			return err
+		}
+		if booking.CustomerID != nil && *booking.CustomerID != job.CustomerID {
+			return ErrJobCustomerMismatch
+		}
+		booking.CustomerID = &job.CustomerID
+		if booking.SiteID == nil {
+			booking.SiteID = job.SiteID
+		}
+		booking.Job = &job
+	}
+	if booking.SiteID != nil {
+		var site customer.Site
+		if result := s.DB.First(&site, *booking.SiteID); result.Error != nil {
+			if gorm.IsRecordNotFoundError(result.Error) {
+				return ErrSiteNotFound
+			}
+			return result.Error
+		}
+		if booking.CustomerID != nil && *booking.CustomerID != site.CustomerID {
+			return ErrSiteCustomerMismatch
+		}
+		booking.CustomerID = &site.CustomerID
+		if booking.Location == "" {
+			booking.Location = site.FullAddress()
+		}
+		if booking.TimeZone == "" {
+			booking.TimeZone = site.TimeZone
+		}
+		booking.Site = &site
+	}
+	if booking.CustomerID != nil {
+		c, err := customers.GetCustomer(*booking.CustomerID)
+		if gorm.IsRecordNotFoundError(err) {
+			return ErrCustomerNotFound
+		} else if err != nil {
+			return err
+		}
+		booking.Customer = &c
+	}
+	if booking.ContactID != nil {
+		if booking.CustomerID == nil {
+			return ErrContactNotFound
+		}
+		contact, err := customers.GetContact(*booking.CustomerID, *booking.ContactID)
+		if gorm.IsRecordNotFoundError(err) {
+			return ErrContactNotFound
+		} else if err != nil {
+			return err
+		}
+		booking.Contact = &contact
+	} else if booking.SiteID != nil {
+		if contact, err := customers.GetSiteContact(*booking.SiteID); err == nil {
+			booking.ContactID, booking.Contact = &contact.ID, &contact
+		} else if !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveInstruments - loads the booking's instruments, returning ErrInstrumentNotFound if any are
+// missing or belong to another customer, and adds the instrument of the booking's job
+func (s *BookService) resolveInstruments(booking *Booking) error {
+	IDs := instrumentIDs(booking.Instruments)
+	if booking.Job != nil && booking.Job.InstrumentID != nil && !containsID(IDs, *booking.Job.InstrumentID) {
+		IDs = append(IDs, *booking.Job.InstrumentID)
+	}
+	if len(IDs) == 0 {
+		booking.Instruments = nil
+		return nil
+	}
+	if booking.CustomerID == nil {
+		return ErrInstrumentNotFound
+	}
+	instruments, err := customer.NewService(s.DB).GetInstrumentsByID(*booking.CustomerID, IDs)
+	if errors.Is(err, customer.ErrInstrumentNotFound) {
+		return ErrInstrumentNotFound
+	} else if err != nil {
+		return err
+	}
+	booking.Instruments = instruments
+	return nil
+}
+
+func instrumentIDs(instruments []customer.Instrument) []uint {
+	IDs := make([]uint, 0, len(instruments))
+	for _, instrument := range instruments {
+		IDs = append(IDs, instrument.ID)
+	}
+	return IDs
+}
+
+func containsID(IDs []uint, ID uint) bool {
+	for _, id := range IDs {
+		if id == ID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConflictHorizon - how far ahead the occurrences of a recurring booking are checked for conflicts,
+// since a series without COUNT or UNTIL never ends
+const ConflictHorizon = 2 * 365 * 24 * time.Hour
+
+// ErrInvalidTimeRange - returned when a booking ends before it starts
+var ErrInvalidTimeRange = errors.New("booking EndDateTime is before StartDateTime")
+
+// ErrEngineerNotFound - returned when a booking references an engineer that does not exist
+var ErrEngineerNotFound = errors.New("booking references an engineer that does not exist")
+
+// ErrCustomerNotFound - returned when a booking references a customer that does not exist
+var ErrCustomerNotFound = errors.New("booking references a customer that does not exist")
+
+// ErrJobNotFound - returned when a booking references a job that does not exist
+var ErrJobNotFound = errors.New("booking references a job that does not exist")
+
+// ErrJobCustomerMismatch - returned when a booking names a different customer to its job's
+var ErrJobCustomerMismatch = errors.New("booking CustomerID does not match the customer of its job")
+
+// ErrSiteNotFound - returned when a booking references a site that does not exist
+var ErrSiteNotFound = errors.New("booking references a site that does not exist")
+
+// ErrSiteCustomerMismatch - returned when a booking's site belongs to a different customer
+var ErrSiteCustomerMismatch = errors.New("booking SiteID is not a site of the booking's customer")
+
+// ErrContactNotFound - returned when a booking's contact does not exist or belongs to another customer
+var ErrContactNotFound = errors.New("booking references a contact that is not one of the booking's customer")
+
+// ErrInstrumentNotFound - returned when a booking's instrument does not exist or belongs to another customer
+var ErrInstrumentNotFound = errors.New("booking references an instrument that is not one of the booking's customer")
+
+// ConflictError - returned when a booking overlaps one or more existing bookings
+type ConflictError struct {
+	Conflicts []Booking
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("booking conflicts with %d existing booking(s)", len(e.Conflicts))
+}
+
+// FindConflicts - retrieves the bookings that overlap the booking's time window at the same location
+// or for any of the same engineers. Bookings touching end-to-start (one ends as the next begins) are
+// not treated as overlapping. Recurring bookings are expanded, so every occurrence of a series is
+// checked up to ConflictHorizon ahead, and a series conflicting with the booking is returned as the
+// occurrences it conflicts with.
+func (s *BookService) FindConflicts(booking Booking) ([]Booking, error) {
+	var conflicts []Booking
+	slots, err := booking.slots()
+	if err != nil || len(slots) == 0 {
+		return conflicts, err
+	}
+	from, to := slots[0].StartDateTime, slots[len(slots)-1].EndDateTime
+
+	var clauses []string
+	var args []interface{}
+	if booking.Location != "" {
+		clauses = append(clauses, "location = ?")
+		args = append(args, booking.Location)
+	}
+	if len(booking.Engineers) > 0 {
+		engineerIDs := make([]uint, len(booking.Engineers))
+		for i, engineer := range booking.Engineers {
+			engineerIDs[i] = engineer.ID
+		}
+		clauses = append(clauses, "id IN (SELECT booking_id FROM booking_engineers WHERE engineer_id IN (?))")
+		args = append(args, engineerIDs)
+	}
+	if len(clauses) == 0 {
+		return conflicts, nil
+	}
+
+	// cancelled and no-show bookings no longer hold their slot
+	query := s.DB.Preload("Engineers").
+		Where("status NOT IN (?)", []Status{StatusCancelled, StatusNoShow}).
+		Where(strings.Join(clauses, " OR "), args...)
+	// an existing booking never conflicts with itself, or the occurrences detached from it, when being updated
+	if booking.ID != 0 {
+		query = query.Where("id <> ? AND series_id <> ?", booking.ID, booking.ID)
+	}
+	// nor does an occurrence detached from a series conflict with the series it came from
+	if booking.SeriesID != 0 {
+		query = query.Where("id <> ?", booking.SeriesID)
+	}
+
+	var candidates []Booking
+	if result := query.Where("r_rule = '' AND start_date_time < ? AND end_date_time > ?", to, from).
+		Find(&candidates); result.Error != nil {
+		return conflicts, result.Error
+	}
+	var series []Booking
+	if result := query.Preload("ExceptionDates").Where("r_rule <> '' AND start_date_time < ?", to).
+		Find(&series); result.Error != nil {
+		return conflicts, result.Error
+	}
+	for _, master := range series {
+		occurrences, err := master.Occurrences(from, to)
+		if err != nil {
+			return conflicts, err
+		}
+		candidates = append(candidates, occurrences...)
+	}
+
+	return overlapping(slots, candidates), nil
+}
+
+// Overlaps - the bookings among others that overlap the booking at the same location or for any of
+// the same engineers, expanding recurring bookings as FindConflicts does. It checks bookings not yet
+// saved, such as the other events of a calendar being imported.
+func Overlaps(booking Booking, others []Booking) ([]Booking, error) {
+	slots, err := booking.slots()
+	if err != nil || len(slots) == 0 {
+		return nil, err
+	}
+	from, to := slots[0].StartDateTime, slots[len(slots)-1].EndDateTime
+
+	var candidates []Booking
+	for _, other := range others {
+		if other.Status == StatusCancelled || other.Status == StatusNoShow || !shareResource(booking, other) {
+			continue
+		}
+		if other.RRule == "" {
+			candidates = append(candidates, other)
+			continue
+		}
+		occurrences, err := other.Occurrences(from, to)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, occurrences...)
+	}
+	return overlapping(slots, candidates), nil
+}
+
+// slots - the times a booking takes up, every occurrence up to ConflictHorizon ahead for a recurring
+// booking and none for a booking without a start and end
+func (b Booking) slots() ([]Booking, error) {
+	if b.StartDateTime.IsZero() || b.EndDateTime.IsZero() {
+		return nil, nil
+	}
+	if b.RRule == "" {
+		return []Booking{b}, nil
+	}
+	return b.Occurrences(b.StartDateTime, b.StartDateTime.Add(ConflictHorizon))
+}
+
+// overlapping - the candidates overlapping any of the slots, soonest first
+func overlapping(slots []Booking, candidates []Booking) []Booking {
+	var conflicts []Booking
+	for _, candidate := range candidates {
+		for _, slot := range slots {
+			if candidate.StartDateTime.Before(slot.EndDateTime) && slot.StartDateTime.Before(candidate.EndDateTime) {
+				conflicts = append(conflicts, candidate)
+				break
+			}
+		}
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		return conflicts[i].StartDateTime.Before(conflicts[j].StartDateTime)
+	})
+	return conflicts
+}
+
+// shareResource - whether two bookings are at the same location or share an engineer
+func shareResource(a, b Booking) bool {
+	if a.Location != "" && a.Location == b.Location {
+		return true
+	}
+	for _, x := range a.Engineers {
+		for _, y := range b.Engineers {
+			if x.ID == y.ID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkConflicts - validates the booking window and returns a *ConflictError if it overlaps other bookings
+func (s *BookService) checkConflicts(booking Booking) error {
+	if booking.EndDateTime.Before(booking.StartDateTime) {
+		return ErrInvalidTimeRange
+	}
+	conflicts, err := s.FindConflicts(booking)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return &ConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package booking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/engineer"
+	"github.com/jinzhu/gorm"
+)
+
+// at - a time on 12 January 2026 (a Monday) in UTC
+func at(hour, min int) time.Time {
+	return time.Date(2026, 1, 12, hour, min, 0, 0, time.UTC)
+}
+
+func withEngineers(IDs ...uint) []engineer.Engineer {
+	engineers := make([]engineer.Engineer, len(IDs))
+	for i, ID := range IDs {
+		engineers[i] = engineer.Engineer{Model: gorm.Model{ID: ID}}
+	}
+	return engineers
+}
+
+func TestOverlaps(t *testing.T) {
+	booking := Booking{Location: "Unit 4", StartDateTime: at(9, 0), EndDateTime: at(11, 0), Engineers: withEngineers(1)}
+
+	tests := []struct {
+		name  string
+		other Booking
+		want  []string
+	}{
+		{
+			name:  "same location",
+			other: Booking{Summary: "a", Location: "Unit 4", StartDateTime: at(10, 0), EndDateTime: at(12, 0)},
+			want:  []string{"a"},
+		},
+		{
+			name:  "same engineer elsewhere",
+			other: Booking{Summary: "a", Location: "Unit 9", StartDateTime: at(8, 0), EndDateTime: at(9, 30), Engineers: withEngineers(2, 1)},
+			want:  []string{"a"},
+		},
+		{
+			name:  "inside",
+			other: Booking{Summary: "a", Location: "Unit 4", StartDateTime: at(9, 30), EndDateTime: at(10, 0)},
+			want:  []string{"a"},
+		},
+		{
+			name:  "ends as it starts",
+			other: Booking{Summary: "a", Location: "Unit 4", StartDateTime: at(8, 0), EndDateTime: at(9, 0)},
+		},
+		{
+			name:  "starts as it ends",
+			other: Booking{Summary: "a", Location: "Unit 4", StartDateTime: at(11, 0), EndDateTime: at(12, 0)},
+		},
+		{
+			name:  "no shared resource",
+			other: Booking{Summary: "a", Location: "Unit 9", StartDateTime: at(9, 0), EndDateTime: at(11, 0), Engineers: withEngineers(2)},
+		},
+		{
+			name:  "cancelled",
+			other: Booking{Summary: "a", Location: "Unit 4", StartDateTime: at(9, 0), EndDateTime: at(11, 0), Status: StatusCancelled},
+		},
+		{
+			name: "occurrence of an earlier series",
+			other: Booking{Summary: "a", Location: "Unit 4", RRule: "FREQ=DAILY",
+				StartDateTime: at(10, 0).AddDate(0, 0, -7), EndDateTime: at(10, 30).AddDate(0, 0, -7)},
+			want: []string{"a"},
+		},
+		{
+			name: "series excepting the day",
+			other: Booking{Summary: "a", Location: "Unit 4", RRule: "FREQ=DAILY",
+				StartDateTime: at(10, 0).AddDate(0, 0, -7), EndDateTime: at(10, 30).AddDate(0, 0, -7),
+				ExceptionDates: []ExceptionDate{{Start: at(10, 0)}}},
+		},
+		{
+			name: "series ended before",
+			other: Booking{Summary: "a", Location: "Unit 4", RRule: "FREQ=DAILY;COUNT=3",
+				StartDateTime: at(10, 0).AddDate(0, 0, -7), EndDateTime: at(10, 30).AddDate(0, 0, -7)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflicts, err := Overlaps(booking, []Booking{tt.other})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(conflicts) != len(tt.want) {
+				t.Fatalf("got %d conflicts, want %d", len(conflicts), len(tt.want))
+			}
+			for i, c := range conflicts {
+				if c.Summary != tt.want[i] {
+					t.Errorf("conflict %d is %q, want %q", i, c.Summary, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestOverlapsSeries - a recurring booking is checked occurrence by occurrence, and conflicts come
+// back as the occurrences that clash, soonest first
+func TestOverlapsSeries(t *testing.T) {
+	series := Booking{Location: "Unit 4", RRule: "FREQ=WEEKLY;COUNT=4", StartDateTime: at(9, 0), EndDateTime: at(10, 0)}
+	others := []Booking{
+		{Summary: "third week", Location: "Unit 4", StartDateTime: at(9, 30).AddDate(0, 0, 14), EndDateTime: at(10, 30).AddDate(0, 0, 14)},
+		{Summary: "between", Location: "Unit 4", StartDateTime: at(9, 0).AddDate(0, 0, 3), EndDateTime: at(10, 0).AddDate(0, 0, 3)},
+		{Summary: "after the last", Location: "Unit 4", StartDateTime: at(9, 0).AddDate(0, 0, 28), EndDateTime: at(10, 0).AddDate(0, 0, 28)},
+		{Summary: "fortnightly", Model: gorm.Model{ID: 7}, Location: "Unit 4", RRule: "FREQ=WEEKLY;INTERVAL=2", StartDateTime: at(9, 45).AddDate(0, 0, 7), EndDateTime: at(10, 15).AddDate(0, 0, 7)},
+	}
+	conflicts, err := Overlaps(series, others)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		summary string
+		start   time.Time
+	}{
+		{"fortnightly", at(9, 45).AddDate(0, 0, 7)},
+		{"third week", at(9, 30).AddDate(0, 0, 14)},
+		{"fortnightly", at(9, 45).AddDate(0, 0, 21)},
+	}
+	if len(conflicts) != len(want) {
+		t.Fatalf("got %d conflicts, want %d", len(conflicts), len(want))
+	}
+	for i, w := range want {
+		if conflicts[i].Summary != w.summary || !conflicts[i].StartDateTime.Equal(w.start) {
+			t.Errorf("conflict %d = %q at %s, want %q at %s", i, conflicts[i].Summary, conflicts[i].StartDateTime, w.summary, w.start)
+		}
+	}
+	if conflicts[0].SeriesID != 7 || conflicts[0].RecurrenceID == nil {
+		t.Errorf("an occurrence conflict should point back at its series, got %+v", conflicts[0])
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	series := Booking{
+		TimeZone:       "Europe/Dublin",
+		RRule:          "FREQ=WEEKLY;COUNT=4",
+		StartDateTime:  time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC),
+		EndDateTime:    time.Date(2026, 3, 16, 10, 0, 0, 0, time.UTC),
+		ExceptionDates: []ExceptionDate{{Start: time.Date(2026, 3, 23, 9, 0, 0, 0, time.UTC)}},
+	}
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []time.Time
+	}{
+		{
+			name: "whole series keeps 09:00 local across the change to summer time",
+			from: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), to: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 30, 8, 0, 0, 0, time.UTC),
+				time.Date(2026, 4, 6, 8, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "an occurrence under way at from",
+			from: time.Date(2026, 3, 30, 8, 30, 0, 0, time.UTC), to: time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{time.Date(2026, 3, 30, 8, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "an occurrence ending at from",
+			from: time.Date(2026, 3, 30, 9, 0, 0, 0, time.UTC), to: time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			occurrences, err := series.Occurrences(tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(occurrences) != len(tt.want) {
+				t.Fatalf("got %d occurrences, want %d", len(occurrences), len(tt.want))
+			}
+			for i, o := range occurrences {
+				if !o.StartDateTime.Equal(tt.want[i]) || o.EndDateTime.Sub(o.StartDateTime) != time.Hour {
+					t.Errorf("occurrence %d runs %s to %s, want an hour from %s", i, o.StartDateTime, o.EndDateTime, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,425 @@
+package booking
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/events"
+	"github.com/Open-FiSE/go-rest-api/internal/recurrence"
+	"github.com/jinzhu/gorm"
+)
+
+// Scope - which occurrences of a recurring booking an edit or delete applies to
+type Scope string
+
+// edit scopes, matching the choices calendar clients offer
+const (
+	ScopeThis      Scope = "this"
+	ScopeFollowing Scope = "following"
+	ScopeAll       Scope = "all"
+)
+
+// errors returned when working with recurring bookings
+var (
+	ErrNotRecurring    = errors.New("booking is not a recurring series")
+	ErrNotAnOccurrence = errors.New("start time is not an occurrence of the booking's series")
+	ErrInvalidScope    = errors.New("scope must be one of this, following or all")
+)
+
+// ExceptionDate - the start of an occurrence removed from a recurring booking (an RFC 5545 EXDATE)
+type ExceptionDate struct {
+	ID        uint
+	BookingID uint
+	Start     time.Time
+}
+
+// Rule - parses the booking's recurrence rule
+func (b Booking) Rule() (recurrence.Rule, error) {
+	if b.RRule == "" {
+		return recurrence.Rule{}, ErrNotRecurring
+	}
+	return recurrence.Parse(b.RRule)
+}
+
+// Occurrences - expands a recurring booking into one Booking per occurrence overlapping [from, to).
+// Each occurrence keeps the series' ID, with SeriesID and RecurrenceID identifying the occurrence.
+func (b Booking) Occurrences(from, to time.Time) ([]Booking, error) {
+	rule, err := b.Rule()
+	if err != nil {
+		return nil, err
+	}
+	exclude := make([]time.Time, len(b.ExceptionDates))
+	for i, ex := range b.ExceptionDates {
+		exclude[i] = ex.Start
+	}
+
+	duration := b.EndDateTime.Sub(b.StartDateTime)
+	starts := rule.Occurrences(b.dtstart(), from.Add(-duration), to, exclude)
+	occurrences := make([]Booking, 0, len(starts))
+	for _, start := range starts {
+		if !start.Add(duration).After(from) {
+			continue
+		}
+		occurrence := b
+		start = start.UTC()
+		occurrence.StartDateTime = start
+		occurrence.EndDateTime = start.Add(duration)
+		occurrence.SeriesID = b.ID
+		recurrenceID := start
+		occurrence.RecurrenceID = &recurrenceID
+		occurrence.ExceptionDates = nil
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences, nil
+}
+
+// dtstart - the series start in the site's zone, so occurrences keep their wall-clock time across
+// daylight saving changes
+func (b Booking) dtstart() time.Time {
+	return b.StartDateTime.In(b.Zone())
+}
+
+// GetOccurrences - retrieves every booking overlapping [from, to) with recurring bookings expanded
+// into their individual occurrences, ordered by start time
+func (s *BookService) GetOccurrences(from, to time.Time) ([]Booking, error) {
+	var bookings []Booking
+	if result := s.DB.Preload("Engineers").
+		Where("r_rule = '' AND start_date_time < ? AND end_date_time > ?", to, from).
+		Find(&bookings); result.Error != nil {
+		return bookings, result.Error
+	}
+
+	var series []Booking
+	if result := s.DB.Preload("Engineers").Preload("ExceptionDates").
+		Where("r_rule <> '' AND start_date_time < ?", to).
+		Find(&series); result.Error != nil {
+		return bookings, result.Error
+	}
+	for _, master := range series {
+		occurrences, err := master.Occurrences(from, to)
+		if err != nil {
+			return bookings, err
+		}
+		bookings = append(bookings, occurrences...)
+	}
+
+	sort.SliceStable(bookings, func(i, j int) bool {
+		return bookings[i].StartDateTime.Before(bookings[j].StartDateTime)
+	})
+	return bookings, nil
+}
+
+// GetEngineerOccurrences - retrieves an engineer's bookings overlapping [from, to) with recurring
+// bookings expanded into their individual occurrences, ordered by start time
+func (s *BookService) GetEngineerOccurrences(engineerID uint, from, to time.Time) ([]Booking, error) {
+	var bookings []Booking
+	query := s.DB.Preload("Customer").Preload("Job").Preload("Site").Preload("Contact").Preload("Engineers").Preload("Instruments").
+		Where("id IN (SELECT booking_id FROM booking_engineers WHERE engineer_id = ?)", engineerID)
+	if result := query.Where("r_rule = '' AND start_date_time < ? AND end_date_time > ?", to, from).
+		Find(&bookings); result.Error != nil {
+		return bookings, result.Error
+	}
+
+	var series []Booking
+	if result := query.Preload("ExceptionDates").Where("r_rule <> '' AND start_date_time < ?", to).
+		Find(&series); result.Error != nil {
+		return bookings, result.Error
+	}
+	for _, master := range series {
+		occurrences, err := master.Occurrences(from, to)
+		if err != nil {
+			return bookings, err
+		}
+		bookings = append(bookings, occurrences...)
+	}
+
+	sort.SliceStable(bookings, func(i, j int) bool {
+		return bookings[i].StartDateTime.Before(bookings[j].StartDateTime)
+	})
+	return bookings, nil
+}
+
+// UpdateOccurrence - edits the occurrence of a recurring booking starting at occurrence.
+// ScopeThis detaches the occurrence into its own booking, ScopeFollowing splits the series in two at
+// the occurrence and ScopeAll edits the whole series.
+func (s *BookService) UpdateOccurrence(ID uint, occurrence time.Time, scope Scope, newBooking Booking, override bool) (Booking, error) {
+	master, rule, err := s.getOccurrence(ID, occurrence)
+	if err != nil {
+		return Booking{}, err
+	}
+	if err := normaliseTimes(&newBooking, master.TimeZone); err != nil {
+		return Booking{}, err
+	}
+
+	switch scope {
+	case ScopeAll:
+		return s.UpdateBooking(ID, newBooking, override)
+	case ScopeThis:
+		return s.detachOccurrence(master, occurrence, newBooking, override)
+	case ScopeFollowing:
+		if occurrence.Equal(master.StartDateTime) {
+			return s.UpdateBooking(ID, newBooking, override)
+		}
+		return s.splitSeries(master, rule, occurrence, newBooking, override)
+	}
+	return Booking{}, ErrInvalidScope
+}
+
+// DeleteOccurrence - removes the occurrence of a recurring booking starting at occurrence, the
+// occurrences following it, or the whole series, including the occurrences detached from those
+// deleted. It returns ErrBookingSigned, deleting nothing, if any of them has been signed off.
+func (s *BookService) DeleteOccurrence(ID uint, occurrence time.Time, scope Scope) error {
+	master, rule, err := s.getOccurrence(ID, occurrence)
+	if err != nil {
+		return err
+	}
+
+	switch scope {
+	case ScopeThis:
+		if err := s.DB.Create(&ExceptionDate{BookingID: ID, Start: occurrence}).Error; err != nil {
+			return err
+		}
+		s.publish(events.Updated, ID)
+		return nil
+	case ScopeFollowing:
+		if !occurrence.Equal(master.StartDateTime) {
+			detached, err := s.detachedOccurrences(ID, occurrence)
+			if err != nil {
+				return err
+			}
+			head, _ := truncate(rule, master.dtstart(), occurrence)
+			tx := s.begin()
+			if err := tx.Model(&master).Update("r_rule", head.String()).Error; err != nil {
+				s.rollback(tx)
+				return err
+			}
+			if len(detached) > 0 {
+				if err := tx.Where("id IN (?)", detached).Delete(&Booking{}).Error; err != nil {
+					s.rollback(tx)
+					return err
+				}
+			}
+			if err := s.commit(tx); err != nil {
+				return err
+			}
+			s.publish(events.Updated, ID)
+			for _, detachedID := range detached {
+				s.publish(events.Deleted, detachedID)
+			}
+			return nil
+		}
+		fallthrough
+	case ScopeAll:
+		detached, err := s.detachedOccurrences(ID, time.Time{})
+		if err != nil {
+			return err
+		}
+		tx := s.begin()
+		if err := tx.Where("series_id = ?", ID).Delete(&Booking{}).Error; err != nil {
+			s.rollback(tx)
+			return err
+		}
+		if err := tx.Delete(&Booking{}, ID).Error; err != nil {
+			s.rollback(tx)
+			return err
+		}
+		if err := s.commit(tx); err != nil {
+			return err
+		}
+		for _, detachedID := range detached {
+			s.publish(events.Deleted, detachedID)
+		}
+		s.publish(events.Deleted, ID)
+		return nil
+	}
+	return ErrInvalidScope
+}
+
+// detachedOccurrences - the IDs of the occurrences detached from a series, those from the occurrence
+// at from on when it is set, returning ErrBookingSigned if any has been signed off, as deleting them
+// with the series would delete what the customer signed
+func (s *BookService) detachedOccurrences(ID uint, from time.Time) ([]uint, error) {
+	query := s.DB.Select("id, signed_at").Where("series_id = ?", ID)
+	if !from.IsZero() {
+		query = query.Where("recurrence_id >= ?", from)
+	}
+	var detached []Booking
+	if err := query.Find(&detached).Error; err != nil {
+		return nil, err
+	}
+	IDs := make([]uint, len(detached))
+	for i, b := range detached {
+		if err := b.editable(); err != nil {
+			return nil, err
+		}
+		IDs[i] = b.ID
+	}
+	return IDs, nil
+}
+
+// getOccurrence - loads a recurring booking and checks occurrence is one of its occurrences
+func (s *BookService) getOccurrence(ID uint, occurrence time.Time) (Booking, recurrence.Rule, error) {
+	master, err := s.GetBooking(ID)
+	if err != nil {
+		return Booking{}, recurrence.Rule{}, err
+	}
+	rule, err := master.occurrenceRule(occurrence)
+	if err != nil {
+		return Booking{}, recurrence.Rule{}, err
+	}
+	return master, rule, nil
+}
+
+// occurrenceRule - checks the series can be changed and occurrence is one of its occurrences,
+// returning its rule
+func (b Booking) occurrenceRule(occurrence time.Time) (recurrence.Rule, error) {
+	if err := b.editable(); err != nil {
+		return recurrence.Rule{}, err
+	}
+	rule, err := b.Rule()
+	if err != nil {
+		return recurrence.Rule{}, err
+	}
+	if !rule.IsOccurrence(b.dtstart(), occurrence) {
+		return recurrence.Rule{}, ErrNotAnOccurrence
+	}
+	return rule, nil
+}
+
+// CheckOccurrence - validates a change to one occurrence of a series as UpdateOccurrence does with
+// ScopeThis, without saving it, returning the occurrence as it would be detached. The series is
+// given rather than loaded by ID, so it may be one not yet saved.
+func (s *BookService) CheckOccurrence(master Booking, occurrence time.Time, newBooking Booking, override bool) (Booking, error) {
+	if _, err := master.occurrenceRule(occurrence); err != nil {
+		return Booking{}, err
+	}
+	if err := normaliseTimes(&newBooking, master.TimeZone); err != nil {
+		return Booking{}, err
+	}
+	return s.validate(detachedCopy(master, occurrence, newBooking), override)
+}
+
+// detachOccurrence - excludes the occurrence from its series and saves the edited copy as a booking of its own
+func (s *BookService) detachOccurrence(master Booking, occurrence time.Time, newBooking Booking, override bool) (Booking, error) {
+	detached := detachedCopy(master, occurrence, newBooking)
+
+	tx := s.begin()
+	exception := ExceptionDate{BookingID: master.ID, Start: occurrence}
+	if err := tx.Where(exception).FirstOrCreate(&exception).Error; err != nil {
+		s.rollback(tx)
+		return Booking{}, err
+	}
+	detached, err := (&BookService{DB: tx}).create(detached, override)
+	if err != nil {
+		s.rollback(tx)
+		return Booking{}, err
+	}
+	if err := s.commit(tx); err != nil {
+		return Booking{}, err
+	}
+	s.publish(events.Updated, master.ID)
+	s.publish(events.Created, detached.ID)
+	return detached, nil
+}
+
+// detachedCopy - the occurrence of a series as a booking of its own, with the changes made to it
+func detachedCopy(master Booking, occurrence time.Time, newBooking Booking) Booking {
+	detached := master
+	detached.Model = gorm.Model{}
+	detached.Status = initialStatus(master.Status)
+	detached.RRule = ""
+	detached.ExceptionDates = nil
+	detached.SeriesID = master.ID
+	detached.RecurrenceID = &occurrence
+	detached.StartDateTime = occurrence
+	detached.EndDateTime = occurrence.Add(master.EndDateTime.Sub(master.StartDateTime))
+	return applyChanges(detached, newBooking)
+}
+
+// splitSeries - ends the series before occurrence and starts a new, edited series from it
+func (s *BookService) splitSeries(master Booking, rule recurrence.Rule, occurrence time.Time, newBooking Booking, override bool) (Booking, error) {
+	head, tail := truncate(rule, master.dtstart(), occurrence)
+
+	following := master
+	following.Model = gorm.Model{}
+	following.Status = initialStatus(master.Status)
+	// the new series is a different calendar event to the one it was split from
+	following.UID = ""
+	following.RRule = tail.String()
+	following.StartDateTime = occurrence
+	following.EndDateTime = occurrence.Add(master.EndDateTime.Sub(master.StartDateTime))
+	following.ExceptionDates = nil
+	for _, ex := range master.ExceptionDates {
+		if ex.Start.After(occurrence) {
+			following.ExceptionDates = append(following.ExceptionDates, ExceptionDate{Start: ex.Start})
+		}
+	}
+	following = applyChanges(following, newBooking)
+
+	tx := s.begin()
+	if err := tx.Model(&master).Update("r_rule", head.String()).Error; err != nil {
+		s.rollback(tx)
+		return Booking{}, err
+	}
+	if err := tx.Where("booking_id = ? AND start > ?", master.ID, occurrence).Delete(&ExceptionDate{}).Error; err != nil {
+		s.rollback(tx)
+		return Booking{}, err
+	}
+	following, err := (&BookService{DB: tx}).create(following, override)
+	if err != nil {
+		s.rollback(tx)
+		return Booking{}, err
+	}
+	if err := s.commit(tx); err != nil {
+		return Booking{}, err
+	}
+	s.publish(events.Updated, master.ID)
+	s.publish(events.Created, following.ID)
+	return following, nil
+}
+
+// initialStatus - the status a booking split off a series starts in, since new bookings can only
+// start out requested or confirmed
+func initialStatus(status Status) Status {
+	if status == StatusRequested {
+		return StatusRequested
+	}
+	return StatusConfirmed
+}
+
+// truncate - splits a rule at occurrence into the rule for the occurrences before it and the rule
+// for the occurrences from it onwards, sharing out COUNT where the rule has one
+func truncate(rule recurrence.Rule, dtstart, occurrence time.Time) (recurrence.Rule, recurrence.Rule) {
+	head, tail := rule, rule
+	if rule.Count > 0 {
+		head.Count = rule.CountBefore(dtstart, occurrence)
+		tail.Count = rule.Count - head.Count
+	} else {
+		head.Until = occurrence.Add(-time.Second)
+	}
+	return head, tail
+}
+
+// SetExceptionDates - replaces the exception dates of a recurring booking
+func (s *BookService) SetExceptionDates(ID uint, dates []time.Time) error {
+	if err := s.checkEditable(ID); err != nil {
+		return err
+	}
+	tx := s.begin()
+	if err := tx.Where("booking_id = ?", ID).Delete(&ExceptionDate{}).Error; err != nil {
+		s.rollback(tx)
+		return err
+	}
+	for _, date := range dates {
+		if err := tx.Create(&ExceptionDate{BookingID: ID, Start: date}).Error; err != nil {
+			s.rollback(tx)
+			return err
+		}
+	}
+	if err := s.commit(tx); err != nil {
+		return err
+	}
+	s.publish(events.Updated, ID)
+	return nil
+}
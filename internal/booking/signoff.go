@@ -0,0 +1,104 @@
+package booking
+
+import (
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/Open-FiSE/go-rest-api/internal/events"
+	"github.com/jinzhu/gorm"
+)
+
+// ErrBookingSigned - returned when changing a booking the customer has signed off, or its job. It is
+// the customer service's error, so either matches errors.Is.
+var ErrBookingSigned = customer.ErrBookingSigned
+
+// SignOff - the customer's acceptance of the work done on a booking: who signed, when, the document
+// holding their signature and the signed report with its hash
+type SignOff struct {
+	gorm.Model
+	BookingID           uint      `gorm:"unique_index" json:"bookingId"`
+	SignerName          string    `json:"signerName"`
+	SignedAt            time.Time `json:"signedAt"`
+	SignatureDocumentID uint      `json:"signatureDocumentId"`
+	ReportDocumentID    uint      `json:"reportDocumentId"`
+	ReportHash          string    `json:"reportHash"`
+}
+
+// GetSignOff - retrieves the sign-off of a booking
+func (s *BookService) GetSignOff(bookingID uint) (SignOff, error) {
+	var signOff SignOff
+	if result := s.DB.Where("booking_id = ?", bookingID).First(&signOff); result.Error != nil {
+		return SignOff{}, result.Error
+	}
+	return signOff, nil
+}
+
+// SignOffBooking - completes a booking with the customer's sign-off, recording the status change
+// and stamping the booking as signed so it can no longer be changed
+func (s *BookService) SignOffBooking(ID uint, signOff SignOff, changedBy string) (Booking, error) {
+	booking, err := s.GetBooking(ID)
+	if err != nil {
+		return Booking{}, err
+	}
+	if err := booking.editable(); err != nil {
+		return Booking{}, err
+	}
+	if !booking.Status.CanTransition(StatusCompleted) {
+		return Booking{}, &TransitionError{From: booking.Status, To: StatusCompleted, Allowed: transitions[booking.Status]}
+	}
+
+	tx := s.begin()
+	// only complete the booking if nobody else has moved it since it was read
+	result := tx.Model(&Booking{}).Where("id = ? AND status = ? AND signed_at IS NULL", ID, booking.Status).
+		Updates(map[string]interface{}{"status": StatusCompleted, "signed_at": signOff.SignedAt})
+	if result.Error != nil {
+		s.rollback(tx)
+		return Booking{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		s.rollback(tx)
+		return Booking{}, &TransitionError{From: booking.Status, To: StatusCompleted, Allowed: transitions[booking.Status]}
+	}
+	change := StatusChange{
+		BookingID: ID,
+		From:      booking.Status,
+		To:        StatusCompleted,
+		ChangedBy: changedBy,
+		Reason:    "Signed off by " + signOff.SignerName,
+		ChangedAt: signOff.SignedAt,
+	}
+	if err := tx.Create(&change).Error; err != nil {
+		s.rollback(tx)
+		return Booking{}, err
+	}
+	signOff.BookingID = ID
+	if err := tx.Create(&signOff).Error; err != nil {
+		s.rollback(tx)
+		return Booking{}, err
+	}
+	if err := s.commit(tx); err != nil {
+		return Booking{}, err
+	}
+	s.publish(events.Updated, ID)
+	return s.GetBooking(ID)
+}
+
+// editable - returns ErrBookingSigned once the customer has signed the booking off
+func (b Booking) editable() error {
+	if b.SignedAt != nil {
+		return ErrBookingSigned
+	}
+	return nil
+}
+
+// checkEditable - returns ErrBookingSigned if the booking with the ID has been signed off
+func (s *BookService) checkEditable(ID uint) error {
+	var count int
+	if result := s.DB.Model(&Booking{}).Where("id = ? AND signed_at IS NOT NULL", ID).Count(&count); result.Error != nil {
+		return result.Error
+	}
+	if count > 0 {
+		return ErrBookingSigned
+	}
+	return nil
+}
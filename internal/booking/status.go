@@ -0,0 +1,142 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/events"
+	"github.com/jinzhu/gorm"
+)
+
+// Status - where a booking is in its lifecycle
+type Status string
+
+// booking statuses
+const (
+	StatusRequested  Status = "requested"
+	StatusConfirmed  Status = "confirmed"
+	StatusDispatched Status = "dispatched"
+	StatusInProgress Status = "in_progress"
+	StatusOnHold     Status = "on_hold"
+	StatusCompleted  Status = "completed"
+	StatusCancelled  Status = "cancelled"
+	StatusNoShow     Status = "no_show"
+)
+
+// transitions - the statuses each status may move to. Completed, cancelled and no-show are final.
+var transitions = map[Status][]Status{
+	StatusRequested:  {StatusConfirmed, StatusCancelled},
+	StatusConfirmed:  {StatusDispatched, StatusOnHold, StatusCancelled},
+	StatusDispatched: {StatusInProgress, StatusConfirmed, StatusOnHold, StatusNoShow, StatusCancelled},
+	StatusInProgress: {StatusOnHold, StatusCompleted},
+	StatusOnHold:     {StatusConfirmed, StatusDispatched, StatusInProgress, StatusCancelled},
+	StatusCompleted:  {},
+	StatusCancelled:  {},
+	StatusNoShow:     {},
+}
+
+// ErrInvalidStatus - returned for a status that is not one of the booking statuses, or that a new
+// booking cannot start in
+var ErrInvalidStatus = errors.New("invalid booking status")
+
+// TransitionError - returned when a booking cannot move from its current status to the requested one
+type TransitionError struct {
+	From    Status
+	To      Status
+	Allowed []Status
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("booking cannot move from %s to %s, allowed: %v", e.From, e.To, e.Allowed)
+}
+
+// StatusChange - an entry in a booking's status history recording who changed it, when and why
+type StatusChange struct {
+	gorm.Model
+	BookingID uint
+	From      Status
+	To        Status
+	ChangedBy string
+	Reason    string
+	ChangedAt time.Time
+}
+
+// ParseStatus - validates a status name
+func ParseStatus(value string) (Status, error) {
+	status := Status(value)
+	if _, ok := transitions[status]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrInvalidStatus, value)
+	}
+	return status, nil
+}
+
+// Active - reports whether a booking in this status still occupies its engineers and location
+func (s Status) Active() bool {
+	return s != StatusCancelled && s != StatusNoShow
+}
+
+// CanTransition - reports whether a booking may move from s to next
+func (s Status) CanTransition(next Status) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionStatus - moves a booking to a new status, recording the change in its history
+func (s *BookService) TransitionStatus(ID uint, to Status, changedBy string, reason string) (Booking, error) {
+	if _, err := ParseStatus(string(to)); err != nil {
+		return Booking{}, err
+	}
+	booking, err := s.GetBooking(ID)
+	if err != nil {
+		return Booking{}, err
+	}
+	if err := booking.editable(); err != nil {
+		return Booking{}, err
+	}
+	if !booking.Status.CanTransition(to) {
+		return Booking{}, &TransitionError{From: booking.Status, To: to, Allowed: transitions[booking.Status]}
+	}
+
+	tx := s.begin()
+	// only move the booking on if nobody else has moved it since it was read
+	result := tx.Model(&Booking{}).Where("id = ? AND status = ?", ID, booking.Status).Update("status", to)
+	if result.Error != nil {
+		s.rollback(tx)
+		return Booking{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		s.rollback(tx)
+		return Booking{}, &TransitionError{From: booking.Status, To: to, Allowed: transitions[booking.Status]}
+	}
+	change := StatusChange{
+		BookingID: ID,
+		From:      booking.Status,
+		To:        to,
+		ChangedBy: changedBy,
+		Reason:    reason,
+		ChangedAt: time.Now().UTC(),
+	}
+	if err := tx.Create(&change).Error; err != nil {
+		s.rollback(tx)
+		return Booking{}, err
+	}
+	if err := s.commit(tx); err != nil {
+		return Booking{}, err
+	}
+	s.publish(events.Updated, ID)
+	return s.GetBooking(ID)
+}
+
+// GetStatusHistory - retrieves a booking's status changes, oldest first
+func (s *BookService) GetStatusHistory(ID uint) ([]StatusChange, error) {
+	var changes []StatusChange
+	if result := s.DB.Where("booking_id = ?", ID).Order("changed_at, id").Find(&changes); result.Error != nil {
+		return changes, result.Error
+	}
+	return changes, nil
+}
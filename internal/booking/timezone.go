@@ -0,0 +1,122 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errors returned when interpreting local site times
+var (
+	ErrInvalidTimeZone      = errors.New("time zone must be an IANA zone name such as Europe/Dublin")
+	ErrInvalidLocalTime     = errors.New("local time must be formatted as 2006-01-02T15:04 or 2006-01-02T15:04:05")
+	ErrNonexistentLocalTime = errors.New("local time does not exist in the site's time zone (clocks go forward)")
+	ErrAmbiguousLocalTime   = errors.New("local time occurs twice in the site's time zone (clocks go back), give a UTC offset instead")
+)
+
+var localLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04"}
+
+// Zone - the site's time zone, UTC when the booking has none
+func (b Booking) Zone() *time.Location {
+	loc, err := time.LoadLocation(b.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// InZone - the booking with its times, and those of its exceptions, shown in loc. LocalStart and
+// LocalEnd are filled with the wall-clock times.
+func (b Booking) InZone(loc *time.Location) Booking {
+	b.StartDateTime = b.StartDateTime.In(loc)
+	b.EndDateTime = b.EndDateTime.In(loc)
+	b.LocalStart = b.StartDateTime.Format(localLayouts[0])
+	b.LocalEnd = b.EndDateTime.Format(localLayouts[0])
+	if b.RecurrenceID != nil {
+		recurrenceID := b.RecurrenceID.In(loc)
+		b.RecurrenceID = &recurrenceID
+	}
+	exceptions := make([]ExceptionDate, len(b.ExceptionDates))
+	for i, ex := range b.ExceptionDates {
+		ex.Start = ex.Start.In(loc)
+		exceptions[i] = ex
+	}
+	b.ExceptionDates = exceptions
+	return b
+}
+
+// ParseLocal - converts a wall-clock time at a site into an instant, rejecting times skipped or
+// repeated by a daylight saving change
+func ParseLocal(value string, loc *time.Location) (time.Time, error) {
+	var wall time.Time
+	var err error
+	for _, layout := range localLayouts {
+		if wall, err = time.ParseInLocation(layout, value, time.UTC); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidLocalTime, value)
+	}
+
+	// try every offset in use around that time, each one that maps back onto the same wall clock
+	// is a valid reading of it
+	var matches []time.Time
+	guess := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc)
+	for _, probe := range []time.Time{guess.Add(-24 * time.Hour), guess, guess.Add(24 * time.Hour)} {
+		_, offset := probe.Zone()
+		candidate := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		if candidate.Format(localLayouts[0]) != wall.Format(localLayouts[0]) {
+			continue
+		}
+		duplicate := false
+		for _, m := range matches {
+			duplicate = duplicate || m.Equal(candidate)
+		}
+		if !duplicate {
+			matches = append(matches, candidate)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return time.Time{}, fmt.Errorf("%w: %s in %s", ErrNonexistentLocalTime, value, loc)
+	case 1:
+		return matches[0].UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: %s in %s", ErrAmbiguousLocalTime, value, loc)
+}
+
+// normaliseTimes - validates the booking's zone, converts any local times given into instants in
+// that zone (falling back to zone when the booking has none) and stores everything in UTC
+func normaliseTimes(b *Booking, zone string) error {
+	if b.TimeZone != "" {
+		zone = b.TimeZone
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidTimeZone, zone)
+	}
+
+	if b.LocalStart != "" {
+		if b.StartDateTime, err = ParseLocal(b.LocalStart, loc); err != nil {
+			return err
+		}
+	}
+	if b.LocalEnd != "" {
+		if b.EndDateTime, err = ParseLocal(b.LocalEnd, loc); err != nil {
+			return err
+		}
+	}
+	b.LocalStart, b.LocalEnd = "", ""
+	if !b.StartDateTime.IsZero() {
+		b.StartDateTime = b.StartDateTime.UTC()
+	}
+	if !b.EndDateTime.IsZero() {
+		b.EndDateTime = b.EndDateTime.UTC()
+	}
+	for i := range b.ExceptionDates {
+		b.ExceptionDates[i].Start = b.ExceptionDates[i].Start.UTC()
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package booking
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseLocal(t *testing.T) {
+	dublin, err := time.LoadLocation("Europe/Dublin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		loc   *time.Location
+		want  string
+		err   error
+	}{
+		{"utc", "2026-01-12T09:00", time.UTC, "2026-01-12T09:00:00Z", nil},
+		{"winter", "2026-01-12T09:00", dublin, "2026-01-12T09:00:00Z", nil},
+		{"summer", "2026-07-12T09:00", dublin, "2026-07-12T08:00:00Z", nil},
+		{"with seconds", "2026-07-12T09:00:30", dublin, "2026-07-12T08:00:30Z", nil},
+		{"west of utc", "2026-07-12T09:00", newYork, "2026-07-12T13:00:00Z", nil},
+		{"just before clocks go forward", "2026-03-29T00:59", dublin, "2026-03-29T00:59:00Z", nil},
+		{"clocks gone forward", "2026-03-29T02:00", dublin, "2026-03-29T01:00:00Z", nil},
+		{"skipped hour", "2026-03-29T01:30", dublin, "", ErrNonexistentLocalTime},
+		{"repeated hour", "2026-10-25T01:30", dublin, "", ErrAmbiguousLocalTime},
+		{"after the repeated hour", "2026-10-25T02:00", dublin, "2026-10-25T02:00:00Z", nil},
+		{"skipped hour west of utc", "2026-03-08T02:30", newYork, "", ErrNonexistentLocalTime},
+		{"repeated hour west of utc", "2026-11-01T01:30", newYork, "", ErrAmbiguousLocalTime},
+		{"date only", "2026-01-12", dublin, "", ErrInvalidLocalTime},
+		{"with offset", "2026-01-12T09:00:00Z", dublin, "", ErrInvalidLocalTime},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLocal(tt.value, tt.loc)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("ParseLocal(%q) error = %v, want %v", tt.value, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLocal(%q): %v", tt.value, err)
+			}
+			if got.Location() != time.UTC || got.Format(time.RFC3339) != tt.want {
+				t.Errorf("ParseLocal(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormaliseTimes(t *testing.T) {
+	tests := []struct {
+		name       string
+		booking    Booking
+		zone       string
+		start, end string
+		err        error
+	}{
+		{
+			name:    "local times in the booking's zone",
+			booking: Booking{TimeZone: "Europe/Dublin", LocalStart: "2026-07-12T09:00", LocalEnd: "2026-07-12T10:30"},
+			start:   "2026-07-12T08:00:00Z", end: "2026-07-12T09:30:00Z",
+		},
+		{
+			name:    "local times in the fallback zone",
+			booking: Booking{LocalStart: "2026-07-12T09:00", LocalEnd: "2026-07-12T10:00"},
+			zone:    "America/New_York",
+			start:   "2026-07-12T13:00:00Z", end: "2026-07-12T14:00:00Z",
+		},
+		{
+			name: "instants are kept",
+			booking: Booking{
+				TimeZone:      "Europe/Dublin",
+				StartDateTime: time.Date(2026, 7, 12, 9, 0, 0, 0, time.FixedZone("IST", 3600)),
+				EndDateTime:   time.Date(2026, 7, 12, 10, 0, 0, 0, time.FixedZone("IST", 3600)),
+			},
+			start: "2026-07-12T08:00:00Z", end: "2026-07-12T09:00:00Z",
+		},
+		{
+			name:    "unknown zone",
+			booking: Booking{TimeZone: "Europe/Atlantis", LocalStart: "2026-07-12T09:00"},
+			err:     ErrInvalidTimeZone,
+		},
+		{
+			name:    "skipped local time",
+			booking: Booking{TimeZone: "Europe/Dublin", LocalStart: "2026-03-29T01:30", LocalEnd: "2026-03-29T03:00"},
+			err:     ErrNonexistentLocalTime,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.booking
+			err := normaliseTimes(&b, tt.zone)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.StartDateTime.Location() != time.UTC || b.StartDateTime.Format(time.RFC3339) != tt.start {
+				t.Errorf("start = %s, want %s", b.StartDateTime, tt.start)
+			}
+			if b.EndDateTime.Location() != time.UTC || b.EndDateTime.Format(time.RFC3339) != tt.end {
+				t.Errorf("end = %s, want %s", b.EndDateTime, tt.end)
+			}
+			if b.LocalStart != "" || b.LocalEnd != "" {
+				t.Errorf("local times %q, %q were kept", b.LocalStart, b.LocalEnd)
+			}
+		})
+	}
+}
+
+func TestInZone(t *testing.T) {
+	b := Booking{
+		TimeZone:       "Europe/Dublin",
+		StartDateTime:  time.Date(2026, 7, 12, 8, 0, 0, 0, time.UTC),
+		EndDateTime:    time.Date(2026, 7, 12, 9, 30, 0, 0, time.UTC),
+		ExceptionDates: []ExceptionDate{{Start: time.Date(2026, 7, 19, 8, 0, 0, 0, time.UTC)}},
+	}
+	local := b.InZone(b.Zone())
+	if local.LocalStart != "2026-07-12T09:00:00" || local.LocalEnd != "2026-07-12T10:30:00" {
+		t.Errorf("local times = %s to %s", local.LocalStart, local.LocalEnd)
+	}
+	if got := local.ExceptionDates[0].Start.Format(localLayouts[0]); got != "2026-07-19T09:00:00" {
+		t.Errorf("exception date = %s", got)
+	}
+	if b.ExceptionDates[0].Start.Location() != time.UTC {
+		t.Error("InZone changed the exception dates of the booking it was called on")
+	}
+}
@@ -0,0 +1,72 @@
+package booking
+
+import (
+	"github.com/Open-FiSE/go-rest-api/internal/events"
+	"github.com/jinzhu/gorm"
+)
+
+// bookingChange - a change to a booking made within a transaction, published once it commits
+type bookingChange struct {
+	action events.Action
+	ID     uint
+}
+
+// Transaction - runs fn with a service whose changes are all made in one transaction, committed when
+// fn returns nil and rolled back when it returns an error. Changes are published once committed.
+// Within a transaction the service's own transactions become part of it.
+func (s *BookService) Transaction(fn func(bookings *BookService) error) error {
+	if s.pending != nil {
+		return fn(s)
+	}
+	tx := s.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	bookings := &BookService{DB: tx, Events: s.Events, pending: &[]bookingChange{}}
+	if err := fn(bookings); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	for _, c := range *bookings.pending {
+		s.Events.Publish(events.Booking, c.action, c.ID)
+	}
+	return nil
+}
+
+// begin - starts a transaction, or carries on the one the service is already in
+func (s *BookService) begin() *gorm.DB {
+	if s.pending != nil {
+		return s.DB
+	}
+	return s.DB.Begin()
+}
+
+// commit - commits a transaction started by begin, leaving one the service was already in to
+// Transaction
+func (s *BookService) commit(tx *gorm.DB) error {
+	if s.pending != nil {
+		return nil
+	}
+	return tx.Commit().Error
+}
+
+// rollback - rolls back a transaction started by begin. Within Transaction the error returned rolls
+// the whole of it back.
+func (s *BookService) rollback(tx *gorm.DB) {
+	if s.pending == nil {
+		tx.Rollback()
+	}
+}
+
+// publish - publishes a change to a booking, or holds it until the transaction the service is in
+// commits
+func (s *BookService) publish(action events.Action, ID uint) {
+	if s.pending != nil {
+		*s.pending = append(*s.pending, bookingChange{action: action, ID: ID})
+		return
+	}
+	s.Events.Publish(events.Booking, action, ID)
+}
@@ -0,0 +1,305 @@
+package calendar
+
+// Calendar object resources backing the per-engineer CalDAV collections.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/engineer"
+	"github.com/jinzhu/gorm"
+)
+
+// errors returned when reading and writing calendar object resources
+var (
+	ErrResourceNotFound   = errors.New("calendar resource not found")
+	ErrPreconditionFailed = errors.New("calendar resource has changed, its ETag does not match")
+	ErrUIDInUse           = errors.New("UID belongs to a booking outside this calendar")
+	ErrMixedUIDs          = errors.New("calendar resource must contain events with a single UID")
+)
+
+// Resource - a calendar object resource: one booking and, for a recurring booking, the occurrences
+// detached from its series
+type Resource struct {
+	Name      string
+	Booking   booking.Booking
+	Overrides []booking.Booking
+	Data      []byte
+	ETag      string
+}
+
+// ResourceName - the href segment of a booking's resource
+func ResourceName(b booking.Booking) string {
+	if b.UID != "" {
+		return url.PathEscape(b.UID) + ".ics"
+	}
+	return fmt.Sprintf("%d.ics", b.ID)
+}
+
+// NewResource - renders a booking and its detached occurrences. The ETag is a hash of the rendered
+// data, so it changes whenever anything a client can see changes.
+func NewResource(b booking.Booking, overrides []booking.Booking) (Resource, error) {
+	events := []Event{EventFromBooking(b)}
+	for _, o := range overrides {
+		events = append(events, EventFromBooking(o))
+	}
+	var data bytes.Buffer
+	if err := Encode(&data, b.Summary, events); err != nil {
+		return Resource{}, err
+	}
+	sum := sha256.Sum256(data.Bytes())
+	return Resource{
+		Name:      ResourceName(b),
+		Booking:   b,
+		Overrides: overrides,
+		Data:      data.Bytes(),
+		ETag:      `"` + hex.EncodeToString(sum[:16]) + `"`,
+	}, nil
+}
+
+// Overlaps - reports whether any occurrence of the resource overlaps [start, end). A zero start or
+// end leaves that end of the range open.
+func (r Resource) Overlaps(start, end time.Time) bool {
+	if start.IsZero() {
+		start = time.Unix(0, 0)
+	}
+	if end.IsZero() {
+		end = start.AddDate(100, 0, 0)
+	}
+	overlaps := func(b booking.Booking) bool {
+		return b.StartDateTime.Before(end) && b.EndDateTime.After(start)
+	}
+	if r.Booking.RRule != "" {
+		occurrences, err := r.Booking.Occurrences(start, end)
+		if err == nil && len(occurrences) > 0 {
+			return true
+		}
+	} else if overlaps(r.Booking) {
+		return true
+	}
+	for _, o := range r.Overrides {
+		if overlaps(o) {
+			return true
+		}
+	}
+	return false
+}
+
+// CTag - a tag for the whole collection that changes whenever any resource in it changes
+func CTag(resources []Resource) string {
+	sum := sha256.New()
+	for _, r := range resources {
+		io.WriteString(sum, r.Name+r.ETag)
+	}
+	return `"` + hex.EncodeToString(sum.Sum(nil)[:16]) + `"`
+}
+
+// EngineerResources - the calendar object resources in an engineer's collection
+func (s *Service) EngineerResources(engineerID uint) ([]Resource, error) {
+	bookings, err := s.Bookings.GetBookingsByEngineer(engineerID, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[uint][]booking.Booking)
+	series := make(map[uint]bool)
+	for _, b := range bookings {
+		if b.RRule != "" {
+			series[b.ID] = true
+		}
+	}
+	var resources []Resource
+	for _, b := range bookings {
+		if b.SeriesID != 0 && series[b.SeriesID] {
+			overrides[b.SeriesID] = append(overrides[b.SeriesID], b)
+		}
+	}
+	for _, b := range bookings {
+		if b.SeriesID != 0 && series[b.SeriesID] {
+			continue
+		}
+		resource, err := NewResource(b, overrides[b.ID])
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+// FindResource - the resource with the unescaped href segment name in an engineer's collection
+func (s *Service) FindResource(engineerID uint, name string) (Resource, error) {
+	resources, err := s.EngineerResources(engineerID)
+	if err != nil {
+		return Resource{}, err
+	}
+	for _, r := range resources {
+		if r.Name == url.PathEscape(name) {
+			return r, nil
+		}
+	}
+	return Resource{}, ErrResourceNotFound
+}
+
+// PutResource - creates or replaces a resource in an engineer's collection from iCalendar data,
+// honouring the If-Match and If-None-Match preconditions. New bookings are assigned to the engineer.
+// The booking and its detached occurrences are saved in one transaction, so a resource is never left
+// half written.
+func (s *Service) PutResource(engineerID uint, name string, data io.Reader, ifMatch, ifNoneMatch string, override bool) (Resource, bool, error) {
+	events, decodeErrs, err := Decode(data)
+	if err != nil {
+		return Resource{}, false, err
+	}
+	for _, err := range decodeErrs {
+		return Resource{}, false, err
+	}
+	master, overrides, err := splitEvents(events)
+	if err != nil {
+		return Resource{}, false, err
+	}
+
+	existing, err := s.FindResource(engineerID, name)
+	if errors.Is(err, ErrResourceNotFound) {
+		// clients may name a new resource anything, after which it is listed under its UID
+		name = master.UID + ".ics"
+		existing, err = s.FindResource(engineerID, name)
+	}
+	exists := err == nil
+	if err != nil && !errors.Is(err, ErrResourceNotFound) {
+		return Resource{}, false, err
+	}
+	if (ifNoneMatch == "*" && exists) || (ifMatch != "" && (!exists || (ifMatch != "*" && ifMatch != existing.ETag))) {
+		return Resource{}, false, ErrPreconditionFailed
+	}
+
+	if !exists {
+		if _, err := s.Bookings.GetBookingByUID(master.UID); err == nil {
+			return Resource{}, false, ErrUIDInUse
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return Resource{}, false, err
+		}
+		b := BookingFromEvent(master)
+		b.Engineers = []engineer.Engineer{{Model: gorm.Model{ID: engineerID}}}
+		err := s.Bookings.Transaction(func(bookings *booking.BookService) error {
+			created, err := bookings.PostBooking(b, override)
+			if err != nil {
+				return err
+			}
+			return applyOverrides(bookings, created, nil, overrides, override)
+		})
+		if err != nil {
+			return Resource{}, false, err
+		}
+		resource, err := s.FindResource(engineerID, name)
+		return resource, true, err
+	}
+
+	// the event replaces the booking, so what the client removed from it is cleared
+	changes := BookingFromEvent(master)
+	changes.UID, changes.ExceptionDates = "", nil
+	// the series keeps an exception for each client EXDATE and for each occurrence it overrides, and a
+	// booking no longer recurring keeps none
+	exceptions := []time.Time{}
+	if changes.RRule != "" {
+		exceptions = append(exceptions, master.ExDates...)
+		for _, o := range overrides {
+			exceptions = append(exceptions, *o.RecurrenceID)
+		}
+	}
+	err = s.Bookings.Transaction(func(bookings *booking.BookService) error {
+		updated, err := bookings.ReplaceBooking(existing.Booking.ID, changes, override)
+		if err != nil {
+			return err
+		}
+		if err := bookings.SetExceptionDates(updated.ID, exceptions); err != nil {
+			return err
+		}
+		return applyOverrides(bookings, updated, existing.Overrides, overrides, override)
+	})
+	if err != nil {
+		return Resource{}, false, err
+	}
+	resource, err := s.FindResource(engineerID, name)
+	return resource, false, err
+}
+
+// DeleteResource - deletes a resource, with the whole of its series, honouring If-Match
+func (s *Service) DeleteResource(engineerID uint, name string, ifMatch string) error {
+	existing, err := s.FindResource(engineerID, name)
+	if err != nil {
+		return err
+	}
+	if ifMatch != "" && ifMatch != "*" && ifMatch != existing.ETag {
+		return ErrPreconditionFailed
+	}
+	if existing.Booking.RRule != "" {
+		return s.Bookings.DeleteOccurrence(existing.Booking.ID, existing.Booking.StartDateTime, booking.ScopeAll)
+	}
+	return s.Bookings.DeleteBooking(existing.Booking.ID)
+}
+
+// applyOverrides - brings the series' detached occurrences in line with the override events,
+// updating those that exist, detaching new ones and deleting those the client dropped
+func applyOverrides(bookings *booking.BookService, series booking.Booking, current []booking.Booking, overrides []Event, override bool) error {
+	byRecurrence := make(map[int64]booking.Booking)
+	for _, b := range current {
+		if b.RecurrenceID != nil {
+			byRecurrence[b.RecurrenceID.Unix()] = b
+		}
+	}
+
+	for _, event := range overrides {
+		changes := BookingFromEvent(event)
+		changes.UID, changes.RRule, changes.ExceptionDates = "", "", nil
+		if detached, ok := byRecurrence[event.RecurrenceID.Unix()]; ok {
+			delete(byRecurrence, event.RecurrenceID.Unix())
+			if _, err := bookings.ReplaceBooking(detached.ID, changes, override); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := bookings.UpdateOccurrence(series.ID, event.RecurrenceID.UTC(), booking.ScopeThis, changes, override); err != nil {
+			return err
+		}
+	}
+	for _, dropped := range byRecurrence {
+		if err := bookings.DeleteBooking(dropped.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitEvents - separates the master event from the overrides of single occurrences
+func splitEvents(events []Event) (Event, []Event, error) {
+	var master *Event
+	var overrides []Event
+	for i, event := range events {
+		if event.UID == "" || event.UID != events[0].UID {
+			return Event{}, nil, ErrMixedUIDs
+		}
+		if event.RecurrenceID != nil {
+			overrides = append(overrides, event)
+			continue
+		}
+		if master != nil {
+			return Event{}, nil, ErrMixedUIDs
+		}
+		master = &events[i]
+	}
+	if master == nil {
+		return Event{}, nil, fmt.Errorf("%w: no VEVENT without a RECURRENCE-ID", ErrInvalidCalendar)
+	}
+	if strings.TrimSpace(master.RRule) == "" && len(overrides) > 0 {
+		return Event{}, nil, fmt.Errorf("%w: RECURRENCE-ID on a non-recurring event", ErrInvalidCalendar)
+	}
+	return *master, overrides, nil
+}
@@ -0,0 +1,139 @@
+package calendar
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/jinzhu/gorm"
+)
+
+// feed scopes, a token for ScopeAll can read every feed
+const (
+	ScopeAll      = "all"
+	ScopeEngineer = "engineer"
+	ScopeCustomer = "customer"
+)
+
+// errors returned by the feed token service
+var (
+	ErrInvalidScope  = errors.New("feed scope must be all, engineer or customer, with a scopeId for engineer and customer")
+	ErrInvalidToken  = errors.New("feed token is missing, revoked or does not grant access to this feed")
+	ErrReadOnlyToken = errors.New("feed token does not allow changes to this calendar")
+)
+
+// Service - the struct for the calendar feed and import service
+type Service struct {
+	DB       *gorm.DB
+	Bookings *booking.BookService
+}
+
+// FeedToken - grants one user read access to a calendar feed, and with Writable lets their calendar
+// client change bookings over CalDAV. Only a hash of the token is stored, the token itself is
+// returned once when it is created.
+type FeedToken struct {
+	gorm.Model
+	Owner     string `json:"owner"`
+	Scope     string `json:"scope"`
+	ScopeID   uint   `json:"scopeId"`
+	Writable  bool   `json:"writable"`
+	TokenHash string `gorm:"unique_index" json:"-"`
+	Token     string `gorm:"-" json:"token,omitempty"`
+}
+
+// FeedService - the interface for our calendar feed service
+type FeedService interface {
+	CreateFeedToken(token FeedToken) (FeedToken, error)
+	RevokeFeedToken(ID uint) error
+	Authorize(token string, scope string, scopeID uint) error
+	AuthorizeWrite(token string, scope string, scopeID uint) error
+	Import(events []Event, decodeErrs map[int]error, dryRun bool, override bool) (ImportReport, error)
+}
+
+// NewService - takes in a pointer to the DB and the booking service bookings are imported through &
+// returns a pointer to a new calendar service
+func NewService(db *gorm.DB, bookings *booking.BookService) *Service {
+	return &Service{
+		DB:       db,
+		Bookings: bookings,
+	}
+}
+
+// CreateFeedToken - issues a new random feed token for the owner and scope, never replacing an
+// existing one
+func (s *Service) CreateFeedToken(token FeedToken) (FeedToken, error) {
+	token.Model = gorm.Model{}
+	if !validScope(token.Scope, token.ScopeID) {
+		return FeedToken{}, ErrInvalidScope
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return FeedToken{}, err
+	}
+	token.Token = hex.EncodeToString(raw)
+	token.TokenHash = hashToken(token.Token)
+	if result := s.DB.Save(&token); result.Error != nil {
+		return FeedToken{}, result.Error
+	}
+	return token, nil
+}
+
+// RevokeFeedToken - deletes a feed token by ID, after which its feed URL stops working
+func (s *Service) RevokeFeedToken(ID uint) error {
+	if result := s.DB.Delete(&FeedToken{}, ID); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// Authorize - checks the token grants access to the feed for scope and scopeID
+func (s *Service) Authorize(token string, scope string, scopeID uint) error {
+	_, err := s.authorize(token, scope, scopeID)
+	return err
+}
+
+// AuthorizeWrite - checks the token grants access to the feed for scope and scopeID and may change it
+func (s *Service) AuthorizeWrite(token string, scope string, scopeID uint) error {
+	feedToken, err := s.authorize(token, scope, scopeID)
+	if err != nil {
+		return err
+	}
+	if !feedToken.Writable {
+		return ErrReadOnlyToken
+	}
+	return nil
+}
+
+func (s *Service) authorize(token string, scope string, scopeID uint) (FeedToken, error) {
+	var feedToken FeedToken
+	if token == "" {
+		return feedToken, ErrInvalidToken
+	}
+	if result := s.DB.Where("token_hash = ?", hashToken(token)).First(&feedToken); result.Error != nil {
+		if gorm.IsRecordNotFoundError(result.Error) {
+			return feedToken, ErrInvalidToken
+		}
+		return feedToken, result.Error
+	}
+	if feedToken.Scope == ScopeAll || (feedToken.Scope == scope && feedToken.ScopeID == scopeID) {
+		return feedToken, nil
+	}
+	return feedToken, ErrInvalidToken
+}
+
+func validScope(scope string, scopeID uint) bool {
+	switch scope {
+	case ScopeAll:
+		return scopeID == 0
+	case ScopeEngineer, ScopeCustomer:
+		return scopeID != 0
+	}
+	return false
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,199 @@
+package calendar
+
+// Render bookings as RFC 5545 iCalendar data.
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+)
+
+const (
+	prodID      = "-//Open-FiSE//FiSES API//EN"
+	uidDomain   = "fises.open-fise"
+	utcFormat   = "20060102T150405Z"
+	maxLineSize = 75
+)
+
+// Event - a VEVENT
+type Event struct {
+	UID          string
+	Summary      string
+	Description  string
+	Location     string
+	Start        time.Time
+	End          time.Time
+	RRule        string
+	ExDates      []time.Time
+	RecurrenceID *time.Time
+	Stamp        time.Time
+	Status       string
+	// Geo - the site's "latitude;longitude" and Contact - the on-site contact, both export only
+	Geo     string
+	Contact string
+	// TimeZone - when set, times are written as local times in this zone with a matching VTIMEZONE
+	TimeZone *time.Location
+}
+
+// BookingUID - the stable UID of a booking, the original UID for bookings imported from a calendar.
+// Occurrences detached from a series share the series' UID and are told apart by their
+// RECURRENCE-ID, as calendar clients expect.
+func BookingUID(b booking.Booking) string {
+	if b.UID != "" {
+		return b.UID
+	}
+	id := b.ID
+	if b.SeriesID != 0 {
+		id = b.SeriesID
+	}
+	return fmt.Sprintf("booking-%d@%s", id, uidDomain)
+}
+
+// EventFromBooking - maps a booking onto a VEVENT
+func EventFromBooking(b booking.Booking) Event {
+	event := Event{
+		UID:          BookingUID(b),
+		Summary:      b.Summary,
+		Description:  b.Description,
+		Location:     b.Location,
+		Start:        b.StartDateTime,
+		End:          b.EndDateTime,
+		RRule:        b.RRule,
+		RecurrenceID: b.RecurrenceID,
+		Stamp:        b.UpdatedAt,
+		Status:       eventStatus(b.Status),
+	}
+	if b.TimeZone != "" {
+		event.TimeZone = b.Zone()
+	}
+	for _, ex := range b.ExceptionDates {
+		event.ExDates = append(event.ExDates, ex.Start)
+	}
+	if b.Site != nil && b.Site.HasLocation() {
+		event.Geo = fmt.Sprintf("%f;%f", *b.Site.Latitude, *b.Site.Longitude)
+	}
+	if b.Contact != nil {
+		details := []string{b.Contact.Name}
+		for _, detail := range []string{b.Contact.Phone, b.Contact.Email} {
+			if detail != "" {
+				details = append(details, detail)
+			}
+		}
+		event.Contact = strings.Join(details, ", ")
+	}
+	return event
+}
+
+// Encode - writes the events as a VCALENDAR named name
+func Encode(w io.Writer, name string, events []Event) error {
+	bw := bufio.NewWriter(w)
+	write := func(line string) {
+		writeFolded(bw, line)
+	}
+
+	write("BEGIN:VCALENDAR")
+	write("VERSION:2.0")
+	write("PRODID:" + prodID)
+	write("CALSCALE:GREGORIAN")
+	write("METHOD:PUBLISH")
+	write("X-WR-CALNAME:" + escapeText(name))
+	zones, ranges := zoneRanges(events)
+	for _, loc := range zones {
+		writeTimezone(write, loc, ranges[loc][0], ranges[loc][1])
+	}
+	for _, event := range events {
+		write("BEGIN:VEVENT")
+		write("UID:" + event.UID)
+		write("DTSTAMP:" + formatUTC(event.Stamp))
+		write("DTSTART" + formatDateTime(event.Start, event.TimeZone))
+		write("DTEND" + formatDateTime(event.End, event.TimeZone))
+		if event.RecurrenceID != nil {
+			write("RECURRENCE-ID" + formatDateTime(*event.RecurrenceID, event.TimeZone))
+		}
+		if event.RRule != "" {
+			write("RRULE:" + event.RRule)
+		}
+		for _, ex := range event.ExDates {
+			write("EXDATE" + formatDateTime(ex, event.TimeZone))
+		}
+		if event.Status != "" {
+			write("STATUS:" + event.Status)
+		}
+		write("SUMMARY:" + escapeText(event.Summary))
+		if event.Description != "" {
+			write("DESCRIPTION:" + escapeText(event.Description))
+		}
+		if event.Location != "" {
+			write("LOCATION:" + escapeText(event.Location))
+		}
+		if event.Geo != "" {
+			write("GEO:" + event.Geo)
+		}
+		if event.Contact != "" {
+			write("CONTACT:" + escapeText(event.Contact))
+		}
+		write("END:VEVENT")
+	}
+	write("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// eventStatus - the VEVENT STATUS matching a booking status
+func eventStatus(status booking.Status) string {
+	switch status {
+	case booking.StatusRequested:
+		return "TENTATIVE"
+	case booking.StatusCancelled, booking.StatusNoShow:
+		return "CANCELLED"
+	case "":
+		return ""
+	}
+	return "CONFIRMED"
+}
+
+// formatDateTime - the parameters and value of a DATE-TIME property, in loc when it is set
+func formatDateTime(t time.Time, loc *time.Location) string {
+	if loc == nil || loc == time.UTC {
+		return ":" + formatUTC(t)
+	}
+	return ";TZID=" + loc.String() + ":" + t.In(loc).Format(localFormat)
+}
+
+func formatUTC(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format(utcFormat)
+}
+
+// escapeText - escapes a TEXT value (RFC 5545 section 3.3.11)
+func escapeText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(value)
+}
+
+// writeFolded - writes a content line, folding it at 75 octets without splitting a UTF-8 character
+func writeFolded(w *bufio.Writer, line string) {
+	limit := maxLineSize
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// continuation lines start with a space, which counts towards their length
+		limit = maxLineSize - 1
+	}
+	w.WriteString(line + "\r\n")
+}
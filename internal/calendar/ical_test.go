@@ -0,0 +1,186 @@
+package calendar
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+	"unicode/utf8"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+)
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", "plain"},
+		{"a;b,c", `a\;b\,c`},
+		{`C:\temp`, `C:\\temp`},
+		{"line one\nline two", `line one\nline two`},
+		{"line one\r\nline two", `line one\nline two`},
+	}
+	for _, tt := range tests {
+		if got := escapeText(tt.value); got != tt.want {
+			t.Errorf("escapeText(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+		if got := unescapeText(escapeText(tt.value)); got != strings.ReplaceAll(tt.value, "\r\n", "\n") {
+			t.Errorf("unescapeText(escapeText(%q)) = %q", tt.value, got)
+		}
+	}
+}
+
+func TestWriteFolded(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"short", "SUMMARY:Boiler service"},
+		{"exactly 75", "SUMMARY:" + strings.Repeat("a", 67)},
+		{"long", "DESCRIPTION:" + strings.Repeat("calibration ", 30)},
+		{"multi-byte", "LOCATION:" + strings.Repeat("Dún Laoghaire ", 12)},
+		{"wide characters", "SUMMARY:" + strings.Repeat("点検", 40)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			writeFolded(w, tt.line)
+			w.Flush()
+
+			folded := strings.TrimSuffix(buf.String(), "\r\n")
+			for i, physical := range strings.Split(folded, "\r\n") {
+				if len(physical) > maxLineSize {
+					t.Errorf("line %d is %d octets", i, len(physical))
+				}
+				if !utf8.ValidString(physical) {
+					t.Errorf("line %d splits a UTF-8 character: %q", i, physical)
+				}
+			}
+			lines, err := unfold(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lines) != 1 || lines[0] != tt.line {
+				t.Errorf("unfold = %q, want %q", lines, tt.line)
+			}
+		})
+	}
+}
+
+func TestEventStatus(t *testing.T) {
+	tests := []struct {
+		status booking.Status
+		want   string
+	}{
+		{"", ""},
+		{booking.StatusRequested, "TENTATIVE"},
+		{booking.StatusCancelled, "CANCELLED"},
+		{booking.StatusNoShow, "CANCELLED"},
+		{booking.StatusCompleted, "CONFIRMED"},
+	}
+	for _, tt := range tests {
+		if got := eventStatus(tt.status); got != tt.want {
+			t.Errorf("eventStatus(%q) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDateTime(t *testing.T) {
+	dublin, err := time.LoadLocation("Europe/Dublin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		t    time.Time
+		loc  *time.Location
+		want string
+	}{
+		{"utc", time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC), nil, ":20260701T080000Z"},
+		{"summer", time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC), dublin, ";TZID=Europe/Dublin:20260701T090000"},
+		{"winter", time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC), dublin, ";TZID=Europe/Dublin:20260101T080000"},
+	}
+	for _, tt := range tests {
+		if got := formatDateTime(tt.t, tt.loc); got != tt.want {
+			t.Errorf("%s: formatDateTime = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestEncodeDecode - what Encode writes, Decode reads back
+func TestEncodeDecode(t *testing.T) {
+	dublin, err := time.LoadLocation("Europe/Dublin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recurrenceID := time.Date(2026, 4, 6, 8, 0, 0, 0, time.UTC)
+	events := []Event{
+		{
+			UID:         "booking-1@" + uidDomain,
+			Summary:     "Annual service; boiler, pump",
+			Description: "Bring the long ladder\nGate code 1234",
+			Location:    "Unit 4, Sandyford",
+			Start:       time.Date(2026, 1, 12, 9, 0, 0, 0, time.UTC),
+			End:         time.Date(2026, 1, 12, 11, 30, 0, 0, time.UTC),
+		},
+		{
+			UID:      "booking-2@" + uidDomain,
+			Summary:  "Weekly calibration",
+			Start:    time.Date(2026, 3, 23, 9, 0, 0, 0, time.UTC),
+			End:      time.Date(2026, 3, 23, 10, 0, 0, 0, time.UTC),
+			RRule:    "FREQ=WEEKLY;COUNT=10",
+			ExDates:  []time.Time{time.Date(2026, 3, 30, 8, 0, 0, 0, time.UTC)},
+			TimeZone: dublin,
+		},
+		{
+			UID:          "booking-2@" + uidDomain,
+			Summary:      "Weekly calibration (moved)",
+			Start:        time.Date(2026, 4, 7, 8, 0, 0, 0, time.UTC),
+			End:          time.Date(2026, 4, 7, 9, 0, 0, 0, time.UTC),
+			RecurrenceID: &recurrenceID,
+			TimeZone:     dublin,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, "Engineer, Dublin", events); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "BEGIN:VTIMEZONE\r\nTZID:Europe/Dublin\r\n") {
+		t.Error("no VTIMEZONE written for Europe/Dublin")
+	}
+	decoded, errs, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) > 0 {
+		t.Fatalf("decode errors: %v", errs)
+	}
+	if len(decoded) != len(events) {
+		t.Fatalf("decoded %d events, want %d", len(decoded), len(events))
+	}
+	for i, want := range events {
+		got := decoded[i]
+		if got.UID != want.UID || got.Summary != want.Summary || got.Description != want.Description ||
+			got.Location != want.Location || got.RRule != want.RRule {
+			t.Errorf("event %d = %+v, want %+v", i, got, want)
+		}
+		if !got.Start.Equal(want.Start) || !got.End.Equal(want.End) {
+			t.Errorf("event %d runs %s to %s, want %s to %s", i, got.Start, got.End, want.Start, want.End)
+		}
+		if want.TimeZone != nil && got.Start.Location().String() != want.TimeZone.String() {
+			t.Errorf("event %d is in %s, want %s", i, got.Start.Location(), want.TimeZone)
+		}
+		if len(got.ExDates) != len(want.ExDates) || (len(want.ExDates) > 0 && !got.ExDates[0].Equal(want.ExDates[0])) {
+			t.Errorf("event %d EXDATEs = %v, want %v", i, got.ExDates, want.ExDates)
+		}
+		if (got.RecurrenceID == nil) != (want.RecurrenceID == nil) ||
+			(want.RecurrenceID != nil && !got.RecurrenceID.Equal(*want.RecurrenceID)) {
+			t.Errorf("event %d RECURRENCE-ID = %v, want %v", i, got.RecurrenceID, want.RecurrenceID)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/jinzhu/gorm"
+)
+
+// import result statuses
+const (
+	StatusCreated     = "created"
+	StatusWouldCreate = "would_create"
+	StatusDuplicate   = "duplicate"
+	StatusConflict    = "conflict"
+	StatusError       = "error"
+)
+
+// ImportResult - what happened, or in a dry run would happen, to one VEVENT
+type ImportResult struct {
+	UID       string            `json:"uid"`
+	Summary   string            `json:"summary"`
+	Status    string            `json:"status"`
+	BookingID uint              `json:"bookingId,omitempty"`
+	Message   string            `json:"message,omitempty"`
+	Booking   *booking.Booking  `json:"booking,omitempty"`
+	Conflicts []booking.Booking `json:"conflicts,omitempty"`
+}
+
+// ImportReport - the outcome of importing a calendar
+type ImportReport struct {
+	DryRun  bool           `json:"dryRun"`
+	Results []ImportResult `json:"results"`
+}
+
+// BookingFromEvent - maps a VEVENT onto a new booking, keeping its UID so re-imports are detected
+func BookingFromEvent(event Event) booking.Booking {
+	b := booking.Booking{
+		UID:           event.UID,
+		Summary:       event.Summary,
+		Description:   event.Description,
+		Location:      event.Location,
+		StartDateTime: event.Start.UTC(),
+		EndDateTime:   event.End.UTC(),
+		RRule:         event.RRule,
+	}
+	// events given in a zone keep it, so a recurring event repeats at the same local time. Zones known
+	// only from the calendar's VTIMEZONE have no IANA name to keep, their times are taken in UTC.
+	if loc := event.Start.Location(); loc != time.UTC && ianaZone(loc) {
+		b.TimeZone = loc.String()
+	}
+	for _, ex := range event.ExDates {
+		b.ExceptionDates = append(b.ExceptionDates, booking.ExceptionDate{Start: ex.UTC()})
+	}
+	return b
+}
+
+// Import - creates a booking for each event. Events whose UID is already booked are reported as
+// duplicates, and overlapping events as conflicts unless override is set. With dryRun nothing is
+// saved and the report shows what would be created, each event checked as it would be when saved,
+// against the existing bookings and the events before it that would be created too. Overrides of
+// single occurrences (events with a RECURRENCE-ID) are applied to their series once the series has
+// been imported.
+func (s *Service) Import(events []Event, decodeErrs map[int]error, dryRun bool, override bool) (ImportReport, error) {
+	report := ImportReport{DryRun: dryRun, Results: make([]ImportResult, len(events))}
+	seen := make(map[string]bool)
+	// the bookings a dry run would create so far, which later events must not overlap
+	var accepted []booking.Booking
+
+	// series and single events first, so overrides can find the series they belong to
+	var overrides []int
+	for i, event := range events {
+		result := &report.Results[i]
+		result.UID, result.Summary = event.UID, event.Summary
+		if err := decodeErrs[i]; err != nil {
+			result.Status, result.Message = StatusError, err.Error()
+			continue
+		}
+		if event.RecurrenceID != nil {
+			overrides = append(overrides, i)
+			continue
+		}
+		s.importEvent(event, result, seen, &accepted, dryRun, override)
+	}
+
+	for _, i := range overrides {
+		s.importOverride(events[i], &report.Results[i], &accepted, dryRun, override)
+	}
+	return report, nil
+}
+
+func (s *Service) importEvent(event Event, result *ImportResult, seen map[string]bool, accepted *[]booking.Booking, dryRun bool, override bool) {
+	if event.UID == "" {
+		result.Status, result.Message = StatusError, "VEVENT has no UID"
+		return
+	}
+	if seen[event.UID] {
+		result.Status, result.Message = StatusDuplicate, "UID appears more than once in the calendar"
+		return
+	}
+	seen[event.UID] = true
+
+	existing, err := s.Bookings.GetBookingByUID(event.UID)
+	switch {
+	case err == nil:
+		result.Status, result.BookingID = StatusDuplicate, existing.ID
+		result.Message = "UID has already been imported"
+		return
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		result.Status, result.Message = StatusError, err.Error()
+		return
+	}
+
+	b := BookingFromEvent(event)
+	s.create(b, result, accepted, dryRun, override)
+	if b.RRule != "" && b.TimeZone == "" && event.Start.Location() != time.UTC && result.Message == "" {
+		result.Message = fmt.Sprintf("TZID %q is not an IANA zone, occurrences repeat at the same UTC time "+
+			"and may move by an hour across daylight saving changes", event.Start.Location())
+	}
+}
+
+// ianaZone - reports whether loc is a zone from the time zone database rather than one read from a VTIMEZONE
+func ianaZone(loc *time.Location) bool {
+	_, err := time.LoadLocation(loc.String())
+	return err == nil
+}
+
+func (s *Service) importOverride(event Event, result *ImportResult, accepted *[]booking.Booking, dryRun bool, override bool) {
+	changes := BookingFromEvent(event)
+	changes.UID, changes.RRule, changes.ExceptionDates = "", "", nil
+	if dryRun {
+		// the series may be one this import would create
+		for i, b := range *accepted {
+			if b.UID == event.UID && b.RRule != "" {
+				s.checkOverride(b, i, changes, event, result, accepted, override)
+				return
+			}
+		}
+	}
+	series, err := s.Bookings.GetBookingByUID(event.UID)
+	if err != nil {
+		result.Status, result.Message = StatusError, fmt.Sprintf("no recurring booking with UID %s to override", event.UID)
+		return
+	}
+	if dryRun {
+		s.checkOverride(series, -1, changes, event, result, accepted, override)
+		return
+	}
+
+	detached, err := s.Bookings.UpdateOccurrence(series.ID, event.RecurrenceID.UTC(), booking.ScopeThis, changes, override)
+	if err != nil {
+		setFailure(result, err)
+		return
+	}
+	result.Status, result.BookingID, result.Booking = StatusCreated, detached.ID, &detached
+}
+
+// checkOverride - checks, for a dry run, the override of an occurrence of the series as it would be
+// applied, against the existing bookings and the other events the import would create. A series the
+// import would create is accepted[i], and has the occurrence excluded as applying the override would;
+// i is -1 for a series already saved.
+func (s *Service) checkOverride(series booking.Booking, i int, changes booking.Booking, event Event, result *ImportResult, accepted *[]booking.Booking, override bool) {
+	occurrence := event.RecurrenceID.UTC()
+	checked, err := s.Bookings.CheckOccurrence(series, occurrence, changes, override)
+	if err != nil {
+		setFailure(result, err)
+		return
+	}
+	others := make([]booking.Booking, 0, len(*accepted))
+	for j, other := range *accepted {
+		if j != i {
+			others = append(others, other)
+		}
+	}
+	conflicts, err := booking.Overlaps(checked, others)
+	if err != nil {
+		setFailure(result, err)
+		return
+	}
+	if len(conflicts) > 0 && !override {
+		setFailure(result, &booking.ConflictError{Conflicts: conflicts})
+		return
+	}
+	if i >= 0 {
+		(*accepted)[i].ExceptionDates = append((*accepted)[i].ExceptionDates, booking.ExceptionDate{Start: occurrence})
+	}
+	*accepted = append(*accepted, checked)
+	result.Status, result.BookingID, result.Booking = StatusWouldCreate, series.ID, &checked
+}
+
+func (s *Service) create(b booking.Booking, result *ImportResult, accepted *[]booking.Booking, dryRun bool, override bool) {
+	if dryRun {
+		checked, err := s.Bookings.CheckBooking(b, override)
+		if err != nil {
+			setFailure(result, err)
+			return
+		}
+		conflicts, err := booking.Overlaps(checked, *accepted)
+		if err != nil {
+			setFailure(result, err)
+			return
+		}
+		if len(conflicts) > 0 && !override {
+			setFailure(result, &booking.ConflictError{Conflicts: conflicts})
+			return
+		}
+		*accepted = append(*accepted, checked)
+		result.Status, result.Booking = StatusWouldCreate, &checked
+		return
+	}
+
+	created, err := s.Bookings.PostBooking(b, override)
+	if err != nil {
+		setFailure(result, err)
+		return
+	}
+	result.Status, result.BookingID, result.Booking = StatusCreated, created.ID, &created
+}
+
+func setFailure(result *ImportResult, err error) {
+	var conflict *booking.ConflictError
+	if errors.As(err, &conflict) {
+		result.Status, result.Message, result.Conflicts = StatusConflict, err.Error(), conflict.Conflicts
+		return
+	}
+	result.Status, result.Message = StatusError, err.Error()
+}
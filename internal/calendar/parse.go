@@ -0,0 +1,328 @@
+package calendar
+
+// Parse RFC 5545 iCalendar data into events.
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCalendar - returned when iCalendar data cannot be parsed
+var ErrInvalidCalendar = errors.New("invalid iCalendar data")
+
+// windowsZones - Outlook and Exchange write Windows zone names as TZIDs, map the common ones to IANA names
+var windowsZones = map[string]string{
+	"UTC":                            "UTC",
+	"GMT Standard Time":              "Europe/London",
+	"Greenwich Standard Time":        "Atlantic/Reykjavik",
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Romance Standard Time":          "Europe/Paris",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"Central European Standard Time": "Europe/Warsaw",
+	"E. Europe Standard Time":        "Europe/Chisinau",
+	"FLE Standard Time":              "Europe/Kiev",
+	"Eastern Standard Time":          "America/New_York",
+	"Central Standard Time":          "America/Chicago",
+	"Mountain Standard Time":         "America/Denver",
+	"Pacific Standard Time":          "America/Los_Angeles",
+}
+
+// property - one content line, NAME;PARAM=VALUE:value
+type property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Decode - reads the VEVENTs from iCalendar data. Events that cannot be interpreted are returned
+// with their error in errs, keyed by position, so one bad event does not fail the whole calendar.
+// TZIDs that are not zone names are resolved against the VTIMEZONEs in the data.
+func Decode(r io.Reader) ([]Event, map[int]error, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	props := make([]property, len(lines))
+	for i, line := range lines {
+		if props[i], err = parseProperty(line); err != nil {
+			return nil, nil, err
+		}
+	}
+	zones := readTimezones(props)
+
+	var events []Event
+	errs := make(map[int]error)
+	var current []property
+	depth := 0
+	inEvent := false
+	for _, prop := range props {
+		switch {
+		case prop.Name == "BEGIN" && strings.EqualFold(prop.Value, "VEVENT") && !inEvent:
+			inEvent, depth, current = true, 0, nil
+		case prop.Name == "BEGIN" && inEvent:
+			// skip nested components such as VALARM
+			depth++
+		case prop.Name == "END" && inEvent && depth > 0:
+			depth--
+		case prop.Name == "END" && strings.EqualFold(prop.Value, "VEVENT") && inEvent:
+			inEvent = false
+			event, err := eventFromProperties(current, zones)
+			if err != nil {
+				errs[len(events)] = err
+			}
+			events = append(events, event)
+		case inEvent && depth == 0:
+			current = append(current, prop)
+		}
+	}
+	if inEvent {
+		return nil, nil, fmt.Errorf("%w: unterminated VEVENT", ErrInvalidCalendar)
+	}
+	return events, errs, nil
+}
+
+func eventFromProperties(props []property, zones map[string]*zone) (Event, error) {
+	var event Event
+	var duration time.Duration
+	allDay := false
+	var err error
+	for _, prop := range props {
+		switch prop.Name {
+		case "UID":
+			event.UID = prop.Value
+		case "SUMMARY":
+			event.Summary = unescapeText(prop.Value)
+		case "DESCRIPTION":
+			event.Description = unescapeText(prop.Value)
+		case "LOCATION":
+			event.Location = unescapeText(prop.Value)
+		case "DTSTART":
+			if event.Start, err = parseDateTime(prop, zones); err != nil {
+				return event, err
+			}
+			allDay = prop.Params["VALUE"] == "DATE" || len(prop.Value) == len("20060102")
+		case "DTEND":
+			if event.End, err = parseDateTime(prop, zones); err != nil {
+				return event, err
+			}
+		case "DURATION":
+			if duration, err = parseDuration(prop.Value); err != nil {
+				return event, err
+			}
+		case "RRULE":
+			event.RRule = prop.Value
+		case "EXDATE":
+			for _, value := range strings.Split(prop.Value, ",") {
+				ex, err := parseDateTime(property{Name: prop.Name, Params: prop.Params, Value: value}, zones)
+				if err != nil {
+					return event, err
+				}
+				event.ExDates = append(event.ExDates, ex)
+			}
+		case "RECURRENCE-ID":
+			recurrenceID, err := parseDateTime(prop, zones)
+			if err != nil {
+				return event, err
+			}
+			event.RecurrenceID = &recurrenceID
+		case "DTSTAMP":
+			event.Stamp, _ = parseDateTime(prop, zones)
+		}
+	}
+
+	if event.Start.IsZero() {
+		return event, fmt.Errorf("%w: VEVENT %q has no DTSTART", ErrInvalidCalendar, event.UID)
+	}
+	if event.End.IsZero() {
+		switch {
+		case duration > 0:
+			event.End = event.Start.Add(duration)
+		case allDay:
+			event.End = event.Start.AddDate(0, 0, 1)
+		default:
+			event.End = event.Start
+		}
+	}
+	return event, nil
+}
+
+// parseDateTime - reads a DATE or DATE-TIME value in UTC, a TZID zone, or floating (taken as UTC)
+func parseDateTime(prop property, zones map[string]*zone) (time.Time, error) {
+	value := strings.TrimSpace(prop.Value)
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+	layout := "20060102T150405"
+	if len(value) == len("20060102") {
+		layout = "20060102"
+	}
+	wall, err := time.Parse(layout, value)
+	if err != nil {
+		return wall, fmt.Errorf("%w: %s %q", ErrInvalidCalendar, prop.Name, value)
+	}
+
+	tzid, ok := prop.Params["TZID"]
+	if !ok {
+		return wall, nil
+	}
+	loc, err := loadZone(tzid, zones, wall)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc), nil
+}
+
+// loadZone - resolves a TZID as an IANA name, one of the common Windows zone names, or a VTIMEZONE
+// in the data. A VTIMEZONE gives the fixed offset in force at the wall-clock time wall.
+func loadZone(tzid string, zones map[string]*zone, wall time.Time) (*time.Location, error) {
+	tzid = strings.Trim(tzid, `"`)
+	if loc, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+		return loc, nil
+	}
+	if name, ok := windowsZones[tzid]; ok {
+		return time.LoadLocation(name)
+	}
+	if z, ok := zones[tzid]; ok {
+		if z.err != nil {
+			return nil, fmt.Errorf("TZID %q: %w", tzid, z.err)
+		}
+		return time.FixedZone(tzid, z.offsetAt(wall)), nil
+	}
+	return nil, fmt.Errorf("%w: unknown TZID %q with no VTIMEZONE", ErrInvalidCalendar, tzid)
+}
+
+// parseDuration - reads a DURATION value such as PT1H30M or P1D
+func parseDuration(value string) (time.Duration, error) {
+	invalid := fmt.Errorf("%w: DURATION %q", ErrInvalidCalendar, value)
+	value = strings.TrimPrefix(value, "+")
+	if !strings.HasPrefix(value, "P") {
+		return 0, invalid
+	}
+
+	var total time.Duration
+	number, units := 0, 0
+	digits := false
+	inTime := false
+	for _, c := range value[1:] {
+		var unit time.Duration
+		switch {
+		case c >= '0' && c <= '9':
+			number = number*10 + int(c-'0')
+			digits = true
+			continue
+		case c == 'T':
+			inTime = true
+			continue
+		case c == 'W' && !inTime:
+			unit = 7 * 24 * time.Hour
+		case c == 'D' && !inTime:
+			unit = 24 * time.Hour
+		case c == 'H' && inTime:
+			unit = time.Hour
+		case c == 'M' && inTime:
+			unit = time.Minute
+		case c == 'S' && inTime:
+			unit = time.Second
+		default:
+			return 0, invalid
+		}
+		if !digits {
+			return 0, invalid
+		}
+		total += time.Duration(number) * unit
+		number, digits = 0, false
+		units++
+	}
+	if digits || units == 0 {
+		return 0, invalid
+	}
+	return total, nil
+}
+
+// unfold - splits iCalendar data into content lines, joining folded continuation lines
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseProperty - splits a content line into its name, parameters and value. Parameter values
+// may be quoted and contain ':' or ';'.
+func parseProperty(line string) (property, error) {
+	prop := property{Params: make(map[string]string)}
+	inQuotes := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+		}
+		if c == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return prop, fmt.Errorf("%w: line %q has no value", ErrInvalidCalendar, line)
+	}
+	prop.Value = line[colon+1:]
+
+	parts := splitParams(line[:colon])
+	prop.Name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 {
+			prop.Params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return prop, nil
+}
+
+func splitParams(value string) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i, c := range value {
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == ';' && !inQuotes:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// unescapeText - reverses escapeText
+func unescapeText(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+			switch value[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(value[i])
+			}
+			continue
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
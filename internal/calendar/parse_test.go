@@ -0,0 +1,269 @@
+package calendar
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// calendarOf - wraps content lines in a VCALENDAR
+func calendarOf(lines ...string) string {
+	return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" + strings.Join(lines, "\r\n") + "\r\nEND:VCALENDAR\r\n"
+}
+
+// customZone - a VTIMEZONE as Outlook writes it for a zone it has no name for, an hour ahead of UTC
+// from the last Sunday of March to the last Sunday of October
+var customZone = []string{
+	"BEGIN:VTIMEZONE",
+	"TZID:Customized Time Zone",
+	"BEGIN:STANDARD",
+	"DTSTART:16010101T020000",
+	"TZOFFSETFROM:+0100",
+	"TZOFFSETTO:+0000",
+	"RRULE:FREQ=YEARLY;INTERVAL=1;BYDAY=-1SU;BYMONTH=10",
+	"END:STANDARD",
+	"BEGIN:DAYLIGHT",
+	"DTSTART:16010101T010000",
+	"TZOFFSETFROM:+0000",
+	"TZOFFSETTO:+0100",
+	"RRULE:FREQ=YEARLY;INTERVAL=1;BYDAY=-1SU;BYMONTH=3",
+	"END:DAYLIGHT",
+	"END:VTIMEZONE",
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"PT1H30M", 90 * time.Minute},
+		{"P1D", 24 * time.Hour},
+		{"P1W", 7 * 24 * time.Hour},
+		{"P1DT2H", 26 * time.Hour},
+		{"+PT45S", 45 * time.Second},
+	}
+	for _, tt := range tests {
+		got, err := parseDuration(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("parseDuration(%q) = %s, %v, want %s", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "1H", "PT", "PTH", "P1H", "PT1D", "PT1", "-PT1H"} {
+		if _, err := parseDuration(value); !errors.Is(err, ErrInvalidCalendar) {
+			t.Errorf("parseDuration(%q) error = %v, want ErrInvalidCalendar", value, err)
+		}
+	}
+}
+
+func TestParseProperty(t *testing.T) {
+	tests := []struct {
+		line   string
+		name   string
+		params map[string]string
+		value  string
+	}{
+		{"SUMMARY:Boiler service", "SUMMARY", map[string]string{}, "Boiler service"},
+		{"dtstart;tzid=Europe/Dublin:20260112T090000", "DTSTART", map[string]string{"TZID": "Europe/Dublin"}, "20260112T090000"},
+		{`DTSTART;TZID="(UTC+01:00) Amsterdam; Berlin":20260112T090000`, "DTSTART",
+			map[string]string{"TZID": "(UTC+01:00) Amsterdam; Berlin"}, "20260112T090000"},
+		{"DESCRIPTION:Time: 09:00", "DESCRIPTION", map[string]string{}, "Time: 09:00"},
+	}
+	for _, tt := range tests {
+		prop, err := parseProperty(tt.line)
+		if err != nil {
+			t.Errorf("parseProperty(%q): %v", tt.line, err)
+			continue
+		}
+		if prop.Name != tt.name || prop.Value != tt.value || len(prop.Params) != len(tt.params) {
+			t.Errorf("parseProperty(%q) = %+v", tt.line, prop)
+		}
+		for k, v := range tt.params {
+			if prop.Params[k] != v {
+				t.Errorf("parseProperty(%q) %s = %q, want %q", tt.line, k, prop.Params[k], v)
+			}
+		}
+	}
+
+	if _, err := parseProperty("SUMMARY"); !errors.Is(err, ErrInvalidCalendar) {
+		t.Errorf("a line with no value gave %v, want ErrInvalidCalendar", err)
+	}
+}
+
+func TestParseOffset(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"+0000", 0},
+		{"+0100", 3600},
+		{"-0500", -5 * 3600},
+		{"+0530", 5*3600 + 30*60},
+		{"-013015", -(3600 + 30*60 + 15)},
+	}
+	for _, tt := range tests {
+		got, err := parseOffset(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("parseOffset(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "0100", "+100", "+01:00", "+01a0"} {
+		if _, err := parseOffset(value); !errors.Is(err, ErrInvalidCalendar) {
+			t.Errorf("parseOffset(%q) error = %v, want ErrInvalidCalendar", value, err)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		start string
+		end   string
+	}{
+		{
+			name:  "utc",
+			lines: []string{"DTSTART:20260112T090000Z", "DTEND:20260112T100000Z"},
+			start: "2026-01-12T09:00:00Z", end: "2026-01-12T10:00:00Z",
+		},
+		{
+			name:  "floating times are utc",
+			lines: []string{"DTSTART:20260112T090000", "DURATION:PT2H"},
+			start: "2026-01-12T09:00:00Z", end: "2026-01-12T11:00:00Z",
+		},
+		{
+			name:  "all day",
+			lines: []string{"DTSTART;VALUE=DATE:20260112"},
+			start: "2026-01-12T00:00:00Z", end: "2026-01-13T00:00:00Z",
+		},
+		{
+			name:  "no end",
+			lines: []string{"DTSTART:20260112T090000Z"},
+			start: "2026-01-12T09:00:00Z", end: "2026-01-12T09:00:00Z",
+		},
+		{
+			name:  "iana zone in summer",
+			lines: []string{"DTSTART;TZID=Europe/Dublin:20260715T090000", "DTEND;TZID=Europe/Dublin:20260715T100000"},
+			start: "2026-07-15T08:00:00Z", end: "2026-07-15T09:00:00Z",
+		},
+		{
+			name:  "windows zone",
+			lines: []string{"DTSTART;TZID=Pacific Standard Time:20260115T090000", "DURATION:PT1H"},
+			start: "2026-01-15T17:00:00Z", end: "2026-01-15T18:00:00Z",
+		},
+		{
+			name:  "custom zone in summer",
+			lines: []string{"DTSTART;TZID=Customized Time Zone:20260715T090000", "DURATION:PT1H"},
+			start: "2026-07-15T08:00:00Z", end: "2026-07-15T09:00:00Z",
+		},
+		{
+			name:  "custom zone in winter",
+			lines: []string{"DTSTART;TZID=Customized Time Zone:20260115T090000", "DURATION:PT1H"},
+			start: "2026-01-15T09:00:00Z", end: "2026-01-15T10:00:00Z",
+		},
+		{
+			name:  "custom zone the day summer time starts",
+			lines: []string{"DTSTART;TZID=Customized Time Zone:20260329T090000", "DURATION:PT1H"},
+			start: "2026-03-29T08:00:00Z", end: "2026-03-29T09:00:00Z",
+		},
+		{
+			name:  "nested alarm",
+			lines: []string{"DTSTART:20260112T090000Z", "BEGIN:VALARM", "TRIGGER:-PT15M", "DTSTART:20200101T000000Z", "END:VALARM"},
+			start: "2026-01-12T09:00:00Z", end: "2026-01-12T09:00:00Z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := append(append([]string{}, customZone...), "BEGIN:VEVENT", "UID:event-1")
+			lines = append(append(lines, tt.lines...), "END:VEVENT")
+			events, errs, err := Decode(strings.NewReader(calendarOf(lines...)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != 1 || errs[0] != nil {
+				t.Fatalf("got %d events, errors %v", len(events), errs)
+			}
+			if got := events[0].Start.UTC().Format(time.RFC3339); got != tt.start {
+				t.Errorf("start = %s, want %s", got, tt.start)
+			}
+			if got := events[0].End.UTC().Format(time.RFC3339); got != tt.end {
+				t.Errorf("end = %s, want %s", got, tt.end)
+			}
+		})
+	}
+}
+
+// TestDecodeErrors - events that cannot be read are reported by position, the rest still decode
+func TestDecodeErrors(t *testing.T) {
+	brokenZone := []string{
+		"BEGIN:VTIMEZONE",
+		"TZID:Broken Zone",
+		"BEGIN:STANDARD",
+		"DTSTART:16010101T020000",
+		"TZOFFSETTO:+0000",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+	}
+	tests := []struct {
+		name    string
+		lines   []string
+		message string
+	}{
+		{"no start", []string{"SUMMARY:No start"}, "has no DTSTART"},
+		{"bad date", []string{"DTSTART:2026-01-12"}, "DTSTART"},
+		{"bad duration", []string{"DTSTART:20260112T090000Z", "DURATION:1H"}, "DURATION"},
+		{"unknown zone", []string{"DTSTART;TZID=Nowhere:20260112T090000"}, `unknown TZID "Nowhere"`},
+		{"broken zone", []string{"DTSTART;TZID=Broken Zone:20260112T090000"}, `TZID "Broken Zone"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := append(append([]string{}, brokenZone...), "BEGIN:VEVENT", "UID:good", "DTSTART:20260112T090000Z", "END:VEVENT")
+			lines = append(append(append(lines, "BEGIN:VEVENT", "UID:bad"), tt.lines...), "END:VEVENT")
+			events, errs, err := Decode(strings.NewReader(calendarOf(lines...)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != 2 || errs[0] != nil {
+				t.Fatalf("got %d events, errors %v", len(events), errs)
+			}
+			if !errors.Is(errs[1], ErrInvalidCalendar) || !strings.Contains(errs[1].Error(), tt.message) {
+				t.Errorf("error = %v, want ErrInvalidCalendar mentioning %q", errs[1], tt.message)
+			}
+		})
+	}
+
+	if _, _, err := Decode(strings.NewReader(calendarOf("BEGIN:VEVENT", "UID:open"))); !errors.Is(err, ErrInvalidCalendar) {
+		t.Errorf("an unterminated VEVENT gave %v, want ErrInvalidCalendar", err)
+	}
+}
+
+func TestBookingFromEvent(t *testing.T) {
+	lines := append(append([]string{}, customZone...),
+		"BEGIN:VEVENT", "UID:iana", "DTSTART;TZID=Europe/Dublin:20260715T090000", "RRULE:FREQ=WEEKLY", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:custom", "DTSTART;TZID=Customized Time Zone:20260715T090000", "RRULE:FREQ=WEEKLY", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:utc", "DTSTART:20260715T090000Z", "END:VEVENT")
+	events, errs, err := Decode(strings.NewReader(calendarOf(lines...)))
+	if err != nil || len(errs) > 0 {
+		t.Fatal(err, errs)
+	}
+	tests := []struct {
+		uid   string
+		zone  string
+		start time.Time
+	}{
+		{"iana", "Europe/Dublin", time.Date(2026, 7, 15, 8, 0, 0, 0, time.UTC)},
+		{"custom", "", time.Date(2026, 7, 15, 8, 0, 0, 0, time.UTC)},
+		{"utc", "", time.Date(2026, 7, 15, 9, 0, 0, 0, time.UTC)},
+	}
+	for i, tt := range tests {
+		b := BookingFromEvent(events[i])
+		if b.UID != tt.uid || b.TimeZone != tt.zone {
+			t.Errorf("booking %q has time zone %q, want %q", b.UID, b.TimeZone, tt.zone)
+		}
+		if !b.StartDateTime.Equal(tt.start) || b.StartDateTime.Location() != time.UTC {
+			t.Errorf("booking %q starts %s, want %s", b.UID, b.StartDateTime, tt.start)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/recurrence"
+)
+
+const localFormat = "20060102T150405"
+
+// transition - a change of UTC offset in a zone
+type transition struct {
+	At         time.Time
+	OffsetFrom int
+	OffsetTo   int
+}
+
+// writeTimezone - writes a VTIMEZONE for loc describing every offset change between from and to,
+// taken from the system time zone database
+func writeTimezone(write func(string), loc *time.Location, from, to time.Time) {
+	write("BEGIN:VTIMEZONE")
+	write("TZID:" + loc.String())
+
+	// the observance in force at the start of the range, then one per transition
+	name, offset := from.In(loc).Zone()
+	writeObservance(write, from.In(loc).IsDST(), from.In(loc), offset, offset, name)
+	for _, t := range transitions(loc, from, to) {
+		local := t.At.In(loc)
+		name, _ := local.Zone()
+		// DTSTART is the wall-clock time the change happens at, read in the offset before it
+		wall := t.At.Add(time.Duration(t.OffsetFrom) * time.Second).UTC()
+		writeObservance(write, local.IsDST(), wall, t.OffsetFrom, t.OffsetTo, name)
+	}
+	write("END:VTIMEZONE")
+}
+
+func writeObservance(write func(string), dst bool, start time.Time, offsetFrom, offsetTo int, name string) {
+	kind := "STANDARD"
+	if dst {
+		kind = "DAYLIGHT"
+	}
+	write("BEGIN:" + kind)
+	write("DTSTART:" + start.Format(localFormat))
+	write("TZOFFSETFROM:" + formatOffset(offsetFrom))
+	write("TZOFFSETTO:" + formatOffset(offsetTo))
+	if name != "" {
+		write("TZNAME:" + escapeText(name))
+	}
+	write("END:" + kind)
+}
+
+// transitions - the offset changes in loc between from and to. Zones change offset at most a few
+// times a year, so days are scanned for a change and the exact second found by bisection.
+func transitions(loc *time.Location, from, to time.Time) []transition {
+	var found []transition
+	_, current := from.In(loc).Zone()
+	for day := from; day.Before(to); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		_, offset := next.In(loc).Zone()
+		if offset == current {
+			continue
+		}
+		lo, hi := day, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if _, o := mid.In(loc).Zone(); o == current {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		found = append(found, transition{At: hi.Truncate(time.Second), OffsetFrom: current, OffsetTo: offset})
+		current = offset
+	}
+	return found
+}
+
+// zoneRanges - the zones used by the events, each with the range its VTIMEZONE must cover. Recurring
+// events may repeat for years, so ranges run five years past the last start.
+func zoneRanges(events []Event) ([]*time.Location, map[*time.Location][2]time.Time) {
+	ranges := make(map[*time.Location][2]time.Time)
+	byName := make(map[string]*time.Location)
+	var zones []*time.Location
+	for _, event := range events {
+		if event.TimeZone == nil || event.TimeZone == time.UTC {
+			continue
+		}
+		loc, ok := byName[event.TimeZone.String()]
+		if !ok {
+			loc = event.TimeZone
+			byName[loc.String()] = loc
+			zones = append(zones, loc)
+			ranges[loc] = [2]time.Time{event.Start, event.Start}
+		}
+		r := ranges[loc]
+		if event.Start.Before(r[0]) {
+			r[0] = event.Start
+		}
+		if event.Start.After(r[1]) {
+			r[1] = event.Start
+		}
+		ranges[loc] = r
+	}
+	for loc, r := range ranges {
+		ranges[loc] = [2]time.Time{r[0].AddDate(-1, 0, 0).UTC().Truncate(24 * time.Hour), r[1].AddDate(5, 0, 0).UTC()}
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].String() < zones[j].String() })
+	return zones, ranges
+}
+
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+// zone - a VTIMEZONE read from imported data, used for TZIDs that are neither IANA nor known Windows
+// names, such as the custom zones Outlook and Exchange write
+type zone struct {
+	TZID        string
+	Observances []observance
+	// err - why the VTIMEZONE cannot be used, reported on each event in the zone
+	err error
+}
+
+// observance - a STANDARD or DAYLIGHT component. Onsets are wall-clock times in OffsetFrom, held as
+// UTC times so they compare directly with the wall-clock times of events.
+type observance struct {
+	Start      time.Time
+	OffsetFrom int
+	OffsetTo   int
+	Rule       *recurrence.Rule
+	RDates     []time.Time
+}
+
+// offsetAt - the UTC offset in force at a wall-clock time, from the latest onset at or before it.
+// Before the first onset the offset is the one that onset changes from.
+func (z *zone) offsetAt(wall time.Time) int {
+	var latest time.Time
+	offset, found := 0, false
+	for _, o := range z.Observances {
+		if latest.IsZero() || o.Start.Before(latest) {
+			latest, offset = o.Start, o.OffsetFrom
+		}
+	}
+	for _, o := range z.Observances {
+		if onset, ok := o.lastOnset(wall); ok && (!found || onset.After(latest)) {
+			latest, offset, found = onset, o.OffsetTo, true
+		}
+	}
+	return offset
+}
+
+// lastOnset - the observance's latest onset at or before a wall-clock time
+func (o observance) lastOnset(wall time.Time) (time.Time, bool) {
+	var last time.Time
+	found := false
+	if o.Rule != nil {
+		if onsets := o.Rule.Occurrences(o.Start, o.Start, wall.Add(time.Second), nil); len(onsets) > 0 {
+			last, found = onsets[len(onsets)-1], true
+		}
+	} else if !o.Start.After(wall) {
+		last, found = o.Start, true
+	}
+	for _, rdate := range o.RDates {
+		if !rdate.After(wall) && (!found || rdate.After(last)) {
+			last, found = rdate, true
+		}
+	}
+	return last, found
+}
+
+// readTimezones - the VTIMEZONEs in the data, by TZID
+func readTimezones(props []property) map[string]*zone {
+	zones := make(map[string]*zone)
+	var current *zone
+	var observanceProps []property
+	inObservance := false
+	for _, prop := range props {
+		isObservance := strings.EqualFold(prop.Value, "STANDARD") || strings.EqualFold(prop.Value, "DAYLIGHT")
+		switch {
+		case prop.Name == "BEGIN" && strings.EqualFold(prop.Value, "VTIMEZONE"):
+			current = &zone{}
+		case current == nil:
+			continue
+		case prop.Name == "BEGIN" && isObservance:
+			inObservance, observanceProps = true, nil
+		case prop.Name == "END" && isObservance && inObservance:
+			inObservance = false
+			o, err := readObservance(observanceProps)
+			if err != nil && current.err == nil {
+				current.err = err
+			}
+			current.Observances = append(current.Observances, o)
+		case prop.Name == "END" && strings.EqualFold(prop.Value, "VTIMEZONE"):
+			if len(current.Observances) == 0 && current.err == nil {
+				current.err = fmt.Errorf("%w: VTIMEZONE has no STANDARD or DAYLIGHT", ErrInvalidCalendar)
+			}
+			if current.TZID != "" {
+				zones[current.TZID] = current
+			}
+			current = nil
+		case inObservance:
+			observanceProps = append(observanceProps, prop)
+		case prop.Name == "TZID":
+			current.TZID = strings.Trim(prop.Value, `"`)
+		}
+	}
+	return zones
+}
+
+func readObservance(props []property) (observance, error) {
+	var o observance
+	var rrule string
+	hasFrom, hasTo := false, false
+	for _, prop := range props {
+		var err error
+		switch prop.Name {
+		case "DTSTART":
+			o.Start, err = time.Parse(localFormat, prop.Value)
+		case "TZOFFSETFROM":
+			o.OffsetFrom, err = parseOffset(prop.Value)
+			hasFrom = true
+		case "TZOFFSETTO":
+			o.OffsetTo, err = parseOffset(prop.Value)
+			hasTo = true
+		case "RRULE":
+			rrule = prop.Value
+		case "RDATE":
+			for _, value := range strings.Split(prop.Value, ",") {
+				rdate, rerr := time.Parse(localFormat, value)
+				if rerr != nil {
+					err = rerr
+					break
+				}
+				o.RDates = append(o.RDates, rdate)
+			}
+		}
+		if err != nil {
+			return o, fmt.Errorf("%w: VTIMEZONE %s %q", ErrInvalidCalendar, prop.Name, prop.Value)
+		}
+	}
+	if o.Start.IsZero() || !hasFrom || !hasTo {
+		return o, fmt.Errorf("%w: VTIMEZONE observance needs DTSTART, TZOFFSETFROM and TZOFFSETTO", ErrInvalidCalendar)
+	}
+	if rrule != "" {
+		rule, err := recurrence.Parse(rrule)
+		if err != nil {
+			return o, fmt.Errorf("%w: VTIMEZONE RRULE %q", ErrInvalidCalendar, rrule)
+		}
+		// RFC 5545 requires UNTIL here in UTC, onsets are wall-clock times in OffsetFrom
+		if !rule.Until.IsZero() {
+			rule.Until = rule.Until.Add(time.Duration(o.OffsetFrom) * time.Second)
+		}
+		o.Rule = &rule
+	}
+	return o, nil
+}
+
+// parseOffset - reads a UTC offset such as +0100, -0430 or +053000, in seconds
+func parseOffset(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if (len(value) != 5 && len(value) != 7) || (value[0] != '+' && value[0] != '-') {
+		return 0, fmt.Errorf("%w: offset %q", ErrInvalidCalendar, value)
+	}
+	seconds := 0
+	for i, unit := range []int{3600, 60, 1} {
+		if 1+2*i >= len(value) {
+			break
+		}
+		n, err := strconv.Atoi(value[1+2*i : 3+2*i])
+		if err != nil {
+			return 0, fmt.Errorf("%w: offset %q", ErrInvalidCalendar, value)
+		}
+		seconds += n * unit
+	}
+	if value[0] == '-' {
+		seconds = -seconds
+	}
+	return seconds, nil
+}
@@ -0,0 +1,158 @@
+package certificate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/Open-FiSE/go-rest-api/internal/document"
+	"github.com/jinzhu/gorm"
+)
+
+// errors returned by the certificate service
+var (
+	ErrNoJob           = errors.New("booking has no job to certify")
+	ErrNoMeasurements  = errors.New("equipment has no measurements to certify")
+	ErrNotCompleted    = errors.New("equipment must be completed before it is certified")
+	ErrInvalidIssuedBy = errors.New("certificate IssuedBy is required")
+)
+
+// Template - the fixed wording and letterhead of a certificate
+type Template struct {
+	Company   string
+	Address   []string
+	Title     string
+	Statement string
+}
+
+// DefaultTemplate - the template used until one is configured
+var DefaultTemplate = Template{
+	Company: "Open-FiSE Field Service",
+	Title:   "Certificate of Calibration",
+	Statement: "The instrument identified above was calibrated against reference standards traceable to national " +
+		"standards. As-found and as-left readings are reported with the permitted tolerance at each point. This " +
+		"certificate refers only to the instrument at the time of calibration and may not be reproduced other than in full.",
+}
+
+// Service - the struct for the certificate service, which renders certificates from job results and
+// files them as documents attached to the booking
+type Service struct {
+	DB        *gorm.DB
+	Bookings  *booking.BookService
+	Customers *customer.Service
+	Documents *document.Service
+	// Dir - the directory certificate files are written to
+	Dir      string
+	Template Template
+}
+
+// CertificateService - the interface for our certificate service
+type CertificateService interface {
+	Generate(bookingID uint, itemID uint, issuedBy string) (document.Document, error)
+}
+
+// NewService - takes in the services a certificate is drawn from and the directory to write them to
+// & returns a pointer to a new certificate service
+func NewService(db *gorm.DB, bookings *booking.BookService, customers *customer.Service, documents *document.Service, dir string) *Service {
+	return &Service{
+		DB:        db,
+		Bookings:  bookings,
+		Customers: customers,
+		Documents: documents,
+		Dir:       dir,
+		Template:  DefaultTemplate,
+	}
+}
+
+// Certificate - everything printed on a calibration certificate
+type Certificate struct {
+	Number   string
+	IssuedBy string
+	Booking  booking.Booking
+	Job      customer.Job
+	Item     customer.Equipment
+}
+
+// Number - the certificate number of an equipment item, the same each time it is regenerated
+func Number(item customer.Equipment) string {
+	return fmt.Sprintf("CAL-%06d-%03d", item.JobID, item.ID)
+}
+
+// Generate - renders the certificate for one of a booking's completed equipment items and stores it
+// as a document attached to the booking and instrument. Regenerating a certificate files a new
+// version of the same document, written to a file of its own so earlier versions are kept.
+func (s *Service) Generate(bookingID uint, itemID uint, issuedBy string) (document.Document, error) {
+	if strings.TrimSpace(issuedBy) == "" {
+		return document.Document{}, ErrInvalidIssuedBy
+	}
+	b, err := s.Bookings.GetBooking(bookingID)
+	if err != nil {
+		return document.Document{}, err
+	}
+	if b.JobID == nil {
+		return document.Document{}, ErrNoJob
+	}
+	job, err := s.Customers.GetJob(*b.JobID)
+	if err != nil {
+		return document.Document{}, err
+	}
+	item, err := s.Customers.GetEquipmentItem(job.ID, itemID)
+	if err != nil {
+		return document.Document{}, err
+	}
+	if len(item.Measurements) == 0 {
+		return document.Document{}, ErrNoMeasurements
+	}
+	if item.Status != customer.EquipmentCompleted {
+		return document.Document{}, ErrNotCompleted
+	}
+
+	cert := Certificate{Number: Number(item), IssuedBy: issuedBy, Booking: b, Job: job, Item: item}
+	data := s.Template.Render(cert).Bytes()
+
+	title := cert.Number + ".pdf"
+	query := s.DB.Where("title = ? AND booking_id = ?", title, b.ID)
+	if item.InstrumentID != nil {
+		query = query.Where("instrument_id = ?", *item.InstrumentID)
+	} else {
+		query = query.Where("instrument_id IS NULL")
+	}
+	var existing document.Document
+	found := true
+	if result := query.First(&existing); gorm.IsRecordNotFoundError(result.Error) {
+		found = false
+	} else if result.Error != nil {
+		return document.Document{}, result.Error
+	}
+
+	doc := document.Document{
+		Title:        title,
+		Version:      existing.Version + 1.0,
+		Author:       issuedBy,
+		BookingID:    &b.ID,
+		InstrumentID: item.InstrumentID,
+	}
+	if err := os.MkdirAll(s.Dir, os.ModePerm); err != nil {
+		return document.Document{}, err
+	}
+	doc.Path = filepath.Join(s.Dir, fmt.Sprintf("%s-v%d.pdf", cert.Number, int(doc.Version)))
+	if err := os.WriteFile(doc.Path, data, 0o644); err != nil {
+		return document.Document{}, err
+	}
+	sum := sha256.Sum256(data)
+	doc.Hash = hex.EncodeToString(sum[:])
+
+	if !found {
+		return s.Documents.PostDocument(doc)
+	}
+	if _, err := s.Documents.UpdateDocument(existing.ID, doc); err != nil {
+		return document.Document{}, err
+	}
+	return s.Documents.GetDocument(existing.ID)
+}
@@ -0,0 +1,220 @@
+package certificate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/Open-FiSE/go-rest-api/internal/pdf"
+)
+
+// page layout, in points
+const (
+	margin      = 50.0
+	lineHeight  = 14.0
+	bodySize    = 10.0
+	tableSize   = 9.0
+	footerSpace = 110.0
+)
+
+// results table columns: heading and left edge
+var columns = []struct {
+	heading string
+	x       float64
+}{
+	{"Parameter", margin},
+	{"Nominal", 190},
+	{"Tolerance", 265},
+	{"As found", 340},
+	{"As left", 415},
+	{"Result", 490},
+}
+
+// page - writes a certificate top to bottom, starting new pages as it fills them
+type page struct {
+	doc  *pdf.Document
+	page *pdf.Page
+	y    float64
+	t    Template
+	cert Certificate
+}
+
+// Render - lays the certificate out as a PDF document
+func (t Template) Render(cert Certificate) *pdf.Document {
+	p := &page{doc: pdf.New(t.Title + " " + cert.Number), t: t, cert: cert}
+	p.newPage()
+
+	p.section("Customer")
+	if c := cert.Booking.Customer; c != nil {
+		p.field("Customer", c.Name)
+	}
+	if site := cert.Booking.Site; site != nil {
+		p.field("Site", site.Name)
+		p.field("Address", site.FullAddress())
+	} else if cert.Booking.Location != "" {
+		p.field("Location", cert.Booking.Location)
+	}
+	p.field("Job reference", cert.Job.Reference)
+
+	p.section("Instrument")
+	p.field("Manufacturer", cert.Item.Manufacturer)
+	p.field("Model", cert.Item.InstrumentModel)
+	p.field("Serial number", cert.Item.SerialNo)
+
+	p.section("Calibration")
+	p.field("Date of calibration", cert.Booking.InZone(cert.Booking.Zone()).StartDateTime.Format("2 January 2006"))
+	var engineers []string
+	for _, e := range cert.Booking.Engineers {
+		engineers = append(engineers, e.Name)
+	}
+	p.field("Engineer", strings.Join(engineers, ", "))
+	if workType := string(cert.Item.WorkType); workType != "" {
+		p.field("Work carried out", strings.ToUpper(workType[:1])+workType[1:])
+	}
+
+	p.section("Results")
+	p.tableHeader()
+	for _, m := range cert.Item.Measurements {
+		p.ensure(lineHeight)
+		p.row(m)
+	}
+	p.y -= lineHeight / 2
+	p.ensure(3 * lineHeight)
+	result := "PASS"
+	if !cert.Item.Passed() {
+		result = "FAIL"
+	}
+	p.page.Text(margin, p.y, pdf.Bold, 12, "Overall result: "+result)
+	p.y -= lineHeight * 1.4
+	if !cert.Item.FoundInTolerance() {
+		p.paragraph(pdf.Regular, "The instrument was found outside tolerance at one or more points before adjustment.")
+	}
+
+	if cert.Item.Findings != "" {
+		p.section("Findings")
+		p.paragraph(pdf.Regular, cert.Item.Findings)
+	}
+	p.footer()
+	return p.doc
+}
+
+// newPage - starts a page with the letterhead and certificate number
+func (p *page) newPage() {
+	p.page = p.doc.AddPage()
+	y := pdf.PageHeight - margin
+	p.page.Text(margin, y, pdf.Bold, 16, p.t.Company)
+	for _, line := range p.t.Address {
+		y -= lineHeight
+		p.page.Text(margin, y, pdf.Regular, bodySize, line)
+	}
+	y -= 2 * lineHeight
+	p.page.Text(margin, y, pdf.Bold, 20, p.t.Title)
+	y -= 1.5 * lineHeight
+	p.page.Text(margin, y, pdf.Regular, bodySize, "Certificate number: "+p.cert.Number)
+	issued := "Date of issue: " + time.Now().In(p.cert.Booking.Zone()).Format("2 January 2006")
+	p.page.Text(pdf.PageWidth-margin-pdf.TextWidth(issued, pdf.Regular, bodySize), y, pdf.Regular, bodySize, issued)
+	y -= lineHeight / 2
+	p.page.Line(margin, y, pdf.PageWidth-margin, y, 1)
+	p.y = y - 1.5*lineHeight
+}
+
+// ensure - starts a new page unless height points fit above the footer
+func (p *page) ensure(height float64) {
+	if p.y-height < footerSpace {
+		p.footer()
+		p.newPage()
+	}
+}
+
+func (p *page) section(title string) {
+	p.ensure(3 * lineHeight)
+	p.y -= lineHeight / 2
+	p.page.Text(margin, p.y, pdf.Bold, 12, title)
+	p.y -= lineHeight * 1.3
+}
+
+func (p *page) field(label, value string) {
+	if value == "" {
+		return
+	}
+	p.ensure(lineHeight)
+	p.page.Text(margin, p.y, pdf.Bold, bodySize, label+":")
+	lines := pdf.Wrap(value, pdf.Regular, bodySize, pdf.PageWidth-2*margin-130)
+	for i, line := range lines {
+		if i > 0 {
+			p.ensure(lineHeight)
+		}
+		p.page.Text(margin+130, p.y, pdf.Regular, bodySize, line)
+		p.y -= lineHeight
+	}
+}
+
+func (p *page) paragraph(font pdf.Font, text string) {
+	for _, line := range pdf.Wrap(text, font, bodySize, pdf.PageWidth-2*margin) {
+		p.ensure(lineHeight)
+		p.page.Text(margin, p.y, font, bodySize, line)
+		p.y -= lineHeight
+	}
+}
+
+func (p *page) tableHeader() {
+	p.ensure(2 * lineHeight)
+	p.page.Rect(margin, p.y-4, pdf.PageWidth-2*margin, lineHeight, 0.88)
+	for _, col := range columns {
+		p.page.Text(col.x+2, p.y, pdf.Bold, tableSize, col.heading)
+	}
+	p.y -= lineHeight
+}
+
+func (p *page) row(m customer.Measurement) {
+	result := "Pass"
+	if !m.Passed() {
+		result = "Fail"
+	}
+	cells := []string{
+		m.Parameter,
+		withUnit(formatNumber(&m.Nominal), m.Unit),
+		"± " + withUnit(formatNumber(&m.Tolerance), m.Unit),
+		withUnit(formatNumber(m.AsFound), m.Unit),
+		withUnit(formatNumber(m.AsLeft), m.Unit),
+		result,
+	}
+	for i, cell := range cells {
+		p.page.Text(columns[i].x+2, p.y, pdf.Regular, tableSize, cell)
+	}
+	p.page.Line(margin, p.y-4, pdf.PageWidth-margin, p.y-4, 0.3)
+	p.y -= lineHeight
+	// a continued table repeats its header at the top of the next page
+	if p.y-lineHeight < footerSpace {
+		p.footer()
+		p.newPage()
+		p.tableHeader()
+	}
+}
+
+// footer - the certificate statement and signatory at the foot of the page
+func (p *page) footer() {
+	y := footerSpace - lineHeight
+	p.page.Line(margin, footerSpace-4, pdf.PageWidth-margin, footerSpace-4, 0.5)
+	for _, line := range pdf.Wrap(p.t.Statement, pdf.Regular, 8, pdf.PageWidth-2*margin) {
+		p.page.Text(margin, y, pdf.Regular, 8, line)
+		y -= 10
+	}
+	p.page.Text(margin, margin-10, pdf.Bold, bodySize, "Issued by: "+p.cert.IssuedBy)
+}
+
+func formatNumber(value *float64) string {
+	if value == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
+
+func withUnit(value, unit string) string {
+	if unit == "" || value == "-" {
+		return value
+	}
+	return fmt.Sprintf("%s %s", value, unit)
+}
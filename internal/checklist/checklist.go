@@ -0,0 +1,328 @@
+package checklist
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/Open-FiSE/go-rest-api/internal/document"
+	"github.com/jinzhu/gorm"
+)
+
+// FieldType - the kind of answer a checklist field takes
+type FieldType string
+
+// checklist field types
+const (
+	FieldYesNo   FieldType = "yes_no"
+	FieldNumeric FieldType = "numeric"
+	FieldText    FieldType = "text"
+	FieldPhoto   FieldType = "photo"
+)
+
+// errors returned by the checklist service
+var (
+	ErrInvalidTemplate = errors.New("checklist template needs a Name and at least one field")
+	ErrInvalidField    = errors.New("checklist field needs a Label and a known Type")
+	ErrInvalidAnswer   = errors.New("answer does not suit the checklist field")
+	ErrLocked          = errors.New("checklist is locked, its booking has been completed")
+	ErrPhotoNotFound   = errors.New("photo document does not exist")
+)
+
+// Service - the struct for the checklist service, covering checklist templates and the checklists
+// made from them on each job
+type Service struct {
+	DB        *gorm.DB
+	Customers *customer.Service
+}
+
+// ChecklistService - the interface for our checklist service
+type ChecklistService interface {
+	GetTemplate(ID uint) (Template, error)
+	GetAllTemplates() ([]Template, error)
+	PostTemplate(template Template) (Template, error)
+	UpdateTemplate(ID uint, newTemplate Template) (Template, error)
+	DeleteTemplate(ID uint) error
+	GetChecklists(jobID uint) ([]Checklist, error)
+	GetChecklist(jobID uint, ID uint) (Checklist, error)
+	AnswerItem(jobID uint, checklistID uint, itemID uint, answer Item) (Item, error)
+	LockJob(jobID uint) error
+}
+
+// NewService - takes in a pointer to the DB and the customer service & returns a pointer to a new
+// checklist service
+func NewService(db *gorm.DB, customers *customer.Service) *Service {
+	return &Service{
+		DB:        db,
+		Customers: customers,
+	}
+}
+
+// Checklist - a checklist made from a template on a job, for one of its equipment items or, from a
+// general template, for the job as a whole. The fields are copied from the template so later changes
+// to it leave the checklist as it was. Checklists are locked once a booking for the job is completed.
+type Checklist struct {
+	gorm.Model
+	JobID       uint   `json:"jobId"`
+	EquipmentID *uint  `json:"equipmentId"`
+	TemplateID  uint   `json:"templateId"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Locked      bool   `json:"locked"`
+	Items       []Item `json:"items"`
+	// Complete - whether every required item has been answered
+	Complete bool `gorm:"-" json:"complete"`
+}
+
+// Item - a question on a checklist and the answer given, in the field suiting its Type. A numeric
+// answer passes when it is within the item's limits; a photo answer is the ID of an uploaded document.
+type Item struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	ChecklistID uint       `json:"checklistId"`
+	Label       string     `json:"label"`
+	Type        FieldType  `json:"type"`
+	Required    bool       `json:"required"`
+	Unit        string     `json:"unit"`
+	Min         *float64   `json:"min"`
+	Max         *float64   `json:"max"`
+	Yes         *bool      `json:"yes"`
+	Number      *float64   `json:"number"`
+	Text        string     `json:"text"`
+	PhotoID     *uint      `json:"photoId"`
+	Pass        *bool      `json:"pass"`
+	AnsweredBy  string     `json:"answeredBy"`
+	AnsweredAt  *time.Time `json:"answeredAt"`
+}
+
+// TableName - the table checklist items are stored in
+func (Item) TableName() string {
+	return "checklist_items"
+}
+
+// Answered - whether the item has been answered
+func (item Item) Answered() bool {
+	return item.AnsweredAt != nil
+}
+
+// GetChecklists - retrieves the checklists of a job, first making a checklist from each template
+// that applies to the job or its equipment and has not been used on it yet. Once the job's checklists
+// are locked no new ones are made.
+func (s *Service) GetChecklists(jobID uint) ([]Checklist, error) {
+	locked, err := s.locked(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		if err := s.instantiate(jobID); err != nil {
+			return nil, err
+		}
+	}
+	var checklists []Checklist
+	if result := s.DB.Preload("Items", byID).Where("job_id = ?", jobID).Order("id").Find(&checklists); result.Error != nil {
+		return checklists, result.Error
+	}
+	for i := range checklists {
+		checklists[i].Complete = checklists[i].complete()
+	}
+	return checklists, nil
+}
+
+// GetChecklist - retrieves one of a job's checklists by ID
+func (s *Service) GetChecklist(jobID uint, ID uint) (Checklist, error) {
+	var checklist Checklist
+	if result := s.DB.Preload("Items", byID).Where("job_id = ?", jobID).First(&checklist, ID); result.Error != nil {
+		return Checklist{}, result.Error
+	}
+	checklist.Complete = checklist.complete()
+	return checklist, nil
+}
+
+// AnswerItem - records the answer to one item of a job's checklist, returning ErrLocked once a
+// booking for the job has been completed, and booking.ErrBookingSigned once one has been signed off
+func (s *Service) AnswerItem(jobID uint, checklistID uint, itemID uint, answer Item) (Item, error) {
+	checklist, err := s.GetChecklist(jobID, checklistID)
+	if err != nil {
+		return Item{}, err
+	}
+	if err := s.Customers.JobEditable(jobID); err != nil {
+		return Item{}, err
+	}
+	locked, err := s.locked(jobID)
+	if err != nil {
+		return Item{}, err
+	}
+	if checklist.Locked || locked {
+		return Item{}, ErrLocked
+	}
+	var item Item
+	if result := s.DB.Where("checklist_id = ?", checklistID).First(&item, itemID); result.Error != nil {
+		return Item{}, result.Error
+	}
+
+	if err := s.score(&item, answer); err != nil {
+		return Item{}, err
+	}
+	now := time.Now().UTC()
+	item.AnsweredBy = answer.AnsweredBy
+	item.AnsweredAt = &now
+	if result := s.DB.Save(&item); result.Error != nil {
+		return Item{}, result.Error
+	}
+	return item, nil
+}
+
+// LockJob - locks the checklists of a job so their answers can no longer change
+func (s *Service) LockJob(jobID uint) error {
+	if result := s.DB.Model(&Checklist{}).Where("job_id = ?", jobID).Update("locked", true); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// locked - whether a job's checklists are locked, which they are once any booking for the job has
+// been completed. A completed booking whose checklists were not locked at the time locks them now.
+func (s *Service) locked(jobID uint) (bool, error) {
+	var count int
+	if result := s.DB.Model(&booking.Booking{}).Where("job_id = ? AND status = ?", jobID, booking.StatusCompleted).
+		Count(&count); result.Error != nil {
+		return false, result.Error
+	}
+	if count == 0 {
+		return false, nil
+	}
+	return true, s.LockJob(jobID)
+}
+
+// instantiate - makes a checklist on the job from each template that applies and is not already
+// used on it
+func (s *Service) instantiate(jobID uint) error {
+	job, err := s.Customers.GetJob(jobID)
+	if err != nil {
+		return err
+	}
+	templates, err := s.GetAllTemplates()
+	if err != nil {
+		return err
+	}
+	var existing []Checklist
+	if result := s.DB.Where("job_id = ?", jobID).Find(&existing); result.Error != nil {
+		return result.Error
+	}
+	used := make(map[string]bool, len(existing))
+	for _, checklist := range existing {
+		used[checklistKey(checklist.TemplateID, checklist.EquipmentID)] = true
+	}
+
+	var missing []Checklist
+	for _, template := range templates {
+		if template.general() {
+			if !used[checklistKey(template.ID, nil)] {
+				missing = append(missing, fromTemplate(template, jobID, nil))
+			}
+			continue
+		}
+		for _, item := range job.Equipment {
+			if template.appliesTo(item) && !used[checklistKey(template.ID, &item.ID)] {
+				itemID := item.ID
+				missing = append(missing, fromTemplate(template, jobID, &itemID))
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	tx := s.DB.Begin()
+	for i := range missing {
+		if result := tx.Create(&missing[i]); result.Error != nil {
+			tx.Rollback()
+			return result.Error
+		}
+	}
+	return tx.Commit().Error
+}
+
+// fromTemplate - a new checklist with a copy of the template's fields
+func fromTemplate(template Template, jobID uint, equipmentID *uint) Checklist {
+	checklist := Checklist{
+		JobID:       jobID,
+		EquipmentID: equipmentID,
+		TemplateID:  template.ID,
+		Name:        template.Name,
+		Description: template.Description,
+		Items:       make([]Item, len(template.Fields)),
+	}
+	for i, field := range template.Fields {
+		checklist.Items[i] = Item{
+			Label:    field.Label,
+			Type:     field.Type,
+			Required: field.Required,
+			Unit:     field.Unit,
+			Min:      field.Min,
+			Max:      field.Max,
+		}
+	}
+	return checklist
+}
+
+func checklistKey(templateID uint, equipmentID *uint) string {
+	if equipmentID == nil {
+		return fmt.Sprintf("%d", templateID)
+	}
+	return fmt.Sprintf("%d/%d", templateID, *equipmentID)
+}
+
+// complete - whether every required item has been answered
+func (c Checklist) complete() bool {
+	for _, item := range c.Items {
+		if item.Required && !item.Answered() {
+			return false
+		}
+	}
+	return true
+}
+
+// score - copies the answer suiting the item's type onto it, checking numeric answers against the
+// item's limits and that a photo has been uploaded
+func (s *Service) score(item *Item, answer Item) error {
+	item.Yes, item.Number, item.Text, item.PhotoID, item.Pass = nil, nil, "", nil, nil
+	switch item.Type {
+	case FieldYesNo:
+		if answer.Yes == nil {
+			return fmt.Errorf("%w: %q needs a yes or no", ErrInvalidAnswer, item.Label)
+		}
+		item.Yes = answer.Yes
+	case FieldNumeric:
+		if answer.Number == nil {
+			return fmt.Errorf("%w: %q needs a number", ErrInvalidAnswer, item.Label)
+		}
+		pass := (item.Min == nil || *answer.Number >= *item.Min) && (item.Max == nil || *answer.Number <= *item.Max)
+		item.Number, item.Pass = answer.Number, &pass
+	case FieldText:
+		if strings.TrimSpace(answer.Text) == "" {
+			return fmt.Errorf("%w: %q needs some text", ErrInvalidAnswer, item.Label)
+		}
+		item.Text = answer.Text
+	case FieldPhoto:
+		if answer.PhotoID == nil {
+			return fmt.Errorf("%w: %q needs the photoId of an uploaded document", ErrInvalidAnswer, item.Label)
+		}
+		var count int
+		if result := s.DB.Model(&document.Document{}).Where("id = ?", *answer.PhotoID).Count(&count); result.Error != nil {
+			return result.Error
+		}
+		if count == 0 {
+			return ErrPhotoNotFound
+		}
+		item.PhotoID = answer.PhotoID
+	}
+	return nil
+}
+
+// byID - orders preloaded rows in the order they were added
+func byID(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
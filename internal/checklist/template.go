@@ -0,0 +1,173 @@
+package checklist
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/jinzhu/gorm"
+)
+
+// Template - an admin-defined checklist, instantiated on jobs for the equipment it applies to. A
+// template applies to equipment matching each of Manufacturer, InstrumentModel and WorkType that is
+// set; a template with none set is a general checklist instantiated once per job.
+type Template struct {
+	gorm.Model
+	Name            string            `json:"name"`
+	Description     string            `json:"description"`
+	Manufacturer    string            `json:"manufacturer"`
+	InstrumentModel string            `json:"instrumentModel"`
+	WorkType        customer.WorkType `json:"workType"`
+	Fields          []TemplateField   `json:"fields"`
+}
+
+// TemplateField - a question on a checklist template. Numeric fields may have limits the answer must
+// fall within to pass.
+type TemplateField struct {
+	ID         uint      `gorm:"primary_key" json:"id"`
+	TemplateID uint      `json:"templateId"`
+	Label      string    `json:"label"`
+	Type       FieldType `json:"type"`
+	Required   bool      `json:"required"`
+	Unit       string    `json:"unit"`
+	Min        *float64  `json:"min"`
+	Max        *float64  `json:"max"`
+}
+
+// TableName - the table templates are stored in, named for the package as "templates" alone is too
+// general
+func (Template) TableName() string {
+	return "checklist_templates"
+}
+
+// TableName - the table template fields are stored in
+func (TemplateField) TableName() string {
+	return "checklist_template_fields"
+}
+
+// GetTemplate - retrieves a checklist template and its fields by ID
+func (s *Service) GetTemplate(ID uint) (Template, error) {
+	var template Template
+	if result := s.DB.Preload("Fields", byID).First(&template, ID); result.Error != nil {
+		return Template{}, result.Error
+	}
+	return template, nil
+}
+
+// GetAllTemplates - retrieves every checklist template, ordered by name
+func (s *Service) GetAllTemplates() ([]Template, error) {
+	var templates []Template
+	if result := s.DB.Preload("Fields", byID).Order("name").Find(&templates); result.Error != nil {
+		return templates, result.Error
+	}
+	return templates, nil
+}
+
+// PostTemplate - adds a new checklist template with its fields
+func (s *Service) PostTemplate(template Template) (Template, error) {
+	// a new template, and the fields posted with it, never take the ID of an existing one
+	template.Model = gorm.Model{}
+	for i := range template.Fields {
+		template.Fields[i].ID, template.Fields[i].TemplateID = 0, 0
+	}
+	if err := validateTemplate(template, false); err != nil {
+		return Template{}, err
+	}
+	if result := s.DB.Save(&template); result.Error != nil {
+		return Template{}, result.Error
+	}
+	return template, nil
+}
+
+// UpdateTemplate - updates a checklist template by ID. When fields are given they replace the
+// template's fields; checklists already made from it keep the fields they were made with.
+func (s *Service) UpdateTemplate(ID uint, newTemplate Template) (Template, error) {
+	template, err := s.GetTemplate(ID)
+	if err != nil {
+		return Template{}, err
+	}
+	if err := validateTemplate(newTemplate, true); err != nil {
+		return Template{}, err
+	}
+
+	tx := s.DB.Begin()
+	fields := newTemplate.Fields
+	newTemplate.Fields = nil
+	if result := tx.Model(&template).Updates(newTemplate); result.Error != nil {
+		tx.Rollback()
+		return Template{}, result.Error
+	}
+	if len(fields) > 0 {
+		if result := tx.Where("template_id = ?", ID).Delete(&TemplateField{}); result.Error != nil {
+			tx.Rollback()
+			return Template{}, result.Error
+		}
+		for i := range fields {
+			fields[i].ID = 0
+			fields[i].TemplateID = ID
+			if result := tx.Create(&fields[i]); result.Error != nil {
+				tx.Rollback()
+				return Template{}, result.Error
+			}
+		}
+	}
+	if result := tx.Commit(); result.Error != nil {
+		return Template{}, result.Error
+	}
+	return s.GetTemplate(ID)
+}
+
+// DeleteTemplate - deletes a checklist template by ID, leaving checklists made from it in place
+func (s *Service) DeleteTemplate(ID uint) error {
+	if result := s.DB.Delete(&Template{}, ID); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// general - whether the template is instantiated once per job rather than per item of equipment
+func (t Template) general() bool {
+	return t.Manufacturer == "" && t.InstrumentModel == "" && t.WorkType == ""
+}
+
+// appliesTo - whether the template applies to an item of equipment
+func (t Template) appliesTo(item customer.Equipment) bool {
+	if t.general() {
+		return false
+	}
+	return (t.Manufacturer == "" || strings.EqualFold(t.Manufacturer, item.Manufacturer)) &&
+		(t.InstrumentModel == "" || strings.EqualFold(t.InstrumentModel, item.InstrumentModel)) &&
+		(t.WorkType == "" || t.WorkType == item.WorkType)
+}
+
+// validateTemplate - checks a template's name, work type and fields, allowing the name and fields
+// to be left out when partial is set
+func validateTemplate(template Template, partial bool) error {
+	if !partial && (strings.TrimSpace(template.Name) == "" || len(template.Fields) == 0) {
+		return ErrInvalidTemplate
+	}
+	switch template.WorkType {
+	case customer.WorkCalibration, customer.WorkRepair, customer.WorkMaintenance,
+		customer.WorkInstallation, customer.WorkInspection, customer.WorkOther, "":
+	default:
+		return fmt.Errorf("%w: unknown WorkType %q", ErrInvalidTemplate, template.WorkType)
+	}
+	for _, field := range template.Fields {
+		if strings.TrimSpace(field.Label) == "" {
+			return ErrInvalidField
+		}
+		switch field.Type {
+		case FieldYesNo, FieldText, FieldPhoto:
+			if field.Min != nil || field.Max != nil {
+				return fmt.Errorf("%w: only numeric fields have limits", ErrInvalidField)
+			}
+		case FieldNumeric:
+			if field.Min != nil && field.Max != nil && *field.Min > *field.Max {
+				return fmt.Errorf("%w: Min is above Max", ErrInvalidField)
+			}
+		default:
+			return fmt.Errorf("%w: unknown Type %q", ErrInvalidField, field.Type)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package contract
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/jinzhu/gorm"
+)
+
+// errors returned by the contract service
+var (
+	ErrInvalidContract  = errors.New("contract needs a Reference, an EndsOn after its StartsOn, and visit and SLA targets of zero or more")
+	ErrContractNotFound = errors.New("contract does not exist for this customer")
+)
+
+// DefaultWarnAt - how far through an SLA target, as a fraction, a booking is warned about
+const DefaultWarnAt = 0.75
+
+// Service - the struct for the contract service, covering customers' service contracts and the SLA
+// clocks of the bookings made under them
+type Service struct {
+	DB        *gorm.DB
+	Bookings  *booking.BookService
+	Customers *customer.Service
+	// WarnAt - how far through an SLA target, as a fraction, a booking is warned about
+	WarnAt float64
+}
+
+// ContractService - the interface for our contract service
+type ContractService interface {
+	GetContracts(customerID uint) ([]Contract, error)
+	GetContract(customerID uint, ID uint) (Contract, error)
+	PostContract(customerID uint, contract Contract) (Contract, error)
+	UpdateContract(customerID uint, ID uint, newContract Contract) (Contract, error)
+	DeleteContract(customerID uint, ID uint) error
+	StartClock(bookingID uint) (*Clock, error)
+	ResponseDeadline(bookingID uint) (*time.Time, error)
+	GetClock(bookingID uint) (Clock, error)
+	GetWarnings(now time.Time) ([]Clock, error)
+	GetReport(customerID uint, from, to time.Time) (Report, error)
+	Run(now time.Time) error
+	Start(interval time.Duration) (stop func())
+}
+
+// NewService - takes in a pointer to the DB and the booking and customer services & returns a pointer
+// to a new contract service
+func NewService(db *gorm.DB, bookings *booking.BookService, customers *customer.Service) *Service {
+	return &Service{
+		DB:        db,
+		Bookings:  bookings,
+		Customers: customers,
+		WarnAt:    DefaultWarnAt,
+	}
+}
+
+// Contract - a service contract with a customer, covering the bookings they request from StartsOn
+// until EndsOn. A contract listing instruments only covers bookings for at least one of them, one with
+// none covers all the customer's bookings. VisitsIncluded is how many visits the contract entitles
+// the customer to, unlimited when zero. ResponseHours is the target from a booking being requested to
+// an engineer arriving on site, ResolutionHours to the booking being completed; zero sets no target.
+type Contract struct {
+	gorm.Model
+	CustomerID      uint                  `gorm:"index" json:"customerId"`
+	Reference       string                `json:"reference"`
+	StartsOn        time.Time             `json:"startsOn"`
+	EndsOn          time.Time             `json:"endsOn"`
+	VisitsIncluded  int                   `json:"visitsIncluded"`
+	ResponseHours   int                   `json:"responseHours"`
+	ResolutionHours int                   `json:"resolutionHours"`
+	Instruments     []customer.Instrument `gorm:"many2many:contract_instruments;association_autoupdate:false;association_autocreate:false" json:"instruments"`
+	// InstrumentIDs - the covered instruments when a contract is posted or updated, by reference
+	InstrumentIDs []uint `gorm:"-" json:"instrumentIds,omitempty"`
+	// VisitsUsed - the visits booked under the contract that have not been cancelled or missed
+	VisitsUsed int `gorm:"-" json:"visitsUsed"`
+}
+
+// Covers - reports whether the contract covers a booking
+func (c Contract) Covers(b booking.Booking) bool {
+	if b.CustomerID == nil || *b.CustomerID != c.CustomerID {
+		return false
+	}
+	if b.CreatedAt.Before(c.StartsOn) || !b.CreatedAt.Before(c.EndsOn) {
+		return false
+	}
+	if len(c.Instruments) == 0 {
+		return true
+	}
+	for _, covered := range c.Instruments {
+		for _, instrument := range b.Instruments {
+			if instrument.ID == covered.ID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetContracts - retrieves a customer's contracts, newest first, with the visits used on each
+func (s *Service) GetContracts(customerID uint) ([]Contract, error) {
+	var contracts []Contract
+	if result := s.DB.Preload("Instruments").Where("customer_id = ?", customerID).Order("starts_on DESC, id DESC").
+		Find(&contracts); result.Error != nil {
+		return contracts, result.Error
+	}
+	for i := range contracts {
+		if err := s.countVisits(&contracts[i]); err != nil {
+			return contracts, err
+		}
+	}
+	return contracts, nil
+}
+
+// GetContract - retrieves one of a customer's contracts by ID, with the visits used on it
+func (s *Service) GetContract(customerID uint, ID uint) (Contract, error) {
+	var contract Contract
+	if result := s.DB.Preload("Instruments").Where("customer_id = ?", customerID).First(&contract, ID); result.Error != nil {
+		return Contract{}, result.Error
+	}
+	if err := s.countVisits(&contract); err != nil {
+		return Contract{}, err
+	}
+	return contract, nil
+}
+
+// PostContract - adds a contract for a customer, covering the instruments given by InstrumentIDs
+func (s *Service) PostContract(customerID uint, contract Contract) (Contract, error) {
+	if err := validateContract(contract); err != nil {
+		return Contract{}, err
+	}
+	if _, err := s.Customers.GetCustomer(customerID); gorm.IsRecordNotFoundError(err) {
+		return Contract{}, customer.ErrCustomerNotFound
+	} else if err != nil {
+		return Contract{}, err
+	}
+	instruments, err := s.Customers.GetInstrumentsByID(customerID, contract.InstrumentIDs)
+	if err != nil {
+		return Contract{}, err
+	}
+	contract.Model = gorm.Model{}
+	contract.CustomerID = customerID
+	contract.Instruments = instruments
+	contract.InstrumentIDs = nil
+	if result := s.DB.Save(&contract); result.Error != nil {
+		return Contract{}, result.Error
+	}
+	return contract, nil
+}
+
+// UpdateContract - updates one of a customer's contracts by ID, replacing the instruments it covers
+// when InstrumentIDs is given. Bookings already under the contract keep the SLA targets they started
+// with.
+func (s *Service) UpdateContract(customerID uint, ID uint, newContract Contract) (Contract, error) {
+	contract, err := s.GetContract(customerID, ID)
+	if err != nil {
+		return Contract{}, err
+	}
+	merged := contract
+	if !newContract.StartsOn.IsZero() {
+		merged.StartsOn = newContract.StartsOn
+	}
+	if !newContract.EndsOn.IsZero() {
+		merged.EndsOn = newContract.EndsOn
+	}
+	if newContract.Reference != "" {
+		merged.Reference = newContract.Reference
+	}
+	merged.VisitsIncluded = newContract.VisitsIncluded
+	merged.ResponseHours = newContract.ResponseHours
+	merged.ResolutionHours = newContract.ResolutionHours
+	if err := validateContract(merged); err != nil {
+		return Contract{}, err
+	}
+
+	tx := s.DB.Begin()
+	if result := tx.Model(&contract).Updates(map[string]interface{}{
+		"reference":        merged.Reference,
+		"starts_on":        merged.StartsOn,
+		"ends_on":          merged.EndsOn,
+		"visits_included":  merged.VisitsIncluded,
+		"response_hours":   merged.ResponseHours,
+		"resolution_hours": merged.ResolutionHours,
+	}); result.Error != nil {
+		tx.Rollback()
+		return Contract{}, result.Error
+	}
+	if newContract.InstrumentIDs != nil {
+		instruments, err := s.Customers.GetInstrumentsByID(customerID, newContract.InstrumentIDs)
+		if err != nil {
+			tx.Rollback()
+			return Contract{}, err
+		}
+		if err := tx.Model(&contract).Association("Instruments").Replace(instruments).Error; err != nil {
+			tx.Rollback()
+			return Contract{}, err
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return Contract{}, err
+	}
+	return s.GetContract(customerID, ID)
+}
+
+// DeleteContract - deletes one of a customer's contracts by ID. The SLA clocks of bookings made
+// under it are kept for reporting.
+func (s *Service) DeleteContract(customerID uint, ID uint) error {
+	result := s.DB.Where("customer_id = ?", customerID).Delete(&Contract{}, ID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrContractNotFound
+	}
+	return nil
+}
+
+// countVisits - fills in the visits used on a contract
+func (s *Service) countVisits(contract *Contract) error {
+	return s.DB.Model(&Clock{}).Where("contract_id = ? AND status <> ?", contract.ID, ClockStopped).
+		Count(&contract.VisitsUsed).Error
+}
+
+func validateContract(contract Contract) error {
+	if strings.TrimSpace(contract.Reference) == "" || contract.StartsOn.IsZero() || !contract.EndsOn.After(contract.StartsOn) {
+		return ErrInvalidContract
+	}
+	if contract.VisitsIncluded < 0 || contract.ResponseHours < 0 || contract.ResolutionHours < 0 {
+		return ErrInvalidContract
+	}
+	return nil
+}
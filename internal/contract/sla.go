@@ -0,0 +1,345 @@
+package contract
+
+import (
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+// ClockStatus - where a booking stands against its SLA targets
+type ClockStatus string
+
+// SLA clock statuses
+const (
+	ClockRunning  ClockStatus = "running"
+	ClockMet      ClockStatus = "met"
+	ClockBreached ClockStatus = "breached"
+	// ClockStopped - the booking was cancelled or missed before any target was breached
+	ClockStopped ClockStatus = "stopped"
+)
+
+// Clock - the SLA clock of a booking made under a contract. It starts when the booking is requested,
+// takes the contract's targets as they were then, and is stopped by the booking's status history:
+// the response target is met when the booking goes in progress, the resolution target when it is
+// completed.
+type Clock struct {
+	gorm.Model
+	BookingID          uint        `gorm:"unique_index" json:"bookingId"`
+	ContractID         uint        `gorm:"index" json:"contractId"`
+	Status             ClockStatus `gorm:"default:'running';index" json:"status"`
+	StartedAt          time.Time   `json:"startedAt"`
+	RespondBy          *time.Time  `json:"respondBy"`
+	ResolveBy          *time.Time  `json:"resolveBy"`
+	RespondedAt        *time.Time  `json:"respondedAt"`
+	ResolvedAt         *time.Time  `json:"resolvedAt"`
+	StoppedAt          *time.Time  `json:"stoppedAt"`
+	ResponseBreached   bool        `json:"responseBreached"`
+	ResolutionBreached bool        `json:"resolutionBreached"`
+	// WarnedAt - when the booking was first warned about as close to breaching a target
+	WarnedAt *time.Time `json:"warnedAt"`
+	// AtRisk - whether the booking is past the warning point of a target it has still to meet
+	AtRisk bool `gorm:"-" json:"atRisk"`
+}
+
+// TableName - the table SLA clocks are stored in
+func (Clock) TableName() string {
+	return "sla_clocks"
+}
+
+// StartClock - starts the SLA clock of a booking made under one of its customer's contracts, the one
+// with the shortest response target when several cover it. It returns nil when no contract covers
+// the booking, and the clock already running when it has one.
+func (s *Service) StartClock(bookingID uint) (*Clock, error) {
+	clock, err := s.clockFor(bookingID)
+	if err != nil || clock == nil || clock.ID != 0 {
+		return clock, err
+	}
+	if result := s.DB.Create(clock); result.Error != nil {
+		return nil, result.Error
+	}
+	return clock, nil
+}
+
+// ResponseDeadline - when a booking must be responded to under its contract, as its SLA clock has
+// it or would have it once started, without starting one. It returns nil when no contract covers the
+// booking or its contract has no response target.
+func (s *Service) ResponseDeadline(bookingID uint) (*time.Time, error) {
+	clock, err := s.clockFor(bookingID)
+	if err != nil || clock == nil {
+		return nil, err
+	}
+	return clock.RespondBy, nil
+}
+
+// clockFor - the SLA clock of a booking, the one already started or, when it has none, an unsaved
+// clock under the contract covering it. It returns nil when no contract covers the booking.
+func (s *Service) clockFor(bookingID uint) (*Clock, error) {
+	var existing Clock
+	if result := s.DB.Where("booking_id = ?", bookingID).First(&existing); result.Error == nil {
+		return &existing, nil
+	} else if !gorm.IsRecordNotFoundError(result.Error) {
+		return nil, result.Error
+	}
+	b, err := s.Bookings.GetBooking(bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if b.CustomerID == nil {
+		return nil, nil
+	}
+	var contracts []Contract
+	if result := s.DB.Preload("Instruments").
+		Where("customer_id = ? AND starts_on <= ? AND ends_on > ?", *b.CustomerID, b.CreatedAt, b.CreatedAt).
+		Order("response_hours = 0, response_hours, id").Find(&contracts); result.Error != nil {
+		return nil, result.Error
+	}
+	for _, contract := range contracts {
+		if !contract.Covers(b) {
+			continue
+		}
+		clock := Clock{BookingID: b.ID, ContractID: contract.ID, Status: ClockRunning, StartedAt: b.CreatedAt.UTC()}
+		if contract.ResponseHours > 0 {
+			by := clock.StartedAt.Add(time.Duration(contract.ResponseHours) * time.Hour)
+			clock.RespondBy = &by
+		}
+		if contract.ResolutionHours > 0 {
+			by := clock.StartedAt.Add(time.Duration(contract.ResolutionHours) * time.Hour)
+			clock.ResolveBy = &by
+		}
+		return &clock, nil
+	}
+	return nil, nil
+}
+
+// GetClock - retrieves a booking's SLA clock as it stands now
+func (s *Service) GetClock(bookingID uint) (Clock, error) {
+	var clock Clock
+	if result := s.DB.Where("booking_id = ?", bookingID).First(&clock); result.Error != nil {
+		return Clock{}, result.Error
+	}
+	if err := s.evaluate(&clock, time.Now().UTC()); err != nil {
+		return Clock{}, err
+	}
+	return clock, nil
+}
+
+// GetWarnings - the running SLA clocks at risk of breaching a target or already breaching one,
+// soonest due first
+func (s *Service) GetWarnings(now time.Time) ([]Clock, error) {
+	var clocks []Clock
+	if result := s.DB.Where("resolved_at IS NULL AND stopped_at IS NULL").Order("started_at").Find(&clocks); result.Error != nil {
+		return nil, result.Error
+	}
+	warnings := []Clock{}
+	for i := range clocks {
+		if err := s.evaluate(&clocks[i], now); err != nil {
+			return nil, err
+		}
+		if clocks[i].AtRisk || clocks[i].Status == ClockBreached {
+			warnings = append(warnings, clocks[i])
+		}
+	}
+	return warnings, nil
+}
+
+// Run - starts the clocks of bookings under contract that do not have one yet, brings running clocks
+// up to date with their bookings' status history, and logs a warning the first time a booking is at
+// risk of breaching a target and when it breaches one
+func (s *Service) Run(now time.Time) error {
+	var bookingIDs []uint
+	if result := s.DB.Table("bookings").
+		Joins("JOIN contracts ON contracts.customer_id = bookings.customer_id AND contracts.deleted_at IS NULL").
+		Where("bookings.deleted_at IS NULL AND bookings.created_at >= contracts.starts_on AND bookings.created_at < contracts.ends_on").
+		Where("bookings.id NOT IN (SELECT booking_id FROM sla_clocks WHERE deleted_at IS NULL)").
+		Pluck("DISTINCT bookings.id", &bookingIDs); result.Error != nil {
+		return result.Error
+	}
+	for _, bookingID := range bookingIDs {
+		if _, err := s.StartClock(bookingID); err != nil {
+			return err
+		}
+	}
+
+	var clocks []Clock
+	if result := s.DB.Where("resolved_at IS NULL AND stopped_at IS NULL").Find(&clocks); result.Error != nil {
+		return result.Error
+	}
+	for _, clock := range clocks {
+		before := clock.Status
+		if err := s.evaluate(&clock, now); err != nil {
+			return err
+		}
+		fields := log.Fields{"booking": clock.BookingID, "contract": clock.ContractID}
+		if clock.Status == ClockBreached && before != ClockBreached {
+			log.WithFields(fields).Warning("Booking has breached its SLA")
+		} else if clock.AtRisk && clock.WarnedAt == nil {
+			log.WithFields(fields).Warning("Booking is at risk of breaching its SLA")
+			clock.WarnedAt = &now
+		}
+		if result := s.DB.Save(&clock); result.Error != nil {
+			return result.Error
+		}
+	}
+	return nil
+}
+
+// Start - runs the SLA checks now and then every interval until stop is called
+func (s *Service) Start(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			if err := s.Run(time.Now().UTC()); err != nil {
+				log.Error(err)
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// evaluate - works out where a clock stands at now from its booking's status history. A clock whose
+// booking has been completed, cancelled or missed has stopped and is left as it is.
+func (s *Service) evaluate(clock *Clock, now time.Time) error {
+	if clock.ResolvedAt != nil || clock.StoppedAt != nil {
+		return nil
+	}
+	history, err := s.Bookings.GetStatusHistory(clock.BookingID)
+	if err != nil {
+		return err
+	}
+	for _, change := range history {
+		at := change.ChangedAt
+		switch change.To {
+		case booking.StatusInProgress:
+			if clock.RespondedAt == nil {
+				clock.RespondedAt = &at
+			}
+		case booking.StatusCompleted:
+			clock.ResolvedAt = &at
+		case booking.StatusCancelled, booking.StatusNoShow:
+			clock.StoppedAt = &at
+		}
+	}
+
+	clock.ResponseBreached = breached(clock.RespondBy, clock.RespondedAt, clock.StoppedAt, now)
+	clock.ResolutionBreached = breached(clock.ResolveBy, clock.ResolvedAt, clock.StoppedAt, now)
+	switch {
+	case clock.ResponseBreached || clock.ResolutionBreached:
+		clock.Status = ClockBreached
+	case clock.StoppedAt != nil:
+		clock.Status = ClockStopped
+	case clock.ResolvedAt != nil:
+		clock.Status = ClockMet
+	default:
+		clock.AtRisk = s.atRisk(clock.StartedAt, clock.RespondBy, clock.RespondedAt, now) ||
+			s.atRisk(clock.StartedAt, clock.ResolveBy, clock.ResolvedAt, now)
+	}
+	return nil
+}
+
+// atRisk - whether a target still to be met is past the warning point
+func (s *Service) atRisk(started time.Time, by *time.Time, metAt *time.Time, now time.Time) bool {
+	if by == nil || metAt != nil {
+		return false
+	}
+	warnAt := started.Add(time.Duration(float64(by.Sub(started)) * s.WarnAt))
+	return !now.Before(warnAt)
+}
+
+// breached - whether a target was missed: met after it was due, or not met and now past due. A booking
+// stopped before it was due did not breach it.
+func breached(by *time.Time, metAt *time.Time, stoppedAt *time.Time, now time.Time) bool {
+	if by == nil {
+		return false
+	}
+	if metAt != nil {
+		return metAt.After(*by)
+	}
+	if stoppedAt != nil {
+		return stoppedAt.After(*by)
+	}
+	return now.After(*by)
+}
+
+// Report - how a customer's bookings under contract measured up against their SLA targets. The
+// compliance percentages are of the targets decided so far, met or breached.
+type Report struct {
+	CustomerID           uint       `json:"customerId"`
+	From                 time.Time  `json:"from"`
+	To                   time.Time  `json:"to"`
+	Contracts            []Contract `json:"contracts"`
+	Bookings             int        `json:"bookings"`
+	Open                 int        `json:"open"`
+	Stopped              int        `json:"stopped"`
+	ResponseMet          int        `json:"responseMet"`
+	ResponseBreached     int        `json:"responseBreached"`
+	ResolutionMet        int        `json:"resolutionMet"`
+	ResolutionBreached   int        `json:"resolutionBreached"`
+	ResponseCompliance   *float64   `json:"responseCompliance"`
+	ResolutionCompliance *float64   `json:"resolutionCompliance"`
+	Clocks               []Clock    `json:"clocks"`
+}
+
+// GetReport - the SLA compliance report for the bookings a customer requested from one time up to
+// another
+func (s *Service) GetReport(customerID uint, from, to time.Time) (Report, error) {
+	report := Report{CustomerID: customerID, From: from, To: to}
+	contracts, err := s.GetContracts(customerID)
+	if err != nil {
+		return Report{}, err
+	}
+	report.Contracts = contracts
+
+	if result := s.DB.Joins("JOIN contracts ON contracts.id = sla_clocks.contract_id").
+		Where("contracts.customer_id = ? AND sla_clocks.started_at >= ? AND sla_clocks.started_at < ?", customerID, from, to).
+		Select("sla_clocks.*").Order("sla_clocks.started_at").Find(&report.Clocks); result.Error != nil {
+		return Report{}, result.Error
+	}
+	now := time.Now().UTC()
+	for i := range report.Clocks {
+		clock := &report.Clocks[i]
+		if err := s.evaluate(clock, now); err != nil {
+			return Report{}, err
+		}
+		if clock.Status == ClockStopped {
+			report.Stopped++
+			continue
+		}
+		report.Bookings++
+		if clock.Status == ClockRunning {
+			report.Open++
+		}
+		if clock.ResponseBreached {
+			report.ResponseBreached++
+		} else if clock.RespondBy != nil && clock.RespondedAt != nil {
+			report.ResponseMet++
+		}
+		if clock.ResolutionBreached {
+			report.ResolutionBreached++
+		} else if clock.ResolveBy != nil && clock.ResolvedAt != nil {
+			report.ResolutionMet++
+		}
+	}
+	report.ResponseCompliance = compliance(report.ResponseMet, report.ResponseBreached)
+	report.ResolutionCompliance = compliance(report.ResolutionMet, report.ResolutionBreached)
+	return report, nil
+}
+
+// compliance - the percentage of decided targets that were met, nil when none have been decided
+func compliance(met, breached int) *float64 {
+	if met+breached == 0 {
+		return nil
+	}
+	percent := float64(met) * 100 / float64(met+breached)
+	return &percent
+}
@@ -0,0 +1,94 @@
+package customer
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidInterval - returned for a negative calibration interval
+var ErrInvalidInterval = errors.New("instrument CalibrationInterval must be zero or more months")
+
+// nextDue - the date a calibration made at from next falls due, nil when the instrument has no
+// calibration interval
+func nextDue(from time.Time, months int) *time.Time {
+	if months <= 0 {
+		return nil
+	}
+	due := from.AddDate(0, months, 0)
+	return &due
+}
+
+// scheduleCalibration - works out when a newly registered instrument is first due, from its last
+// calibration or else from when it was installed, unless a due date was given
+func (instrument *Instrument) scheduleCalibration() {
+	if instrument.CalibrationDue != nil {
+		return
+	}
+	switch {
+	case instrument.LastCalibratedAt != nil:
+		instrument.CalibrationDue = nextDue(*instrument.LastCalibratedAt, instrument.CalibrationInterval)
+	case instrument.InstalledAt != nil:
+		instrument.CalibrationDue = nextDue(*instrument.InstalledAt, instrument.CalibrationInterval)
+	}
+}
+
+// RecordCalibration - records an instrument as calibrated at the given time, moving its due date on
+// by its calibration interval and clearing any recall booked for the calibration just made
+func (s *Service) RecordCalibration(ID uint, at time.Time) (Instrument, error) {
+	instrument, err := s.GetInstrument(ID)
+	if err != nil {
+		return Instrument{}, err
+	}
+	// a map so the due date and recall booking are cleared rather than skipped as zero values
+	changes := map[string]interface{}{
+		"last_calibrated_at": at,
+		"recall_booking_id":  nil,
+	}
+	if due := nextDue(at, instrument.CalibrationInterval); due != nil {
+		changes["calibration_due"] = *due
+	}
+	if result := s.DB.Model(&instrument).Updates(changes); result.Error != nil {
+		return Instrument{}, result.Error
+	}
+	return s.GetInstrument(ID)
+}
+
+// GetInstrumentsDue - retrieves the instruments due for calibration by the given time, soonest
+// first, only the customer's when customerID is not zero. Instruments already recalled are included
+// with their RecallBookingID set.
+func (s *Service) GetInstrumentsDue(before time.Time, customerID uint) ([]Instrument, error) {
+	var instruments []Instrument
+	query := s.DB.Where("calibration_due IS NOT NULL AND calibration_due <= ?", before)
+	if customerID != 0 {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	if result := query.Order("calibration_due, serial_no").Find(&instruments); result.Error != nil {
+		return instruments, result.Error
+	}
+	return instruments, nil
+}
+
+// SetRecallBooking - marks instruments as recalled by a booking, so they are not recalled again
+// before they are next calibrated
+func (s *Service) SetRecallBooking(IDs []uint, bookingID uint) error {
+	if len(IDs) == 0 {
+		return nil
+	}
+	if result := s.DB.Model(&Instrument{}).Where("id IN (?)", IDs).Update("recall_booking_id", bookingID); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// recordCompletedCalibration - records the calibration of the registered instrument a calibration
+// line item was carried out on, when the item has just been completed
+func (s *Service) recordCompletedCalibration(before EquipmentStatus, item Equipment) error {
+	if item.WorkType != WorkCalibration || item.Status != EquipmentCompleted || before == EquipmentCompleted {
+		return nil
+	}
+	if item.InstrumentID == nil {
+		return nil
+	}
+	_, err := s.RecordCalibration(*item.InstrumentID, time.Now().UTC())
+	return err
+}
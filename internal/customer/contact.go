@@ -0,0 +1,113 @@
+package customer
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+)
+
+// errors returned for contacts
+var (
+	ErrInvalidContact  = errors.New("contact Name and an Email or Phone are required")
+	ErrContactNotFound = errors.New("contact does not exist for this customer")
+)
+
+// Contact - a person to deal with at a customer, Customer has 0-* contacts. A contact given a SiteID
+// is the on-site contact for that site, and Primary marks the one to call first.
+type Contact struct {
+	gorm.Model
+	CustomerID uint   `json:"customerId"`
+	SiteID     *uint  `json:"siteId"`
+	Name       string `json:"name"`
+	Role       string `json:"role"`
+	Email      string `json:"email"`
+	Phone      string `json:"phone"`
+	Primary    bool   `json:"primary"`
+}
+
+// GetContacts - retrieves a customer's contacts, primary contacts first
+func (s *Service) GetContacts(customerID uint) ([]Contact, error) {
+	var contacts []Contact
+	if result := s.DB.Where("customer_id = ?", customerID).Order("\"primary\" DESC, name").Find(&contacts); result.Error != nil {
+		return contacts, result.Error
+	}
+	return contacts, nil
+}
+
+// GetContact - retrieves one of a customer's contacts by ID
+func (s *Service) GetContact(customerID uint, ID uint) (Contact, error) {
+	var contact Contact
+	if result := s.DB.Where("customer_id = ?", customerID).First(&contact, ID); result.Error != nil {
+		return Contact{}, result.Error
+	}
+	return contact, nil
+}
+
+// GetSiteContact - retrieves the on-site contact for a site, preferring its primary contact
+func (s *Service) GetSiteContact(siteID uint) (Contact, error) {
+	var contact Contact
+	if result := s.DB.Where("site_id = ?", siteID).Order("\"primary\" DESC, id").First(&contact); result.Error != nil {
+		return Contact{}, result.Error
+	}
+	return contact, nil
+}
+
+// PostContact - adds a new contact for a customer, at one of their sites when SiteID is set
+func (s *Service) PostContact(customerID uint, contact Contact) (Contact, error) {
+	if err := validateContact(contact); err != nil {
+		return Contact{}, err
+	}
+	if _, err := s.GetCustomer(customerID); err != nil {
+		return Contact{}, err
+	}
+	if contact.SiteID != nil {
+		if err := s.siteBelongsTo(customerID, *contact.SiteID); err != nil {
+			return Contact{}, err
+		}
+	}
+	contact.Model = gorm.Model{}
+	contact.CustomerID = customerID
+	if result := s.DB.Save(&contact); result.Error != nil {
+		return Contact{}, result.Error
+	}
+	return contact, nil
+}
+
+// UpdateContact - updates one of a customer's contacts by ID. A contact cannot be moved to another
+// customer, only to another of their sites.
+func (s *Service) UpdateContact(customerID uint, ID uint, newContact Contact) (Contact, error) {
+	contact, err := s.GetContact(customerID, ID)
+	if err != nil {
+		return Contact{}, err
+	}
+	if newContact.SiteID != nil {
+		if err := s.siteBelongsTo(customerID, *newContact.SiteID); err != nil {
+			return Contact{}, err
+		}
+	}
+	newContact.CustomerID = 0
+	if result := s.DB.Model(&contact).Updates(newContact); result.Error != nil {
+		return Contact{}, result.Error
+	}
+	// gorm ignores false on Updates, so Primary is always written and leaving it out clears it
+	if result := s.DB.Model(&contact).Update("primary", newContact.Primary); result.Error != nil {
+		return Contact{}, result.Error
+	}
+	return contact, nil
+}
+
+// DeleteContact - deletes one of a customer's contacts by ID
+func (s *Service) DeleteContact(customerID uint, ID uint) error {
+	if result := s.DB.Where("customer_id = ?", customerID).Delete(&Contact{}, ID); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// validateContact - a contact needs a name and some way of reaching them
+func validateContact(contact Contact) error {
+	if contact.Name == "" || (contact.Email == "" && contact.Phone == "") {
+		return ErrInvalidContact
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package customer
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// errors returned by the customer service
+var (
+	ErrInvalidCustomer  = errors.New("customer Name is required")
+	ErrCustomerNotFound = errors.New("customer does not exist")
+)
+
+// Service - the struct for the customer service, covering their sites, contacts, instruments and jobs
+type Service struct {
+	DB *gorm.DB
+}
+
+// Customer - an organisation that books work, Customer has 0-* sites, jobs and bookings
+type Customer struct {
+	gorm.Model
+	Name           string    `json:"name"`
+	Email          string    `json:"email"`
+	Phone          string    `json:"phone"`
+	BillingAddress string    `json:"billingAddress"`
+	Sites          []Site    `json:"sites"`
+	Contacts       []Contact `json:"contacts"`
+}
+
+// CustomerService - the interface for our customer service
+type CustomerService interface {
+	GetCustomer(ID uint) (Customer, error)
+	PostCustomer(customer Customer) (Customer, error)
+	UpdateCustomer(ID uint, newCustomer Customer) (Customer, error)
+	DeleteCustomer(ID uint) error
+	GetAllCustomers() ([]Customer, error)
+	GetSites(customerID uint) ([]Site, error)
+	GetSite(customerID uint, ID uint) (Site, error)
+	PostSite(customerID uint, site Site) (Site, error)
+	UpdateSite(customerID uint, ID uint, newSite Site) (Site, error)
+	DeleteSite(customerID uint, ID uint) error
+	GetContacts(customerID uint) ([]Contact, error)
+	GetContact(customerID uint, ID uint) (Contact, error)
+	GetSiteContact(siteID uint) (Contact, error)
+	PostContact(customerID uint, contact Contact) (Contact, error)
+	UpdateContact(customerID uint, ID uint, newContact Contact) (Contact, error)
+	DeleteContact(customerID uint, ID uint) error
+	GetInstrument(ID uint) (Instrument, error)
+	GetInstrumentBySerial(serialNo string) (Instrument, error)
+	GetInstruments(customerID uint, siteID uint) ([]Instrument, error)
+	GetInstrumentsByID(customerID uint, IDs []uint) ([]Instrument, error)
+	PostInstrument(instrument Instrument) (Instrument, error)
+	UpdateInstrument(ID uint, newInstrument Instrument) (Instrument, error)
+	DeleteInstrument(ID uint) error
+	RecordCalibration(ID uint, at time.Time) (Instrument, error)
+	GetInstrumentsDue(before time.Time, customerID uint) ([]Instrument, error)
+	SetRecallBooking(IDs []uint, bookingID uint) error
+	GetJob(ID uint) (Job, error)
+	GetJobsByInstrument(instrumentID uint) ([]Job, error)
+	GetJobs(customerID uint) ([]Job, error)
+	PostJob(job Job) (Job, error)
+	UpdateJob(ID uint, newJob Job) (Job, error)
+	DeleteJob(ID uint) error
+	JobEditable(ID uint) error
+	GetEquipment(jobID uint) ([]Equipment, error)
+	GetEquipmentItem(jobID uint, ID uint) (Equipment, error)
+	PostEquipment(jobID uint, item Equipment) (Equipment, error)
+	UpdateEquipment(jobID uint, ID uint, newItem Equipment) (Equipment, error)
+	DeleteEquipment(jobID uint, ID uint) error
+	GetMeasurements(jobID uint, itemID uint) ([]Measurement, error)
+	SetMeasurements(jobID uint, itemID uint, measurements []Measurement) ([]Measurement, error)
+}
+
+// NewService - takes in a pointer to the DB & returns a pointer to a new customer service
+func NewService(db *gorm.DB) *Service {
+	return &Service{
+		DB: db,
+	}
+}
+
+// GetCustomer - retrieves a customer with their sites and contacts by ID from the database
+func (s *Service) GetCustomer(ID uint) (Customer, error) {
+	var customer Customer
+	if result := s.DB.Preload("Sites").Preload("Contacts").First(&customer, ID); result.Error != nil {
+		return Customer{}, result.Error
+	}
+	return customer, nil
+}
+
+// PostCustomer - adds a new customer, along with any sites and contacts posted with them
+func (s *Service) PostCustomer(customer Customer) (Customer, error) {
+	if customer.Name == "" {
+		return Customer{}, ErrInvalidCustomer
+	}
+	// a new customer, and the sites and contacts posted with it, never take the ID of an existing one
+	customer.Model = gorm.Model{}
+	for i := range customer.Sites {
+		customer.Sites[i].Model = gorm.Model{}
+		customer.Sites[i].CustomerID = 0
+	}
+	for i := range customer.Contacts {
+		customer.Contacts[i].Model = gorm.Model{}
+		customer.Contacts[i].CustomerID = 0
+	}
+	for _, site := range customer.Sites {
+		if err := validateSite(site, false); err != nil {
+			return Customer{}, err
+		}
+	}
+	for _, contact := range customer.Contacts {
+		if err := validateContact(contact); err != nil {
+			return Customer{}, err
+		}
+		// the customer's sites have no IDs yet, contacts are tied to a site once it exists
+		if contact.SiteID != nil {
+			return Customer{}, ErrSiteNotFound
+		}
+	}
+	if result := s.DB.Save(&customer); result.Error != nil {
+		return Customer{}, result.Error
+	}
+	return customer, nil
+}
+
+// UpdateCustomer - updates a customer by ID with new customer info. Sites and contacts are changed
+// through their own endpoints, never as a side effect of an update.
+func (s *Service) UpdateCustomer(ID uint, newCustomer Customer) (Customer, error) {
+	customer, err := s.GetCustomer(ID)
+	if err != nil {
+		return Customer{}, err
+	}
+	newCustomer.Sites = nil
+	newCustomer.Contacts = nil
+	if result := s.DB.Model(&customer).Updates(newCustomer); result.Error != nil {
+		return Customer{}, result.Error
+	}
+	return customer, nil
+}
+
+// DeleteCustomer - deletes a customer from the database by ID
+func (s *Service) DeleteCustomer(ID uint) error {
+	if result := s.DB.Delete(&Customer{}, ID); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// GetAllCustomers - retrieves all customers with their sites and contacts from the database
+func (s *Service) GetAllCustomers() ([]Customer, error) {
+	var customers []Customer
+	if result := s.DB.Preload("Sites").Preload("Contacts").Order("name").Find(&customers); result.Error != nil {
+		return customers, result.Error
+	}
+	return customers, nil
+}
+
+// customerExists - returns ErrCustomerNotFound when no customer has the ID
+func (s *Service) customerExists(ID uint) error {
+	if _, err := s.GetCustomer(ID); err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return ErrCustomerNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package customer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// WorkType - the kind of work carried out on an item of equipment
+type WorkType string
+
+// work types for equipment line items
+const (
+	WorkCalibration  WorkType = "calibration"
+	WorkRepair       WorkType = "repair"
+	WorkMaintenance  WorkType = "maintenance"
+	WorkInstallation WorkType = "installation"
+	WorkInspection   WorkType = "inspection"
+	WorkOther        WorkType = "other"
+)
+
+// EquipmentStatus - how far the work on an item of equipment has got
+type EquipmentStatus string
+
+// statuses of equipment line items
+const (
+	EquipmentPending    EquipmentStatus = "pending"
+	EquipmentInProgress EquipmentStatus = "in_progress"
+	EquipmentCompleted  EquipmentStatus = "completed"
+	EquipmentNotDone    EquipmentStatus = "not_done"
+)
+
+// ErrInvalidEquipment - returned when an equipment line item has an unknown work type or status, or
+// negative time spent
+var ErrInvalidEquipment = errors.New("equipment needs a known WorkType and Status and a TimeSpent of zero or more minutes")
+
+// Equipment - one item of equipment worked on as part of a job, Job has 0-* equipment. Like a job, an
+// item on a registered instrument keeps a copy of its SerialNo, InstrumentModel and Manufacturer.
+type Equipment struct {
+	gorm.Model
+	JobID           uint            `json:"jobId"`
+	InstrumentID    *uint           `json:"instrumentId"`
+	SerialNo        string          `json:"serialNo"`
+	InstrumentModel string          `json:"instrumentModel"`
+	Manufacturer    string          `json:"manufacturer"`
+	WorkType        WorkType        `json:"workType"`
+	Status          EquipmentStatus `gorm:"default:'pending'" json:"status"`
+	Findings        string          `json:"findings"`
+	// minutes spent working on the item
+	TimeSpent    int           `json:"timeSpent"`
+	Measurements []Measurement `json:"measurements"`
+}
+
+// GetEquipment - retrieves the equipment line items of a job, in the order they were added
+func (s *Service) GetEquipment(jobID uint) ([]Equipment, error) {
+	var equipment []Equipment
+	if result := s.DB.Preload("Measurements", byID).Where("job_id = ?", jobID).Order("id").Find(&equipment); result.Error != nil {
+		return equipment, result.Error
+	}
+	return equipment, nil
+}
+
+// GetEquipmentItem - retrieves one of a job's equipment line items by ID
+func (s *Service) GetEquipmentItem(jobID uint, ID uint) (Equipment, error) {
+	var item Equipment
+	if result := s.DB.Preload("Measurements", byID).Where("job_id = ?", jobID).First(&item, ID); result.Error != nil {
+		return Equipment{}, result.Error
+	}
+	return item, nil
+}
+
+// PostEquipment - adds an equipment line item to a job
+func (s *Service) PostEquipment(jobID uint, item Equipment) (Equipment, error) {
+	item = newEquipment(item)
+	if item.Status == "" {
+		item.Status = EquipmentPending
+	}
+	if err := validateEquipment(item, false); err != nil {
+		return Equipment{}, err
+	}
+	if err := scoreMeasurements(item.Measurements); err != nil {
+		return Equipment{}, err
+	}
+	job, err := s.GetJob(jobID)
+	if err != nil {
+		return Equipment{}, err
+	}
+	if err := s.JobEditable(jobID); err != nil {
+		return Equipment{}, err
+	}
+	instrument, err := s.lookupInstrument(job.CustomerID, item.InstrumentID, item.SerialNo)
+	if err != nil {
+		return Equipment{}, err
+	}
+	item.linkTo(instrument)
+	item.JobID = jobID
+	if result := s.DB.Save(&item); result.Error != nil {
+		return Equipment{}, result.Error
+	}
+	if err := s.recordCompletedCalibration("", item); err != nil {
+		return Equipment{}, err
+	}
+	return item, nil
+}
+
+// UpdateEquipment - updates one of a job's equipment line items, recording progress, findings and
+// time spent
+func (s *Service) UpdateEquipment(jobID uint, ID uint, newItem Equipment) (Equipment, error) {
+	item, err := s.GetEquipmentItem(jobID, ID)
+	if err != nil {
+		return Equipment{}, err
+	}
+	if err := s.JobEditable(jobID); err != nil {
+		return Equipment{}, err
+	}
+	if err := validateEquipment(newItem, true); err != nil {
+		return Equipment{}, err
+	}
+	if newItem.InstrumentID != nil || (newItem.SerialNo != "" && newItem.SerialNo != item.SerialNo) {
+		job, err := s.GetJob(jobID)
+		if err != nil {
+			return Equipment{}, err
+		}
+		instrument, err := s.lookupInstrument(job.CustomerID, newItem.InstrumentID, newItem.SerialNo)
+		if err != nil {
+			return Equipment{}, err
+		}
+		newItem.linkTo(instrument)
+	}
+	// measurements are replaced through SetMeasurements, never as a side effect of an update
+	newItem.JobID = 0
+	newItem.Measurements = nil
+	before := item.Status
+	if result := s.DB.Model(&item).Updates(newItem); result.Error != nil {
+		return Equipment{}, result.Error
+	}
+	if err := s.recordCompletedCalibration(before, item); err != nil {
+		return Equipment{}, err
+	}
+	return item, nil
+}
+
+// DeleteEquipment - deletes one of a job's equipment line items by ID
+func (s *Service) DeleteEquipment(jobID uint, ID uint) error {
+	if err := s.JobEditable(jobID); err != nil {
+		return err
+	}
+	if result := s.DB.Where("job_id = ?", jobID).Delete(&Equipment{}, ID); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// newEquipment - clears what a new equipment item can never be given: its identity, the job it is on
+// and the identity of its measurements
+func newEquipment(item Equipment) Equipment {
+	item.Model = gorm.Model{}
+	item.JobID = 0
+	measurements := make([]Measurement, len(item.Measurements))
+	for i, m := range item.Measurements {
+		m.ID, m.EquipmentID = 0, 0
+		measurements[i] = m
+	}
+	item.Measurements = measurements
+	return item
+}
+
+// byID - orders preloaded rows in the order they were added
+func byID(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
+
+// linkTo - copies the registered instrument's details onto the item, leaving an item on an
+// unregistered instrument as it is
+func (item *Equipment) linkTo(instrument *Instrument) {
+	if instrument == nil {
+		return
+	}
+	item.InstrumentID = &instrument.ID
+	item.SerialNo = instrument.SerialNo
+	item.InstrumentModel = instrument.InstrumentModel
+	item.Manufacturer = instrument.Manufacturer
+}
+
+// validateEquipment - checks an item's work type, status and time spent, ignoring those left empty
+// when partial is set
+func validateEquipment(item Equipment, partial bool) error {
+	switch item.WorkType {
+	case WorkCalibration, WorkRepair, WorkMaintenance, WorkInstallation, WorkInspection, WorkOther:
+	case "":
+		if !partial {
+			return fmt.Errorf("%w: WorkType is required", ErrInvalidEquipment)
+		}
+	default:
+		return fmt.Errorf("%w: unknown WorkType %q", ErrInvalidEquipment, item.WorkType)
+	}
+	switch item.Status {
+	case EquipmentPending, EquipmentInProgress, EquipmentCompleted, EquipmentNotDone, "":
+	default:
+		return fmt.Errorf("%w: unknown Status %q", ErrInvalidEquipment, item.Status)
+	}
+	if item.TimeSpent < 0 {
+		return ErrInvalidEquipment
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package customer
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// errors returned for instruments
+var (
+	ErrInvalidInstrument  = errors.New("instrument SerialNo and CustomerID are required")
+	ErrSerialInUse        = errors.New("an instrument with this SerialNo is already registered")
+	ErrInstrumentNotFound = errors.New("instrument does not exist for this customer")
+)
+
+// Instrument - an instrument in the asset register, owned by a customer and kept at one of their
+// sites. Serial numbers are unique across the register and identify the instrument's service history.
+// An instrument with a CalibrationInterval (in months) falls due for calibration that long after it
+// was last calibrated, and is recalled once it comes due.
+type Instrument struct {
+	gorm.Model
+	CustomerID      uint       `json:"customerId"`
+	SiteID          *uint      `json:"siteId"`
+	SerialNo        string     `gorm:"unique_index" json:"serialNo"`
+	Manufacturer    string     `json:"manufacturer"`
+	InstrumentModel string     `json:"instrumentModel"`
+	AssetTag        string     `json:"assetTag"`
+	Description     string     `json:"description"`
+	InstalledAt     *time.Time `json:"installedAt"`
+	// calibration is recorded when a calibration line item on the instrument is completed
+	CalibrationInterval int        `json:"calibrationInterval"`
+	LastCalibratedAt    *time.Time `json:"lastCalibratedAt"`
+	CalibrationDue      *time.Time `gorm:"index" json:"calibrationDue"`
+	// the booking raised to recall the instrument for its next calibration, cleared once calibrated
+	RecallBookingID *uint `json:"recallBookingId"`
+}
+
+// GetInstrument - retrieves an instrument by ID from the database
+func (s *Service) GetInstrument(ID uint) (Instrument, error) {
+	var instrument Instrument
+	if result := s.DB.First(&instrument, ID); result.Error != nil {
+		return Instrument{}, result.Error
+	}
+	return instrument, nil
+}
+
+// GetInstrumentBySerial - retrieves an instrument by its serial number
+func (s *Service) GetInstrumentBySerial(serialNo string) (Instrument, error) {
+	var instrument Instrument
+	if result := s.DB.Where("serial_no = ?", serialNo).First(&instrument); result.Error != nil {
+		return Instrument{}, result.Error
+	}
+	return instrument, nil
+}
+
+// GetInstruments - retrieves the register, only a customer's or a site's instruments when
+// customerID or siteID are not zero
+func (s *Service) GetInstruments(customerID uint, siteID uint) ([]Instrument, error) {
+	var instruments []Instrument
+	query := s.DB
+	if customerID != 0 {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	if siteID != 0 {
+		query = query.Where("site_id = ?", siteID)
+	}
+	if result := query.Order("serial_no").Find(&instruments); result.Error != nil {
+		return instruments, result.Error
+	}
+	return instruments, nil
+}
+
+// PostInstrument - registers a new instrument for an existing customer and, when SiteID is set, one
+// of their sites
+func (s *Service) PostInstrument(instrument Instrument) (Instrument, error) {
+	if instrument.SerialNo == "" || instrument.CustomerID == 0 {
+		return Instrument{}, ErrInvalidInstrument
+	}
+	if instrument.CalibrationInterval < 0 {
+		return Instrument{}, ErrInvalidInterval
+	}
+	if err := s.customerExists(instrument.CustomerID); err != nil {
+		return Instrument{}, err
+	}
+	if instrument.SiteID != nil {
+		if err := s.siteBelongsTo(instrument.CustomerID, *instrument.SiteID); err != nil {
+			return Instrument{}, err
+		}
+	}
+	if err := s.serialAvailable(instrument.SerialNo, 0); err != nil {
+		return Instrument{}, err
+	}
+	instrument.Model = gorm.Model{}
+	instrument.RecallBookingID = nil
+	instrument.scheduleCalibration()
+	if result := s.DB.Save(&instrument); result.Error != nil {
+		return Instrument{}, result.Error
+	}
+	return instrument, nil
+}
+
+// UpdateInstrument - updates an instrument by ID, for example when it moves to another site or is
+// sold on to another customer
+func (s *Service) UpdateInstrument(ID uint, newInstrument Instrument) (Instrument, error) {
+	instrument, err := s.GetInstrument(ID)
+	if err != nil {
+		return Instrument{}, err
+	}
+	customerID := instrument.CustomerID
+	if newInstrument.CustomerID != 0 && newInstrument.CustomerID != customerID {
+		if err := s.customerExists(newInstrument.CustomerID); err != nil {
+			return Instrument{}, err
+		}
+		customerID = newInstrument.CustomerID
+	}
+	siteID := instrument.SiteID
+	if newInstrument.SiteID != nil {
+		siteID = newInstrument.SiteID
+	}
+	if siteID != nil {
+		if err := s.siteBelongsTo(customerID, *siteID); err != nil {
+			return Instrument{}, err
+		}
+	}
+	if newInstrument.SerialNo != "" && newInstrument.SerialNo != instrument.SerialNo {
+		if err := s.serialAvailable(newInstrument.SerialNo, ID); err != nil {
+			return Instrument{}, err
+		}
+	}
+	if newInstrument.CalibrationInterval < 0 {
+		return Instrument{}, ErrInvalidInterval
+	}
+	// a new interval moves the due date on from the last calibration, unless a due date is given
+	if newInstrument.CalibrationInterval != 0 && newInstrument.CalibrationDue == nil && instrument.LastCalibratedAt != nil {
+		newInstrument.CalibrationDue = nextDue(*instrument.LastCalibratedAt, newInstrument.CalibrationInterval)
+	}
+	// recalls are only raised by the recall scheduler
+	newInstrument.RecallBookingID = nil
+	if result := s.DB.Model(&instrument).Updates(newInstrument); result.Error != nil {
+		return Instrument{}, result.Error
+	}
+	return instrument, nil
+}
+
+// DeleteInstrument - removes an instrument from the register by ID. Its jobs and bookings keep the
+// serial number they were raised against.
+func (s *Service) DeleteInstrument(ID uint) error {
+	if result := s.DB.Delete(&Instrument{}, ID); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// GetInstrumentsByID - retrieves the instruments with the given IDs, returning ErrInstrumentNotFound
+// if any of them do not exist or belong to another customer
+func (s *Service) GetInstrumentsByID(customerID uint, IDs []uint) ([]Instrument, error) {
+	var instruments []Instrument
+	if len(IDs) == 0 {
+		return instruments, nil
+	}
+	if result := s.DB.Where("customer_id = ? AND id IN (?)", customerID, IDs).Find(&instruments); result.Error != nil {
+		return instruments, result.Error
+	}
+	seen := make(map[uint]bool, len(IDs))
+	for _, id := range IDs {
+		seen[id] = true
+	}
+	if len(instruments) != len(seen) {
+		return instruments, ErrInstrumentNotFound
+	}
+	return instruments, nil
+}
+
+// serialAvailable - returns ErrSerialInUse if an instrument other than ID has the serial number
+func (s *Service) serialAvailable(serialNo string, ID uint) error {
+	var count int
+	if result := s.DB.Model(&Instrument{}).Where("serial_no = ? AND id <> ?", serialNo, ID).Count(&count); result.Error != nil {
+		return result.Error
+	}
+	if count > 0 {
+		return ErrSerialInUse
+	}
+	return nil
+}
+
+// lookupInstrument - finds the registered instrument a job or equipment item refers to, by
+// instrumentID or else by serial number. An unregistered serial number gives no instrument, while an
+// instrumentID that does not exist or belongs to another customer gives ErrInstrumentNotFound.
+func (s *Service) lookupInstrument(customerID uint, instrumentID *uint, serialNo string) (*Instrument, error) {
+	var instrument Instrument
+	var err error
+	switch {
+	case instrumentID != nil:
+		instrument, err = s.GetInstrument(*instrumentID)
+	case serialNo != "":
+		instrument, err = s.GetInstrumentBySerial(serialNo)
+		if gorm.IsRecordNotFoundError(err) || (err == nil && instrument.CustomerID != customerID) {
+			return nil, nil
+		}
+	default:
+		return nil, nil
+	}
+	if gorm.IsRecordNotFoundError(err) || (err == nil && instrument.CustomerID != customerID) {
+		return nil, ErrInstrumentNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &instrument, nil
+}
+
+// linkInstrument - ties a job to the register. A job on a registered instrument takes its serial
+// number, model, manufacturer and site.
+func (s *Service) linkInstrument(job *Job) error {
+	instrument, err := s.lookupInstrument(job.CustomerID, job.InstrumentID, job.SerialNo)
+	if err != nil || instrument == nil {
+		return err
+	}
+	job.InstrumentID = &instrument.ID
+	job.SerialNo = instrument.SerialNo
+	job.InstrumentModel = instrument.InstrumentModel
+	job.Manufacturer = instrument.Manufacturer
+	if job.SiteID == nil {
+		job.SiteID = instrument.SiteID
+	}
+	return nil
+}
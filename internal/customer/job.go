@@ -0,0 +1,182 @@
+package customer
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrInvalidJob - returned when a job is not raised for a customer
+var ErrInvalidJob = errors.New("job CustomerID is required")
+
+// ErrBookingSigned - returned when changing a job, or its equipment, once a booking for it has been
+// signed off by the customer
+var ErrBookingSigned = errors.New("booking has been signed off by the customer and can no longer be changed")
+
+// Job - a piece of work raised for a customer, optionally at one of their sites. A job is carried out
+// over one or more bookings and consists of 0-* equipment line items. A job on a single registered
+// instrument has its InstrumentID set, and keeps a copy of the instrument's SerialNo, InstrumentModel
+// and Manufacturer as they were when it was raised.
+type Job struct {
+	gorm.Model
+	CustomerID      uint        `json:"customerId"`
+	SiteID          *uint       `json:"siteId"`
+	InstrumentID    *uint       `json:"instrumentId"`
+	Reference       string      `json:"reference"`
+	Description     string      `json:"description"`
+	SerialNo        string      `json:"serialNo"`
+	InstrumentModel string      `json:"instrumentModel"`
+	Manufacturer    string      `json:"manufacturer"`
+	Equipment       []Equipment `json:"equipment"`
+}
+
+// GetJob - retrieves a job and its equipment by ID from the database
+func (s *Service) GetJob(ID uint) (Job, error) {
+	var job Job
+	if result := s.DB.Preload("Equipment", byID).Preload("Equipment.Measurements", byID).First(&job, ID); result.Error != nil {
+		return Job{}, result.Error
+	}
+	return job, nil
+}
+
+// GetJobsByInstrument - retrieves the jobs raised on a registered instrument or with it among their
+// equipment, oldest first
+func (s *Service) GetJobsByInstrument(instrumentID uint) ([]Job, error) {
+	var jobs []Job
+	if result := s.DB.Preload("Equipment").
+		Where("instrument_id = ? OR id IN (SELECT job_id FROM equipment WHERE instrument_id = ? AND deleted_at IS NULL)", instrumentID, instrumentID).
+		Order("created_at").Find(&jobs); result.Error != nil {
+		return jobs, result.Error
+	}
+	return jobs, nil
+}
+
+// GetJobs - retrieves all jobs, only the customer's when customerID is not zero
+func (s *Service) GetJobs(customerID uint) ([]Job, error) {
+	var jobs []Job
+	query := s.DB
+	if customerID != 0 {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	if result := query.Order("created_at").Find(&jobs); result.Error != nil {
+		return jobs, result.Error
+	}
+	return jobs, nil
+}
+
+// PostJob - adds a new job for an existing customer and, when SiteID is set, one of their sites,
+// along with any equipment posted with it
+func (s *Service) PostJob(job Job) (Job, error) {
+	// a new job, and the equipment posted with it, never takes the ID of an existing one
+	job.Model = gorm.Model{}
+	for i := range job.Equipment {
+		job.Equipment[i] = newEquipment(job.Equipment[i])
+	}
+	if job.CustomerID == 0 {
+		return Job{}, ErrInvalidJob
+	}
+	if err := s.customerExists(job.CustomerID); err != nil {
+		return Job{}, err
+	}
+	if err := s.linkInstrument(&job); err != nil {
+		return Job{}, err
+	}
+	for i := range job.Equipment {
+		if job.Equipment[i].Status == "" {
+			job.Equipment[i].Status = EquipmentPending
+		}
+		if err := validateEquipment(job.Equipment[i], false); err != nil {
+			return Job{}, err
+		}
+		if err := scoreMeasurements(job.Equipment[i].Measurements); err != nil {
+			return Job{}, err
+		}
+		instrument, err := s.lookupInstrument(job.CustomerID, job.Equipment[i].InstrumentID, job.Equipment[i].SerialNo)
+		if err != nil {
+			return Job{}, err
+		}
+		job.Equipment[i].linkTo(instrument)
+	}
+	if job.SiteID != nil {
+		if err := s.siteBelongsTo(job.CustomerID, *job.SiteID); err != nil {
+			return Job{}, err
+		}
+	}
+	if result := s.DB.Save(&job); result.Error != nil {
+		return Job{}, result.Error
+	}
+	for _, item := range job.Equipment {
+		if err := s.recordCompletedCalibration("", item); err != nil {
+			return Job{}, err
+		}
+	}
+	return job, nil
+}
+
+// UpdateJob - updates a job by ID with new job info, checking any new customer or site exists
+func (s *Service) UpdateJob(ID uint, newJob Job) (Job, error) {
+	job, err := s.GetJob(ID)
+	if err != nil {
+		return Job{}, err
+	}
+	if err := s.JobEditable(ID); err != nil {
+		return Job{}, err
+	}
+	customerID := job.CustomerID
+	if newJob.CustomerID != 0 && newJob.CustomerID != customerID {
+		if err := s.customerExists(newJob.CustomerID); err != nil {
+			return Job{}, err
+		}
+		customerID = newJob.CustomerID
+	}
+	if newJob.InstrumentID != nil || (newJob.SerialNo != "" && newJob.SerialNo != job.SerialNo) {
+		linked := Job{CustomerID: customerID, SiteID: newJob.SiteID, InstrumentID: newJob.InstrumentID, SerialNo: newJob.SerialNo}
+		if err := s.linkInstrument(&linked); err != nil {
+			return Job{}, err
+		}
+		if linked.InstrumentID != nil {
+			newJob.InstrumentID, newJob.SiteID = linked.InstrumentID, linked.SiteID
+			newJob.SerialNo, newJob.InstrumentModel, newJob.Manufacturer = linked.SerialNo, linked.InstrumentModel, linked.Manufacturer
+		}
+	}
+	siteID := job.SiteID
+	if newJob.SiteID != nil {
+		siteID = newJob.SiteID
+	}
+	if siteID != nil {
+		if err := s.siteBelongsTo(customerID, *siteID); err != nil {
+			return Job{}, err
+		}
+	}
+	// equipment is changed through its own endpoints, never as a side effect of an update
+	newJob.Equipment = nil
+	if result := s.DB.Model(&job).Updates(newJob); result.Error != nil {
+		return Job{}, result.Error
+	}
+	return job, nil
+}
+
+// DeleteJob - deletes a job from the database by ID
+func (s *Service) DeleteJob(ID uint) error {
+	if err := s.JobEditable(ID); err != nil {
+		return err
+	}
+	if result := s.DB.Delete(&Job{}, ID); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// JobEditable - returns ErrBookingSigned if any booking for the job has been signed off, the work
+// recorded on the job being part of what the customer signed
+func (s *Service) JobEditable(ID uint) error {
+	var count int
+	if result := s.DB.Table("bookings").Where("job_id = ? AND signed_at IS NOT NULL AND deleted_at IS NULL", ID).
+		Count(&count); result.Error != nil {
+		return result.Error
+	}
+	if count > 0 {
+		return ErrBookingSigned
+	}
+	return nil
+}
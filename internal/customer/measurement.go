@@ -0,0 +1,127 @@
+package customer
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrInvalidMeasurement - returned when a measurement has no parameter, a negative tolerance or no reading
+var ErrInvalidMeasurement = errors.New("measurement needs a Parameter, a Tolerance of zero or more and an AsFound or AsLeft reading")
+
+// Measurement - one calibration point on an item of equipment: the reading as found and as left
+// against the nominal value, and whether each was within ± Tolerance of it. Equipment has 0-*
+// measurements, in the order they were taken.
+type Measurement struct {
+	ID          uint     `gorm:"primary_key" json:"id"`
+	EquipmentID uint     `json:"equipmentId"`
+	Parameter   string   `json:"parameter"`
+	Unit        string   `json:"unit"`
+	Nominal     float64  `json:"nominal"`
+	Tolerance   float64  `json:"tolerance"`
+	AsFound     *float64 `json:"asFound"`
+	AsLeft      *float64 `json:"asLeft"`
+	// worked out from the readings when the measurement is saved
+	AsFoundPass *bool `json:"asFoundPass"`
+	AsLeftPass  *bool `json:"asLeftPass"`
+}
+
+// Passed - reports whether the instrument was left within tolerance at this point, going by the
+// as-found reading when no adjustment was made
+func (m Measurement) Passed() bool {
+	if m.AsLeftPass != nil {
+		return *m.AsLeftPass
+	}
+	return m.AsFoundPass != nil && *m.AsFoundPass
+}
+
+// Passed - reports whether every measurement on the item passed. An item without measurements has
+// not passed.
+func (item Equipment) Passed() bool {
+	for _, m := range item.Measurements {
+		if !m.Passed() {
+			return false
+		}
+	}
+	return len(item.Measurements) > 0
+}
+
+// FoundInTolerance - reports whether every as-found reading on the item was within tolerance, that
+// is whether the instrument was in calibration when the engineer arrived
+func (item Equipment) FoundInTolerance() bool {
+	for _, m := range item.Measurements {
+		if m.AsFoundPass != nil && !*m.AsFoundPass {
+			return false
+		}
+	}
+	return true
+}
+
+// GetMeasurements - retrieves the measurements taken on one of a job's equipment items
+func (s *Service) GetMeasurements(jobID uint, itemID uint) ([]Measurement, error) {
+	var measurements []Measurement
+	if _, err := s.GetEquipmentItem(jobID, itemID); err != nil {
+		return measurements, err
+	}
+	if result := s.DB.Where("equipment_id = ?", itemID).Order("id").Find(&measurements); result.Error != nil {
+		return measurements, result.Error
+	}
+	return measurements, nil
+}
+
+// SetMeasurements - replaces the measurements taken on one of a job's equipment items
+func (s *Service) SetMeasurements(jobID uint, itemID uint, measurements []Measurement) ([]Measurement, error) {
+	if _, err := s.GetEquipmentItem(jobID, itemID); err != nil {
+		return nil, err
+	}
+	if err := s.JobEditable(jobID); err != nil {
+		return nil, err
+	}
+	if err := scoreMeasurements(measurements); err != nil {
+		return nil, err
+	}
+
+	tx := s.DB.Begin()
+	if result := tx.Where("equipment_id = ?", itemID).Delete(&Measurement{}); result.Error != nil {
+		tx.Rollback()
+		return nil, result.Error
+	}
+	for i := range measurements {
+		measurements[i].ID = 0
+		measurements[i].EquipmentID = itemID
+		if result := tx.Create(&measurements[i]); result.Error != nil {
+			tx.Rollback()
+			return nil, result.Error
+		}
+	}
+	// measurements carry no timestamps, the item counts as updated when they change
+	if result := tx.Model(&Equipment{}).Where("id = ?", itemID).UpdateColumn("updated_at", time.Now().UTC()); result.Error != nil {
+		tx.Rollback()
+		return nil, result.Error
+	}
+	if result := tx.Commit(); result.Error != nil {
+		return nil, result.Error
+	}
+	return measurements, nil
+}
+
+// scoreMeasurements - validates the measurements and works out whether each reading passed
+func scoreMeasurements(measurements []Measurement) error {
+	for i := range measurements {
+		m := &measurements[i]
+		if m.Parameter == "" || m.Tolerance < 0 || (m.AsFound == nil && m.AsLeft == nil) {
+			return ErrInvalidMeasurement
+		}
+		m.AsFoundPass, m.AsLeftPass = withinTolerance(m.AsFound, m.Nominal, m.Tolerance), withinTolerance(m.AsLeft, m.Nominal, m.Tolerance)
+	}
+	return nil
+}
+
+func withinTolerance(reading *float64, nominal float64, tolerance float64) *bool {
+	if reading == nil {
+		return nil
+	}
+	// allow for the rounding of readings entered as decimals
+	pass := math.Abs(*reading-nominal) <= tolerance+1e-9
+	return &pass
+}
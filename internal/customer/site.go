@@ -0,0 +1,135 @@
+package customer
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// errors returned for sites
+var (
+	ErrSiteNotFound    = errors.New("site does not exist for this customer")
+	ErrInvalidSite     = errors.New("site Name and Address are required")
+	ErrInvalidLocation = errors.New("site coordinates must be a Latitude in [-90, 90] and Longitude in [-180, 180], given together")
+	ErrInvalidTimeZone = errors.New("time zone must be an IANA zone name such as Europe/Dublin")
+)
+
+// Site - a customer location where work is carried out, Customer has 0-* sites. Bookings at a site
+// take its address as their Location and its TimeZone as their own.
+type Site struct {
+	gorm.Model
+	CustomerID uint   `json:"customerId"`
+	Name       string `json:"name"`
+	Address    string `json:"address"`
+	Postcode   string `json:"postcode"`
+	// WGS 84 coordinates, nil until the site has been located
+	Latitude           *float64 `json:"latitude"`
+	Longitude          *float64 `json:"longitude"`
+	AccessInstructions string   `json:"accessInstructions"`
+	OpeningHours       string   `json:"openingHours"`
+	SafetyRequirements string   `json:"safetyRequirements"`
+	TimeZone           string   `json:"timeZone"`
+}
+
+// FullAddress - the site's address with its postcode, as given to engineers
+func (site Site) FullAddress() string {
+	if site.Postcode == "" {
+		return site.Address
+	}
+	return site.Address + ", " + site.Postcode
+}
+
+// HasLocation - reports whether the site's coordinates are known
+func (site Site) HasLocation() bool {
+	return site.Latitude != nil && site.Longitude != nil
+}
+
+// validateSite - checks the fields of a site being saved, ignoring those left empty when partial is set
+func validateSite(site Site, partial bool) error {
+	if !partial && (site.Name == "" || site.Address == "") {
+		return ErrInvalidSite
+	}
+	if (site.Latitude == nil) != (site.Longitude == nil) && !partial {
+		return ErrInvalidLocation
+	}
+	if site.Latitude != nil && (*site.Latitude < -90 || *site.Latitude > 90) {
+		return ErrInvalidLocation
+	}
+	if site.Longitude != nil && (*site.Longitude < -180 || *site.Longitude > 180) {
+		return ErrInvalidLocation
+	}
+	if _, err := time.LoadLocation(site.TimeZone); err != nil {
+		return ErrInvalidTimeZone
+	}
+	return nil
+}
+
+// GetSites - retrieves a customer's sites
+func (s *Service) GetSites(customerID uint) ([]Site, error) {
+	var sites []Site
+	if result := s.DB.Where("customer_id = ?", customerID).Order("name").Find(&sites); result.Error != nil {
+		return sites, result.Error
+	}
+	return sites, nil
+}
+
+// GetSite - retrieves one of a customer's sites by ID
+func (s *Service) GetSite(customerID uint, ID uint) (Site, error) {
+	var site Site
+	if result := s.DB.Where("customer_id = ?", customerID).First(&site, ID); result.Error != nil {
+		return Site{}, result.Error
+	}
+	return site, nil
+}
+
+// PostSite - adds a new site for a customer
+func (s *Service) PostSite(customerID uint, site Site) (Site, error) {
+	if err := validateSite(site, false); err != nil {
+		return Site{}, err
+	}
+	if _, err := s.GetCustomer(customerID); err != nil {
+		return Site{}, err
+	}
+	site.Model = gorm.Model{}
+	site.CustomerID = customerID
+	if result := s.DB.Save(&site); result.Error != nil {
+		return Site{}, result.Error
+	}
+	return site, nil
+}
+
+// UpdateSite - updates one of a customer's sites by ID. A site cannot be moved to another customer.
+func (s *Service) UpdateSite(customerID uint, ID uint, newSite Site) (Site, error) {
+	site, err := s.GetSite(customerID, ID)
+	if err != nil {
+		return Site{}, err
+	}
+	if err := validateSite(newSite, true); err != nil {
+		return Site{}, err
+	}
+	newSite.CustomerID = 0
+	if result := s.DB.Model(&site).Updates(newSite); result.Error != nil {
+		return Site{}, result.Error
+	}
+	return site, nil
+}
+
+// DeleteSite - deletes one of a customer's sites by ID
+func (s *Service) DeleteSite(customerID uint, ID uint) error {
+	if result := s.DB.Where("customer_id = ?", customerID).Delete(&Site{}, ID); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// siteBelongsTo - returns ErrSiteNotFound unless the site exists and belongs to the customer
+func (s *Service) siteBelongsTo(customerID uint, ID uint) error {
+	if _, err := s.GetSite(customerID, ID); err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return ErrSiteNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -1,8 +1,17 @@
 package database
 
 import (
+	"github.com/Open-FiSE/go-rest-api/internal/billing"
 	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/calendar"
+	"github.com/Open-FiSE/go-rest-api/internal/checklist"
+	"github.com/Open-FiSE/go-rest-api/internal/contract"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
 	"github.com/Open-FiSE/go-rest-api/internal/document"
+	"github.com/Open-FiSE/go-rest-api/internal/engineer"
+	"github.com/Open-FiSE/go-rest-api/internal/fieldsync"
+	"github.com/Open-FiSE/go-rest-api/internal/inventory"
+	"github.com/Open-FiSE/go-rest-api/internal/timesheet"
 	"github.com/jinzhu/gorm"
 )
 
@@ -10,8 +19,105 @@ import (
 func MigrateDB(db *gorm.DB) error {
 	// AutoMigrate - takes in document model (struct) &
 	// define DB columns Path | Body | Author as well as predefined gorm (ID, update time etc).
-	if result := db.AutoMigrate(&document.Document{}, &booking.Booking{}); result.Error != nil {
+	if result := db.AutoMigrate(
+		&document.Document{},
+		&booking.Booking{},
+		&booking.ExceptionDate{},
+		&booking.StatusChange{},
+		&booking.SignOff{},
+		&customer.Customer{},
+		&customer.Site{},
+		&customer.Contact{},
+		&customer.Instrument{},
+		&customer.Job{},
+		&customer.Equipment{},
+		&customer.Measurement{},
+		&engineer.Engineer{},
+		&engineer.Certification{},
+		&engineer.Absence{},
+		&calendar.FeedToken{},
+		&checklist.Template{},
+		&checklist.TemplateField{},
+		&checklist.Checklist{},
+		&checklist.Item{},
+		&inventory.Part{},
+		&inventory.Location{},
+		&inventory.StockLevel{},
+		&inventory.Usage{},
+		&timesheet.Timesheet{},
+		&timesheet.Entry{},
+		&billing.LabourRate{},
+		&billing.WorkPrice{},
+		&billing.TaxRule{},
+		&billing.Sequence{},
+		&billing.Invoice{},
+		&billing.Line{},
+		&contract.Contract{},
+		&contract.Clock{},
+		&fieldsync.Receipt{},
+	); result.Error != nil {
 		return result.Error
 	}
+
+	// bookings, jobs, sites and contacts reference their customer by ID. Rows are soft deleted, so RESTRICT only
+	// stops a customer that still has work from being purged from the database.
+	foreignKeys := []struct {
+		model      interface{}
+		field      string
+		references string
+	}{
+		{&customer.Site{}, "customer_id", "customers(id)"},
+		{&customer.Job{}, "customer_id", "customers(id)"},
+		{&customer.Job{}, "site_id", "sites(id)"},
+		{&customer.Job{}, "instrument_id", "instruments(id)"},
+		{&customer.Equipment{}, "job_id", "jobs(id)"},
+		{&customer.Equipment{}, "instrument_id", "instruments(id)"},
+		{&customer.Measurement{}, "equipment_id", "equipment(id)"},
+		{&customer.Instrument{}, "customer_id", "customers(id)"},
+		{&customer.Instrument{}, "site_id", "sites(id)"},
+		{&customer.Instrument{}, "recall_booking_id", "bookings(id)"},
+		{&customer.Contact{}, "customer_id", "customers(id)"},
+		{&customer.Contact{}, "site_id", "sites(id)"},
+		{&booking.Booking{}, "customer_id", "customers(id)"},
+		{&booking.Booking{}, "job_id", "jobs(id)"},
+		{&booking.Booking{}, "site_id", "sites(id)"},
+		{&booking.Booking{}, "contact_id", "contacts(id)"},
+		{&checklist.TemplateField{}, "template_id", "checklist_templates(id)"},
+		{&checklist.Checklist{}, "job_id", "jobs(id)"},
+		{&checklist.Checklist{}, "equipment_id", "equipment(id)"},
+		{&checklist.Item{}, "checklist_id", "checklists(id)"},
+		{&checklist.Item{}, "photo_id", "documents(id)"},
+		{&booking.SignOff{}, "booking_id", "bookings(id)"},
+		{&booking.SignOff{}, "signature_document_id", "documents(id)"},
+		{&booking.SignOff{}, "report_document_id", "documents(id)"},
+		{&inventory.Location{}, "engineer_id", "engineers(id)"},
+		{&inventory.StockLevel{}, "part_id", "parts(id)"},
+		{&inventory.StockLevel{}, "location_id", "stock_locations(id)"},
+		{&inventory.Usage{}, "job_id", "jobs(id)"},
+		{&inventory.Usage{}, "booking_id", "bookings(id)"},
+		{&inventory.Usage{}, "part_id", "parts(id)"},
+		{&inventory.Usage{}, "location_id", "stock_locations(id)"},
+		{&timesheet.Timesheet{}, "engineer_id", "engineers(id)"},
+		{&timesheet.Entry{}, "booking_id", "bookings(id)"},
+		{&timesheet.Entry{}, "engineer_id", "engineers(id)"},
+		{&timesheet.Entry{}, "timesheet_id", "timesheets(id)"},
+		{&billing.TaxRule{}, "customer_id", "customers(id)"},
+		{&billing.Invoice{}, "booking_id", "bookings(id)"},
+		{&billing.Invoice{}, "job_id", "jobs(id)"},
+		{&billing.Invoice{}, "customer_id", "customers(id)"},
+		{&billing.Invoice{}, "document_id", "documents(id)"},
+		{&billing.Line{}, "invoice_id", "invoices(id)"},
+		{&contract.Contract{}, "customer_id", "customers(id)"},
+		{&contract.Clock{}, "booking_id", "bookings(id)"},
+		{&contract.Clock{}, "contract_id", "contracts(id)"},
+		{&document.Document{}, "booking_id", "bookings(id)"},
+		{&document.Document{}, "instrument_id", "instruments(id)"},
+		{&fieldsync.Receipt{}, "engineer_id", "engineers(id)"},
+	}
+	for _, fk := range foreignKeys {
+		if result := db.Model(fk.model).AddForeignKey(fk.field, fk.references, "RESTRICT", "RESTRICT"); result.Error != nil {
+			return result.Error
+		}
+	}
 	return nil
 }
@@ -0,0 +1,206 @@
+package dispatch
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/availability"
+	"github.com/Open-FiSE/go-rest-api/internal/engineer"
+	"github.com/Open-FiSE/go-rest-api/internal/routing"
+)
+
+// day - the engineers' days as a dispatch run fills them, the visits they already had and those
+// proposed so far, by engineer and date in the engineer's time zone
+type day struct {
+	service *Service
+	visits  map[uint]map[string][]visit
+}
+
+// candidate - a free, qualified engineer for a job and the travel it would add to their day
+type candidate struct {
+	engineer engineer.Engineer
+	travel   time.Duration
+	visits   int
+}
+
+// assign - proposes the engineer for a job, or returns why there is none
+func (d *day) assign(j job, engineers []engineer.Engineer) (*Assignment, string, error) {
+	var qualified []engineer.Engineer
+	var IDs []uint
+	for _, e := range engineers {
+		if j.qualified(e) {
+			qualified = append(qualified, e)
+			IDs = append(IDs, e.ID)
+		}
+	}
+	if len(qualified) == 0 {
+		return nil, reasonUnqualified, nil
+	}
+
+	b := j.booking
+	// an engineer is free when their working hours, bookings and absences leave the booked time open
+	slots, err := d.service.Availability.FreeSlots(availability.Query{
+		From:        b.StartDateTime,
+		To:          b.EndDateTime,
+		Duration:    b.EndDateTime.Sub(b.StartDateTime),
+		EngineerIDs: IDs,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	free := make(map[uint]bool, len(slots))
+	for _, slot := range slots {
+		free[slot.EngineerID] = true
+	}
+
+	var best *candidate
+	anyFree := false
+	for _, e := range qualified {
+		if !free[e.ID] {
+			continue
+		}
+		visits, err := d.day(e, b.StartDateTime)
+		if err != nil {
+			return nil, "", err
+		}
+		if overlaps(visits, b.StartDateTime, b.EndDateTime) {
+			continue
+		}
+		anyFree = true
+		travel, ok := d.insertion(e, visits, visit{start: b.StartDateTime, end: b.EndDateTime, point: j.point})
+		if !ok {
+			continue
+		}
+		c := candidate{engineer: e, travel: travel, visits: len(visits)}
+		if best == nil || c.travel < best.travel || (c.travel == best.travel && c.visits < best.visits) {
+			best = &c
+		}
+	}
+	if best == nil {
+		if anyFree {
+			return nil, reasonTravel, nil
+		}
+		return nil, reasonBusy, nil
+	}
+
+	d.add(best.engineer, visit{start: b.StartDateTime, end: b.EndDateTime, point: j.point})
+	assignment := &Assignment{
+		BookingID:     b.ID,
+		Summary:       b.Summary,
+		Start:         b.StartDateTime,
+		End:           b.EndDateTime,
+		EngineerID:    best.engineer.ID,
+		EngineerName:  best.engineer.Name,
+		TravelMinutes: int(math.Round(best.travel.Minutes())),
+		RespondBy:     j.respondBy,
+	}
+	if j.respondBy != nil {
+		within := !b.StartDateTime.After(*j.respondBy)
+		assignment.WithinSLA = &within
+	}
+	return assignment, "", nil
+}
+
+// insertion - the travel a visit adds to an engineer's day, from the visit before it (or the
+// engineer's base when it is the first) and on to the visit after, and whether the engineer can make
+// both journeys in the time between. Journeys to or from a site without coordinates are not counted.
+func (d *day) insertion(e engineer.Engineer, visits []visit, v visit) (time.Duration, bool) {
+	var prev, next *visit
+	for i := range visits {
+		if !visits[i].end.After(v.start) {
+			prev = &visits[i]
+		} else if next == nil && !visits[i].start.Before(v.end) {
+			next = &visits[i]
+		}
+	}
+	from := prev
+	if from == nil && e.HasBase() {
+		from = &visit{point: &routing.Point{Latitude: *e.BaseLatitude, Longitude: *e.BaseLongitude}}
+	}
+
+	var added time.Duration
+	if in, ok := d.travel(from, &v); ok {
+		if prev != nil && prev.end.Add(in).After(v.start) {
+			return 0, false
+		}
+		added += in
+	}
+	if out, ok := d.travel(&v, next); ok {
+		if v.end.Add(out).After(next.start) {
+			return 0, false
+		}
+		added += out
+	}
+	// the journey the visit replaces
+	if direct, ok := d.travel(from, next); ok {
+		added -= direct
+	}
+	if added < 0 {
+		added = 0
+	}
+	return added, true
+}
+
+// travel - the travel time between two visits, false when either end is not known
+func (d *day) travel(from, to *visit) (time.Duration, bool) {
+	if from == nil || to == nil || from.point == nil || to.point == nil {
+		return 0, false
+	}
+	durations, _, err := d.service.Matrix.Travel([]routing.Point{*from.point, *to.point})
+	if err != nil {
+		return 0, false
+	}
+	return durations[0][1], true
+}
+
+// day - the visits in an engineer's day around a time, loading their bookings the first time the
+// day is needed
+func (d *day) day(e engineer.Engineer, at time.Time) ([]visit, error) {
+	_, _, _, loc := e.WorkingHours()
+	local := at.In(loc)
+	key := local.Format("2006-01-02")
+	if visits, ok := d.visits[e.ID][key]; ok {
+		return visits, nil
+	}
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	bookings, err := d.service.Bookings.GetEngineerOccurrences(e.ID, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	visits := []visit{}
+	for _, b := range bookings {
+		if !b.Status.Active() {
+			continue
+		}
+		v := visit{start: b.StartDateTime, end: b.EndDateTime}
+		if b.Site != nil && b.Site.HasLocation() {
+			v.point = &routing.Point{Latitude: *b.Site.Latitude, Longitude: *b.Site.Longitude}
+		}
+		visits = append(visits, v)
+	}
+	if d.visits[e.ID] == nil {
+		d.visits[e.ID] = map[string][]visit{}
+	}
+	d.visits[e.ID][key] = visits
+	return visits, nil
+}
+
+// add - puts a proposed visit in an engineer's day, keeping the day in time order
+func (d *day) add(e engineer.Engineer, v visit) {
+	_, _, _, loc := e.WorkingHours()
+	key := v.start.In(loc).Format("2006-01-02")
+	visits := append(d.visits[e.ID][key], v)
+	sort.Slice(visits, func(i, j int) bool { return visits[i].start.Before(visits[j].start) })
+	d.visits[e.ID][key] = visits
+}
+
+// overlaps - whether any visit overlaps [start, end)
+func overlaps(visits []visit, start, end time.Time) bool {
+	for _, v := range visits {
+		if v.start.Before(end) && start.Before(v.end) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,289 @@
+package dispatch
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/availability"
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/contract"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/Open-FiSE/go-rest-api/internal/engineer"
+	"github.com/Open-FiSE/go-rest-api/internal/routing"
+)
+
+// MaxRange - the longest window of bookings a single dispatch run covers
+const MaxRange = 31 * 24 * time.Hour
+
+// errors returned by the dispatch service
+var (
+	ErrInvalidRequest = errors.New("dispatch needs From before To, at most 31 days apart")
+	ErrNoAssignments  = errors.New("there are no assignments to apply")
+)
+
+// reasons a booking is left unassigned
+const (
+	reasonUnqualified = "no engineer has the skills and manufacturer training the job needs"
+	reasonBusy        = "no qualified engineer is free at the booked time"
+	reasonTravel      = "no free qualified engineer can travel to the site in time"
+)
+
+// Service - the struct for the dispatch service, which proposes engineers for unassigned bookings
+type Service struct {
+	Bookings     *booking.BookService
+	Engineers    *engineer.Service
+	Customers    *customer.Service
+	Availability *availability.Service
+	Contracts    *contract.Service
+	Matrix       routing.Matrix
+}
+
+// DispatchService - the interface for our dispatch service
+type DispatchService interface {
+	Propose(request Request) (Proposal, error)
+	Apply(assignments []Assignment) (Result, error)
+}
+
+// NewService - takes in the services dispatch draws on & returns a pointer to a new dispatch service
+// estimating travel from straight-line distances
+func NewService(bookings *booking.BookService, engineers *engineer.Service, customers *customer.Service,
+	availability *availability.Service, contracts *contract.Service) *Service {
+	return &Service{
+		Bookings:     bookings,
+		Engineers:    engineers,
+		Customers:    customers,
+		Availability: availability,
+		Contracts:    contracts,
+		Matrix:       routing.Haversine{Speed: routing.DefaultSpeed, Detour: routing.DefaultDetour},
+	}
+}
+
+// Request - the bookings to dispatch, those without engineers starting from From up to To, only the
+// ones in BookingIDs when it is given, and the engineers to consider, all of them unless EngineerIDs
+// is given
+type Request struct {
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	BookingIDs  []uint    `json:"bookingIds"`
+	EngineerIDs []uint    `json:"engineerIds"`
+}
+
+// Assignment - an engineer proposed for a booking. TravelMinutes is the travel the booking adds to
+// the engineer's day. RespondBy is the booking's SLA response target when it is under contract, and
+// WithinSLA whether its booked time meets it.
+type Assignment struct {
+	BookingID     uint       `json:"bookingId"`
+	Summary       string     `json:"summary"`
+	Start         time.Time  `json:"start"`
+	End           time.Time  `json:"end"`
+	EngineerID    uint       `json:"engineerId"`
+	EngineerName  string     `json:"engineerName"`
+	TravelMinutes int        `json:"travelMinutes"`
+	RespondBy     *time.Time `json:"respondBy"`
+	WithinSLA     *bool      `json:"withinSla"`
+}
+
+// Unassigned - a booking no engineer could be proposed for, or an assignment that could not be
+// applied, and why
+type Unassigned struct {
+	BookingID uint   `json:"bookingId"`
+	Reason    string `json:"reason"`
+}
+
+// Proposal - the engineers proposed for a run's bookings, and the bookings left unassigned
+type Proposal struct {
+	Assignments   []Assignment `json:"assignments"`
+	Unassigned    []Unassigned `json:"unassigned"`
+	TravelMinutes int          `json:"travelMinutes"`
+	// SLABookings - how many of the assigned bookings are under contract, and SLAWithin how many of
+	// those are booked within their response target
+	SLABookings int `json:"slaBookings"`
+	SLAWithin   int `json:"slaWithin"`
+}
+
+// Result - the assignments applied, and those that could not be
+type Result struct {
+	Applied []Assignment `json:"applied"`
+	Failed  []Unassigned `json:"failed"`
+}
+
+// job - what a booking needs of its engineer, and where and when it is
+type job struct {
+	booking       booking.Booking
+	point         *routing.Point
+	skills        []string
+	manufacturers []string
+	respondBy     *time.Time
+}
+
+// visit - a booking in an engineer's day, already assigned or proposed in this run
+type visit struct {
+	start, end time.Time
+	point      *routing.Point
+}
+
+// Propose - proposes an engineer for each unassigned booking without assigning any. Bookings under
+// contract are dispatched first, soonest response target first, then the rest by start time, so
+// scarce engineers go where the SLA needs them. Each booking goes to the qualified engineer, free at
+// its booked time and able to travel to it between their other visits, whose day it adds the least
+// travel to, the engineer with fewer visits that day on a tie. An engineer is qualified when their
+// skills include the work types of the job's equipment and they are trained on the manufacturers of
+// its instruments.
+func (s *Service) Propose(request Request) (Proposal, error) {
+	proposal := Proposal{Assignments: []Assignment{}, Unassigned: []Unassigned{}}
+	if !request.From.Before(request.To) || request.To.Sub(request.From) > MaxRange {
+		return proposal, ErrInvalidRequest
+	}
+	bookings, err := s.Bookings.GetUnassignedBookings(request.From, request.To)
+	if err != nil {
+		return proposal, err
+	}
+	var engineers []engineer.Engineer
+	if len(request.EngineerIDs) > 0 {
+		engineers, err = s.Engineers.GetEngineers(request.EngineerIDs)
+	} else {
+		engineers, err = s.Engineers.GetAllEngineers()
+	}
+	if err != nil {
+		return proposal, err
+	}
+
+	wanted := make(map[uint]bool, len(request.BookingIDs))
+	for _, id := range request.BookingIDs {
+		wanted[id] = true
+	}
+	var jobs []job
+	for _, b := range bookings {
+		if len(wanted) > 0 && !wanted[b.ID] {
+			continue
+		}
+		j, err := s.job(b)
+		if err != nil {
+			return proposal, err
+		}
+		jobs = append(jobs, j)
+	}
+	sort.SliceStable(jobs, func(a, b int) bool {
+		ra, rb := jobs[a].respondBy, jobs[b].respondBy
+		if (ra == nil) != (rb == nil) {
+			return ra != nil
+		}
+		if ra != nil && !ra.Equal(*rb) {
+			return ra.Before(*rb)
+		}
+		return jobs[a].booking.StartDateTime.Before(jobs[b].booking.StartDateTime)
+	})
+
+	d := &day{service: s, visits: map[uint]map[string][]visit{}}
+	for _, j := range jobs {
+		assignment, reason, err := d.assign(j, engineers)
+		if err != nil {
+			return proposal, err
+		}
+		if assignment == nil {
+			proposal.Unassigned = append(proposal.Unassigned, Unassigned{BookingID: j.booking.ID, Reason: reason})
+			continue
+		}
+		proposal.Assignments = append(proposal.Assignments, *assignment)
+		proposal.TravelMinutes += assignment.TravelMinutes
+		if assignment.WithinSLA != nil {
+			proposal.SLABookings++
+			if *assignment.WithinSLA {
+				proposal.SLAWithin++
+			}
+		}
+	}
+	return proposal, nil
+}
+
+// Apply - assigns the engineers of a proposal, which may have been edited since it was made. A
+// booking that has had engineers assigned in the meantime, or whose engineer has since been booked
+// elsewhere at the same time, is left as it is and reported as failed. An assigned booking under a
+// contract has its SLA clock started if it is not already running.
+func (s *Service) Apply(assignments []Assignment) (Result, error) {
+	result := Result{Applied: []Assignment{}, Failed: []Unassigned{}}
+	if len(assignments) == 0 {
+		return result, ErrNoAssignments
+	}
+	for _, a := range assignments {
+		b, err := s.Bookings.GetBooking(a.BookingID)
+		if err != nil {
+			result.Failed = append(result.Failed, Unassigned{BookingID: a.BookingID, Reason: err.Error()})
+			continue
+		}
+		if len(b.Engineers) > 0 {
+			result.Failed = append(result.Failed, Unassigned{BookingID: a.BookingID, Reason: "booking already has engineers assigned"})
+			continue
+		}
+		if _, err := s.Bookings.AssignEngineers(b.ID, []uint{a.EngineerID}, false); err != nil {
+			result.Failed = append(result.Failed, Unassigned{BookingID: a.BookingID, Reason: err.Error()})
+			continue
+		}
+		result.Applied = append(result.Applied, a)
+		// a booking under a contract has its SLA clock running once it is dispatched
+		if _, err := s.Contracts.StartClock(b.ID); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// job - works out what a booking needs of its engineer: the skills for the work types on its job's
+// equipment, training on the manufacturers of its instruments and equipment, and its SLA target
+func (s *Service) job(b booking.Booking) (job, error) {
+	j := job{booking: b}
+	if b.Site != nil && b.Site.HasLocation() {
+		j.point = &routing.Point{Latitude: *b.Site.Latitude, Longitude: *b.Site.Longitude}
+	}
+	for _, instrument := range b.Instruments {
+		j.manufacturers = appendFold(j.manufacturers, instrument.Manufacturer)
+	}
+	if b.JobID != nil {
+		customerJob, err := s.Customers.GetJob(*b.JobID)
+		if err != nil {
+			return job{}, err
+		}
+		j.manufacturers = appendFold(j.manufacturers, customerJob.Manufacturer)
+		for _, item := range customerJob.Equipment {
+			j.manufacturers = appendFold(j.manufacturers, item.Manufacturer)
+			j.skills = appendFold(j.skills, string(item.WorkType))
+		}
+	}
+	// a proposal changes nothing, so the SLA target is looked up without starting the booking's clock
+	respondBy, err := s.Contracts.ResponseDeadline(b.ID)
+	if err != nil {
+		return job{}, err
+	}
+	j.respondBy = respondBy
+	return j, nil
+}
+
+// qualified - whether an engineer has the skills and training a job needs
+func (j job) qualified(e engineer.Engineer) bool {
+	for _, skill := range j.skills {
+		if !e.HasSkill(skill) {
+			return false
+		}
+	}
+	for _, manufacturer := range j.manufacturers {
+		if !e.ServicesManufacturer(manufacturer) {
+			return false
+		}
+	}
+	return true
+}
+
+// appendFold - adds a value to a list unless it is empty or already there, ignoring case
+func appendFold(values []string, value string) []string {
+	if value = strings.TrimSpace(value); value == "" {
+		return values
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -3,6 +3,7 @@ package document
 import (
 	"os"
 
+	"github.com/Open-FiSE/go-rest-api/internal/events"
 	"github.com/jinzhu/gorm"
 	log "github.com/sirupsen/logrus"
 )
@@ -10,6 +11,8 @@ import (
 //  Service - the struct for the document service
 type Service struct {
 	DB *gorm.DB
+	// Events - where changes to documents are published, none when nil
+	Events *events.Broker
 }
 
 // Document - Defines the Document Model Structure
@@ -21,6 +24,10 @@ type Document struct {
 	Author     string  `json:"author"`
 	Body       string  `json:"body"`
 	Hash       string  `json:"hash"`
+
+	// the booking and instrument a report or certificate belongs to, if any
+	BookingID    *uint `json:"bookingId"`
+	InstrumentID *uint `json:"instrumentId"`
 }
 
 // https://www.baeldung.com/linux/sha-256-from-command-line
@@ -34,12 +41,15 @@ type DocumentService interface {
 	UpdateDocument(ID uint, newDocument Document) (Document error)
 	DeleteDocument(ID uint) error
 	GetAllDocuments() ([]Document, error)
+	GetLinkedDocuments(instrumentID uint, bookingIDs []uint) ([]Document, error)
 }
 
-// NewService - takes in a pointer to the DB & returns a pointer to a new document service
-func NewService(db *gorm.DB) *Service {
+// NewService - takes in a pointer to the DB and the broker changes are published to & returns a
+// pointer to a new document service
+func NewService(db *gorm.DB, broker *events.Broker) *Service {
 	return &Service{
-		DB: db,
+		DB:     db,
+		Events: broker,
 	}
 }
 
@@ -69,6 +79,7 @@ func (s *Service) PostDocument(document Document) (Document, error) {
 	if result := s.DB.Save(&document); result.Error != nil {
 		return Document{}, result.Error
 	}
+	s.Events.Publish(events.Document, events.Created, document.ID)
 	return document, nil
 }
 
@@ -82,6 +93,7 @@ func (s *Service) UpdateDocument(ID uint, newDocument Document) (Document, error
 	if result := s.DB.Model(&document).Updates(newDocument); result.Error != nil {
 		return Document{}, result.Error
 	}
+	s.Events.Publish(events.Document, events.Updated, ID)
 
 	return document, nil
 }
@@ -91,6 +103,7 @@ func (s *Service) DeleteDocument(ID uint) error {
 	if result := s.DB.Delete(&Document{}, ID); result.Error != nil {
 		return result.Error
 	}
+	s.Events.Publish(events.Document, events.Deleted, ID)
 	return nil
 }
 
@@ -102,3 +115,17 @@ func (s *Service) GetAllDocuments() ([]Document, error) {
 	}
 	return documents, nil
 }
+
+// GetLinkedDocuments - retrieves the documents tied to an instrument, or to any of the bookings,
+// oldest first
+func (s *Service) GetLinkedDocuments(instrumentID uint, bookingIDs []uint) ([]Document, error) {
+	var documents []Document
+	query := s.DB.Where("instrument_id = ?", instrumentID)
+	if len(bookingIDs) > 0 {
+		query = s.DB.Where("instrument_id = ? OR booking_id IN (?)", instrumentID, bookingIDs)
+	}
+	if result := query.Order("created_at").Find(&documents); result.Error != nil {
+		return documents, result.Error
+	}
+	return documents, nil
+}
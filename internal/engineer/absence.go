@@ -0,0 +1,61 @@
+package engineer
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrInvalidAbsence - returned when an absence does not end after it starts
+var ErrInvalidAbsence = errors.New("absence End must be after Start")
+
+// Absence - a period of leave, training or sickness during which an engineer cannot be booked
+type Absence struct {
+	gorm.Model
+	EngineerID uint      `json:"engineerId"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Reason     string    `json:"reason"`
+}
+
+// GetAbsences - retrieves an engineer's absences overlapping the window. A zero from or to leaves
+// that end of the window open.
+func (s *Service) GetAbsences(engineerID uint, from, to time.Time) ([]Absence, error) {
+	var absences []Absence
+	query := s.DB.Where("engineer_id = ?", engineerID)
+	if !from.IsZero() {
+		query = query.Where("\"end\" > ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("start < ?", to)
+	}
+	if result := query.Order("start").Find(&absences); result.Error != nil {
+		return absences, result.Error
+	}
+	return absences, nil
+}
+
+// PostAbsence - records a new absence for an engineer
+func (s *Service) PostAbsence(engineerID uint, absence Absence) (Absence, error) {
+	if !absence.End.After(absence.Start) {
+		return Absence{}, ErrInvalidAbsence
+	}
+	if _, err := s.GetEngineer(engineerID); err != nil {
+		return Absence{}, err
+	}
+	absence.Model = gorm.Model{}
+	absence.EngineerID = engineerID
+	if result := s.DB.Save(&absence); result.Error != nil {
+		return Absence{}, result.Error
+	}
+	return absence, nil
+}
+
+// DeleteAbsence - deletes one of an engineer's absences by ID
+func (s *Service) DeleteAbsence(engineerID uint, ID uint) error {
+	if result := s.DB.Where("engineer_id = ?", engineerID).Delete(&Absence{}, ID); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package engineer
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+// ErrInvalidWorkingHours - returned when WorkStart/WorkEnd are not "HH:MM" or end before they start
+var ErrInvalidWorkingHours = errors.New("working hours must be HH:MM with WorkStart before WorkEnd")
+
+// ErrInvalidWorkDays - returned when WorkDays are not day names Mon to Sun
+var ErrInvalidWorkDays = errors.New("work days must be Mon, Tue, Wed, Thu, Fri, Sat or Sun")
+
+// Service - the struct for the engineer service
+type Service struct {
+	DB *gorm.DB
+}
+
+// Engineer - a field engineer who carries out the work on a booking
+type Engineer struct {
+	gorm.Model
+	Name     string         `json:"name"`
+	Email    string         `json:"email"`
+	Phone    string         `json:"phone"`
+	HomeBase string         `json:"homeBase"`
+	Skills   pq.StringArray `gorm:"type:text[]" json:"skills"`
+	// instrument manufacturers the engineer is trained to service
+	Manufacturers pq.StringArray `gorm:"type:text[]" json:"manufacturers"`
+	// working hours are wall-clock times ("08:00") in TimeZone (an IANA name, UTC when empty)
+	// applied on each of the WorkDays ("Mon", "Tue" ...)
+	WorkStart      string          `json:"workStart"`
+	WorkEnd        string          `json:"workEnd"`
+	WorkDays       pq.StringArray  `gorm:"type:text[]" json:"workDays"`
+	TimeZone       string          `json:"timeZone"`
+	Certifications []Certification `json:"certifications"`
+	// WGS 84 coordinates of HomeBase, nil until it has been located
+	BaseLatitude  *float64 `json:"baseLatitude"`
+	BaseLongitude *float64 `json:"baseLongitude"`
+}
+
+// default working pattern for engineers that have not had their hours set
+const (
+	DefaultWorkStart = "08:00"
+	DefaultWorkEnd   = "17:00"
+)
+
+// DefaultWorkDays - the days worked by engineers that have not had their days set
+var DefaultWorkDays = []string{"Mon", "Tue", "Wed", "Thu", "Fri"}
+
+// ErrInvalidTimeZone - returned when an engineer's TimeZone is not a known IANA zone name
+var ErrInvalidTimeZone = errors.New("time zone must be an IANA zone name such as Europe/Dublin")
+
+// ErrInvalidBase - returned when an engineer's base coordinates are out of range or only one is given
+var ErrInvalidBase = errors.New("base coordinates must be a BaseLatitude in [-90, 90] and BaseLongitude in [-180, 180], given together")
+
+// WorkingHours - returns the engineer's working pattern with the defaults filled in
+func (e Engineer) WorkingHours() (start, end string, days []string, loc *time.Location) {
+	start, end, days = e.WorkStart, e.WorkEnd, e.WorkDays
+	if start == "" || end == "" {
+		start, end = DefaultWorkStart, DefaultWorkEnd
+	}
+	if len(days) == 0 {
+		days = DefaultWorkDays
+	}
+	loc, err := time.LoadLocation(e.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return start, end, days, loc
+}
+
+// HasBase - reports whether the coordinates of the engineer's home base are known
+func (e Engineer) HasBase() bool {
+	return e.BaseLatitude != nil && e.BaseLongitude != nil
+}
+
+// HasSkill - reports whether the engineer lists the skill, ignoring case
+func (e Engineer) HasSkill(skill string) bool {
+	return containsFold(e.Skills, skill)
+}
+
+// ServicesManufacturer - reports whether the engineer is trained on the manufacturer's instruments
+func (e Engineer) ServicesManufacturer(manufacturer string) bool {
+	return containsFold(e.Manufacturers, manufacturer)
+}
+
+// Certification - a qualification held by an engineer, Engineer has 0-* certifications
+type Certification struct {
+	gorm.Model
+	EngineerID uint       `json:"engineerId"`
+	Name       string     `json:"name"`
+	Issuer     string     `json:"issuer"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+}
+
+// EngineerService - the interface for our engineer service
+type EngineerService interface {
+	GetEngineer(ID uint) (Engineer, error)
+	GetEngineers(IDs []uint) ([]Engineer, error)
+	PostEngineer(engineer Engineer) (Engineer, error)
+	UpdateEngineer(ID uint, newEngineer Engineer) (Engineer, error)
+	DeleteEngineer(ID uint) error
+	GetAllEngineers() ([]Engineer, error)
+}
+
+// NewService - takes in a pointer to the DB & returns a pointer to a new engineer service
+func NewService(db *gorm.DB) *Service {
+	return &Service{
+		DB: db,
+	}
+}
+
+// GetEngineer - retrieves an engineer and their certifications by ID from the database
+func (s *Service) GetEngineer(ID uint) (Engineer, error) {
+	var engineer Engineer
+	if result := s.DB.Preload("Certifications").First(&engineer, ID); result.Error != nil {
+		return Engineer{}, result.Error
+	}
+	return engineer, nil
+}
+
+// GetEngineers - retrieves the engineers with the given IDs, failing if any of them do not exist
+func (s *Service) GetEngineers(IDs []uint) ([]Engineer, error) {
+	var engineers []Engineer
+	if len(IDs) == 0 {
+		return engineers, nil
+	}
+	if result := s.DB.Where("id IN (?)", IDs).Find(&engineers); result.Error != nil {
+		return engineers, result.Error
+	}
+	if len(engineers) != len(uniqueIDs(IDs)) {
+		return engineers, gorm.ErrRecordNotFound
+	}
+	return engineers, nil
+}
+
+// PostEngineer - adds a new engineer to the database
+func (s *Service) PostEngineer(engineer Engineer) (Engineer, error) {
+	// a new engineer, and the certifications posted with them, never take the ID of an existing one
+	engineer.Model = gorm.Model{}
+	for i := range engineer.Certifications {
+		engineer.Certifications[i].Model = gorm.Model{}
+		engineer.Certifications[i].EngineerID = 0
+	}
+	if err := validateWorkingHours(engineer.WorkStart, engineer.WorkEnd); err != nil {
+		return Engineer{}, err
+	}
+	if err := validateWorkDays(engineer.WorkDays); err != nil {
+		return Engineer{}, err
+	}
+	if _, err := time.LoadLocation(engineer.TimeZone); err != nil {
+		return Engineer{}, ErrInvalidTimeZone
+	}
+	if err := validateBase(engineer.BaseLatitude, engineer.BaseLongitude, false); err != nil {
+		return Engineer{}, err
+	}
+	if result := s.DB.Save(&engineer); result.Error != nil {
+		return Engineer{}, result.Error
+	}
+	return engineer, nil
+}
+
+// UpdateEngineer - updates an engineer by ID with new engineer info
+func (s *Service) UpdateEngineer(ID uint, newEngineer Engineer) (Engineer, error) {
+	engineer, err := s.GetEngineer(ID)
+	if err != nil {
+		return Engineer{}, err
+	}
+	start, end := engineer.WorkStart, engineer.WorkEnd
+	if newEngineer.WorkStart != "" {
+		start = newEngineer.WorkStart
+	}
+	if newEngineer.WorkEnd != "" {
+		end = newEngineer.WorkEnd
+	}
+	if err := validateWorkingHours(start, end); err != nil {
+		return Engineer{}, err
+	}
+	if err := validateWorkDays(newEngineer.WorkDays); err != nil {
+		return Engineer{}, err
+	}
+	if newEngineer.TimeZone != "" {
+		if _, err := time.LoadLocation(newEngineer.TimeZone); err != nil {
+			return Engineer{}, ErrInvalidTimeZone
+		}
+	}
+	if err := validateBase(newEngineer.BaseLatitude, newEngineer.BaseLongitude, true); err != nil {
+		return Engineer{}, err
+	}
+	if result := s.DB.Model(&engineer).Updates(newEngineer); result.Error != nil {
+		return Engineer{}, result.Error
+	}
+	return engineer, nil
+}
+
+// DeleteEngineer - deletes an engineer from the database by ID
+func (s *Service) DeleteEngineer(ID uint) error {
+	if result := s.DB.Delete(&Engineer{}, ID); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// GetAllEngineers - retrieves all engineers from the database
+func (s *Service) GetAllEngineers() ([]Engineer, error) {
+	var engineers []Engineer
+	if result := s.DB.Preload("Certifications").Find(&engineers); result.Error != nil {
+		return engineers, result.Error
+	}
+	return engineers, nil
+}
+
+// validateWorkingHours - working hours are optional, but when given both must parse and start before end
+func validateWorkingHours(start, end string) error {
+	if start == "" && end == "" {
+		return nil
+	}
+	from, err := time.Parse("15:04", start)
+	if err != nil {
+		return ErrInvalidWorkingHours
+	}
+	to, err := time.Parse("15:04", end)
+	if err != nil || !from.Before(to) {
+		return ErrInvalidWorkingHours
+	}
+	return nil
+}
+
+// validateWorkDays - work days are optional, but each must be a day name as in DefaultWorkDays
+func validateWorkDays(days []string) error {
+	for _, day := range days {
+		switch day {
+		case "Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun":
+		default:
+			return ErrInvalidWorkDays
+		}
+	}
+	return nil
+}
+
+// validateBase - base coordinates are optional, but must be in range and, unless partial, given together
+func validateBase(latitude, longitude *float64, partial bool) error {
+	if (latitude == nil) != (longitude == nil) && !partial {
+		return ErrInvalidBase
+	}
+	if latitude != nil && (*latitude < -90 || *latitude > 90) {
+		return ErrInvalidBase
+	}
+	if longitude != nil && (*longitude < -180 || *longitude > 180) {
+		return ErrInvalidBase
+	}
+	return nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueIDs(IDs []uint) map[uint]bool {
+	seen := make(map[uint]bool, len(IDs))
+	for _, id := range IDs {
+		seen[id] = true
+	}
+	return seen
+}
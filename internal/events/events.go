@@ -0,0 +1,180 @@
+package events
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHistory - how many recent events are kept for clients resuming a stream
+const DefaultHistory = 1000
+
+// buffer - how many events a subscriber can fall behind by before it is dropped
+const buffer = 64
+
+// ErrInvalidResource - returned when a stream is filtered by a resource that has no events
+var ErrInvalidResource = errors.New("types must be a comma separated list of booking and document")
+
+// Resource - the kind of record an event is about
+type Resource string
+
+// resources with change events
+const (
+	Booking  Resource = "booking"
+	Document Resource = "document"
+)
+
+// Action - what happened to the record
+type Action string
+
+// actions reported by events
+const (
+	Created Action = "created"
+	Updated Action = "updated"
+	Deleted Action = "deleted"
+)
+
+// Event - a change to a booking or document. IDs increase with every event, across restarts too,
+// so a client can resume a stream from the last one it saw.
+type Event struct {
+	ID         uint64    `json:"id"`
+	Resource   Resource  `json:"resource"`
+	Action     Action    `json:"action"`
+	ResourceID uint      `json:"resourceId"`
+	At         time.Time `json:"at"`
+}
+
+// Name - the event's name on the stream, resource.action, as in booking.updated
+func (e Event) Name() string {
+	return string(e.Resource) + "." + string(e.Action)
+}
+
+// Broker - fans the changes published by the services out to the streams subscribed to them,
+// keeping the most recent for streams resuming after a disconnect. A nil broker drops what is
+// published, so services work without one.
+type Broker struct {
+	mu          sync.Mutex
+	last        uint64
+	history     []Event
+	size        int
+	subscribers map[*Subscription]bool
+}
+
+// Subscription - a stream's feed of events, of the resources it asked for. Latest is the ID of the
+// last event published before it started.
+type Subscription struct {
+	Latest    uint64
+	broker    *Broker
+	resources map[Resource]bool
+	events    chan Event
+}
+
+// NewBroker - returns a pointer to a new broker keeping the last history events. Event IDs start
+// from the current time in microseconds, above any issued before a restart and small enough for
+// JavaScript numbers.
+func NewBroker(history int) *Broker {
+	if history <= 0 {
+		history = DefaultHistory
+	}
+	return &Broker{
+		last:        uint64(time.Now().UnixNano() / int64(time.Microsecond)),
+		size:        history,
+		subscribers: map[*Subscription]bool{},
+	}
+}
+
+// Publish - records a change and sends it to the subscribers that want it. A subscriber too far
+// behind to take it is dropped, closing its channel, so its client reconnects and resumes from
+// history rather than silently missing events.
+func (b *Broker) Publish(resource Resource, action Action, ID uint) {
+	if b == nil || ID == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.last++
+	event := Event{ID: b.last, Resource: resource, Action: action, ResourceID: ID, At: time.Now().UTC()}
+	b.history = append(b.history, event)
+	if len(b.history) > b.size {
+		b.history = b.history[len(b.history)-b.size:]
+	}
+	for sub := range b.subscribers {
+		if !sub.wants(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			delete(b.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// Subscribe - subscribes to the events of the resources, all of them when none are given. When
+// lastEventID is set the events after it are returned to be sent first; complete is false when some
+// of them are no longer kept, and the client should reload what it shows.
+func (b *Broker) Subscribe(resources []Resource, lastEventID uint64) (sub *Subscription, backlog []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = &Subscription{Latest: b.last, broker: b, resources: map[Resource]bool{}, events: make(chan Event, buffer)}
+	for _, resource := range resources {
+		sub.resources[resource] = true
+	}
+	b.subscribers[sub] = true
+
+	complete = true
+	if lastEventID == 0 || lastEventID == b.last {
+		return sub, backlog, complete
+	}
+	// the events after lastEventID are all kept if the oldest kept follows on from it
+	oldest := b.last + 1
+	if len(b.history) > 0 {
+		oldest = b.history[0].ID
+	}
+	complete = lastEventID < b.last && lastEventID+1 >= oldest
+	for _, event := range b.history {
+		if event.ID > lastEventID && sub.wants(event) {
+			backlog = append(backlog, event)
+		}
+	}
+	return sub, backlog, complete
+}
+
+// Events - the subscription's events, closed when the subscriber is dropped for falling behind
+func (sub *Subscription) Events() <-chan Event {
+	return sub.events
+}
+
+// Close - stops the subscription
+func (sub *Subscription) Close() {
+	b := sub.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers[sub] {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+func (sub *Subscription) wants(event Event) bool {
+	return len(sub.resources) == 0 || sub.resources[event.Resource]
+}
+
+// ParseResources - parses a comma separated list of resources, as in booking,document
+func ParseResources(list string) ([]Resource, error) {
+	var resources []Resource
+	for _, name := range strings.Split(list, ",") {
+		switch resource := Resource(strings.TrimSpace(name)); resource {
+		case "":
+		case Booking, Document:
+			resources = append(resources, resource)
+		default:
+			return nil, ErrInvalidResource
+		}
+	}
+	return resources, nil
+}
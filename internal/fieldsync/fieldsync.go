@@ -0,0 +1,181 @@
+package fieldsync
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/Open-FiSE/go-rest-api/internal/document"
+	"github.com/Open-FiSE/go-rest-api/internal/engineer"
+	"github.com/jinzhu/gorm"
+)
+
+// History - how long after it ends a one-off booking stays on an engineer's device
+const History = 30 * 24 * time.Hour
+
+// Overlap - how far before the cursor changes are read again, so rows written by transactions still
+// in flight when the cursor was issued are not missed. Clients apply changes by ID, so seeing a
+// change twice is harmless.
+const Overlap = 30 * time.Second
+
+// ErrInvalidCursor - returned when a cursor is not one issued by Changes
+var ErrInvalidCursor = errors.New("cursor must be the cursor returned by the previous sync")
+
+// Service - the struct for the sync service, which keeps the field engineer app's offline copy of
+// an engineer's work up to date
+type Service struct {
+	DB        *gorm.DB
+	Bookings  *booking.BookService
+	Customers *customer.Service
+	Documents *document.Service
+	Engineers *engineer.Service
+}
+
+// SyncService - the interface for our sync service
+type SyncService interface {
+	Changes(engineerID uint, cursor string) (Changes, error)
+	Push(engineerID uint, mutations []Mutation) ([]Result, error)
+}
+
+// NewService - takes in a pointer to the DB and the services synced & returns a pointer to a new
+// sync service
+func NewService(db *gorm.DB, bookings *booking.BookService, customers *customer.Service, documents *document.Service,
+	engineers *engineer.Service) *Service {
+	return &Service{
+		DB:        db,
+		Bookings:  bookings,
+		Customers: customers,
+		Documents: documents,
+		Engineers: engineers,
+	}
+}
+
+// Changes - what changed in an engineer's work since a cursor. BookingIDs lists every booking that
+// belongs on the engineer's device, so bookings they have been taken off, or that ended more than
+// History ago, can be dropped. Deleted lists the rows deleted since the cursor.
+type Changes struct {
+	Cursor     string              `json:"cursor"`
+	BookingIDs []uint              `json:"bookingIds"`
+	Bookings   []booking.Booking   `json:"bookings"`
+	Jobs       []customer.Job      `json:"jobs"`
+	Documents  []document.Document `json:"documents"`
+	Deleted    Tombstones          `json:"deleted"`
+}
+
+// Tombstones - the IDs of rows deleted since the cursor
+type Tombstones struct {
+	Bookings  []uint `json:"bookings"`
+	Jobs      []uint `json:"jobs"`
+	Documents []uint `json:"documents"`
+}
+
+// Changes - retrieves the engineer's bookings, their jobs with equipment and measurements, and their
+// documents changed since the cursor, everything when the cursor is empty. A booking counts as
+// changed when it is edited, changes status or has its engineers or instruments reassigned, and a
+// job when it or any of its equipment is. The job and documents of a changed booking are always
+// sent, so a booking newly assigned to the engineer arrives complete.
+func (s *Service) Changes(engineerID uint, cursor string) (Changes, error) {
+	changes := Changes{
+		BookingIDs: []uint{},
+		Bookings:   []booking.Booking{},
+		Jobs:       []customer.Job{},
+		Documents:  []document.Document{},
+		Deleted:    Tombstones{Bookings: []uint{}, Jobs: []uint{}, Documents: []uint{}},
+	}
+	var since time.Time
+	if cursor != "" {
+		issued, err := time.Parse(time.RFC3339Nano, cursor)
+		if err != nil {
+			return changes, ErrInvalidCursor
+		}
+		since = issued.Add(-Overlap)
+	}
+	if _, err := s.Engineers.GetEngineer(engineerID); err != nil {
+		return changes, err
+	}
+	// the cursor is read before the changes, so anything written while they are read is sent again
+	now := time.Now().UTC()
+	changes.Cursor = now.Format(time.RFC3339Nano)
+	full := since.IsZero()
+	assigned := s.assigned(engineerID)
+
+	if result := s.DB.Model(&booking.Booking{}).Where("id IN (?)", assigned).
+		Where("r_rule <> '' OR end_date_time > ?", now.Add(-History)).
+		Order("id").Pluck("id", &changes.BookingIDs); result.Error != nil {
+		return changes, result.Error
+	}
+	if !full {
+		if err := s.tombstones(assigned, since, &changes.Deleted); err != nil {
+			return changes, err
+		}
+	}
+	if len(changes.BookingIDs) == 0 {
+		return changes, nil
+	}
+
+	query := s.DB.Preload("Customer").Preload("Job").Preload("Site").Preload("Contact").Preload("Engineers").Preload("Instruments").Preload("ExceptionDates").
+		Where("id IN (?)", changes.BookingIDs)
+	if !full {
+		query = query.Where("updated_at > ?", since)
+	}
+	if result := query.Order("id").Find(&changes.Bookings); result.Error != nil {
+		return changes, result.Error
+	}
+	changed := make([]uint, len(changes.Bookings))
+	for i, b := range changes.Bookings {
+		changed[i] = b.ID
+	}
+
+	query = s.DB.Preload("Equipment", byID).Preload("Equipment.Measurements", byID).Where("id IN (?)", jobsOf(s.DB, changes.BookingIDs))
+	if !full {
+		equipment := s.DB.Unscoped().Model(&customer.Equipment{}).Select("job_id").
+			Where("updated_at > ? OR deleted_at > ?", since, since).QueryExpr()
+		query = query.Where("updated_at > ? OR id IN (?) OR id IN (?)", since, equipment, jobsOf(s.DB, changed))
+	}
+	if result := query.Order("id").Find(&changes.Jobs); result.Error != nil {
+		return changes, result.Error
+	}
+
+	query = s.DB.Where("booking_id IN (?)", changes.BookingIDs)
+	if !full {
+		query = query.Where("updated_at > ? OR booking_id IN (?)", since, changed)
+	}
+	if result := query.Order("id").Find(&changes.Documents); result.Error != nil {
+		return changes, result.Error
+	}
+	return changes, nil
+}
+
+// tombstones - collects the engineer's bookings, and the jobs and documents of their bookings,
+// deleted since a time
+func (s *Service) tombstones(assigned *gorm.SqlExpr, since time.Time, deleted *Tombstones) error {
+	if result := s.DB.Unscoped().Model(&booking.Booking{}).Where("id IN (?) AND deleted_at > ?", assigned, since).
+		Order("id").Pluck("id", &deleted.Bookings); result.Error != nil {
+		return result.Error
+	}
+	if result := s.DB.Unscoped().Model(&customer.Job{}).Where("id IN (?) AND deleted_at > ?", jobsOf(s.DB.Unscoped(), assigned), since).
+		Order("id").Pluck("id", &deleted.Jobs); result.Error != nil {
+		return result.Error
+	}
+	if result := s.DB.Unscoped().Model(&document.Document{}).Where("booking_id IN (?) AND deleted_at > ?", assigned, since).
+		Order("id").Pluck("id", &deleted.Documents); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// assigned - a subquery for the IDs of the bookings an engineer is assigned to
+func (s *Service) assigned(engineerID uint) *gorm.SqlExpr {
+	return s.DB.Table("booking_engineers").Select("booking_id").Where("engineer_id = ?", engineerID).QueryExpr()
+}
+
+// jobsOf - a subquery for the IDs of the jobs of some bookings, given as IDs or a subquery
+func jobsOf(db *gorm.DB, bookingIDs interface{}) *gorm.SqlExpr {
+	return db.Model(&booking.Booking{}).Select("job_id").Where("id IN (?) AND job_id IS NOT NULL", bookingIDs).QueryExpr()
+}
+
+// byID - orders preloaded rows in the order they were added
+func byID(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
@@ -0,0 +1,339 @@
+package fieldsync
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/Open-FiSE/go-rest-api/internal/document"
+	"github.com/Open-FiSE/go-rest-api/internal/engineer"
+	"github.com/jinzhu/gorm"
+)
+
+// MaxBatch - the most mutations pushed at once
+const MaxBatch = 500
+
+// errors returned when a batch of mutations cannot be read
+var (
+	ErrNoMutations     = errors.New("there are no mutations to apply")
+	ErrInvalidMutation = errors.New("mutations need a unique ID and a known Kind, at most 500 at a time")
+)
+
+// Kind - what a mutation changes
+type Kind string
+
+// kinds of change the field engineer app makes offline
+const (
+	KindStatus       Kind = "booking.status"
+	KindEquipment    Kind = "equipment.update"
+	KindMeasurements Kind = "equipment.measurements"
+	KindDocument     Kind = "document.create"
+)
+
+// Outcome - what became of a mutation
+type Outcome string
+
+// outcomes of a mutation. A conflict is a change made against a version of the row that has since
+// changed on the server, which keeps its version, and a rejection a change that is invalid whatever
+// the version.
+const (
+	OutcomeApplied  Outcome = "applied"
+	OutcomeConflict Outcome = "conflict"
+	OutcomeRejected Outcome = "rejected"
+)
+
+// Mutation - a change made on the device while offline. ID is generated on the device and identifies
+// the change across retries. At is when it was made.
+//
+// booking.status moves BookingID from the From status the device saw to Status. equipment.update and
+// equipment.measurements change EquipmentID on JobID as it was at BaseUpdatedAt, the UpdatedAt the
+// device last synced. document.create files Document against BookingID.
+type Mutation struct {
+	ID            string                 `json:"id"`
+	Kind          Kind                   `json:"kind"`
+	At            time.Time              `json:"at"`
+	BookingID     uint                   `json:"bookingId"`
+	JobID         uint                   `json:"jobId"`
+	EquipmentID   uint                   `json:"equipmentId"`
+	BaseUpdatedAt time.Time              `json:"baseUpdatedAt"`
+	From          booking.Status         `json:"from"`
+	Status        booking.Status         `json:"status"`
+	Reason        string                 `json:"reason"`
+	Equipment     *customer.Equipment    `json:"equipment"`
+	Measurements  []customer.Measurement `json:"measurements"`
+	Document      *document.Document     `json:"document"`
+}
+
+// Result - the outcome of a mutation. EntityID is the row changed or created, and Current the
+// server's version of a row in conflict. Replayed is set when the mutation had already been pushed,
+// its first outcome being returned again.
+type Result struct {
+	MutationID string      `json:"mutationId"`
+	Outcome    Outcome     `json:"outcome"`
+	Reason     string      `json:"reason,omitempty"`
+	EntityID   uint        `json:"entityId,omitempty"`
+	Current    interface{} `json:"current,omitempty"`
+	Replayed   bool        `json:"replayed,omitempty"`
+}
+
+// Receipt - the recorded outcome of a mutation, so a batch pushed again after a lost response is not
+// applied twice
+type Receipt struct {
+	gorm.Model
+	EngineerID uint   `gorm:"unique_index:idx_sync_receipt" json:"engineerId"`
+	MutationID string `gorm:"unique_index:idx_sync_receipt" json:"mutationId"`
+	Kind       Kind   `json:"kind"`
+	Outcome    Outcome
+	Reason     string
+	EntityID   uint
+}
+
+// TableName - stores receipts as sync_receipts
+func (Receipt) TableName() string {
+	return "sync_receipts"
+}
+
+// rebase - a row changed earlier in the batch, so later mutations made against the version the
+// device saw are applied against the version the batch left
+type rebase struct {
+	from, to time.Time
+}
+
+// Push - applies a batch of offline mutations for an engineer. Mutations are applied in the order
+// they were made, ties broken by ID, so the same batch always has the same outcome. A mutation made
+// against a row that has changed since the device synced it is a conflict and leaves the server's
+// version as it is; a status change conflicts only when the booking's status has moved on. Changes
+// are limited to the engineer's bookings and their jobs. Each outcome is recorded, and a mutation
+// pushed again returns its first outcome.
+func (s *Service) Push(engineerID uint, mutations []Mutation) ([]Result, error) {
+	if err := checkBatch(mutations); err != nil {
+		return nil, err
+	}
+	e, err := s.Engineers.GetEngineer(engineerID)
+	if err != nil {
+		return nil, err
+	}
+
+	rebased := map[string]rebase{}
+	results := make([]Result, 0, len(mutations))
+	for _, m := range inOrder(mutations) {
+		var receipt Receipt
+		err := s.DB.Where("engineer_id = ? AND mutation_id = ?", engineerID, m.ID).First(&receipt).Error
+		if err == nil {
+			results = append(results, Result{MutationID: m.ID, Outcome: receipt.Outcome, Reason: receipt.Reason,
+				EntityID: receipt.EntityID, Replayed: true})
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return results, err
+		}
+
+		result, err := s.apply(e, m, rebased)
+		if err != nil {
+			return results, err
+		}
+		receipt = Receipt{EngineerID: engineerID, MutationID: m.ID, Kind: m.Kind, Outcome: result.Outcome,
+			Reason: result.Reason, EntityID: result.EntityID}
+		if err := s.DB.Create(&receipt).Error; err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// checkBatch - validates a batch of mutations: at least one and at most MaxBatch, each with an ID
+// unique in the batch and a known Kind
+func checkBatch(mutations []Mutation) error {
+	if len(mutations) == 0 {
+		return ErrNoMutations
+	}
+	if len(mutations) > MaxBatch {
+		return ErrInvalidMutation
+	}
+	seen := make(map[string]bool, len(mutations))
+	for _, m := range mutations {
+		if m.ID == "" || seen[m.ID] || !m.Kind.valid() {
+			return ErrInvalidMutation
+		}
+		seen[m.ID] = true
+	}
+	return nil
+}
+
+// inOrder - the mutations in the order they were made, ties broken by ID, whatever order they were
+// pushed in
+func inOrder(mutations []Mutation) []Mutation {
+	ordered := make([]Mutation, len(mutations))
+	copy(ordered, mutations)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if !ordered[i].At.Equal(ordered[j].At) {
+			return ordered[i].At.Before(ordered[j].At)
+		}
+		return ordered[i].ID < ordered[j].ID
+	})
+	return ordered
+}
+
+// apply - applies one mutation, returning an error only when the database fails
+func (s *Service) apply(e engineer.Engineer, m Mutation, rebased map[string]rebase) (Result, error) {
+	switch m.Kind {
+	case KindStatus:
+		return s.applyStatus(e, m)
+	case KindDocument:
+		return s.applyDocument(e, m)
+	}
+	return s.applyEquipment(e, m, rebased)
+}
+
+// applyStatus - moves a booking on to a new status, unless it has already moved on from the status
+// the device saw. A booking already in the new status counts as applied.
+func (s *Service) applyStatus(e engineer.Engineer, m Mutation) (Result, error) {
+	result := Result{MutationID: m.ID, EntityID: m.BookingID}
+	if ok, err := s.isAssigned(e.ID, m.BookingID); err != nil || !ok {
+		return rejected(result, "booking is not assigned to the engineer"), err
+	}
+	current, err := s.Bookings.GetBooking(m.BookingID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return conflicted(result, "booking has been deleted", nil), nil
+	} else if err != nil {
+		return result, err
+	}
+	if current.Status == m.Status {
+		result.Outcome = OutcomeApplied
+		return result, nil
+	}
+	if m.From != "" && current.Status != m.From {
+		return conflicted(result, fmt.Sprintf("booking status has changed from %s to %s", m.From, current.Status), current), nil
+	}
+
+	_, err = s.Bookings.TransitionStatus(m.BookingID, m.Status, e.Name, m.Reason)
+	var transition *booking.TransitionError
+	switch {
+	case errors.As(err, &transition), errors.Is(err, booking.ErrBookingSigned):
+		return conflicted(result, err.Error(), current), nil
+	case errors.Is(err, booking.ErrInvalidStatus):
+		return rejected(result, err.Error()), nil
+	case err != nil:
+		return result, err
+	}
+	result.Outcome = OutcomeApplied
+	return result, nil
+}
+
+// applyDocument - files a document taken on the device, a photo or a report, against a booking
+func (s *Service) applyDocument(e engineer.Engineer, m Mutation) (Result, error) {
+	result := Result{MutationID: m.ID, EntityID: m.BookingID}
+	if ok, err := s.isAssigned(e.ID, m.BookingID); err != nil || !ok {
+		return rejected(result, "booking is not assigned to the engineer"), err
+	}
+	if m.Document == nil {
+		return rejected(result, "document.create needs a Document"), nil
+	}
+	doc := *m.Document
+	doc.Model = gorm.Model{}
+	doc.BookingID, doc.InstrumentID = &m.BookingID, nil
+	doc, err := s.Documents.PostDocument(doc)
+	if err != nil {
+		return result, err
+	}
+	result.Outcome, result.EntityID = OutcomeApplied, doc.ID
+	return result, nil
+}
+
+// applyEquipment - updates an equipment item, or replaces its measurements, unless the item has
+// changed since the device synced it. A change applied earlier in the batch does not count.
+func (s *Service) applyEquipment(e engineer.Engineer, m Mutation, rebased map[string]rebase) (Result, error) {
+	result := Result{MutationID: m.ID, EntityID: m.EquipmentID}
+	var count int
+	if err := s.DB.Model(&booking.Booking{}).Where("job_id = ? AND id IN (?)", m.JobID, s.assigned(e.ID)).
+		Count(&count).Error; err != nil || count == 0 {
+		return rejected(result, "job is not on a booking assigned to the engineer"), err
+	}
+	if m.Kind == KindEquipment && m.Equipment == nil {
+		return rejected(result, "equipment.update needs the Equipment changes"), nil
+	}
+
+	item, err := s.Customers.GetEquipmentItem(m.JobID, m.EquipmentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return conflicted(result, "equipment has been deleted", nil), nil
+	} else if err != nil {
+		return result, err
+	}
+	key := fmt.Sprintf("equipment:%d", m.EquipmentID)
+	if !sameVersion(item.UpdatedAt, rebasedVersion(rebased, key, m.BaseUpdatedAt)) {
+		return conflicted(result, "equipment has changed since it was synced", item), nil
+	}
+
+	if m.Kind == KindEquipment {
+		_, err = s.Customers.UpdateEquipment(m.JobID, m.EquipmentID, *m.Equipment)
+	} else {
+		_, err = s.Customers.SetMeasurements(m.JobID, m.EquipmentID, m.Measurements)
+	}
+	if errors.Is(err, customer.ErrBookingSigned) {
+		return conflicted(result, err.Error(), item), nil
+	} else if invalid(err) {
+		return rejected(result, err.Error()), nil
+	} else if err != nil {
+		return result, err
+	}
+	if item, err = s.Customers.GetEquipmentItem(m.JobID, m.EquipmentID); err != nil {
+		return result, err
+	}
+	rebased[key] = rebase{from: m.BaseUpdatedAt, to: item.UpdatedAt}
+	result.Outcome = OutcomeApplied
+	return result, nil
+}
+
+func rejected(result Result, reason string) Result {
+	result.Outcome, result.Reason = OutcomeRejected, reason
+	return result
+}
+
+func conflicted(result Result, reason string, current interface{}) Result {
+	result.Outcome, result.Reason, result.Current = OutcomeConflict, reason, current
+	return result
+}
+
+// isAssigned - whether an engineer is assigned to a booking
+func (s *Service) isAssigned(engineerID, bookingID uint) (bool, error) {
+	var count int
+	if result := s.DB.Table("booking_engineers").Where("engineer_id = ? AND booking_id = ?", engineerID, bookingID).
+		Count(&count); result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
+// valid - whether the kind is one the app can push
+func (k Kind) valid() bool {
+	switch k {
+	case KindStatus, KindEquipment, KindMeasurements, KindDocument:
+		return true
+	}
+	return false
+}
+
+// rebasedVersion - the version of a row a mutation made against base is checked against: the version
+// left by a change earlier in the batch that was made against the same base, otherwise base
+func rebasedVersion(rebased map[string]rebase, key string, base time.Time) time.Time {
+	if r, ok := rebased[key]; ok && sameVersion(r.from, base) {
+		return r.to
+	}
+	return base
+}
+
+// sameVersion - whether two UpdatedAt times are the same version of a row. The database keeps
+// microseconds, so anything finer is ignored.
+func sameVersion(a, b time.Time) bool {
+	return a.Truncate(time.Microsecond).Equal(b.Truncate(time.Microsecond))
+}
+
+// invalid - whether an equipment change was refused for what it contains rather than failing
+func invalid(err error) bool {
+	return errors.Is(err, customer.ErrInvalidEquipment) || errors.Is(err, customer.ErrInvalidMeasurement) ||
+		errors.Is(err, customer.ErrInstrumentNotFound)
+}
@@ -0,0 +1,125 @@
+package fieldsync
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestCheckBatch(t *testing.T) {
+	full := make([]Mutation, MaxBatch+1)
+	for i := range full {
+		full[i] = Mutation{ID: fmt.Sprintf("m%d", i), Kind: KindStatus}
+	}
+
+	tests := []struct {
+		name      string
+		mutations []Mutation
+		want      error
+	}{
+		{"one", []Mutation{{ID: "a", Kind: KindStatus}}, nil},
+		{"every kind", []Mutation{{ID: "a", Kind: KindStatus}, {ID: "b", Kind: KindEquipment},
+			{ID: "c", Kind: KindMeasurements}, {ID: "d", Kind: KindDocument}}, nil},
+		{"as many as allowed", full[:MaxBatch], nil},
+		{"none", nil, ErrNoMutations},
+		{"too many", full, ErrInvalidMutation},
+		{"no ID", []Mutation{{Kind: KindStatus}}, ErrInvalidMutation},
+		{"repeated ID", []Mutation{{ID: "a", Kind: KindStatus}, {ID: "a", Kind: KindDocument}}, ErrInvalidMutation},
+		{"unknown kind", []Mutation{{ID: "a", Kind: "booking.delete"}}, ErrInvalidMutation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkBatch(tt.mutations); !errors.Is(err, tt.want) {
+				t.Errorf("checkBatch = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestInOrder - mutations are applied in the order they were made, ties broken by ID, so however a
+// batch is shuffled it has the same outcome
+func TestInOrder(t *testing.T) {
+	at := time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)
+	mutations := []Mutation{
+		{ID: "c", At: at},
+		{ID: "e", At: at.Add(-time.Minute)},
+		{ID: "a", At: at},
+		{ID: "d", At: at.Add(time.Second)},
+		// the same instant given in another zone
+		{ID: "b", At: at.In(time.FixedZone("IST", 3600))},
+	}
+	want := []string{"e", "a", "b", "c", "d"}
+
+	random := rand.New(rand.NewSource(49))
+	for trial := 0; trial < 20; trial++ {
+		shuffled := make([]Mutation, len(mutations))
+		copy(shuffled, mutations)
+		random.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+		pushed := ids(shuffled)
+		ordered := inOrder(shuffled)
+		for i, m := range ordered {
+			if m.ID != want[i] {
+				t.Fatalf("inOrder = %v, want %v", ids(ordered), want)
+			}
+		}
+		for i, m := range shuffled {
+			if m.ID != pushed[i] {
+				t.Fatal("inOrder reordered the batch it was given")
+			}
+		}
+	}
+}
+
+func ids(mutations []Mutation) []string {
+	out := make([]string, len(mutations))
+	for i, m := range mutations {
+		out[i] = m.ID
+	}
+	return out
+}
+
+func TestRebasedVersion(t *testing.T) {
+	synced := time.Date(2026, 5, 4, 10, 0, 0, 123456000, time.UTC)
+	applied := synced.Add(time.Minute)
+	rebased := map[string]rebase{"equipment:1": {from: synced, to: applied}}
+
+	tests := []struct {
+		name string
+		key  string
+		base time.Time
+		want time.Time
+	}{
+		{"changed earlier in the batch", "equipment:1", synced, applied},
+		{"made against the database's precision", "equipment:1", synced.Add(789 * time.Nanosecond), applied},
+		{"made against another version", "equipment:1", synced.Add(-time.Hour), synced.Add(-time.Hour)},
+		{"not changed in the batch", "equipment:2", synced, synced},
+	}
+	for _, tt := range tests {
+		if got := rebasedVersion(rebased, tt.key, tt.base); !got.Equal(tt.want) {
+			t.Errorf("%s: rebasedVersion = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSameVersion(t *testing.T) {
+	version := time.Date(2026, 5, 4, 10, 0, 0, 123456000, time.UTC)
+	tests := []struct {
+		name string
+		b    time.Time
+		want bool
+	}{
+		{"equal", version, true},
+		{"nanoseconds the database drops", version.Add(999 * time.Nanosecond), true},
+		{"another zone", version.In(time.FixedZone("IST", 3600)), true},
+		{"a microsecond later", version.Add(time.Microsecond), false},
+		{"never synced", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		if got := sameVersion(version, tt.b); got != tt.want {
+			t.Errorf("%s: sameVersion = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package inventory
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/jinzhu/gorm"
+)
+
+// LocationType - the kind of place stock is held
+type LocationType string
+
+// stock location types
+const (
+	LocationWarehouse LocationType = "warehouse"
+	LocationVan       LocationType = "van"
+)
+
+// errors returned by the inventory service
+var (
+	ErrInvalidPart       = errors.New("part PartNo and Name are required, and UnitPrice cannot be negative")
+	ErrPartNoInUse       = errors.New("a part with this PartNo is already in the catalogue")
+	ErrInvalidLocation   = errors.New("stock location needs a Name and a Type of warehouse or van")
+	ErrInvalidQuantity   = errors.New("quantity must be more than zero")
+	ErrInsufficientStock = errors.New("not enough of the part in stock at the location")
+	ErrPartNotFound      = errors.New("part does not exist")
+	ErrLocationNotFound  = errors.New("stock location does not exist")
+	ErrSameLocation      = errors.New("stock must be transferred between two different locations")
+	ErrInvalidStockLevel = errors.New("stock Quantity and ReorderLevel must be zero or more")
+	ErrEngineerNotFound  = errors.New("stock location references an engineer that does not exist")
+	ErrJobNotFound       = errors.New("job does not exist")
+)
+
+// Service - the struct for the inventory service, covering the parts catalogue, where stock is held
+// and the parts used on jobs
+type Service struct {
+	DB        *gorm.DB
+	Customers *customer.Service
+}
+
+// InventoryService - the interface for our inventory service
+type InventoryService interface {
+	GetPart(ID uint) (Part, error)
+	GetAllParts() ([]Part, error)
+	PostPart(part Part) (Part, error)
+	UpdatePart(ID uint, newPart Part) (Part, error)
+	DeletePart(ID uint) error
+	GetLocation(ID uint) (Location, error)
+	GetAllLocations() ([]Location, error)
+	PostLocation(location Location) (Location, error)
+	UpdateLocation(ID uint, newLocation Location) (Location, error)
+	DeleteLocation(ID uint) error
+	GetStock(locationID uint) ([]StockLevel, error)
+	SetStock(locationID uint, partID uint, level StockLevel) (StockLevel, error)
+	TransferStock(partID uint, fromID uint, toID uint, quantity int) error
+	GetLowStock() ([]StockLevel, error)
+	GetUsage(jobID uint) ([]Usage, error)
+	UseParts(jobID uint, bookingID *uint, usage Usage) (Usage, error)
+	ReturnParts(jobID uint, ID uint) error
+}
+
+// NewService - takes in a pointer to the DB and the customer service & returns a pointer to a new
+// inventory service
+func NewService(db *gorm.DB, customers *customer.Service) *Service {
+	return &Service{
+		DB:        db,
+		Customers: customers,
+	}
+}
+
+// Part - an entry in the parts catalogue. UnitPrice is in minor units (pence or cents).
+type Part struct {
+	gorm.Model
+	PartNo       string `gorm:"unique_index" json:"partNo"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Manufacturer string `json:"manufacturer"`
+	// the unit the part is counted in, "each" when empty
+	Unit      string `json:"unit"`
+	UnitPrice int64  `json:"unitPrice"`
+}
+
+// GetPart - retrieves a part from the catalogue by ID
+func (s *Service) GetPart(ID uint) (Part, error) {
+	var part Part
+	if result := s.DB.First(&part, ID); result.Error != nil {
+		return Part{}, result.Error
+	}
+	return part, nil
+}
+
+// GetAllParts - retrieves the parts catalogue, ordered by part number
+func (s *Service) GetAllParts() ([]Part, error) {
+	var parts []Part
+	if result := s.DB.Order("part_no").Find(&parts); result.Error != nil {
+		return parts, result.Error
+	}
+	return parts, nil
+}
+
+// PostPart - adds a part to the catalogue
+func (s *Service) PostPart(part Part) (Part, error) {
+	if strings.TrimSpace(part.PartNo) == "" || strings.TrimSpace(part.Name) == "" || part.UnitPrice < 0 {
+		return Part{}, ErrInvalidPart
+	}
+	if err := s.partNoAvailable(part.PartNo, 0); err != nil {
+		return Part{}, err
+	}
+	if part.Unit == "" {
+		part.Unit = "each"
+	}
+	part.Model = gorm.Model{}
+	if result := s.DB.Save(&part); result.Error != nil {
+		return Part{}, result.Error
+	}
+	return part, nil
+}
+
+// UpdatePart - updates a part in the catalogue by ID. Parts already used on jobs keep the price
+// they were used at.
+func (s *Service) UpdatePart(ID uint, newPart Part) (Part, error) {
+	part, err := s.GetPart(ID)
+	if err != nil {
+		return Part{}, err
+	}
+	if newPart.UnitPrice < 0 {
+		return Part{}, ErrInvalidPart
+	}
+	if newPart.PartNo != "" && newPart.PartNo != part.PartNo {
+		if err := s.partNoAvailable(newPart.PartNo, ID); err != nil {
+			return Part{}, err
+		}
+	}
+	if result := s.DB.Model(&part).Updates(newPart); result.Error != nil {
+		return Part{}, result.Error
+	}
+	return part, nil
+}
+
+// DeletePart - removes a part from the catalogue by ID
+func (s *Service) DeletePart(ID uint) error {
+	if result := s.DB.Delete(&Part{}, ID); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// partNoAvailable - returns ErrPartNoInUse if a part other than ID has the part number
+func (s *Service) partNoAvailable(partNo string, ID uint) error {
+	var count int
+	if result := s.DB.Model(&Part{}).Where("part_no = ? AND id <> ?", partNo, ID).Count(&count); result.Error != nil {
+		return result.Error
+	}
+	if count > 0 {
+		return ErrPartNoInUse
+	}
+	return nil
+}
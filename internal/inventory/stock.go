@@ -0,0 +1,221 @@
+package inventory
+
+import (
+	"strings"
+
+	"github.com/Open-FiSE/go-rest-api/internal/engineer"
+	"github.com/jinzhu/gorm"
+)
+
+// Location - somewhere stock is held, a warehouse or an engineer's van
+type Location struct {
+	gorm.Model
+	Name string       `json:"name"`
+	Type LocationType `json:"type"`
+	// the engineer whose van it is
+	EngineerID *uint  `json:"engineerId"`
+	Address    string `json:"address"`
+}
+
+// TableName - the table stock locations are stored in, "locations" alone is too general
+func (Location) TableName() string {
+	return "stock_locations"
+}
+
+// StockLevel - how many of a part are held at a location. A part is low on stock at a location once
+// its Quantity falls to its ReorderLevel.
+type StockLevel struct {
+	ID           uint      `gorm:"primary_key" json:"id"`
+	PartID       uint      `gorm:"unique_index:idx_stock_part_location" json:"partId"`
+	LocationID   uint      `gorm:"unique_index:idx_stock_part_location" json:"locationId"`
+	Quantity     int       `json:"quantity"`
+	ReorderLevel int       `json:"reorderLevel"`
+	Part         *Part     `gorm:"association_autoupdate:false;association_autocreate:false;association_save_reference:false" json:"part,omitempty"`
+	Location     *Location `gorm:"association_autoupdate:false;association_autocreate:false;association_save_reference:false" json:"location,omitempty"`
+}
+
+// GetLocation - retrieves a stock location by ID
+func (s *Service) GetLocation(ID uint) (Location, error) {
+	var location Location
+	if result := s.DB.First(&location, ID); result.Error != nil {
+		return Location{}, result.Error
+	}
+	return location, nil
+}
+
+// GetAllLocations - retrieves every stock location, warehouses first
+func (s *Service) GetAllLocations() ([]Location, error) {
+	var locations []Location
+	if result := s.DB.Order("type DESC, name").Find(&locations); result.Error != nil {
+		return locations, result.Error
+	}
+	return locations, nil
+}
+
+// PostLocation - adds a new warehouse or van
+func (s *Service) PostLocation(location Location) (Location, error) {
+	if err := s.validateLocation(location, false); err != nil {
+		return Location{}, err
+	}
+	location.Model = gorm.Model{}
+	if result := s.DB.Save(&location); result.Error != nil {
+		return Location{}, result.Error
+	}
+	return location, nil
+}
+
+// UpdateLocation - updates a stock location by ID, for example when a van changes hands
+func (s *Service) UpdateLocation(ID uint, newLocation Location) (Location, error) {
+	location, err := s.GetLocation(ID)
+	if err != nil {
+		return Location{}, err
+	}
+	if err := s.validateLocation(newLocation, true); err != nil {
+		return Location{}, err
+	}
+	if result := s.DB.Model(&location).Updates(newLocation); result.Error != nil {
+		return Location{}, result.Error
+	}
+	return location, nil
+}
+
+// DeleteLocation - removes a stock location by ID
+func (s *Service) DeleteLocation(ID uint) error {
+	if result := s.DB.Delete(&Location{}, ID); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// GetStock - retrieves the stock held at a location with each part, ordered by part number
+func (s *Service) GetStock(locationID uint) ([]StockLevel, error) {
+	var levels []StockLevel
+	if _, err := s.GetLocation(locationID); err != nil {
+		return levels, err
+	}
+	if result := s.DB.Preload("Part").Select("stock_levels.*").Joins("JOIN parts ON parts.id = stock_levels.part_id").
+		Where("stock_levels.location_id = ?", locationID).Order("parts.part_no").Find(&levels); result.Error != nil {
+		return levels, result.Error
+	}
+	return levels, nil
+}
+
+// SetStock - records how many of a part are held at a location and the level it is reordered at,
+// as found by a stock take or when a delivery is booked in
+func (s *Service) SetStock(locationID uint, partID uint, level StockLevel) (StockLevel, error) {
+	if level.Quantity < 0 || level.ReorderLevel < 0 {
+		return StockLevel{}, ErrInvalidStockLevel
+	}
+	if err := s.exists(&Location{}, locationID, ErrLocationNotFound); err != nil {
+		return StockLevel{}, err
+	}
+	if err := s.exists(&Part{}, partID, ErrPartNotFound); err != nil {
+		return StockLevel{}, err
+	}
+	var existing StockLevel
+	result := s.DB.Where("part_id = ? AND location_id = ?", partID, locationID).First(&existing)
+	if result.Error != nil && !gorm.IsRecordNotFoundError(result.Error) {
+		return StockLevel{}, result.Error
+	}
+	existing.PartID, existing.LocationID = partID, locationID
+	existing.Quantity, existing.ReorderLevel = level.Quantity, level.ReorderLevel
+	if result := s.DB.Save(&existing); result.Error != nil {
+		return StockLevel{}, result.Error
+	}
+	return existing, nil
+}
+
+// TransferStock - moves stock of a part from one location to another, such as restocking a van from
+// the warehouse, returning ErrInsufficientStock when there is not enough to move
+func (s *Service) TransferStock(partID uint, fromID uint, toID uint, quantity int) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+	if fromID == toID {
+		return ErrSameLocation
+	}
+	if err := s.exists(&Location{}, toID, ErrLocationNotFound); err != nil {
+		return err
+	}
+	tx := s.DB.Begin()
+	if err := decrement(tx, partID, fromID, quantity); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := increment(tx, partID, toID, quantity); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// GetLowStock - retrieves the stock levels at or below their reorder level, across every location
+func (s *Service) GetLowStock() ([]StockLevel, error) {
+	var levels []StockLevel
+	if result := s.DB.Preload("Part").Preload("Location").Where("quantity <= reorder_level").
+		Order("location_id, part_id").Find(&levels); result.Error != nil {
+		return levels, result.Error
+	}
+	return levels, nil
+}
+
+// decrement - takes stock away from a location inside tx, only if there is enough of it. The check
+// and the update are one statement so two engineers drawing on the same stock cannot both take the
+// last one.
+func decrement(tx *gorm.DB, partID uint, locationID uint, quantity int) error {
+	result := tx.Model(&StockLevel{}).Where("part_id = ? AND location_id = ? AND quantity >= ?", partID, locationID, quantity).
+		UpdateColumn("quantity", gorm.Expr("quantity - ?", quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+// increment - adds stock to a location inside tx, creating its stock level if it has none
+func increment(tx *gorm.DB, partID uint, locationID uint, quantity int) error {
+	result := tx.Model(&StockLevel{}).Where("part_id = ? AND location_id = ?", partID, locationID).
+		UpdateColumn("quantity", gorm.Expr("quantity + ?", quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return tx.Create(&StockLevel{PartID: partID, LocationID: locationID, Quantity: quantity}).Error
+	}
+	return nil
+}
+
+// exists - returns notFound if there is no row of the model with the ID
+func (s *Service) exists(model interface{}, ID uint, notFound error) error {
+	var count int
+	if result := s.DB.Model(model).Where("id = ?", ID).Count(&count); result.Error != nil {
+		return result.Error
+	}
+	if count == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// validateLocation - checks a location's name and type and that the engineer of a van exists,
+// ignoring those left empty when partial is set
+func (s *Service) validateLocation(location Location, partial bool) error {
+	if !partial && strings.TrimSpace(location.Name) == "" {
+		return ErrInvalidLocation
+	}
+	switch location.Type {
+	case LocationWarehouse, LocationVan:
+	case "":
+		if !partial {
+			return ErrInvalidLocation
+		}
+	default:
+		return ErrInvalidLocation
+	}
+	if location.EngineerID != nil {
+		return s.exists(&engineer.Engineer{}, *location.EngineerID, ErrEngineerNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package inventory
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// Usage - parts used on a job, drawn from the stock at a location. The part's price is copied when it
+// is used so later changes to the catalogue leave the job as it was.
+type Usage struct {
+	gorm.Model
+	JobID      uint   `json:"jobId"`
+	BookingID  *uint  `json:"bookingId"`
+	PartID     uint   `json:"partId"`
+	LocationID uint   `json:"locationId"`
+	Quantity   int    `json:"quantity"`
+	UnitPrice  int64  `json:"unitPrice"`
+	UsedBy     string `json:"usedBy"`
+	Part       *Part  `gorm:"association_autoupdate:false;association_autocreate:false;association_save_reference:false" json:"part,omitempty"`
+}
+
+// TableName - the table part usage is stored in
+func (Usage) TableName() string {
+	return "part_usages"
+}
+
+// GetUsage - retrieves the parts used on a job, in the order they were used
+func (s *Service) GetUsage(jobID uint) ([]Usage, error) {
+	var usage []Usage
+	if result := s.DB.Preload("Part").Where("job_id = ?", jobID).Order("id").Find(&usage); result.Error != nil {
+		return usage, result.Error
+	}
+	return usage, nil
+}
+
+// UseParts - records parts used on a job, optionally during one of its bookings, taking them out of
+// stock at the location in the same transaction. It returns ErrInsufficientStock, recording nothing,
+// when the location does not hold enough of the part, and customer.ErrBookingSigned once a booking
+// for the job has been signed off.
+func (s *Service) UseParts(jobID uint, bookingID *uint, usage Usage) (Usage, error) {
+	if usage.Quantity <= 0 {
+		return Usage{}, ErrInvalidQuantity
+	}
+	if err := s.jobEditable(jobID); err != nil {
+		return Usage{}, err
+	}
+	part, err := s.GetPart(usage.PartID)
+	if gorm.IsRecordNotFoundError(err) {
+		return Usage{}, ErrPartNotFound
+	} else if err != nil {
+		return Usage{}, err
+	}
+	if err := s.exists(&Location{}, usage.LocationID, ErrLocationNotFound); err != nil {
+		return Usage{}, err
+	}
+	usage.Model = gorm.Model{}
+	usage.JobID, usage.BookingID = jobID, bookingID
+	usage.UnitPrice = part.UnitPrice
+	usage.Part = nil
+
+	tx := s.DB.Begin()
+	if err := decrement(tx, usage.PartID, usage.LocationID, usage.Quantity); err != nil {
+		tx.Rollback()
+		return Usage{}, err
+	}
+	if err := tx.Create(&usage).Error; err != nil {
+		tx.Rollback()
+		return Usage{}, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return Usage{}, err
+	}
+	usage.Part = &part
+	return usage, nil
+}
+
+// ReturnParts - removes a usage line recorded in error from a job, putting the parts back into stock
+// at the location they were drawn from, unless a booking for the job has been signed off
+func (s *Service) ReturnParts(jobID uint, ID uint) error {
+	var usage Usage
+	if result := s.DB.Where("job_id = ?", jobID).First(&usage, ID); result.Error != nil {
+		return result.Error
+	}
+	if err := s.jobEditable(jobID); err != nil {
+		return err
+	}
+	tx := s.DB.Begin()
+	if err := increment(tx, usage.PartID, usage.LocationID, usage.Quantity); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Delete(&usage).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// jobEditable - checks the job exists and has no signed off booking, the parts used on it being
+// listed on the service report the customer signs
+func (s *Service) jobEditable(jobID uint) error {
+	if _, err := s.Customers.GetJob(jobID); gorm.IsRecordNotFoundError(err) {
+		return ErrJobNotFound
+	} else if err != nil {
+		return err
+	}
+	return s.Customers.JobEditable(jobID)
+}
@@ -0,0 +1,240 @@
+package pdf
+
+// Write simple PDF documents of text and ruled lines. Only the standard Helvetica fonts are used, so
+// no font data needs embedding and text is limited to the Windows-1252 (WinAnsi) character set.
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+)
+
+// A4 page size in points
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Font - one of the standard fonts every PDF reader provides
+type Font int
+
+// fonts available to Text
+const (
+	Regular Font = iota
+	Bold
+)
+
+var fontNames = []string{"Helvetica", "Helvetica-Bold"}
+
+// Document - a PDF document built up page by page
+type Document struct {
+	Title  string
+	pages  []*Page
+	images []*pdfImage
+}
+
+// Page - a page of a Document. Coordinates are in points from the bottom left corner.
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+	images  []int
+}
+
+// pdfImage - an image drawn in the document, stored once however many times it is drawn
+type pdfImage struct {
+	width, height int
+	data          []byte
+}
+
+// New - returns an empty document with the given title
+func New(title string) *Document {
+	return &Document{Title: title}
+}
+
+// AddPage - adds a new A4 page to the end of the document
+func (d *Document) AddPage() *Page {
+	page := &Page{doc: d}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Text - writes a line of text with its baseline starting at x, y
+func (p *Page) Text(x, y float64, font Font, size float64, text string) {
+	fmt.Fprintf(&p.content, "BT /F%d %.2f Tf %.2f %.2f Td (%s) Tj ET\n", int(font)+1, size, x, y, escape(text))
+}
+
+// Line - draws a straight line width points thick from x1, y1 to x2, y2
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, y1, x2, y2)
+}
+
+// Polyline - draws straight lines width points thick joining each point to the next, with rounded
+// joins and ends
+func (p *Page) Polyline(points [][2]float64, width float64) {
+	if len(points) == 0 {
+		return
+	}
+	fmt.Fprintf(&p.content, "q 1 J 1 j %.2f w %.2f %.2f m", width, points[0][0], points[0][1])
+	for _, point := range points[1:] {
+		fmt.Fprintf(&p.content, " %.2f %.2f l", point[0], point[1])
+	}
+	// a single point is drawn as a dot
+	if len(points) == 1 {
+		fmt.Fprintf(&p.content, " %.2f %.2f l", points[0][0], points[0][1])
+	}
+	p.content.WriteString(" S Q\n")
+}
+
+// Rect - fills a rectangle with a shade of grey, 0 being black and 1 white
+func (p *Page) Rect(x, y, width, height, grey float64) {
+	fmt.Fprintf(&p.content, "q %.2f g %.2f %.2f %.2f %.2f re f Q\n", grey, x, y, width, height)
+}
+
+// Image - draws an image scaled to fill the box with its bottom left corner at x, y. Transparent
+// parts of the image are drawn as white.
+func (p *Page) Image(x, y, width, height float64, img image.Image) {
+	bounds := img.Bounds()
+	var raw bytes.Buffer
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			r, g, b, a := img.At(px, py).RGBA()
+			// composite over white, RGBA gives premultiplied 16 bit values
+			white := 0xffff - a
+			raw.Write([]byte{byte((r + white) >> 8), byte((g + white) >> 8), byte((b + white) >> 8)})
+		}
+	}
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(raw.Bytes())
+	zw.Close()
+
+	p.doc.images = append(p.doc.images, &pdfImage{width: bounds.Dx(), height: bounds.Dy(), data: compressed.Bytes()})
+	index := len(p.doc.images)
+	p.images = append(p.images, index)
+	fmt.Fprintf(&p.content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", width, height, x, y, index)
+}
+
+// TextWidth - the approximate width of text in points, from the average Helvetica character width
+func TextWidth(text string, font Font, size float64) float64 {
+	average := 0.52
+	if font == Bold {
+		average = 0.57
+	}
+	return float64(len([]rune(text))) * average * size
+}
+
+// Wrap - splits text into lines no wider than width, breaking between words where it can
+func Wrap(text string, font Font, size float64, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line != "" && TextWidth(candidate, font, size) > width {
+				lines = append(lines, line)
+				candidate = word
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// WriteTo - writes the document as PDF 1.4
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	// 1 catalog, 2 page tree, 3 info, then a font object each, an object per image, then a page and
+	// its content per page
+	firstFont := 4
+	firstImage := firstFont + len(fontNames)
+	firstPage := firstImage + len(d.images)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object(fmt.Sprintf("<< /Title (%s) /Producer (FiSES API) >>", escape(d.Title)))
+	fonts := make([]string, len(fontNames))
+	for i, name := range fontNames {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+		fonts[i] = fmt.Sprintf("/F%d %d 0 R", i+1, firstFont+i)
+	}
+	for _, img := range d.images {
+		object(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream",
+			img.width, img.height, len(img.data), img.data))
+	}
+	for i, page := range d.pages {
+		xobjects := ""
+		if len(page.images) > 0 {
+			refs := make([]string, len(page.images))
+			for j, index := range page.images {
+				refs[j] = fmt.Sprintf("/Im%d %d 0 R", index, firstImage+index-1)
+			}
+			xobjects = fmt.Sprintf(" /XObject << %s >>", strings.Join(refs, " "))
+		}
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << %s >>%s >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, strings.Join(fonts, " "), xobjects, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.WriteTo(w)
+}
+
+// Bytes - the document as PDF
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	d.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// winAnsi - the Windows-1252 bytes for the characters it does not share with Latin-1
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88, '‰': 0x89,
+	'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95,
+	'–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// escape - encodes text as the contents of a PDF string in WinAnsi, replacing characters it cannot
+// represent with '?'
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			b.WriteByte(byte(r))
+		case winAnsi[r] != 0:
+			b.WriteByte(winAnsi[r])
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
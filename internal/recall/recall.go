@@ -0,0 +1,264 @@
+package recall
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaults for the recall scheduler
+const (
+	// DefaultLeadDays - how many days before an instrument is due it is recalled
+	DefaultLeadDays = 30
+	// DefaultVisitLength - how long a recall visit is booked for
+	DefaultVisitLength = 2 * time.Hour
+	// DefaultStartHour - the hour of the day, site time, a recall visit starts
+	DefaultStartHour = 9
+	// maxAttempts - how many working days a recall visit is moved on by when its slot is taken
+	maxAttempts = 10
+)
+
+// Service - the struct for the recall service, which books instruments coming due for calibration
+// back in with tentative visits
+type Service struct {
+	Bookings  *booking.BookService
+	Customers *customer.Service
+	// LeadDays - how many days before an instrument is due it is recalled
+	LeadDays    int
+	VisitLength time.Duration
+	StartHour   int
+}
+
+// RecallService - the interface for our recall service
+type RecallService interface {
+	GetDueList(within int, customerID uint) ([]DueInstrument, error)
+	Run(now time.Time) ([]booking.Booking, error)
+	Start(interval time.Duration) (stop func())
+}
+
+// NewService - takes in the booking and customer services & returns a pointer to a new recall service
+func NewService(bookings *booking.BookService, customers *customer.Service) *Service {
+	return &Service{
+		Bookings:    bookings,
+		Customers:   customers,
+		LeadDays:    DefaultLeadDays,
+		VisitLength: DefaultVisitLength,
+		StartHour:   DefaultStartHour,
+	}
+}
+
+// DueInstrument - an entry in the due list, an instrument with how long until its calibration is due
+type DueInstrument struct {
+	Instrument   customer.Instrument `json:"instrument"`
+	CustomerName string              `json:"customerName"`
+	SiteName     string              `json:"siteName"`
+	DaysUntilDue int                 `json:"daysUntilDue"`
+	Overdue      bool                `json:"overdue"`
+	Recalled     bool                `json:"recalled"`
+}
+
+// GetDueList - lists the instruments due for calibration within the given number of days, overdue
+// ones included, soonest first and only the customer's when customerID is not zero
+func (s *Service) GetDueList(within int, customerID uint) ([]DueInstrument, error) {
+	now := time.Now().UTC()
+	instruments, err := s.Customers.GetInstrumentsDue(now.AddDate(0, 0, within), customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	customers := make(map[uint]string)
+	sites := make(map[uint]string)
+	list := make([]DueInstrument, 0, len(instruments))
+	for _, instrument := range instruments {
+		entry := DueInstrument{
+			Instrument:   instrument,
+			DaysUntilDue: int(instrument.CalibrationDue.Sub(now).Hours() / 24),
+			Overdue:      instrument.CalibrationDue.Before(now),
+			Recalled:     instrument.RecallBookingID != nil,
+		}
+		if _, ok := customers[instrument.CustomerID]; !ok {
+			c, err := s.Customers.GetCustomer(instrument.CustomerID)
+			if err != nil {
+				return nil, err
+			}
+			customers[c.ID] = c.Name
+			for _, site := range c.Sites {
+				sites[site.ID] = site.Name
+			}
+		}
+		entry.CustomerName = customers[instrument.CustomerID]
+		if instrument.SiteID != nil {
+			entry.SiteName = sites[*instrument.SiteID]
+		}
+		list = append(list, entry)
+	}
+	return list, nil
+}
+
+// siteKey - the customer site a group of recalled instruments is visited at, SiteID 0 for
+// instruments not kept at a site
+type siteKey struct {
+	CustomerID uint
+	SiteID     uint
+}
+
+// Run - books a tentative visit for each customer site with instruments coming due within LeadDays
+// that have not already been recalled, or whose recall visit was cancelled. Each visit is requested on the earliest due date at the site,
+// or the next working day if that has passed, and moved on a working day at a time if the slot is
+// taken. It returns the bookings it made.
+func (s *Service) Run(now time.Time) ([]booking.Booking, error) {
+	instruments, err := s.Customers.GetInstrumentsDue(now.AddDate(0, 0, s.LeadDays), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[siteKey][]customer.Instrument)
+	var keys []siteKey
+	for _, instrument := range instruments {
+		if instrument.RecallBookingID != nil && s.stillBooked(*instrument.RecallBookingID) {
+			continue
+		}
+		key := siteKey{CustomerID: instrument.CustomerID}
+		if instrument.SiteID != nil {
+			key.SiteID = *instrument.SiteID
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], instrument)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CustomerID != keys[j].CustomerID {
+			return keys[i].CustomerID < keys[j].CustomerID
+		}
+		return keys[i].SiteID < keys[j].SiteID
+	})
+
+	var booked []booking.Booking
+	for _, key := range keys {
+		b, err := s.recall(key, groups[key], now)
+		if err != nil {
+			// one site failing to book leaves the rest to be recalled
+			log.WithFields(log.Fields{"customerId": key.CustomerID, "siteId": key.SiteID}).Error(err)
+			continue
+		}
+		booked = append(booked, b)
+	}
+	return booked, nil
+}
+
+// recall - books the visit recalling one site's instruments and marks them as recalled
+func (s *Service) recall(key siteKey, instruments []customer.Instrument, now time.Time) (booking.Booking, error) {
+	visit := booking.Booking{
+		Summary:     fmt.Sprintf("Calibration recall (%d instrument%s)", len(instruments), plural(len(instruments))),
+		CustomerID:  &key.CustomerID,
+		Status:      booking.StatusRequested,
+		Instruments: instruments,
+	}
+	loc := time.UTC
+	if key.SiteID != 0 {
+		visit.SiteID = &key.SiteID
+		site, err := s.Customers.GetSite(key.CustomerID, key.SiteID)
+		if err != nil {
+			return booking.Booking{}, err
+		}
+		if site.TimeZone != "" {
+			if loc, err = time.LoadLocation(site.TimeZone); err != nil {
+				return booking.Booking{}, err
+			}
+		}
+	}
+
+	serials := make([]string, len(instruments))
+	due := *instruments[0].CalibrationDue
+	IDs := make([]uint, len(instruments))
+	for i, instrument := range instruments {
+		serials[i] = instrument.SerialNo
+		IDs[i] = instrument.ID
+		if instrument.CalibrationDue.Before(due) {
+			due = *instrument.CalibrationDue
+		}
+	}
+	visit.Description = "Calibration due for " + strings.Join(serials, ", ")
+
+	day := due.In(loc)
+	if tomorrow := now.In(loc).AddDate(0, 0, 1); day.Before(tomorrow) {
+		day = tomorrow
+	}
+	for attempt := 0; ; attempt++ {
+		day = workingDay(day)
+		start := time.Date(day.Year(), day.Month(), day.Day(), s.StartHour, 0, 0, 0, loc)
+		visit.LocalStart = start.Format("2006-01-02T15:04")
+		visit.LocalEnd = start.Add(s.VisitLength).Format("2006-01-02T15:04")
+
+		b, err := s.Bookings.PostBooking(visit, false)
+		var conflict *booking.ConflictError
+		if errors.As(err, &conflict) && attempt < maxAttempts {
+			day = day.AddDate(0, 0, 1)
+			continue
+		} else if err != nil {
+			return booking.Booking{}, err
+		}
+		if err := s.Customers.SetRecallBooking(IDs, b.ID); err != nil {
+			return booking.Booking{}, err
+		}
+		return b, nil
+	}
+}
+
+// Start - runs the recall every interval in the background until stop is called
+func (s *Service) Start(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			if booked, err := s.Run(time.Now().UTC()); err != nil {
+				log.Error(err)
+			} else if len(booked) > 0 {
+				log.WithField("bookings", len(booked)).Info("Booked calibration recalls")
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// stillBooked - whether a recall visit still stands, it has not been cancelled, missed or deleted
+func (s *Service) stillBooked(bookingID uint) bool {
+	b, err := s.Bookings.GetBooking(bookingID)
+	if err != nil {
+		return false
+	}
+	return b.Status != booking.StatusCancelled && b.Status != booking.StatusNoShow
+}
+
+// workingDay - the day itself, or the Monday after when it falls at the weekend
+func workingDay(day time.Time) time.Time {
+	switch day.Weekday() {
+	case time.Saturday:
+		return day.AddDate(0, 0, 2)
+	case time.Sunday:
+		return day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
@@ -0,0 +1,396 @@
+package recurrence
+
+// Parse and expand the subset of RFC 5545 recurrence rules used for service visits:
+// FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY and BYMONTH.
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRule - returned when a recurrence rule cannot be parsed or uses unsupported parts
+var ErrInvalidRule = errors.New("invalid or unsupported RRULE")
+
+// maxIterations - guards expansion against rules that never produce an occurrence in the window
+const maxIterations = 100000
+
+// Frequency - the FREQ part of a rule
+type Frequency string
+
+// supported frequencies
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// WeekdayNum - a BYDAY entry, N is the optional ordinal within the month or year ("2MO", "-1FR")
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// Rule - a parsed RRULE
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Parse - parses an RRULE value such as "FREQ=MONTHLY;INTERVAL=6;COUNT=4". A leading "RRULE:" is ignored.
+func Parse(value string) (Rule, error) {
+	rule := Rule{Interval: 1}
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return rule, ErrInvalidRule
+	}
+
+	for _, part := range strings.Split(value, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return rule, fmt.Errorf("%w: %q", ErrInvalidRule, part)
+		}
+		key, val := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+		var err error
+		switch key {
+		case "FREQ":
+			rule.Freq = Frequency(val)
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly && rule.Freq != Yearly {
+				return rule, fmt.Errorf("%w: unsupported FREQ %s", ErrInvalidRule, val)
+			}
+		case "INTERVAL":
+			if rule.Interval, err = strconv.Atoi(val); err != nil || rule.Interval < 1 {
+				return rule, fmt.Errorf("%w: INTERVAL %s", ErrInvalidRule, val)
+			}
+		case "COUNT":
+			if rule.Count, err = strconv.Atoi(val); err != nil || rule.Count < 1 {
+				return rule, fmt.Errorf("%w: COUNT %s", ErrInvalidRule, val)
+			}
+		case "UNTIL":
+			if rule.Until, err = parseUntil(val); err != nil {
+				return rule, fmt.Errorf("%w: UNTIL %s", ErrInvalidRule, val)
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				wd, err := parseWeekdayNum(day)
+				if err != nil {
+					return rule, err
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return rule, fmt.Errorf("%w: BYMONTHDAY %s", ErrInvalidRule, day)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, month := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(month)
+				if err != nil || n < 1 || n > 12 {
+					return rule, fmt.Errorf("%w: BYMONTH %s", ErrInvalidRule, month)
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(n))
+			}
+		case "WKST":
+			// weeks always start on Monday, the RFC 5545 default
+			if val != "MO" {
+				return rule, fmt.Errorf("%w: only WKST=MO is supported", ErrInvalidRule)
+			}
+		default:
+			return rule, fmt.Errorf("%w: unsupported part %s", ErrInvalidRule, key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return rule, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return rule, fmt.Errorf("%w: COUNT and UNTIL are mutually exclusive", ErrInvalidRule)
+	}
+	return rule, nil
+}
+
+// String - formats the rule back into RRULE value syntax
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = wd.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = strconv.Itoa(int(m))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+func (wd WeekdayNum) String() string {
+	for code, day := range weekdays {
+		if day == wd.Day {
+			if wd.N != 0 {
+				return strconv.Itoa(wd.N) + code
+			}
+			return code
+		}
+	}
+	return ""
+}
+
+// Occurrences - returns the start times of every occurrence in [from, to), in order. dtstart is
+// always the first occurrence and sets the wall-clock time and zone of the rest. Excluded start times
+// still count towards COUNT, as RFC 5545 applies EXDATE after expansion.
+func (r Rule) Occurrences(dtstart, from, to time.Time, exclude []time.Time) []time.Time {
+	excluded := make(map[int64]bool, len(exclude))
+	for _, ex := range exclude {
+		excluded[ex.Unix()] = true
+	}
+
+	var occurrences []time.Time
+	emitted := 0
+	if dtstart.Before(to) {
+		emitted++
+		if !dtstart.Before(from) && !excluded[dtstart.Unix()] {
+			occurrences = append(occurrences, dtstart)
+		}
+	}
+	for period := 0; period < maxIterations; period++ {
+		candidates := r.expand(dtstart, period)
+		for _, c := range candidates {
+			if !c.After(dtstart) {
+				continue
+			}
+			if r.Count > 0 && emitted >= r.Count {
+				return occurrences
+			}
+			if !r.Until.IsZero() && c.After(r.Until) {
+				return occurrences
+			}
+			if !c.Before(to) {
+				return occurrences
+			}
+			emitted++
+			if !c.Before(from) && !excluded[c.Unix()] {
+				occurrences = append(occurrences, c)
+			}
+		}
+	}
+	return occurrences
+}
+
+// CountBefore - the number of occurrences, excluded or not, that start before t
+func (r Rule) CountBefore(dtstart, t time.Time) int {
+	return len(r.Occurrences(dtstart, dtstart, t, nil))
+}
+
+// IsOccurrence - reports whether t is the start of one of the rule's occurrences
+func (r Rule) IsOccurrence(dtstart, t time.Time) bool {
+	occurrences := r.Occurrences(dtstart, t, t.Add(time.Second), nil)
+	return len(occurrences) == 1 && occurrences[0].Equal(t)
+}
+
+// expand - the sorted candidate start times in the n-th period (day, week, month or year) after dtstart
+func (r Rule) expand(dtstart time.Time, n int) []time.Time {
+	loc := dtstart.Location()
+	hour, min, sec := dtstart.Clock()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hour, min, sec, 0, loc)
+	}
+	step := n * r.Interval
+
+	var candidates []time.Time
+	switch r.Freq {
+	case Daily:
+		day := at(dtstart.Year(), dtstart.Month(), dtstart.Day()+step)
+		if r.matchesMonth(day.Month()) && r.matchesWeekday(day) && r.matchesMonthDay(day) {
+			candidates = append(candidates, day)
+		}
+	case Weekly:
+		// weeks start on Monday
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		monday := at(dtstart.Year(), dtstart.Month(), dtstart.Day()-offset+7*step)
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []WeekdayNum{{Day: dtstart.Weekday()}}
+		}
+		for _, wd := range days {
+			day := at(monday.Year(), monday.Month(), monday.Day()+(int(wd.Day)+6)%7)
+			if r.matchesMonth(day.Month()) {
+				candidates = append(candidates, day)
+			}
+		}
+	case Monthly:
+		first := at(dtstart.Year(), dtstart.Month()+time.Month(step), 1)
+		if r.matchesMonth(first.Month()) {
+			candidates = r.daysInMonth(dtstart, first, at)
+		}
+	case Yearly:
+		year := dtstart.Year() + step
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{dtstart.Month()}
+		}
+		for _, month := range months {
+			candidates = append(candidates, r.daysInMonth(dtstart, at(year, month, 1), at)...)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	return candidates
+}
+
+// daysInMonth - the candidate days in the month starting at first. Days that do not exist in the
+// month (the 31st of April) are skipped rather than rolled over, as RFC 5545 requires.
+func (r Rule) daysInMonth(dtstart, first time.Time, at func(int, time.Month, int) time.Time) []time.Time {
+	length := time.Date(first.Year(), first.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	var days []time.Time
+	add := func(d int) {
+		if d >= 1 && d <= length {
+			days = append(days, at(first.Year(), first.Month(), d))
+		}
+	}
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = length + d + 1
+			}
+			day := at(first.Year(), first.Month(), d)
+			if d >= 1 && d <= length && r.matchesWeekday(day) {
+				add(d)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			firstMatch := 1 + (int(wd.Day)-int(first.Weekday())+7)%7
+			var matches []int
+			for d := firstMatch; d <= length; d += 7 {
+				matches = append(matches, d)
+			}
+			switch {
+			case wd.N > 0 && wd.N <= len(matches):
+				add(matches[wd.N-1])
+			case wd.N < 0 && -wd.N <= len(matches):
+				add(matches[len(matches)+wd.N])
+			case wd.N == 0:
+				for _, d := range matches {
+					add(d)
+				}
+			}
+		}
+	default:
+		add(dtstart.Day())
+	}
+	return days
+}
+
+func (r Rule) matchesMonth(month time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWeekday - BYDAY as a filter, used where it does not expand the set (DAILY, BYMONTHDAY)
+func (r Rule) matchesWeekday(day time.Time) bool {
+	if len(r.ByDay) == 0 || (r.Freq != Daily && len(r.ByMonthDay) == 0) {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matchesMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	length := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, d := range r.ByMonthDay {
+		if d == day.Day() || (d < 0 && length+d+1 == day.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseWeekdayNum(value string) (WeekdayNum, error) {
+	if len(value) < 2 {
+		return WeekdayNum{}, fmt.Errorf("%w: BYDAY %s", ErrInvalidRule, value)
+	}
+	day, ok := weekdays[value[len(value)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("%w: BYDAY %s", ErrInvalidRule, value)
+	}
+	wd := WeekdayNum{Day: day}
+	if ordinal := value[:len(value)-2]; ordinal != "" {
+		n, err := strconv.Atoi(ordinal)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, fmt.Errorf("%w: BYDAY %s", ErrInvalidRule, value)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+// parseUntil - UNTIL is either a UTC date-time or a date, which includes the whole of that day
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102T150405", value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("20060102", value)
+	if err != nil {
+		return t, err
+	}
+	return t.Add(24*time.Hour - time.Second), nil
+}
@@ -0,0 +1,203 @@
+package recurrence
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"weekly days", "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=6", "FREQ=WEEKLY;COUNT=6;BYDAY=MO,WE,FR"},
+		{"prefix and lower case", "RRULE:freq=monthly;interval=6", "FREQ=MONTHLY;INTERVAL=6"},
+		{"ordinal days", "FREQ=MONTHLY;BYDAY=2TU,-1FR", "FREQ=MONTHLY;BYDAY=2TU,-1FR"},
+		{"until date time", "FREQ=DAILY;UNTIL=20260110T090000Z", "FREQ=DAILY;UNTIL=20260110T090000Z"},
+		{"until date takes the whole day", "FREQ=DAILY;UNTIL=20260110", "FREQ=DAILY;UNTIL=20260110T235959Z"},
+		{"yearly", "FREQ=YEARLY;BYMONTH=3,10;BYMONTHDAY=-1;WKST=MO", "FREQ=YEARLY;BYMONTHDAY=-1;BYMONTH=3,10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.value)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.value, err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Errorf("Parse(%q).String() = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"empty", ""},
+		{"no frequency", "COUNT=3"},
+		{"unsupported frequency", "FREQ=HOURLY"},
+		{"zero interval", "FREQ=DAILY;INTERVAL=0"},
+		{"count and until", "FREQ=DAILY;COUNT=3;UNTIL=20260110"},
+		{"unsupported part", "FREQ=MONTHLY;BYDAY=MO;BYSETPOS=1"},
+		{"bad weekday", "FREQ=WEEKLY;BYDAY=XX"},
+		{"ordinal out of range", "FREQ=MONTHLY;BYDAY=6MO"},
+		{"month day out of range", "FREQ=MONTHLY;BYMONTHDAY=32"},
+		{"month out of range", "FREQ=YEARLY;BYMONTH=13"},
+		{"week start", "FREQ=WEEKLY;WKST=SU"},
+		{"part without value", "FREQ=DAILY;COUNT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.value); !errors.Is(err, ErrInvalidRule) {
+				t.Errorf("Parse(%q) error = %v, want ErrInvalidRule", tt.value, err)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	dublin, err := time.LoadLocation("Europe/Dublin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(value string) time.Time {
+		v, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name     string
+		rule     string
+		dtstart  time.Time
+		from, to string
+		exclude  []string
+		want     []string
+	}{
+		{
+			name: "weekly on several days", rule: "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=5",
+			dtstart: utc("2026-01-05T09:00:00Z"), from: "2026-01-01T00:00:00Z", to: "2027-01-01T00:00:00Z",
+			want: []string{"2026-01-05T09:00:00Z", "2026-01-07T09:00:00Z", "2026-01-09T09:00:00Z", "2026-01-12T09:00:00Z", "2026-01-14T09:00:00Z"},
+		},
+		{
+			name: "every other week", rule: "FREQ=WEEKLY;INTERVAL=2;COUNT=3",
+			dtstart: utc("2026-01-06T09:00:00Z"), from: "2026-01-01T00:00:00Z", to: "2027-01-01T00:00:00Z",
+			want: []string{"2026-01-06T09:00:00Z", "2026-01-20T09:00:00Z", "2026-02-03T09:00:00Z"},
+		},
+		{
+			name: "second tuesday", rule: "FREQ=MONTHLY;BYDAY=2TU;COUNT=3",
+			dtstart: utc("2026-01-13T09:00:00Z"), from: "2026-01-01T00:00:00Z", to: "2027-01-01T00:00:00Z",
+			want: []string{"2026-01-13T09:00:00Z", "2026-02-10T09:00:00Z", "2026-03-10T09:00:00Z"},
+		},
+		{
+			name: "last friday", rule: "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			dtstart: utc("2026-01-30T09:00:00Z"), from: "2026-01-01T00:00:00Z", to: "2027-01-01T00:00:00Z",
+			want: []string{"2026-01-30T09:00:00Z", "2026-02-27T09:00:00Z", "2026-03-27T09:00:00Z"},
+		},
+		{
+			name: "31st skips short months", rule: "FREQ=MONTHLY;COUNT=3",
+			dtstart: utc("2026-01-31T09:00:00Z"), from: "2026-01-01T00:00:00Z", to: "2027-01-01T00:00:00Z",
+			want: []string{"2026-01-31T09:00:00Z", "2026-03-31T09:00:00Z", "2026-05-31T09:00:00Z"},
+		},
+		{
+			name: "last day of the month", rule: "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3",
+			dtstart: utc("2026-01-31T09:00:00Z"), from: "2026-01-01T00:00:00Z", to: "2027-01-01T00:00:00Z",
+			want: []string{"2026-01-31T09:00:00Z", "2026-02-28T09:00:00Z", "2026-03-31T09:00:00Z"},
+		},
+		{
+			name: "yearly last sunday of march", rule: "FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU;COUNT=3",
+			dtstart: utc("2026-03-29T01:00:00Z"), from: "2026-01-01T00:00:00Z", to: "2030-01-01T00:00:00Z",
+			want: []string{"2026-03-29T01:00:00Z", "2027-03-28T01:00:00Z", "2028-03-26T01:00:00Z"},
+		},
+		{
+			name: "until is inclusive", rule: "FREQ=DAILY;UNTIL=20260103T090000Z",
+			dtstart: utc("2026-01-01T09:00:00Z"), from: "2026-01-01T00:00:00Z", to: "2027-01-01T00:00:00Z",
+			want: []string{"2026-01-01T09:00:00Z", "2026-01-02T09:00:00Z", "2026-01-03T09:00:00Z"},
+		},
+		{
+			name: "until date includes the day", rule: "FREQ=DAILY;UNTIL=20260102",
+			dtstart: utc("2026-01-01T17:00:00Z"), from: "2026-01-01T00:00:00Z", to: "2027-01-01T00:00:00Z",
+			want: []string{"2026-01-01T17:00:00Z", "2026-01-02T17:00:00Z"},
+		},
+		{
+			name: "excluded dates count towards count", rule: "FREQ=DAILY;COUNT=3",
+			dtstart: utc("2026-01-01T09:00:00Z"), from: "2026-01-01T00:00:00Z", to: "2027-01-01T00:00:00Z",
+			exclude: []string{"2026-01-02T09:00:00Z"},
+			want:    []string{"2026-01-01T09:00:00Z", "2026-01-03T09:00:00Z"},
+		},
+		{
+			name: "window", rule: "FREQ=DAILY",
+			dtstart: utc("2026-01-01T09:00:00Z"), from: "2026-01-03T00:00:00Z", to: "2026-01-05T09:00:00Z",
+			want: []string{"2026-01-03T09:00:00Z", "2026-01-04T09:00:00Z"},
+		},
+		{
+			name: "same local time across daylight saving", rule: "FREQ=WEEKLY;COUNT=3",
+			dtstart: time.Date(2026, 3, 23, 9, 0, 0, 0, dublin), from: "2026-01-01T00:00:00Z", to: "2027-01-01T00:00:00Z",
+			want: []string{"2026-03-23T09:00:00Z", "2026-03-30T08:00:00Z", "2026-04-06T08:00:00Z"},
+		},
+		{
+			name: "same local time back to winter time", rule: "FREQ=DAILY;COUNT=2",
+			dtstart: time.Date(2026, 10, 24, 9, 0, 0, 0, dublin), from: "2026-01-01T00:00:00Z", to: "2027-01-01T00:00:00Z",
+			want: []string{"2026-10-24T08:00:00Z", "2026-10-25T09:00:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			var exclude []time.Time
+			for _, ex := range tt.exclude {
+				exclude = append(exclude, utc(ex))
+			}
+			got := rule.Occurrences(tt.dtstart, utc(tt.from), utc(tt.to), exclude)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences %v, want %v", len(got), got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(utc(tt.want[i])) {
+					t.Errorf("occurrence %d = %s, want %s", i, got[i].UTC().Format(time.RFC3339), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIsOccurrence(t *testing.T) {
+	rule, err := Parse("FREQ=WEEKLY;BYDAY=TU,TH;COUNT=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtstart := time.Date(2026, 1, 6, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		t      time.Time
+		want   bool
+		before int
+	}{
+		{"first", dtstart, true, 0},
+		{"thursday", time.Date(2026, 1, 8, 9, 0, 0, 0, time.UTC), true, 1},
+		{"wrong time", time.Date(2026, 1, 8, 10, 0, 0, 0, time.UTC), false, 2},
+		{"wrong day", time.Date(2026, 1, 7, 9, 0, 0, 0, time.UTC), false, 1},
+		{"last", time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC), true, 3},
+		{"after count", time.Date(2026, 1, 20, 9, 0, 0, 0, time.UTC), false, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rule.IsOccurrence(dtstart, tt.t); got != tt.want {
+				t.Errorf("IsOccurrence(%s) = %v, want %v", tt.t, got, tt.want)
+			}
+			if got := rule.CountBefore(dtstart, tt.t); got != tt.before {
+				t.Errorf("CountBefore(%s) = %d, want %d", tt.t, got, tt.before)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package routing
+
+import (
+	"math"
+	"time"
+)
+
+// earthRadius - the mean radius of the Earth in kilometres
+const earthRadius = 6371.0
+
+// defaults for straight-line travel estimates
+const (
+	// DefaultSpeed - the average speed in km/h an engineer is taken to travel at
+	DefaultSpeed = 50.0
+	// DefaultDetour - how much further than the straight line between two points the road is taken to be
+	DefaultDetour = 1.3
+)
+
+// Point - a WGS 84 position
+type Point struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Valid - reports whether the point is on the globe
+func (p Point) Valid() bool {
+	return p.Latitude >= -90 && p.Latitude <= 90 && p.Longitude >= -180 && p.Longitude <= 180
+}
+
+// Matrix - works out the travel between points. Straight-line estimates are used by default; a road
+// routing service can be plugged in by implementing Matrix.
+type Matrix interface {
+	// Travel - the travel time and distance in kilometres from each point to each other point, with
+	// durations[i][j] the time from points[i] to points[j]
+	Travel(points []Point) (durations [][]time.Duration, distances [][]float64, err error)
+}
+
+// Haversine - estimates travel from the great-circle distance between points, lengthened by Detour
+// to allow for the roads and covered at Speed km/h
+type Haversine struct {
+	Speed  float64
+	Detour float64
+}
+
+// Travel - the estimated travel time and road distance between each pair of points
+func (h Haversine) Travel(points []Point) ([][]time.Duration, [][]float64, error) {
+	durations := make([][]time.Duration, len(points))
+	distances := make([][]float64, len(points))
+	for i, from := range points {
+		durations[i] = make([]time.Duration, len(points))
+		distances[i] = make([]float64, len(points))
+		for j, to := range points {
+			if i == j {
+				continue
+			}
+			distances[i][j] = Distance(from, to) * h.Detour
+			durations[i][j] = time.Duration(distances[i][j] / h.Speed * float64(time.Hour))
+		}
+	}
+	return durations, distances, nil
+}
+
+// Distance - the great-circle distance in kilometres between two points
+func Distance(from, to Point) float64 {
+	lat1, lat2 := radians(from.Latitude), radians(to.Latitude)
+	dLat, dLon := lat2-lat1, radians(to.Longitude-from.Longitude)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
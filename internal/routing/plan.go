@@ -0,0 +1,255 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/engineer"
+)
+
+// errors returned by the routing service
+var (
+	ErrInvalidDate  = errors.New("date must be formatted as 2006-01-02")
+	ErrInvalidStart = errors.New("start must be a latitude in [-90, 90] and longitude in [-180, 180]")
+)
+
+// Service - the struct for the routing service, which orders an engineer's visits to cut travel
+type Service struct {
+	Bookings  *booking.BookService
+	Engineers *engineer.Service
+	Matrix    Matrix
+}
+
+// RoutingService - the interface for our routing service
+type RoutingService interface {
+	PlanDay(engineerID uint, date string, start *Point) (Plan, error)
+}
+
+// NewService - takes in the booking and engineer services & returns a pointer to a new routing
+// service estimating travel from straight-line distances
+func NewService(bookings *booking.BookService, engineers *engineer.Service) *Service {
+	return &Service{
+		Bookings:  bookings,
+		Engineers: engineers,
+		Matrix:    Haversine{Speed: DefaultSpeed, Detour: DefaultDetour},
+	}
+}
+
+// Stop - a visit on a route, with the travel to it from the stop before (or the start point) and when
+// the engineer would arrive following the route
+type Stop struct {
+	BookingID     uint      `json:"bookingId"`
+	Summary       string    `json:"summary"`
+	Location      string    `json:"location"`
+	Point         Point     `json:"point"`
+	PlannedStart  time.Time `json:"plannedStart"`
+	PlannedEnd    time.Time `json:"plannedEnd"`
+	TravelMinutes int       `json:"travelMinutes"`
+	Distance      float64   `json:"distance"`
+	Arrival       time.Time `json:"arrival"`
+	// Late - the engineer would arrive after the visit's planned start
+	Late bool `json:"late"`
+}
+
+// Route - an engineer's visits in the order they are made. A route is feasible when every visit can
+// start at its planned time and the day ends within the engineer's working hours.
+type Route struct {
+	Stops         []Stop    `json:"stops"`
+	TravelMinutes int       `json:"travelMinutes"`
+	Distance      float64   `json:"distance"`
+	Finish        time.Time `json:"finish"`
+	Feasible      bool      `json:"feasible"`
+}
+
+// Warning - a problem with an engineer's day, about one booking when BookingID is set
+type Warning struct {
+	BookingID uint   `json:"bookingId,omitempty"`
+	Message   string `json:"message"`
+}
+
+// Plan - an engineer's day as booked, in the order of the planned times, and in the order that
+// travels least. Distances are in kilometres.
+type Plan struct {
+	EngineerID    uint      `json:"engineerId"`
+	Date          string    `json:"date"`
+	Start         *Point    `json:"start"`
+	Planned       Route     `json:"planned"`
+	Optimised     Route     `json:"optimised"`
+	SavingMinutes int       `json:"savingMinutes"`
+	Unrouted      []uint    `json:"unrouted"`
+	Warnings      []Warning `json:"warnings"`
+}
+
+// PlanDay - orders an engineer's visits on a date, in their time zone, to minimise travel between the
+// sites, starting from start when it is given and otherwise from the engineer's base when it has been
+// located. Visits to sites without coordinates are left
+// unrouted. Warnings are given for visits the engineer cannot reach by their planned time, a day that
+// runs past the engineer's working hours, and an optimised order the planned times do not fit.
+func (s *Service) PlanDay(engineerID uint, date string, start *Point) (Plan, error) {
+	if start != nil && !start.Valid() {
+		return Plan{}, ErrInvalidStart
+	}
+	e, err := s.Engineers.GetEngineer(engineerID)
+	if err != nil {
+		return Plan{}, err
+	}
+	if start == nil && e.HasBase() {
+		start = &Point{Latitude: *e.BaseLatitude, Longitude: *e.BaseLongitude}
+	}
+	workStart, workEnd, _, loc := e.WorkingHours()
+	day, err := time.ParseInLocation("2006-01-02", date, loc)
+	if err != nil {
+		return Plan{}, ErrInvalidDate
+	}
+	dayStart, dayEnd := at(day, workStart), at(day, workEnd)
+
+	bookings, err := s.Bookings.GetEngineerOccurrences(engineerID, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return Plan{}, err
+	}
+	plan := Plan{EngineerID: engineerID, Date: date, Start: start, Unrouted: []uint{}, Warnings: []Warning{}}
+	var visits []booking.Booking
+	var points []Point
+	if start != nil {
+		points = append(points, *start)
+	}
+	for _, b := range bookings {
+		if !b.Status.Active() {
+			continue
+		}
+		if b.Site == nil || !b.Site.HasLocation() {
+			plan.Unrouted = append(plan.Unrouted, b.ID)
+			plan.Warnings = append(plan.Warnings, Warning{BookingID: b.ID, Message: "site has no coordinates, the visit is left out of the route"})
+			continue
+		}
+		visits = append(visits, b.InZone(loc))
+		points = append(points, Point{Latitude: *b.Site.Latitude, Longitude: *b.Site.Longitude})
+	}
+	if len(visits) == 0 {
+		return plan, nil
+	}
+
+	durations, distances, err := s.Matrix.Travel(points)
+	if err != nil {
+		return Plan{}, err
+	}
+	// the visits are indexed from 1 in the matrix when it starts with the start point
+	offset := 0
+	if start != nil {
+		offset = 1
+	}
+	planned := make([]int, 0, len(points))
+	for i := range points {
+		planned = append(planned, i)
+	}
+	firstStop := -1
+	if start != nil {
+		firstStop = 0
+	}
+	r := route{visits: visits, offset: offset, durations: durations, distances: distances, dayStart: dayStart, dayEnd: dayEnd}
+	plan.Planned = r.build(planned)
+	plan.Optimised = r.build(Order(durations, firstStop))
+	plan.SavingMinutes = plan.Planned.TravelMinutes - plan.Optimised.TravelMinutes
+
+	for i, stop := range plan.Planned.Stops {
+		if !stop.Late {
+			continue
+		}
+		from := "the start point"
+		if i > 0 {
+			from = fmt.Sprintf("booking %d", plan.Planned.Stops[i-1].BookingID)
+		}
+		plan.Warnings = append(plan.Warnings, Warning{
+			BookingID: stop.BookingID,
+			Message: fmt.Sprintf("arrives at %s, %d minutes after the planned start, after %d minutes travelling from %s",
+				stop.Arrival.Format("15:04"), minutes(stop.Arrival.Sub(stop.PlannedStart)), stop.TravelMinutes, from),
+		})
+	}
+	if plan.Planned.Finish.After(dayEnd) {
+		plan.Warnings = append(plan.Warnings, Warning{
+			Message: fmt.Sprintf("the day finishes at %s, after working hours end at %s", plan.Planned.Finish.Format("15:04"), workEnd),
+		})
+	}
+	if !plan.Optimised.Feasible && plan.SavingMinutes > 0 {
+		plan.Warnings = append(plan.Warnings, Warning{
+			Message: fmt.Sprintf("the optimised order saves %d minutes of travel but does not fit the planned times, the visits would need rebooking", plan.SavingMinutes),
+		})
+	}
+	return plan, nil
+}
+
+// route - builds routes through a day's visits in a given order
+type route struct {
+	visits    []booking.Booking
+	offset    int
+	durations [][]time.Duration
+	distances [][]float64
+	dayStart  time.Time
+	dayEnd    time.Time
+}
+
+// build - the route visiting in order, given as indexes into the travel matrix. Each visit keeps its
+// planned length and starts at its planned time, or on arrival when the engineer is late. Without a
+// start point the day begins at the first visit's planned start.
+func (r route) build(order []int) Route {
+	result := Route{Stops: make([]Stop, 0, len(r.visits)), Feasible: true}
+	var free time.Time
+	prev := -1
+	for _, index := range order {
+		if index < r.offset {
+			// the start point, where the engineer is when the working day begins
+			free, prev = r.dayStart, index
+			continue
+		}
+		b := r.visits[index-r.offset]
+		stop := Stop{
+			BookingID:    b.ID,
+			Summary:      b.Summary,
+			Location:     b.Location,
+			Point:        Point{Latitude: *b.Site.Latitude, Longitude: *b.Site.Longitude},
+			PlannedStart: b.StartDateTime,
+			PlannedEnd:   b.EndDateTime,
+			Arrival:      b.StartDateTime,
+		}
+		if prev >= 0 {
+			travel := r.durations[prev][index]
+			stop.TravelMinutes = minutes(travel)
+			stop.Distance = math.Round(r.distances[prev][index]*10) / 10
+			stop.Arrival = free.Add(travel)
+			result.TravelMinutes += stop.TravelMinutes
+			result.Distance += r.distances[prev][index]
+		}
+		stop.Late = stop.Arrival.After(stop.PlannedStart)
+		begin := stop.PlannedStart
+		if stop.Late {
+			begin = stop.Arrival
+			result.Feasible = false
+		}
+		free = begin.Add(stop.PlannedEnd.Sub(stop.PlannedStart))
+		result.Stops = append(result.Stops, stop)
+		prev = index
+	}
+	result.Distance = math.Round(result.Distance*10) / 10
+	result.Finish = free
+	if free.After(r.dayEnd) {
+		result.Feasible = false
+	}
+	return result
+}
+
+// at - the time of day ("08:00") on a day, in the day's location
+func at(day time.Time, clock string) time.Time {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return day
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, day.Location())
+}
+
+// minutes - a duration in whole minutes, rounded
+func minutes(d time.Duration) int {
+	return int(math.Round(d.Minutes()))
+}
@@ -0,0 +1,84 @@
+package routing
+
+import "time"
+
+// Order - the order to visit the points of a travel time matrix in to spend the least time
+// travelling, as indexes into it. The route starts at start, or wherever gives the shortest route
+// when start is negative, and does not return. It is built by nearest neighbour and improved by
+// 2-opt, reversing stretches of the route while that shortens it, so it is short but not always the
+// shortest possible.
+func Order(durations [][]time.Duration, start int) []int {
+	if len(durations) == 0 {
+		return nil
+	}
+	starts := []int{start}
+	if start < 0 {
+		starts = make([]int, len(durations))
+		for i := range starts {
+			starts[i] = i
+		}
+	}
+	var best []int
+	var bestCost time.Duration
+	for _, from := range starts {
+		route := twoOpt(durations, nearestNeighbour(durations, from))
+		if cost := Cost(durations, route); best == nil || cost < bestCost {
+			best, bestCost = route, cost
+		}
+	}
+	return best
+}
+
+// Cost - the total travel time of visiting points in the order of route
+func Cost(durations [][]time.Duration, route []int) time.Duration {
+	var total time.Duration
+	for i := 1; i < len(route); i++ {
+		total += durations[route[i-1]][route[i]]
+	}
+	return total
+}
+
+// nearestNeighbour - a route from start that always goes on to the closest point not yet visited
+func nearestNeighbour(durations [][]time.Duration, start int) []int {
+	visited := make([]bool, len(durations))
+	route := []int{start}
+	visited[start] = true
+	for len(route) < len(durations) {
+		from, next := route[len(route)-1], -1
+		for to := range durations {
+			if !visited[to] && (next < 0 || durations[from][to] < durations[from][next]) {
+				next = to
+			}
+		}
+		route = append(route, next)
+		visited[next] = true
+	}
+	return route
+}
+
+// twoOpt - shortens a route by reversing stretches of it until no reversal helps. The first point
+// stays first. Each candidate is costed in full so travel times need not be the same both ways.
+func twoOpt(durations [][]time.Duration, route []int) []int {
+	best := Cost(durations, route)
+	for improved := true; improved; {
+		improved = false
+		for i := 1; i < len(route)-1; i++ {
+			for j := i + 1; j < len(route); j++ {
+				reverse(route, i, j)
+				if cost := Cost(durations, route); cost < best {
+					best, improved = cost, true
+				} else {
+					reverse(route, i, j)
+				}
+			}
+		}
+	}
+	return route
+}
+
+// reverse - reverses route[i:j+1] in place
+func reverse(route []int, i, j int) {
+	for ; i < j; i, j = i+1, j-1 {
+		route[i], route[j] = route[j], route[i]
+	}
+}
@@ -0,0 +1,211 @@
+package routing
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// matrixOf - a travel time matrix from minutes
+func matrixOf(minutes [][]int) [][]time.Duration {
+	durations := make([][]time.Duration, len(minutes))
+	for i, row := range minutes {
+		durations[i] = make([]time.Duration, len(row))
+		for j, m := range row {
+			durations[i][j] = time.Duration(m) * time.Minute
+		}
+	}
+	return durations
+}
+
+// shortest - the least cost of any route from start (any start when negative), by trying them all
+func shortest(durations [][]time.Duration, start int) time.Duration {
+	route := make([]int, len(durations))
+	for i := range route {
+		route[i] = i
+	}
+	best := time.Duration(math.MaxInt64)
+	var permute func(k int)
+	permute = func(k int) {
+		if k == len(route) {
+			if cost := Cost(durations, route); (start < 0 || route[0] == start) && cost < best {
+				best = cost
+			}
+			return
+		}
+		for i := k; i < len(route); i++ {
+			route[k], route[i] = route[i], route[k]
+			permute(k + 1)
+			route[k], route[i] = route[i], route[k]
+		}
+	}
+	permute(0)
+	return best
+}
+
+func TestOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		minutes [][]int
+		start   int
+		want    []int
+	}{
+		{"empty", nil, 0, nil},
+		{"one point", [][]int{{0}}, 0, []int{0}},
+		{
+			name:    "points on a line from one end",
+			minutes: [][]int{{0, 30, 10, 20}, {30, 0, 20, 10}, {10, 20, 0, 10}, {20, 10, 10, 0}},
+			start:   0,
+			want:    []int{0, 2, 3, 1},
+		},
+		{
+			name:    "points on a line from the middle",
+			minutes: [][]int{{0, 30, 10, 20}, {30, 0, 20, 10}, {10, 20, 0, 10}, {20, 10, 10, 0}},
+			start:   2,
+			want:    []int{2, 0, 3, 1},
+		},
+		{
+			name:    "any start picks an end",
+			minutes: [][]int{{0, 30, 10, 20}, {30, 0, 20, 10}, {10, 20, 0, 10}, {20, 10, 10, 0}},
+			start:   -1,
+			want:    []int{0, 2, 3, 1},
+		},
+		{
+			// nearest neighbour goes 0, 1, 2, 3 (1 + 1 + 100), 2-opt reverses the end to 0, 1, 3, 2 (1 + 2 + 2)
+			name:    "2-opt improves on nearest neighbour",
+			minutes: [][]int{{0, 1, 200, 200}, {1, 0, 1, 2}, {5, 1, 0, 100}, {5, 2, 2, 0}},
+			start:   0,
+			want:    []int{0, 1, 3, 2},
+		},
+		{
+			name:    "one-way travel times",
+			minutes: [][]int{{0, 5, 50}, {50, 0, 5}, {5, 50, 0}},
+			start:   0,
+			want:    []int{0, 1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Order(matrixOf(tt.minutes), tt.start)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Order = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Order = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// TestOrderNearShortest - on random points the route visits each point once, from the start asked
+// for, and is never much longer than the shortest possible
+func TestOrderNearShortest(t *testing.T) {
+	random := rand.New(rand.NewSource(47))
+	for n := 2; n <= 7; n++ {
+		for trial := 0; trial < 20; trial++ {
+			points := make([]Point, n)
+			for i := range points {
+				points[i] = Point{Latitude: 53 + random.Float64(), Longitude: -7 + random.Float64()}
+			}
+			durations, _, err := Haversine{Speed: DefaultSpeed, Detour: DefaultDetour}.Travel(points)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, start := range []int{0, -1} {
+				route := Order(durations, start)
+				seen := make(map[int]bool)
+				for _, i := range route {
+					seen[i] = true
+				}
+				if len(route) != n || len(seen) != n || (start >= 0 && route[0] != start) {
+					t.Fatalf("Order(%d points, %d) = %v", n, start, route)
+				}
+				if cost, best := Cost(durations, route), shortest(durations, start); float64(cost) > 1.25*float64(best) {
+					t.Errorf("%d points from %d: route takes %s, shortest %s", n, start, cost, best)
+				}
+			}
+		}
+	}
+}
+
+func TestCost(t *testing.T) {
+	durations := matrixOf([][]int{{0, 5, 50}, {50, 0, 5}, {5, 50, 0}})
+	tests := []struct {
+		route []int
+		want  time.Duration
+	}{
+		{nil, 0},
+		{[]int{1}, 0},
+		{[]int{0, 1, 2}, 10 * time.Minute},
+		{[]int{2, 1, 0}, 100 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := Cost(durations, tt.route); got != tt.want {
+			t.Errorf("Cost(%v) = %s, want %s", tt.route, got, tt.want)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to Point
+		want     float64
+	}{
+		{"same point", Point{53.35, -6.26}, Point{53.35, -6.26}, 0},
+		{"one degree of longitude at the equator", Point{0, 0}, Point{0, 1}, 111.19},
+		{"one degree of latitude", Point{10, 20}, Point{11, 20}, 111.19},
+		{"dublin to cork", Point{53.3498, -6.2603}, Point{51.8985, -8.4756}, 220.0},
+		{"across the antimeridian", Point{0, 179.5}, Point{0, -179.5}, 111.19},
+		{"pole to pole", Point{90, 0}, Point{-90, 0}, math.Pi * earthRadius},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Distance(tt.from, tt.to)
+			if math.Abs(got-tt.want) > 0.1 {
+				t.Errorf("Distance = %.2f km, want %.2f km", got, tt.want)
+			}
+			if back := Distance(tt.to, tt.from); math.Abs(back-got) > 1e-9 {
+				t.Errorf("Distance back = %.2f km, there %.2f km", back, got)
+			}
+		})
+	}
+}
+
+func TestHaversineTravel(t *testing.T) {
+	points := []Point{{0, 0}, {0, 1}}
+	durations, distances, err := Haversine{Speed: 50, Detour: 1.5}.Travel(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if distances[0][0] != 0 || durations[1][1] != 0 {
+		t.Errorf("travel from a point to itself = %.2f km, %s", distances[0][0], durations[1][1])
+	}
+	// 111.19 km in a straight line, 166.8 km by road at 50 km/h
+	if math.Abs(distances[0][1]-166.8) > 0.1 {
+		t.Errorf("distance = %.2f km, want 166.8 km", distances[0][1])
+	}
+	if want := time.Duration(distances[0][1] / 50 * float64(time.Hour)); durations[0][1] != want || durations[1][0] != want {
+		t.Errorf("durations = %s and %s, want %s", durations[0][1], durations[1][0], want)
+	}
+}
+
+func TestPointValid(t *testing.T) {
+	tests := []struct {
+		point Point
+		want  bool
+	}{
+		{Point{53.35, -6.26}, true},
+		{Point{-90, 180}, true},
+		{Point{90.1, 0}, false},
+		{Point{0, -180.1}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.point.Valid(); got != tt.want {
+			t.Errorf("%+v.Valid() = %v, want %v", tt.point, got, tt.want)
+		}
+	}
+}
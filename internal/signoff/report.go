@@ -0,0 +1,259 @@
+package signoff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/checklist"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/Open-FiSE/go-rest-api/internal/inventory"
+	"github.com/Open-FiSE/go-rest-api/internal/pdf"
+)
+
+// page layout, in points
+const (
+	margin          = 50.0
+	lineHeight      = 14.0
+	bodySize        = 10.0
+	labelWidth      = 130.0
+	signatureWidth  = 200.0
+	signatureHeight = 70.0
+)
+
+// Report - the service report the customer signs: the visit, the work done on its job, the parts
+// used and the checklists filled in, with the signer and their signature
+type Report struct {
+	Booking       booking.Booking
+	Job           *customer.Job
+	Parts         []inventory.Usage
+	Checklists    []checklist.Checklist
+	SignerName    string
+	SignedAt      time.Time
+	SignatureHash string
+	Signature     captured
+}
+
+// layout - writes a report top to bottom, starting new pages as it fills them
+type layout struct {
+	doc     *pdf.Document
+	page    *pdf.Page
+	y       float64
+	company string
+	title   string
+}
+
+// Render - lays the report out as a PDF document
+func (r Report) Render(company string) *pdf.Document {
+	title := fmt.Sprintf("Service Report - Booking %d", r.Booking.ID)
+	l := &layout{doc: pdf.New(title), company: company, title: title}
+	l.newPage()
+	b := r.Booking.InZone(r.Booking.Zone())
+
+	l.section("Visit")
+	l.field("Summary", b.Summary)
+	if c := b.Customer; c != nil {
+		l.field("Customer", c.Name)
+	}
+	if site := b.Site; site != nil {
+		l.field("Site", site.Name)
+		l.field("Address", site.FullAddress())
+	} else {
+		l.field("Location", b.Location)
+	}
+	if contact := b.Contact; contact != nil {
+		l.field("Contact", contact.Name)
+	}
+	l.field("Date", b.StartDateTime.Format("Monday 2 January 2006"))
+	l.field("Time", b.StartDateTime.Format("15:04")+" - "+b.EndDateTime.Format("15:04 MST"))
+	var engineers []string
+	for _, e := range b.Engineers {
+		engineers = append(engineers, e.Name)
+	}
+	l.field("Engineers", strings.Join(engineers, ", "))
+	l.field("Notes", b.Description)
+
+	if job := r.Job; job != nil {
+		l.section("Job")
+		l.field("Reference", job.Reference)
+		l.field("Description", job.Description)
+		for _, item := range job.Equipment {
+			l.subheading(strings.TrimSpace(fmt.Sprintf("%s %s %s", item.Manufacturer, item.InstrumentModel, item.SerialNo)))
+			l.field("Work", capitalise(string(item.WorkType)))
+			l.field("Status", capitalise(strings.ReplaceAll(string(item.Status), "_", " ")))
+			if item.TimeSpent > 0 {
+				l.field("Time spent", fmt.Sprintf("%d min", item.TimeSpent))
+			}
+			l.field("Findings", item.Findings)
+		}
+	}
+
+	if len(r.Parts) > 0 {
+		l.section("Parts used")
+		for _, usage := range r.Parts {
+			l.field(partLabel(usage), partLine(usage))
+		}
+	}
+
+	for _, c := range r.Checklists {
+		l.section("Checklist: " + c.Name)
+		for _, item := range c.Items {
+			l.field(item.Label, answer(item))
+		}
+	}
+
+	l.section("Customer sign-off")
+	l.paragraph("I confirm the work described above has been carried out to my satisfaction.")
+	l.y -= lineHeight / 2
+	l.field("Signed by", r.SignerName)
+	l.field("Signed at", r.SignedAt.In(r.Booking.Zone()).Format("2 January 2006 15:04:05 MST"))
+	l.ensure(signatureHeight + lineHeight)
+	l.signature(r.Signature)
+	l.field("Signature SHA-256", r.SignatureHash)
+	return l.doc
+}
+
+// newPage - starts a page with the letterhead and report title
+func (l *layout) newPage() {
+	l.page = l.doc.AddPage()
+	y := pdf.PageHeight - margin
+	l.page.Text(margin, y, pdf.Bold, 16, l.company)
+	y -= 2 * lineHeight
+	l.page.Text(margin, y, pdf.Bold, 18, l.title)
+	y -= lineHeight / 2
+	l.page.Line(margin, y, pdf.PageWidth-margin, y, 1)
+	l.y = y - 1.5*lineHeight
+}
+
+// ensure - starts a new page unless height points fit above the bottom margin
+func (l *layout) ensure(height float64) {
+	if l.y-height < margin {
+		l.newPage()
+	}
+}
+
+func (l *layout) section(title string) {
+	l.ensure(3 * lineHeight)
+	l.y -= lineHeight / 2
+	l.page.Text(margin, l.y, pdf.Bold, 12, title)
+	l.y -= lineHeight * 1.3
+}
+
+func (l *layout) subheading(text string) {
+	if text == "" {
+		return
+	}
+	l.ensure(2 * lineHeight)
+	l.page.Text(margin, l.y, pdf.Bold, bodySize, text)
+	l.y -= lineHeight
+}
+
+func (l *layout) field(label, value string) {
+	if value == "" {
+		return
+	}
+	labels := pdf.Wrap(label+":", pdf.Bold, bodySize, labelWidth-10)
+	values := pdf.Wrap(value, pdf.Regular, bodySize, pdf.PageWidth-2*margin-labelWidth)
+	for i := 0; i < len(labels) || i < len(values); i++ {
+		l.ensure(lineHeight)
+		if i < len(labels) {
+			l.page.Text(margin, l.y, pdf.Bold, bodySize, labels[i])
+		}
+		if i < len(values) {
+			l.page.Text(margin+labelWidth, l.y, pdf.Regular, bodySize, values[i])
+		}
+		l.y -= lineHeight
+	}
+}
+
+func (l *layout) paragraph(text string) {
+	for _, line := range pdf.Wrap(text, pdf.Regular, bodySize, pdf.PageWidth-2*margin) {
+		l.ensure(lineHeight)
+		l.page.Text(margin, l.y, pdf.Regular, bodySize, line)
+		l.y -= lineHeight
+	}
+}
+
+// signature - draws the signature in a box under the signer's details, scaled to fit and keeping
+// its proportions
+func (l *layout) signature(sig captured) {
+	x, y := margin+labelWidth, l.y-signatureHeight
+	l.page.Line(x, y, x+signatureWidth, y, 0.5)
+
+	width, height := sig.width, sig.height
+	if sig.image != nil {
+		bounds := sig.image.Bounds()
+		width, height = float64(bounds.Dx()), float64(bounds.Dy())
+	}
+	scale := signatureWidth / width
+	if signatureHeight/height < scale {
+		scale = signatureHeight / height
+	}
+	if sig.image != nil {
+		l.page.Image(x, y, width*scale, height*scale, sig.image)
+	}
+	for _, stroke := range sig.strokes {
+		points := make([][2]float64, len(stroke))
+		for i, point := range stroke {
+			// pad coordinates run down from the top, the page's run up from the bottom
+			points[i] = [2]float64{x + point[0]*scale, y + (sig.height-point[1])*scale}
+		}
+		l.page.Polyline(points, 1.2)
+	}
+	l.y = y - lineHeight
+}
+
+// answer - the answer given to a checklist item as written on the report
+func answer(item checklist.Item) string {
+	if !item.Answered() {
+		return "Not answered"
+	}
+	switch item.Type {
+	case checklist.FieldYesNo:
+		if item.Yes != nil && *item.Yes {
+			return "Yes"
+		}
+		return "No"
+	case checklist.FieldNumeric:
+		value := strconv.FormatFloat(*item.Number, 'f', -1, 64)
+		if item.Unit != "" {
+			value += " " + item.Unit
+		}
+		if item.Pass != nil && !*item.Pass {
+			value += " (outside limits)"
+		}
+		return value
+	case checklist.FieldPhoto:
+		return fmt.Sprintf("Photo attached (document %d)", *item.PhotoID)
+	}
+	return item.Text
+}
+
+// partLabel - the part number of a usage line, its ID when the part has since left the catalogue
+func partLabel(usage inventory.Usage) string {
+	if usage.Part == nil {
+		return fmt.Sprintf("Part %d", usage.PartID)
+	}
+	return usage.Part.PartNo
+}
+
+// partLine - how many of the part were used, and what it is
+func partLine(usage inventory.Usage) string {
+	if usage.Part == nil {
+		return strconv.Itoa(usage.Quantity)
+	}
+	quantity := strconv.Itoa(usage.Quantity)
+	if unit := usage.Part.Unit; unit != "" && unit != "each" {
+		quantity += " " + unit
+	}
+	return quantity + " x " + usage.Part.Name
+}
+
+func capitalise(text string) string {
+	if text == "" {
+		return text
+	}
+	return strings.ToUpper(text[:1]) + text[1:]
+}
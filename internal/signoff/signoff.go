@@ -0,0 +1,240 @@
+package signoff
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	// image formats a signature may be captured in
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/checklist"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/Open-FiSE/go-rest-api/internal/document"
+	"github.com/Open-FiSE/go-rest-api/internal/inventory"
+)
+
+// maxSignatureSize - the largest signature image accepted, in bytes
+const maxSignatureSize = 1 << 20
+
+// errors returned by the sign-off service
+var (
+	ErrInvalidSignerName = errors.New("sign-off SignerName is required")
+	ErrInvalidSignature  = errors.New("signature must be a PNG or JPEG data URI or a list of strokes")
+)
+
+// Signature - the customer's signature, either an image captured as a data URI
+// ("data:image/png;base64,...") or the strokes drawn on a signature pad. Each stroke is a list of
+// [x, y] points with y running down a pad Width by Height in size.
+type Signature struct {
+	Image   string         `json:"image"`
+	Strokes [][][2]float64 `json:"strokes"`
+	Width   float64        `json:"width"`
+	Height  float64        `json:"height"`
+}
+
+// Request - a customer signing off a booking, and the engineer completing it
+type Request struct {
+	SignerName  string    `json:"signerName"`
+	CompletedBy string    `json:"completedBy"`
+	Signature   Signature `json:"signature"`
+}
+
+// Service - the struct for the sign-off service, which completes bookings with the customer's
+// signature and files the signed service report
+type Service struct {
+	Bookings   *booking.BookService
+	Customers  *customer.Service
+	Checklists *checklist.Service
+	Inventory  *inventory.Service
+	Documents  *document.Service
+	// Dir - the directory signatures and signed reports are written to
+	Dir     string
+	Company string
+}
+
+// SignOffService - the interface for our sign-off service
+type SignOffService interface {
+	SignOff(bookingID uint, request Request) (booking.SignOff, error)
+}
+
+// NewService - takes in the services a sign-off draws on and the directory to write documents to
+// & returns a pointer to a new sign-off service
+func NewService(bookings *booking.BookService, customers *customer.Service, checklists *checklist.Service,
+	inventory *inventory.Service, documents *document.Service, dir string) *Service {
+	return &Service{
+		Bookings:   bookings,
+		Customers:  customers,
+		Checklists: checklists,
+		Inventory:  inventory,
+		Documents:  documents,
+		Dir:        dir,
+		Company:    "Open-FiSE Field Service",
+	}
+}
+
+// SignOff - completes a booking with the customer's signature. The signature and a service report
+// of the visit, signed and timestamped, are filed as documents on the booking, the report's hash is
+// recorded with the sign-off, and the booking and its job's checklists can no longer be changed.
+func (s *Service) SignOff(bookingID uint, request Request) (booking.SignOff, error) {
+	if strings.TrimSpace(request.SignerName) == "" {
+		return booking.SignOff{}, ErrInvalidSignerName
+	}
+	signature, err := decode(request.Signature)
+	if err != nil {
+		return booking.SignOff{}, err
+	}
+
+	b, err := s.Bookings.GetBooking(bookingID)
+	if err != nil {
+		return booking.SignOff{}, err
+	}
+	if b.SignedAt != nil {
+		return booking.SignOff{}, booking.ErrBookingSigned
+	}
+	if !b.Status.CanTransition(booking.StatusCompleted) {
+		return booking.SignOff{}, &booking.TransitionError{From: b.Status, To: booking.StatusCompleted}
+	}
+	report := Report{Booking: b, SignerName: request.SignerName, SignedAt: time.Now().UTC(), Signature: signature}
+	if b.JobID != nil {
+		job, err := s.Customers.GetJob(*b.JobID)
+		if err != nil {
+			return booking.SignOff{}, err
+		}
+		report.Job = &job
+		if report.Checklists, err = s.Checklists.GetChecklists(job.ID); err != nil {
+			return booking.SignOff{}, err
+		}
+		if report.Parts, err = s.Inventory.GetUsage(job.ID); err != nil {
+			return booking.SignOff{}, err
+		}
+	}
+
+	if err := os.MkdirAll(s.Dir, os.ModePerm); err != nil {
+		return booking.SignOff{}, err
+	}
+	signatureDoc, err := s.file(b.ID, fmt.Sprintf("booking-%d-signature.%s", b.ID, signature.ext), signature.data, request.SignerName)
+	if err != nil {
+		return booking.SignOff{}, err
+	}
+	report.SignatureHash = signatureDoc.Hash
+	reportDoc, err := s.file(b.ID, fmt.Sprintf("booking-%d-report.pdf", b.ID), report.Render(s.Company).Bytes(), request.CompletedBy)
+	if err != nil {
+		return booking.SignOff{}, err
+	}
+
+	signOff := booking.SignOff{
+		SignerName:          request.SignerName,
+		SignedAt:            report.SignedAt,
+		SignatureDocumentID: signatureDoc.ID,
+		ReportDocumentID:    reportDoc.ID,
+		ReportHash:          reportDoc.Hash,
+	}
+	if _, err := s.Bookings.SignOffBooking(b.ID, signOff, request.CompletedBy); err != nil {
+		return booking.SignOff{}, err
+	}
+	if b.JobID != nil {
+		if err := s.Checklists.LockJob(*b.JobID); err != nil {
+			return booking.SignOff{}, err
+		}
+	}
+	return s.Bookings.GetSignOff(b.ID)
+}
+
+// file - writes a document for the booking to the sign-off directory and records it with its hash
+func (s *Service) file(bookingID uint, title string, data []byte, author string) (document.Document, error) {
+	path := filepath.Join(s.Dir, title)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return document.Document{}, err
+	}
+	sum := sha256.Sum256(data)
+	return s.Documents.PostDocument(document.Document{
+		Path:      path,
+		Title:     title,
+		Version:   1.0,
+		Author:    author,
+		Hash:      hex.EncodeToString(sum[:]),
+		BookingID: &bookingID,
+	})
+}
+
+// captured - a decoded signature: the file it is stored as, and what is drawn on the report
+type captured struct {
+	data    []byte
+	ext     string
+	image   image.Image
+	strokes [][][2]float64
+	width   float64
+	height  float64
+}
+
+// decode - checks a signature and works out the file it is stored as, an image as it was sent and
+// strokes as SVG
+func decode(signature Signature) (captured, error) {
+	if signature.Image != "" {
+		parts := strings.SplitN(signature.Image, ",", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], "data:image/") || !strings.HasSuffix(parts[0], ";base64") {
+			return captured{}, ErrInvalidSignature
+		}
+		data, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil || len(data) > maxSignatureSize {
+			return captured{}, ErrInvalidSignature
+		}
+		img, format, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return captured{}, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+		ext := format
+		if format == "jpeg" {
+			ext = "jpg"
+		}
+		return captured{data: data, ext: ext, image: img}, nil
+	}
+
+	points := 0
+	for _, stroke := range signature.Strokes {
+		points += len(stroke)
+	}
+	if points == 0 {
+		return captured{}, ErrInvalidSignature
+	}
+	sig := captured{ext: "svg", strokes: signature.Strokes, width: signature.Width, height: signature.Height}
+	// a pad with no size given is taken to be as large as the signature drawn on it
+	if sig.width <= 0 || sig.height <= 0 {
+		for _, stroke := range signature.Strokes {
+			for _, point := range stroke {
+				sig.width, sig.height = math.Max(sig.width, point[0]), math.Max(sig.height, point[1])
+			}
+		}
+		sig.width, sig.height = math.Max(sig.width, 1), math.Max(sig.height, 1)
+	}
+
+	var svg bytes.Buffer
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %g %g" width="%g" height="%g">`+"\n",
+		sig.width, sig.height, sig.width, sig.height)
+	for _, stroke := range signature.Strokes {
+		if len(stroke) == 0 {
+			continue
+		}
+		coords := make([]string, len(stroke))
+		for i, point := range stroke {
+			coords[i] = fmt.Sprintf("%g,%g", point[0], point[1])
+		}
+		fmt.Fprintf(&svg, `<polyline points="%s" fill="none" stroke="black" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"/>`+"\n",
+			strings.Join(coords, " "))
+	}
+	svg.WriteString("</svg>\n")
+	sig.data = svg.Bytes()
+	return sig, nil
+}
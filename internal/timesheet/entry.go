@@ -0,0 +1,268 @@
+package timesheet
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/engineer"
+	"github.com/jinzhu/gorm"
+)
+
+// EntryType - what an engineer's time was spent on
+type EntryType string
+
+// time entry types
+const (
+	EntryTravel EntryType = "travel"
+	EntryWork   EntryType = "work"
+	EntryWait   EntryType = "wait"
+)
+
+// errors returned by the timesheet service
+var (
+	ErrInvalidEntry        = errors.New("time entry needs an EngineerID, a Type of travel, work or wait, and an EndedAt after its StartedAt")
+	ErrEngineerNotAssigned = errors.New("engineer is not assigned to the booking")
+	ErrTimerRunning        = errors.New("engineer already has a timer running")
+	ErrNoTimerRunning      = errors.New("engineer has no timer running on the booking")
+	ErrTimesheetLocked     = errors.New("timesheet has been submitted or approved and can no longer be changed")
+	ErrInvalidWeek         = errors.New("week must be a date formatted as 2006-01-02")
+	ErrInvalidReview       = errors.New("timesheet can only be approved or rejected once submitted, by a named supervisor")
+)
+
+// Service - the struct for the timesheet service, covering the time engineers record against
+// bookings and the weekly timesheets it is approved on
+type Service struct {
+	DB        *gorm.DB
+	Bookings  *booking.BookService
+	Engineers *engineer.Service
+}
+
+// TimesheetService - the interface for our timesheet service
+type TimesheetService interface {
+	GetEntries(bookingID uint) ([]Entry, error)
+	StartTimer(bookingID uint, entry Entry) (Entry, error)
+	StopTimer(bookingID uint, engineerID uint) (Entry, error)
+	PostEntry(bookingID uint, entry Entry) (Entry, error)
+	UpdateEntry(bookingID uint, ID uint, newEntry Entry) (Entry, error)
+	DeleteEntry(bookingID uint, ID uint) error
+	GetTimesheet(engineerID uint, week string) (Timesheet, error)
+	GetTimesheets(status Status) ([]Timesheet, error)
+	SubmitTimesheet(engineerID uint, week string) (Timesheet, error)
+	ApproveTimesheet(ID uint, approvedBy string) (Timesheet, error)
+	RejectTimesheet(ID uint, rejectedBy string, reason string) (Timesheet, error)
+	GetApprovedEntries(bookingID uint) ([]Entry, error)
+}
+
+// NewService - takes in a pointer to the DB and the booking and engineer services & returns a pointer
+// to a new timesheet service
+func NewService(db *gorm.DB, bookings *booking.BookService, engineers *engineer.Service) *Service {
+	return &Service{
+		DB:        db,
+		Bookings:  bookings,
+		Engineers: engineers,
+	}
+}
+
+// Entry - time an engineer spent travelling to, working on or waiting at a booking. An entry started
+// with a timer has no EndedAt until it is stopped; Minutes is worked out once it has one. Each entry
+// falls on the timesheet for the week it started in.
+type Entry struct {
+	gorm.Model
+	BookingID   uint       `json:"bookingId"`
+	EngineerID  uint       `json:"engineerId"`
+	TimesheetID uint       `json:"timesheetId"`
+	Type        EntryType  `json:"type"`
+	StartedAt   time.Time  `json:"startedAt"`
+	EndedAt     *time.Time `json:"endedAt"`
+	Minutes     int        `json:"minutes"`
+	Notes       string     `json:"notes"`
+}
+
+// TableName - the table time entries are stored in
+func (Entry) TableName() string {
+	return "time_entries"
+}
+
+// Running - whether the entry's timer is still running
+func (e Entry) Running() bool {
+	return e.EndedAt == nil
+}
+
+// GetEntries - retrieves the time recorded against a booking, in the order it started
+func (s *Service) GetEntries(bookingID uint) ([]Entry, error) {
+	var entries []Entry
+	if result := s.DB.Where("booking_id = ?", bookingID).Order("started_at, id").Find(&entries); result.Error != nil {
+		return entries, result.Error
+	}
+	return entries, nil
+}
+
+// StartTimer - starts timing an engineer's travel, work or wait on a booking from now. An engineer
+// only has one timer running at a time.
+func (s *Service) StartTimer(bookingID uint, entry Entry) (Entry, error) {
+	entry.StartedAt, entry.EndedAt = time.Now().UTC().Truncate(time.Second), nil
+	if err := s.validateEntry(bookingID, entry); err != nil {
+		return Entry{}, err
+	}
+	var count int
+	if result := s.DB.Model(&Entry{}).Where("engineer_id = ? AND ended_at IS NULL", entry.EngineerID).Count(&count); result.Error != nil {
+		return Entry{}, result.Error
+	}
+	if count > 0 {
+		return Entry{}, ErrTimerRunning
+	}
+	return s.save(bookingID, entry)
+}
+
+// StopTimer - stops the engineer's timer running on a booking now
+func (s *Service) StopTimer(bookingID uint, engineerID uint) (Entry, error) {
+	var entry Entry
+	result := s.DB.Where("booking_id = ? AND engineer_id = ? AND ended_at IS NULL", bookingID, engineerID).First(&entry)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return Entry{}, ErrNoTimerRunning
+	} else if result.Error != nil {
+		return Entry{}, result.Error
+	}
+	end := time.Now().UTC().Truncate(time.Second)
+	entry.EndedAt = &end
+	entry.Minutes = minutes(entry.StartedAt, end)
+	if result := s.DB.Save(&entry); result.Error != nil {
+		return Entry{}, result.Error
+	}
+	return entry, nil
+}
+
+// PostEntry - records time entered by hand, with both its StartedAt and EndedAt
+func (s *Service) PostEntry(bookingID uint, entry Entry) (Entry, error) {
+	if entry.EndedAt == nil {
+		return Entry{}, ErrInvalidEntry
+	}
+	if err := s.validateEntry(bookingID, entry); err != nil {
+		return Entry{}, err
+	}
+	entry.Minutes = minutes(entry.StartedAt, *entry.EndedAt)
+	return s.save(bookingID, entry)
+}
+
+// UpdateEntry - corrects a time entry, while its timesheet is still open
+func (s *Service) UpdateEntry(bookingID uint, ID uint, newEntry Entry) (Entry, error) {
+	entry, err := s.getEntry(bookingID, ID)
+	if err != nil {
+		return Entry{}, err
+	}
+	if err := s.checkOpen(entry.TimesheetID); err != nil {
+		return Entry{}, err
+	}
+	if newEntry.Type != "" {
+		entry.Type = newEntry.Type
+	}
+	if !newEntry.StartedAt.IsZero() {
+		entry.StartedAt = newEntry.StartedAt
+	}
+	if newEntry.EndedAt != nil {
+		entry.EndedAt = newEntry.EndedAt
+	}
+	if newEntry.Notes != "" {
+		entry.Notes = newEntry.Notes
+	}
+	if err := s.validateEntry(bookingID, entry); err != nil {
+		return Entry{}, err
+	}
+	if entry.EndedAt != nil {
+		entry.Minutes = minutes(entry.StartedAt, *entry.EndedAt)
+	}
+	// a start moved into another week moves the entry onto that week's timesheet
+	sheet, err := s.timesheetFor(entry.EngineerID, entry.StartedAt)
+	if err != nil {
+		return Entry{}, err
+	}
+	if !sheet.Editable() {
+		return Entry{}, ErrTimesheetLocked
+	}
+	entry.TimesheetID = sheet.ID
+	if result := s.DB.Save(&entry); result.Error != nil {
+		return Entry{}, result.Error
+	}
+	return entry, nil
+}
+
+// DeleteEntry - removes a time entry recorded in error, while its timesheet is still open
+func (s *Service) DeleteEntry(bookingID uint, ID uint) error {
+	entry, err := s.getEntry(bookingID, ID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkOpen(entry.TimesheetID); err != nil {
+		return err
+	}
+	if result := s.DB.Delete(&entry); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// GetApprovedEntries - retrieves the time on a booking from approved timesheets, the time that is
+// billed
+func (s *Service) GetApprovedEntries(bookingID uint) ([]Entry, error) {
+	var entries []Entry
+	if result := s.DB.Where("booking_id = ? AND ended_at IS NOT NULL AND timesheet_id IN (SELECT id FROM timesheets WHERE status = ? AND deleted_at IS NULL)",
+		bookingID, StatusApproved).Order("started_at, id").Find(&entries); result.Error != nil {
+		return entries, result.Error
+	}
+	return entries, nil
+}
+
+func (s *Service) getEntry(bookingID uint, ID uint) (Entry, error) {
+	var entry Entry
+	if result := s.DB.Where("booking_id = ?", bookingID).First(&entry, ID); result.Error != nil {
+		return Entry{}, result.Error
+	}
+	return entry, nil
+}
+
+// save - files a new entry on the engineer's timesheet for the week it starts in
+func (s *Service) save(bookingID uint, entry Entry) (Entry, error) {
+	sheet, err := s.timesheetFor(entry.EngineerID, entry.StartedAt)
+	if err != nil {
+		return Entry{}, err
+	}
+	if !sheet.Editable() {
+		return Entry{}, ErrTimesheetLocked
+	}
+	entry.Model = gorm.Model{}
+	entry.BookingID = bookingID
+	entry.TimesheetID = sheet.ID
+	if result := s.DB.Save(&entry); result.Error != nil {
+		return Entry{}, result.Error
+	}
+	return entry, nil
+}
+
+// validateEntry - checks an entry's type and times, and that its engineer is assigned to the booking
+func (s *Service) validateEntry(bookingID uint, entry Entry) error {
+	switch entry.Type {
+	case EntryTravel, EntryWork, EntryWait:
+	default:
+		return fmt.Errorf("%w: unknown Type %q", ErrInvalidEntry, entry.Type)
+	}
+	if entry.EngineerID == 0 || entry.StartedAt.IsZero() || (entry.EndedAt != nil && !entry.EndedAt.After(entry.StartedAt)) {
+		return ErrInvalidEntry
+	}
+	b, err := s.Bookings.GetBooking(bookingID)
+	if err != nil {
+		return err
+	}
+	for _, e := range b.Engineers {
+		if e.ID == entry.EngineerID {
+			return nil
+		}
+	}
+	return ErrEngineerNotAssigned
+}
+
+// minutes - the whole minutes from start to end, to the nearest minute
+func minutes(start, end time.Time) int {
+	return int(end.Sub(start).Round(time.Minute) / time.Minute)
+}
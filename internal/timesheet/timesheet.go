@@ -0,0 +1,188 @@
+package timesheet
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Status - where a weekly timesheet is in its approval
+type Status string
+
+// timesheet statuses. Entries can only change while their timesheet is open, or once it has been
+// rejected back to the engineer.
+const (
+	StatusOpen      Status = "open"
+	StatusSubmitted Status = "submitted"
+	StatusApproved  Status = "approved"
+	StatusRejected  Status = "rejected"
+)
+
+// weekLayout - how the Monday a timesheet starts on is written
+const weekLayout = "2006-01-02"
+
+// Timesheet - an engineer's time for a week, Monday to Sunday in the engineer's time zone. Once
+// submitted it is approved or rejected by a supervisor, and only approved time is billed.
+type Timesheet struct {
+	gorm.Model
+	EngineerID  uint       `gorm:"unique_index:idx_timesheet_engineer_week" json:"engineerId"`
+	WeekStart   string     `gorm:"unique_index:idx_timesheet_engineer_week" json:"weekStart"`
+	Status      Status     `gorm:"default:'open';index" json:"status"`
+	SubmittedAt *time.Time `json:"submittedAt"`
+	ReviewedBy  string     `json:"reviewedBy"`
+	ReviewedAt  *time.Time `json:"reviewedAt"`
+	// why a rejected timesheet was sent back
+	Reason  string  `json:"reason"`
+	Entries []Entry `json:"entries"`
+	// minutes of each type of time over the week
+	Totals map[EntryType]int `gorm:"-" json:"totals"`
+}
+
+// Editable - whether entries on the timesheet can still change
+func (t Timesheet) Editable() bool {
+	return t.Status == StatusOpen || t.Status == StatusRejected || t.Status == ""
+}
+
+// GetTimesheet - retrieves an engineer's timesheet for the week containing the given date, empty
+// and open when no time has been recorded that week
+func (s *Service) GetTimesheet(engineerID uint, week string) (Timesheet, error) {
+	day, err := time.Parse(weekLayout, week)
+	if err != nil {
+		return Timesheet{}, ErrInvalidWeek
+	}
+	var sheet Timesheet
+	result := s.DB.Preload("Entries", byStart).Where("engineer_id = ? AND week_start = ?", engineerID, monday(day)).First(&sheet)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		if _, err := s.Engineers.GetEngineer(engineerID); err != nil {
+			return Timesheet{}, err
+		}
+		sheet = Timesheet{EngineerID: engineerID, WeekStart: monday(day), Status: StatusOpen}
+	} else if result.Error != nil {
+		return Timesheet{}, result.Error
+	}
+	sheet.total()
+	return sheet, nil
+}
+
+// GetTimesheets - retrieves the timesheets in a status, such as those submitted for approval, oldest
+// week first
+func (s *Service) GetTimesheets(status Status) ([]Timesheet, error) {
+	var sheets []Timesheet
+	if result := s.DB.Preload("Entries", byStart).Where("status = ?", status).Order("week_start, engineer_id").
+		Find(&sheets); result.Error != nil {
+		return sheets, result.Error
+	}
+	for i := range sheets {
+		sheets[i].total()
+	}
+	return sheets, nil
+}
+
+// SubmitTimesheet - submits an engineer's week for approval. Every timer that week must have been
+// stopped first.
+func (s *Service) SubmitTimesheet(engineerID uint, week string) (Timesheet, error) {
+	sheet, err := s.GetTimesheet(engineerID, week)
+	if err != nil {
+		return Timesheet{}, err
+	}
+	if !sheet.Editable() {
+		return Timesheet{}, ErrTimesheetLocked
+	}
+	for _, entry := range sheet.Entries {
+		if entry.Running() {
+			return Timesheet{}, ErrTimerRunning
+		}
+	}
+	now := time.Now().UTC()
+	sheet.Status, sheet.SubmittedAt = StatusSubmitted, &now
+	sheet.Entries = nil
+	if result := s.DB.Save(&sheet); result.Error != nil {
+		return Timesheet{}, result.Error
+	}
+	return s.GetTimesheet(engineerID, sheet.WeekStart)
+}
+
+// ApproveTimesheet - approves a submitted timesheet, after which its time is billed
+func (s *Service) ApproveTimesheet(ID uint, approvedBy string) (Timesheet, error) {
+	return s.review(ID, StatusApproved, approvedBy, "")
+}
+
+// RejectTimesheet - sends a submitted timesheet back to the engineer to correct
+func (s *Service) RejectTimesheet(ID uint, rejectedBy string, reason string) (Timesheet, error) {
+	return s.review(ID, StatusRejected, rejectedBy, reason)
+}
+
+// review - records a supervisor's decision on a submitted timesheet
+func (s *Service) review(ID uint, status Status, reviewedBy string, reason string) (Timesheet, error) {
+	if strings.TrimSpace(reviewedBy) == "" {
+		return Timesheet{}, ErrInvalidReview
+	}
+	now := time.Now().UTC()
+	// only a timesheet still awaiting review can be decided, so two supervisors cannot both decide it
+	result := s.DB.Model(&Timesheet{}).Where("id = ? AND status = ?", ID, StatusSubmitted).
+		Updates(map[string]interface{}{"status": status, "reviewed_by": reviewedBy, "reviewed_at": now, "reason": reason})
+	if result.Error != nil {
+		return Timesheet{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if err := s.DB.First(&Timesheet{}, ID).Error; err != nil {
+			return Timesheet{}, err
+		}
+		return Timesheet{}, ErrInvalidReview
+	}
+	var sheet Timesheet
+	if result := s.DB.Preload("Entries", byStart).First(&sheet, ID); result.Error != nil {
+		return Timesheet{}, result.Error
+	}
+	sheet.total()
+	return sheet, nil
+}
+
+// timesheetFor - finds or opens the engineer's timesheet for the week containing at, in the
+// engineer's time zone
+func (s *Service) timesheetFor(engineerID uint, at time.Time) (Timesheet, error) {
+	e, err := s.Engineers.GetEngineer(engineerID)
+	if err != nil {
+		return Timesheet{}, err
+	}
+	_, _, _, loc := e.WorkingHours()
+	var sheet Timesheet
+	result := s.DB.Where(Timesheet{EngineerID: engineerID, WeekStart: monday(at.In(loc))}).
+		Attrs(Timesheet{Status: StatusOpen}).FirstOrCreate(&sheet)
+	if result.Error != nil {
+		return Timesheet{}, result.Error
+	}
+	return sheet, nil
+}
+
+// checkOpen - returns ErrTimesheetLocked unless entries on the timesheet can still change
+func (s *Service) checkOpen(timesheetID uint) error {
+	var sheet Timesheet
+	if result := s.DB.First(&sheet, timesheetID); result.Error != nil {
+		return result.Error
+	}
+	if !sheet.Editable() {
+		return ErrTimesheetLocked
+	}
+	return nil
+}
+
+// total - adds up the minutes of each type of time on the timesheet
+func (t *Timesheet) total() {
+	t.Totals = map[EntryType]int{EntryTravel: 0, EntryWork: 0, EntryWait: 0}
+	for _, entry := range t.Entries {
+		t.Totals[entry.Type] += entry.Minutes
+	}
+}
+
+// monday - the Monday of the week containing day, as a date
+func monday(day time.Time) string {
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset).Format(weekLayout)
+}
+
+// byStart - orders preloaded entries by when they started
+func byStart(db *gorm.DB) *gorm.DB {
+	return db.Order("started_at, id")
+}
@@ -0,0 +1,81 @@
+package http
+
+// Define the endpoint for finding free engineer time.
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/availability"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetAvailability - find free slots, e.g.
+// /availability?from=2026-10-19T00:00:00Z&to=2026-10-24T00:00:00Z&duration=3h&buffer=30m&skill=calibration&manufacturer=Agilent&engineer=1,2
+func (h *Handler) GetAvailability(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	query, err := parseAvailabilityQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slots, err := h.AvailabilityService.FreeSlots(query)
+	if err != nil {
+		if errors.Is(err, availability.ErrInvalidQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Engineer not found", http.StatusNotFound)
+			return
+		}
+		log.Error(err)
+		http.Error(w, "Failed to retrieve availability", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(slots); err != nil {
+		log.Warning(err)
+	}
+}
+
+func parseAvailabilityQuery(r *http.Request) (availability.Query, error) {
+	values := r.URL.Query()
+	query := availability.Query{
+		Skill:        values.Get("skill"),
+		Manufacturer: values.Get("manufacturer"),
+	}
+
+	from, to, err := parseWindow(r)
+	if err != nil {
+		return query, err
+	}
+	query.From, query.To = from, to
+
+	if query.Duration, err = time.ParseDuration(values.Get("duration")); err != nil {
+		return query, errors.New("unable to parse duration, expected a value such as 90m or 2h")
+	}
+	if buffer := values.Get("buffer"); buffer != "" {
+		if query.TravelBuffer, err = time.ParseDuration(buffer); err != nil {
+			return query, errors.New("unable to parse buffer, expected a value such as 30m")
+		}
+	}
+	if engineers := values.Get("engineer"); engineers != "" {
+		for _, id := range strings.Split(engineers, ",") {
+			engineerID, err := strconv.ParseUint(strings.TrimSpace(id), 10, 64)
+			if err != nil {
+				return query, errors.New("unable to parse UINT from engineer")
+			}
+			query.EngineerIDs = append(query.EngineerIDs, uint(engineerID))
+		}
+	}
+	return query, nil
+}
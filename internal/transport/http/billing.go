@@ -0,0 +1,404 @@
+package http
+
+// Define endpoints for the price list, and the quotes and invoices issued for bookings.
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Open-FiSE/go-rest-api/internal/billing"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/Open-FiSE/go-rest-api/internal/timesheet"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+// IssueRequest - the body of a request issuing a quote or invoice for a booking
+type IssueRequest struct {
+	IssuedBy string `json:"issuedBy"`
+}
+
+// VoidRequest - the body of a request voiding a quote or invoice
+type VoidRequest struct {
+	VoidedBy string `json:"voidedBy"`
+	Reason   string `json:"reason"`
+}
+
+// GetRates - fetch the price list: labour rates, fixed work prices and tax rules
+func (h *Handler) GetRates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	rates, err := h.BillingService.GetRates()
+	if err != nil {
+		writeBillingError(w, err, "Failed to retrieve price list")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(rates); err != nil {
+		log.Warning(err)
+	}
+}
+
+// SetLabourRate - sets the hourly rate charged for the {type} of time
+func (h *Handler) SetLabourRate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var rate billing.LabourRate
+	if err := json.NewDecoder(r.Body).Decode(&rate); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+	rate.Type = timesheet.EntryType(mux.Vars(r)["type"])
+
+	rate, err := h.BillingService.SetLabourRate(rate)
+	if err != nil {
+		writeBillingError(w, err, "Failed to set labour rate")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(rate); err != nil {
+		log.Warning(err)
+	}
+}
+
+// SetWorkPrice - sets the fixed price charged for each completed item of the {workType}
+func (h *Handler) SetWorkPrice(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var price billing.WorkPrice
+	if err := json.NewDecoder(r.Body).Decode(&price); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+	price.WorkType = customer.WorkType(mux.Vars(r)["workType"])
+
+	price, err := h.BillingService.SetWorkPrice(price)
+	if err != nil {
+		writeBillingError(w, err, "Failed to set work price")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(price); err != nil {
+		log.Warning(err)
+	}
+}
+
+// SetTaxRule - sets the tax rule for the kind of line and customer given in the body
+func (h *Handler) SetTaxRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var rule billing.TaxRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.BillingService.SetTaxRule(rule)
+	if err != nil {
+		writeBillingError(w, err, "Failed to set tax rule")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(rule); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeleteTaxRule - removes a tax rule by ID
+func (h *Handler) DeleteTaxRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	ruleID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.BillingService.DeleteTaxRule(uint(ruleID)); err != nil {
+		writeBillingError(w, err, "Failed to delete tax rule")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully deleted tax rule"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetSequences - fetch the quote and invoice numbering sequences
+func (h *Handler) GetSequences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	sequences, err := h.BillingService.GetSequences()
+	if err != nil {
+		writeBillingError(w, err, "Failed to retrieve sequences")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(sequences); err != nil {
+		log.Warning(err)
+	}
+}
+
+// SetSequence - changes the prefix, padding or next number of the {kind} sequence
+func (h *Handler) SetSequence(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var sequence billing.Sequence
+	if err := json.NewDecoder(r.Body).Decode(&sequence); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	sequence, err := h.BillingService.SetSequence(billing.Kind(mux.Vars(r)["kind"]), sequence)
+	if err != nil {
+		writeBillingError(w, err, "Failed to set sequence")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(sequence); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetBookingInvoices - fetch the quotes and invoices issued for a booking
+func (h *Handler) GetBookingInvoices(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	bookingID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	invoices, err := h.BillingService.GetBookingInvoices(uint(bookingID))
+	if err != nil {
+		writeBillingError(w, err, "Failed to retrieve invoices")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(invoices); err != nil {
+		log.Warning(err)
+	}
+}
+
+// QuoteBooking - issues a quote for the work recorded on a booking so far
+func (h *Handler) QuoteBooking(w http.ResponseWriter, r *http.Request) {
+	h.issueInvoice(w, r, billing.KindQuote)
+}
+
+// InvoiceBooking - issues an invoice for a completed booking
+func (h *Handler) InvoiceBooking(w http.ResponseWriter, r *http.Request) {
+	h.issueInvoice(w, r, billing.KindInvoice)
+}
+
+func (h *Handler) issueInvoice(w http.ResponseWriter, r *http.Request, kind billing.Kind) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var request IssueRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	bookingID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	var invoice billing.Invoice
+	if kind == billing.KindQuote {
+		invoice, err = h.BillingService.Quote(uint(bookingID), request.IssuedBy)
+	} else {
+		invoice, err = h.BillingService.Invoice(uint(bookingID), request.IssuedBy)
+	}
+	if err != nil {
+		writeBillingError(w, err, "Failed to issue "+string(kind))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(invoice); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetInvoices - fetch quotes and invoices, optionally only those of a ?kind= or for a ?customerId=
+func (h *Handler) GetInvoices(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var customerID uint64
+	if value := r.URL.Query().Get("customerId"); value != "" {
+		var err error
+		if customerID, err = strconv.ParseUint(value, 10, 64); err != nil {
+			http.Error(w, "Unable to parse UINT from customerId", http.StatusBadRequest)
+			return
+		}
+	}
+
+	invoices, err := h.BillingService.GetInvoices(billing.Kind(r.URL.Query().Get("kind")), uint(customerID))
+	if err != nil {
+		writeBillingError(w, err, "Failed to retrieve invoices")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(invoices); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetInvoice - fetch a quote or invoice and its lines by ID
+func (h *Handler) GetInvoice(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	invoiceID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	invoice, err := h.BillingService.GetInvoice(uint(invoiceID))
+	if err != nil {
+		writeBillingError(w, err, "Failed to retrieve invoice")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(invoice); err != nil {
+		log.Warning(err)
+	}
+}
+
+// VoidInvoice - withdraws an issued quote or invoice
+func (h *Handler) VoidInvoice(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var request VoidRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	invoiceID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	invoice, err := h.BillingService.Void(uint(invoiceID), request.VoidedBy, request.Reason)
+	if err != nil {
+		writeBillingError(w, err, "Failed to void invoice")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(invoice); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetInvoiceUBL - fetch an invoice as a UBL 2.1 XML document
+func (h *Handler) GetInvoiceUBL(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+
+	invoiceID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	invoice, err := h.BillingService.GetInvoice(uint(invoiceID))
+	if err != nil {
+		writeBillingError(w, err, "Failed to retrieve invoice")
+		return
+	}
+	data, err := billing.UBL(invoice, h.BillingService.Seller)
+	if err != nil {
+		writeBillingError(w, err, "Failed to export invoice")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(invoice.Number+".xml"))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		log.Warning(err)
+	}
+}
+
+// ExportInvoices - fetch the quotes or invoices (?kind=, invoices by default) issued in a ?from=&to=
+// window as CSV, one row per line
+func (h *Handler) ExportInvoices(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+
+	from, to, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if from.IsZero() || to.IsZero() {
+		http.Error(w, "from and to are required", http.StatusBadRequest)
+		return
+	}
+	kind := billing.KindInvoice
+	if value := r.URL.Query().Get("kind"); value != "" {
+		kind = billing.Kind(value)
+	}
+
+	invoices, err := h.BillingService.GetIssued(kind, from, to)
+	if err != nil {
+		writeBillingError(w, err, "Failed to retrieve invoices")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(string(kind)+"s.csv"))
+	w.WriteHeader(http.StatusOK)
+	if err := billing.WriteCSV(w, invoices); err != nil {
+		log.Warning(err)
+	}
+}
+
+// writeBillingError - maps billing service errors onto HTTP responses
+func writeBillingError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, billing.ErrInvalidIssuedBy), errors.Is(err, billing.ErrNoCustomer),
+		errors.Is(err, billing.ErrInvalidVoid), errors.Is(err, billing.ErrNotInvoice),
+		errors.Is(err, billing.ErrInvalidLabourRate), errors.Is(err, billing.ErrInvalidWorkPrice),
+		errors.Is(err, billing.ErrInvalidTaxRule), errors.Is(err, billing.ErrInvalidSequence):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, billing.ErrNotCompleted), errors.Is(err, billing.ErrAlreadyInvoiced),
+		errors.Is(err, billing.ErrNothingToBill), errors.Is(err, billing.ErrMissingRate):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	log.Error(err)
+	http.Error(w, message, http.StatusInternalServerError)
+}
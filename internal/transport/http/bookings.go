@@ -3,52 +3,83 @@ package http
 // Define endpoints and map them to the booking service.
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/recurrence"
 	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
 	log "github.com/sirupsen/logrus"
 )
 
+// GetBooking - retrieve a single booking by ID, in a local time zone with ?tz=site or ?tz=<IANA zone>
 func (h *Handler) GetBooking(w http.ResponseWriter, r *http.Request) {
 	// retrieve the ID of the booking you want to fetch
 	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
 	enableCors(&w)
-	w.WriteHeader(http.StatusOK)
 
 	vars := mux.Vars(r)
 	id := vars["id"]
 
 	i, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		fmt.Fprintf(w, "Unable to parse UINT from ID")
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+	inZone, err := responseZone(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 	// GetBooking is expecting a uint, so parse string to uint.
 	booking, err := h.BookService.GetBooking(uint(i))
 	if err != nil {
-		fmt.Fprintf(w, "Error retrieving Booking by ID")
+		writeBookingError(w, err, "Error retrieving Booking by ID")
+		return
 	}
 
-	// Return the newly update booking as json
-	if err := json.NewEncoder(w).Encode(booking); err != nil {
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(inZone(booking)); err != nil {
 		log.Warning(err)
 	}
-
 }
 
-// GetAllBookings - fetch all bookings from the booking service
+// GetAllBookings - fetch all bookings from the booking service, optionally filtered by
+// ?status=confirmed,dispatched and shown in a local time zone with ?tz=
 func (h *Handler) GetAllBookings(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
 	enableCors(&w)
-	w.WriteHeader(http.StatusOK)
 
-	bookings, err := h.BookService.GetAllBookings()
+	var statuses []booking.Status
+	if filter := r.URL.Query().Get("status"); filter != "" {
+		for _, value := range strings.Split(filter, ",") {
+			status, err := booking.ParseStatus(strings.TrimSpace(value))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			statuses = append(statuses, status)
+		}
+	}
+
+	inZone, err := responseZone(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bookings, err := h.BookService.GetAllBookings(statuses...)
 	if err != nil {
-		fmt.Fprintf(w, "Failed to retrieve bookings")
+		http.Error(w, "Failed to retrieve bookings", http.StatusInternalServerError)
+		return
 	}
-	if err := json.NewEncoder(w).Encode(bookings); err != nil {
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(inZoneAll(inZone, bookings)); err != nil {
 		log.Warning(err)
 	}
 }
@@ -57,12 +88,12 @@ func (h *Handler) GetAllBookings(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UpdateBooking(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
 	enableCors(&w)
-	w.WriteHeader(http.StatusOK)
 
 	var booking booking.Booking
 	// Parse the request body as booking
 	if err := json.NewDecoder(r.Body).Decode(&booking); err != nil {
-		fmt.Fprintf(w, "Failed to decode JSON Body")
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
 	}
 
 	vars := mux.Vars(r)
@@ -70,14 +101,17 @@ func (h *Handler) UpdateBooking(w http.ResponseWriter, r *http.Request) {
 
 	bookingID, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		fmt.Fprintf(w, "Unable to parse UINT from ID")
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
 	}
 
-	booking, err = h.BookService.UpdateBooking(uint(bookingID), booking)
+	booking, err = h.BookService.UpdateBooking(uint(bookingID), booking, overrideRequested(r))
 	if err != nil {
-		fmt.Fprintf(w, "Failed to update booking")
+		writeBookingError(w, err, "Failed to update booking")
+		return
 	}
 
+	w.WriteHeader(http.StatusOK)
 	// Return the newly update booking as json
 	if err := json.NewEncoder(w).Encode(booking); err != nil {
 		log.Warning(err)
@@ -88,21 +122,22 @@ func (h *Handler) UpdateBooking(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) DeleteBooking(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
 	enableCors(&w)
-	w.WriteHeader(http.StatusOK)
 
 	vars := mux.Vars(r)
 	id := vars["id"]
 
 	bookingID, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		fmt.Fprintf(w, "Unable to parse UINT from ID")
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
 	}
 
-	err = h.BookService.DeleteBooking(uint(bookingID))
-	if err != nil {
-		fmt.Fprintf(w, "Failed to delete booking")
+	if err := h.BookService.DeleteBooking(uint(bookingID)); err != nil {
+		writeBookingError(w, err, "Failed to delete booking")
+		return
 	}
 
+	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully deleted booking"}); err != nil {
 		log.Warning(err)
 	}
@@ -112,20 +147,340 @@ func (h *Handler) DeleteBooking(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) PostBooking(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
 	enableCors(&w)
-	w.WriteHeader(http.StatusOK)
 
 	var booking booking.Booking
 	// Parse the request body as booking
 	if err := json.NewDecoder(r.Body).Decode(&booking); err != nil {
-		fmt.Fprintf(w, "Failed to decode JSON Body")
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
 	}
 	// Post to booking service
-	booking, err := h.BookService.PostBooking(booking)
+	booking, err := h.BookService.PostBooking(booking, overrideRequested(r))
 	if err != nil {
-		fmt.Fprintf(w, "Failed to post new booking")
+		writeBookingError(w, err, "Failed to post new booking")
+		return
 	}
+	// a booking requested under a customer's contract starts its SLA clock
+	if _, err := h.ContractService.StartClock(booking.ID); err != nil {
+		log.Error(err)
+	}
+
+	w.WriteHeader(http.StatusOK)
 	// return the booking
 	if err := json.NewEncoder(w).Encode(booking); err != nil {
 		log.Warning(err)
 	}
 }
+
+// AssignEngineersRequest - the body of a request assigning engineers to a booking
+type AssignEngineersRequest struct {
+	EngineerIDs []uint `json:"engineerIds"`
+}
+
+// AssignEngineers - replace the engineers assigned to a booking
+func (h *Handler) AssignEngineers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var request AssignEngineersRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	bookingID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	booking, err := h.BookService.AssignEngineers(uint(bookingID), request.EngineerIDs, overrideRequested(r))
+	if err != nil {
+		writeBookingError(w, err, "Failed to assign engineers")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(booking); err != nil {
+		log.Warning(err)
+	}
+}
+
+// AssignInstrumentsRequest - the body of a request setting the instruments worked on during a booking
+type AssignInstrumentsRequest struct {
+	InstrumentIDs []uint `json:"instrumentIds"`
+}
+
+// AssignInstruments - replace the instruments worked on during a booking
+func (h *Handler) AssignInstruments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var request AssignInstrumentsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bookingID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	booking, err := h.BookService.AssignInstruments(uint(bookingID), request.InstrumentIDs)
+	if err != nil {
+		writeBookingError(w, err, "Failed to assign instruments")
+		return
+	}
+	// the instruments may bring the booking under a contract covering them
+	if _, err := h.ContractService.StartClock(booking.ID); err != nil {
+		log.Error(err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(booking); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetBookingOccurrences - fetch every booking in a ?from=&to= window with recurring bookings expanded
+func (h *Handler) GetBookingOccurrences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	from, to, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if from.IsZero() || to.IsZero() {
+		http.Error(w, "from and to are required", http.StatusBadRequest)
+		return
+	}
+
+	inZone, err := responseZone(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bookings, err := h.BookService.GetOccurrences(from, to)
+	if err != nil {
+		writeBookingError(w, err, "Failed to retrieve bookings")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(inZoneAll(inZone, bookings)); err != nil {
+		log.Warning(err)
+	}
+}
+
+// UpdateBookingOccurrence - edit one occurrence of a recurring booking, picked by its ?start= time,
+// together with the following occurrences or the whole series depending on ?scope=this|following|all
+func (h *Handler) UpdateBookingOccurrence(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var booking booking.Booking
+	if err := json.NewDecoder(r.Body).Decode(&booking); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	bookingID, occurrence, scope, err := parseOccurrence(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	booking, err = h.BookService.UpdateOccurrence(bookingID, occurrence, scope, booking, overrideRequested(r))
+	if err != nil {
+		writeBookingError(w, err, "Failed to update booking occurrence")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(booking); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeleteBookingOccurrence - delete one occurrence of a recurring booking, the occurrences following
+// it or the whole series depending on ?scope=this|following|all
+func (h *Handler) DeleteBookingOccurrence(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	bookingID, occurrence, scope, err := parseOccurrence(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.BookService.DeleteOccurrence(bookingID, occurrence, scope); err != nil {
+		writeBookingError(w, err, "Failed to delete booking occurrence")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully deleted booking occurrence"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+// parseOccurrence - reads the booking ID, the occurrence ?start= and the edit ?scope=, which defaults to this
+func parseOccurrence(r *http.Request) (uint, time.Time, booking.Scope, error) {
+	vars := mux.Vars(r)
+	bookingID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return 0, time.Time{}, "", errors.New("unable to parse UINT from ID")
+	}
+	occurrence, err := time.Parse(time.RFC3339, r.URL.Query().Get("start"))
+	if err != nil {
+		return 0, time.Time{}, "", errors.New("unable to parse start, expected RFC 3339")
+	}
+	scope := booking.Scope(r.URL.Query().Get("scope"))
+	if scope == "" {
+		scope = booking.ScopeThis
+	}
+	return uint(bookingID), occurrence, scope, nil
+}
+
+// StatusRequest - the body of a request moving a booking to a new status
+type StatusRequest struct {
+	Status    booking.Status `json:"status"`
+	ChangedBy string         `json:"changedBy"`
+	Reason    string         `json:"reason"`
+}
+
+// TransitionBookingStatus - move a booking to a new status, recording who changed it and why
+func (h *Handler) TransitionBookingStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var request StatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bookingID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	b, err := h.BookService.TransitionStatus(uint(bookingID), request.Status, request.ChangedBy, request.Reason)
+	if err != nil {
+		writeBookingError(w, err, "Failed to change booking status")
+		return
+	}
+	// a completed visit locks the checklists of its job
+	if b.Status == booking.StatusCompleted && b.JobID != nil {
+		if err := h.ChecklistService.LockJob(*b.JobID); err != nil {
+			log.Error(err)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(b); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetBookingStatusHistory - fetch the status changes made to a booking, oldest first
+func (h *Handler) GetBookingStatusHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+	bookingID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.BookService.GetStatusHistory(uint(bookingID))
+	if err != nil {
+		writeBookingError(w, err, "Failed to retrieve booking status history")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		log.Warning(err)
+	}
+}
+
+// ConflictResponse - the 409 body listing the bookings a new or updated booking overlaps
+type ConflictResponse struct {
+	Message   string
+	Conflicts []booking.Booking
+}
+
+// responseZone - reads ?tz=, which shows booking times in each booking's own site zone with
+// ?tz=site, or in any IANA zone such as ?tz=Europe/Paris. Times are UTC without it.
+func responseZone(r *http.Request) (func(booking.Booking) booking.Booking, error) {
+	switch tz := r.URL.Query().Get("tz"); tz {
+	case "":
+		return func(b booking.Booking) booking.Booking { return b }, nil
+	case "site":
+		return func(b booking.Booking) booking.Booking { return b.InZone(b.Zone()) }, nil
+	default:
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", booking.ErrInvalidTimeZone, tz)
+		}
+		return func(b booking.Booking) booking.Booking { return b.InZone(loc) }, nil
+	}
+}
+
+func inZoneAll(inZone func(booking.Booking) booking.Booking, bookings []booking.Booking) []booking.Booking {
+	for i := range bookings {
+		bookings[i] = inZone(bookings[i])
+	}
+	return bookings
+}
+
+// overrideRequested - dispatchers can knowingly double-book by passing ?override=true
+func overrideRequested(r *http.Request) bool {
+	override, err := strconv.ParseBool(r.URL.Query().Get("override"))
+	return err == nil && override
+}
+
+// writeBookingError - maps booking service errors onto HTTP status codes
+func writeBookingError(w http.ResponseWriter, err error, message string) {
+	var conflict *booking.ConflictError
+	var transition *booking.TransitionError
+	switch {
+	case errors.As(err, &transition), errors.Is(err, booking.ErrBookingSigned):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.As(err, &conflict):
+		w.WriteHeader(http.StatusConflict)
+		if err := json.NewEncoder(w).Encode(ConflictResponse{Message: err.Error(), Conflicts: conflict.Conflicts}); err != nil {
+			log.Warning(err)
+		}
+	case errors.Is(err, booking.ErrInvalidTimeRange), errors.Is(err, booking.ErrEngineerNotFound),
+		errors.Is(err, booking.ErrNotRecurring), errors.Is(err, booking.ErrNotAnOccurrence),
+		errors.Is(err, booking.ErrInvalidScope), errors.Is(err, recurrence.ErrInvalidRule),
+		errors.Is(err, booking.ErrInvalidStatus), errors.Is(err, booking.ErrInvalidTimeZone),
+		errors.Is(err, booking.ErrInvalidLocalTime), errors.Is(err, booking.ErrNonexistentLocalTime),
+		errors.Is(err, booking.ErrAmbiguousLocalTime), errors.Is(err, booking.ErrCustomerNotFound),
+		errors.Is(err, booking.ErrJobNotFound), errors.Is(err, booking.ErrJobCustomerMismatch),
+		errors.Is(err, booking.ErrSiteNotFound), errors.Is(err, booking.ErrSiteCustomerMismatch),
+		errors.Is(err, booking.ErrContactNotFound), errors.Is(err, booking.ErrInstrumentNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Booking not found", http.StatusNotFound)
+	default:
+		log.Error(err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
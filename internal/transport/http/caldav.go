@@ -0,0 +1,464 @@
+package http
+
+// Define the CalDAV endpoints (RFC 4791) exposing each engineer's bookings as a calendar collection.
+// Calendar clients authenticate with HTTP Basic auth, using a feed token as the password.
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/calendar"
+	"github.com/Open-FiSE/go-rest-api/internal/recurrence"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// XML namespaces used by CalDAV
+const (
+	nsDAV      = "DAV:"
+	nsCalDAV   = "urn:ietf:params:xml:ns:caldav"
+	nsCalendar = "http://calendarserver.org/ns/"
+)
+
+var davPrefixes = map[string]string{nsDAV: "d", nsCalDAV: "c", nsCalendar: "cs"}
+
+// davElement - any XML element, used to read the names of requested properties
+type davElement struct {
+	XMLName xml.Name
+}
+
+type davProp struct {
+	Names []davElement `xml:",any"`
+}
+
+type davPropfind struct {
+	Prop    *davProp  `xml:"DAV: prop"`
+	AllProp *struct{} `xml:"DAV: allprop"`
+}
+
+type davTimeRange struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+type davCompFilter struct {
+	Name      string          `xml:"name,attr"`
+	TimeRange *davTimeRange   `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+	Comps     []davCompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type davReport struct {
+	XMLName xml.Name
+	Prop    *davProp       `xml:"DAV: prop"`
+	Hrefs   []string       `xml:"DAV: href"`
+	Filter  *davCompFilter `xml:"urn:ietf:params:xml:ns:caldav filter>comp-filter"`
+}
+
+// davResponse - one <response> of a multistatus, with the inner XML of each property found
+type davResponse struct {
+	Href    string
+	Found   []davProperty
+	Missing []xml.Name
+	Status  int
+}
+
+type davProperty struct {
+	Name  xml.Name
+	Inner string
+}
+
+// CalDAVOptions - advertise CalDAV support
+func (h *Handler) CalDAVOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	w.Header().Set("Allow", "OPTIONS, GET, PUT, DELETE, PROPFIND, REPORT")
+	w.WriteHeader(http.StatusOK)
+}
+
+// CalDAVPropfind - describe an engineer's collection and, with Depth: 1, the resources in it
+func (h *Handler) CalDAVPropfind(w http.ResponseWriter, r *http.Request) {
+	engineerID, ok := h.caldavAuthorize(w, r, false)
+	if !ok {
+		return
+	}
+
+	var request davPropfind
+	if err := decodeDAV(r.Body, &request); err != nil {
+		http.Error(w, "Failed to decode XML Body", http.StatusBadRequest)
+		return
+	}
+
+	resources, err := h.CalendarService.EngineerResources(engineerID)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, "Failed to retrieve calendar", http.StatusInternalServerError)
+		return
+	}
+
+	collection := caldavCollection(engineerID)
+	var responses []davResponse
+	if name, isResource := mux.Vars(r)["resource"]; isResource {
+		resource, found := findResource(resources, name)
+		if !found {
+			http.Error(w, calendar.ErrResourceNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		responses = append(responses, resourceResponse(collection, resource, request.Prop, false))
+	} else {
+		engineer, err := h.EngineerService.GetEngineer(engineerID)
+		if err != nil {
+			http.Error(w, "Error retrieving Engineer by ID", http.StatusNotFound)
+			return
+		}
+		responses = append(responses, collectionResponse(collection, engineer.Name, resources, request.Prop))
+		if r.Header.Get("Depth") != "0" {
+			for _, resource := range resources {
+				responses = append(responses, resourceResponse(collection, resource, request.Prop, false))
+			}
+		}
+	}
+	writeMultistatus(w, responses)
+}
+
+// CalDAVReport - answer calendar-query and calendar-multiget reports
+func (h *Handler) CalDAVReport(w http.ResponseWriter, r *http.Request) {
+	engineerID, ok := h.caldavAuthorize(w, r, false)
+	if !ok {
+		return
+	}
+
+	var request davReport
+	if err := decodeDAV(r.Body, &request); err != nil {
+		http.Error(w, "Failed to decode XML Body", http.StatusBadRequest)
+		return
+	}
+
+	resources, err := h.CalendarService.EngineerResources(engineerID)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, "Failed to retrieve calendar", http.StatusInternalServerError)
+		return
+	}
+
+	collection := caldavCollection(engineerID)
+	var responses []davResponse
+	switch request.XMLName {
+	case xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		start, end, err := timeRange(request.Filter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, resource := range resources {
+			if resource.Overlaps(start, end) {
+				responses = append(responses, resourceResponse(collection, resource, request.Prop, true))
+			}
+		}
+	case xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		for _, href := range request.Hrefs {
+			name, _ := url.PathUnescape(path.Base(href))
+			if resource, found := findResource(resources, name); found {
+				responses = append(responses, resourceResponse(collection, resource, request.Prop, true))
+			} else {
+				responses = append(responses, davResponse{Href: href, Status: http.StatusNotFound})
+			}
+		}
+	default:
+		http.Error(w, "Unsupported REPORT", http.StatusForbidden)
+		return
+	}
+	writeMultistatus(w, responses)
+}
+
+// CalDAVGet - fetch one calendar resource
+func (h *Handler) CalDAVGet(w http.ResponseWriter, r *http.Request) {
+	engineerID, ok := h.caldavAuthorize(w, r, false)
+	if !ok {
+		return
+	}
+
+	resource, err := h.CalendarService.FindResource(engineerID, mux.Vars(r)["resource"])
+	if err != nil {
+		writeCalDAVError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("ETag", resource.ETag)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(resource.Data); err != nil {
+		log.Warning(err)
+	}
+}
+
+// CalDAVPut - create or replace a calendar resource, turning it into booking changes
+func (h *Handler) CalDAVPut(w http.ResponseWriter, r *http.Request) {
+	engineerID, ok := h.caldavAuthorize(w, r, true)
+	if !ok {
+		return
+	}
+
+	resource, created, err := h.CalendarService.PutResource(engineerID, mux.Vars(r)["resource"],
+		http.MaxBytesReader(w, r.Body, maxImportSize), r.Header.Get("If-Match"), r.Header.Get("If-None-Match"), false)
+	if err != nil {
+		writeCalDAVError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", resource.ETag)
+	if created {
+		w.Header().Set("Location", caldavCollection(engineerID)+resource.Name)
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CalDAVDelete - delete a calendar resource and the booking behind it
+func (h *Handler) CalDAVDelete(w http.ResponseWriter, r *http.Request) {
+	engineerID, ok := h.caldavAuthorize(w, r, true)
+	if !ok {
+		return
+	}
+
+	if err := h.CalendarService.DeleteResource(engineerID, mux.Vars(r)["resource"], r.Header.Get("If-Match")); err != nil {
+		writeCalDAVError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// caldavAuthorize - checks the Basic auth password (or ?token=) is a feed token for the engineer's
+// calendar, writing the 401/403 response and returning false when it is not
+func (h *Handler) caldavAuthorize(w http.ResponseWriter, r *http.Request, write bool) (uint, bool) {
+	engineerID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return 0, false
+	}
+
+	token := r.URL.Query().Get("token")
+	if _, password, ok := r.BasicAuth(); ok {
+		token = password
+	}
+	if write {
+		err = h.CalendarService.AuthorizeWrite(token, calendar.ScopeEngineer, uint(engineerID))
+	} else {
+		err = h.CalendarService.Authorize(token, calendar.ScopeEngineer, uint(engineerID))
+	}
+	switch {
+	case err == nil:
+		return uint(engineerID), true
+	case errors.Is(err, calendar.ErrInvalidToken):
+		w.Header().Set("WWW-Authenticate", `Basic realm="FiSES CalDAV"`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, calendar.ErrReadOnlyToken):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		log.Error(err)
+		http.Error(w, "Failed to authorize calendar", http.StatusInternalServerError)
+	}
+	return 0, false
+}
+
+// writeCalDAVError - maps calendar and booking errors onto HTTP status codes
+func writeCalDAVError(w http.ResponseWriter, err error) {
+	var conflict *booking.ConflictError
+	var transition *booking.TransitionError
+	switch {
+	case errors.Is(err, calendar.ErrResourceNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, calendar.ErrPreconditionFailed):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, calendar.ErrUIDInUse), errors.As(err, &conflict), errors.As(err, &transition),
+		errors.Is(err, booking.ErrBookingSigned):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, calendar.ErrInvalidCalendar), errors.Is(err, calendar.ErrMixedUIDs),
+		errors.Is(err, booking.ErrInvalidTimeRange), errors.Is(err, booking.ErrNotAnOccurrence),
+		errors.Is(err, recurrence.ErrInvalidRule), errors.Is(err, booking.ErrInvalidStatus),
+		errors.Is(err, booking.ErrInvalidTimeZone), errors.Is(err, booking.ErrInvalidLocalTime),
+		errors.Is(err, booking.ErrNonexistentLocalTime), errors.Is(err, booking.ErrAmbiguousLocalTime),
+		errors.Is(err, booking.ErrEngineerNotFound), errors.Is(err, booking.ErrCustomerNotFound),
+		errors.Is(err, booking.ErrJobNotFound), errors.Is(err, booking.ErrJobCustomerMismatch),
+		errors.Is(err, booking.ErrSiteNotFound), errors.Is(err, booking.ErrSiteCustomerMismatch),
+		errors.Is(err, booking.ErrContactNotFound), errors.Is(err, booking.ErrInstrumentNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Error(err)
+		http.Error(w, "Failed to update calendar", http.StatusInternalServerError)
+	}
+}
+
+func caldavCollection(engineerID uint) string {
+	return fmt.Sprintf("%scaldav/engineer/%d/", apiPrefix, engineerID)
+}
+
+// findResource - looks a resource up by its unescaped href segment
+func findResource(resources []calendar.Resource, name string) (calendar.Resource, bool) {
+	for _, r := range resources {
+		if r.Name == url.PathEscape(name) {
+			return r, true
+		}
+	}
+	return calendar.Resource{}, false
+}
+
+// collectionResponse - the properties of the calendar collection itself
+func collectionResponse(href string, name string, resources []calendar.Resource, prop *davProp) davResponse {
+	ctag := calendar.CTag(resources)
+	reports := "<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+		"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>"
+	known := []davProperty{
+		{xml.Name{Space: nsDAV, Local: "resourcetype"}, "<d:collection/><c:calendar/>"},
+		{xml.Name{Space: nsDAV, Local: "displayname"}, escapeXML("FiSES - " + name)},
+		{xml.Name{Space: nsDAV, Local: "current-user-principal"}, "<d:href>" + escapeXML(href) + "</d:href>"},
+		{xml.Name{Space: nsDAV, Local: "supported-report-set"}, reports},
+		{xml.Name{Space: nsDAV, Local: "getetag"}, escapeXML(ctag)},
+		{xml.Name{Space: nsCalDAV, Local: "calendar-home-set"}, "<d:href>" + escapeXML(href) + "</d:href>"},
+		{xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}, `<c:comp name="VEVENT"/>`},
+		{xml.Name{Space: nsCalendar, Local: "getctag"}, escapeXML(ctag)},
+	}
+	return selectProps(href, known, prop)
+}
+
+// resourceResponse - the properties of one calendar resource, including its data in reports
+func resourceResponse(collection string, resource calendar.Resource, prop *davProp, report bool) davResponse {
+	known := []davProperty{
+		{xml.Name{Space: nsDAV, Local: "getetag"}, escapeXML(resource.ETag)},
+		{xml.Name{Space: nsDAV, Local: "getcontenttype"}, "text/calendar; charset=utf-8; component=VEVENT"},
+		{xml.Name{Space: nsDAV, Local: "resourcetype"}, ""},
+		{xml.Name{Space: nsCalDAV, Local: "calendar-data"}, escapeXML(string(resource.Data))},
+	}
+	if prop == nil && !report {
+		// allprop does not include calendar-data
+		known = known[:3]
+	}
+	return selectProps(collection+resource.Name, known, prop)
+}
+
+// selectProps - splits the requested properties into those found and those missing. With no
+// <prop> element every known property is returned.
+func selectProps(href string, known []davProperty, prop *davProp) davResponse {
+	response := davResponse{Href: href, Status: http.StatusOK}
+	if prop == nil {
+		response.Found = known
+		return response
+	}
+	for _, requested := range prop.Names {
+		found := false
+		for _, p := range known {
+			if p.Name == requested.XMLName {
+				response.Found = append(response.Found, p)
+				found = true
+				break
+			}
+		}
+		if !found {
+			response.Missing = append(response.Missing, requested.XMLName)
+		}
+	}
+	return response
+}
+
+// writeMultistatus - writes a 207 Multi-Status response
+func writeMultistatus(w http.ResponseWriter, responses []davResponse) {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	fmt.Fprintf(&b, `<d:multistatus xmlns:d="%s" xmlns:c="%s" xmlns:cs="%s">`, nsDAV, nsCalDAV, nsCalendar)
+	for _, response := range responses {
+		b.WriteString("<d:response><d:href>" + escapeXML(response.Href) + "</d:href>")
+		if response.Status != http.StatusOK {
+			fmt.Fprintf(&b, "<d:status>HTTP/1.1 %d %s</d:status></d:response>", response.Status, http.StatusText(response.Status))
+			continue
+		}
+		if len(response.Found) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, p := range response.Found {
+				b.WriteString(davTag(p.Name, p.Inner))
+			}
+			b.WriteString("</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>")
+		}
+		if len(response.Missing) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, name := range response.Missing {
+				b.WriteString(davTag(name, ""))
+			}
+			b.WriteString("</d:prop><d:status>HTTP/1.1 404 Not Found</d:status></d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+	b.WriteString("</d:multistatus>")
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	if _, err := w.Write(b.Bytes()); err != nil {
+		log.Warning(err)
+	}
+}
+
+// davTag - renders an element, declaring its namespace inline when it has no known prefix
+func davTag(name xml.Name, inner string) string {
+	tag := name.Local
+	attrs := ""
+	if prefix, ok := davPrefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	} else if name.Space != "" {
+		tag = "x:" + name.Local
+		attrs = ` xmlns:x="` + escapeXML(name.Space) + `"`
+	}
+	if inner == "" {
+		return "<" + tag + attrs + "/>"
+	}
+	return "<" + tag + attrs + ">" + inner + "</" + tag + ">"
+}
+
+func escapeXML(value string) string {
+	var b strings.Builder
+	if err := xml.EscapeText(&b, []byte(value)); err != nil {
+		return ""
+	}
+	return b.String()
+}
+
+// decodeDAV - decodes an XML request body, treating an empty body as a request for everything
+func decodeDAV(body io.Reader, v interface{}) error {
+	err := xml.NewDecoder(body).Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// timeRange - finds the time-range of a calendar-query filter, zero times when there is none
+func timeRange(filter *davCompFilter) (time.Time, time.Time, error) {
+	var start, end time.Time
+	for filter != nil {
+		if filter.TimeRange != nil {
+			var err error
+			if filter.TimeRange.Start != "" {
+				if start, err = time.Parse("20060102T150405Z", filter.TimeRange.Start); err != nil {
+					return start, end, errors.New("unable to parse time-range start")
+				}
+			}
+			if filter.TimeRange.End != "" {
+				if end, err = time.Parse("20060102T150405Z", filter.TimeRange.End); err != nil {
+					return start, end, errors.New("unable to parse time-range end")
+				}
+			}
+			return start, end, nil
+		}
+		if len(filter.Comps) == 0 {
+			break
+		}
+		filter = &filter.Comps[0]
+	}
+	return start, end, nil
+}
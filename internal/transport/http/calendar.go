@@ -0,0 +1,205 @@
+package http
+
+// Define the iCalendar feed endpoints and the feed tokens that secure them.
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/calendar"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetBookingFeed - all bookings as an iCalendar feed, /booking.ics?token=
+func (h *Handler) GetBookingFeed(w http.ResponseWriter, r *http.Request) {
+	if err := h.CalendarService.Authorize(r.URL.Query().Get("token"), calendar.ScopeAll, 0); err != nil {
+		writeFeedError(w, err)
+		return
+	}
+
+	bookings, err := h.BookService.GetAllBookings()
+	if err != nil {
+		http.Error(w, "Failed to retrieve bookings", http.StatusInternalServerError)
+		return
+	}
+	writeFeed(w, "FiSES Bookings", bookings)
+}
+
+// GetEngineerFeed - an engineer's schedule as an iCalendar feed, /engineer/{id}/bookings.ics?token=
+func (h *Handler) GetEngineerFeed(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	engineerID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.CalendarService.Authorize(r.URL.Query().Get("token"), calendar.ScopeEngineer, uint(engineerID)); err != nil {
+		writeFeedError(w, err)
+		return
+	}
+
+	engineer, err := h.EngineerService.GetEngineer(uint(engineerID))
+	if err != nil {
+		http.Error(w, "Error retrieving Engineer by ID", http.StatusNotFound)
+		return
+	}
+	bookings, err := h.BookService.GetBookingsByEngineer(engineer.ID, time.Time{}, time.Time{})
+	if err != nil {
+		http.Error(w, "Failed to retrieve bookings", http.StatusInternalServerError)
+		return
+	}
+	writeFeed(w, fmt.Sprintf("FiSES Bookings - %s", engineer.Name), bookings)
+}
+
+// GetCustomerFeed - a customer's bookings as an iCalendar feed, /customer/{id}/bookings.ics?token=
+func (h *Handler) GetCustomerFeed(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	customerID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.CalendarService.Authorize(r.URL.Query().Get("token"), calendar.ScopeCustomer, uint(customerID)); err != nil {
+		writeFeedError(w, err)
+		return
+	}
+
+	customer, err := h.CustomerService.GetCustomer(uint(customerID))
+	if err != nil {
+		http.Error(w, "Error retrieving Customer by ID", http.StatusNotFound)
+		return
+	}
+	bookings, err := h.BookService.GetBookingsByCustomer(customer.ID, time.Time{}, time.Time{})
+	if err != nil {
+		http.Error(w, "Failed to retrieve bookings", http.StatusInternalServerError)
+		return
+	}
+	writeFeed(w, fmt.Sprintf("FiSES Bookings - %s", customer.Name), bookings)
+}
+
+// PostFeedToken - issue a feed token. The token is only ever returned in this response.
+func (h *Handler) PostFeedToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var token calendar.FeedToken
+	if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.CalendarService.CreateFeedToken(token)
+	if err != nil {
+		if errors.Is(err, calendar.ErrInvalidScope) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Error(err)
+		http.Error(w, "Failed to create feed token", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(token); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeleteFeedToken - revoke a feed token by ID
+func (h *Handler) DeleteFeedToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+	tokenID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.CalendarService.RevokeFeedToken(uint(tokenID)); err != nil {
+		http.Error(w, "Failed to revoke feed token", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully revoked feed token"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+// maxImportSize - the largest calendar accepted by the import endpoint
+const maxImportSize = 10 << 20
+
+// ImportBookings - create bookings from an iCalendar file, sent either as the request body or as the
+// "file" field of a multipart form. ?dryRun=true reports what would be created without saving it.
+func (h *Handler) ImportBookings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var body io.Reader = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxImportSize); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Error Retrieving file from form-data", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	events, decodeErrs, err := calendar.Decode(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+	report, err := h.CalendarService.Import(events, decodeErrs, dryRun, overrideRequested(r))
+	if err != nil {
+		log.Error(err)
+		http.Error(w, "Failed to import calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Warning(err)
+	}
+}
+
+// writeFeed - renders the bookings as a text/calendar response
+func writeFeed(w http.ResponseWriter, name string, bookings []booking.Booking) {
+	events := make([]calendar.Event, len(bookings))
+	for i, b := range bookings {
+		events[i] = calendar.EventFromBooking(b)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := calendar.Encode(w, name, events); err != nil {
+		log.Warning(err)
+	}
+}
+
+func writeFeedError(w http.ResponseWriter, err error) {
+	if errors.Is(err, calendar.ErrInvalidToken) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	log.Error(err)
+	http.Error(w, "Failed to authorize feed", http.StatusInternalServerError)
+}
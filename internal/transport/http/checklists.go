@@ -0,0 +1,243 @@
+package http
+
+// Define endpoints for checklist templates and the checklists filled in on each job, reached either
+// through the job or through a booking for it.
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/checklist"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetAllChecklistTemplates - fetch every checklist template
+func (h *Handler) GetAllChecklistTemplates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	templates, err := h.ChecklistService.GetAllTemplates()
+	if err != nil {
+		writeChecklistError(w, err, "Failed to retrieve checklist templates")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(templates); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetChecklistTemplate - retrieve a checklist template and its fields by ID
+func (h *Handler) GetChecklistTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	templateID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.ChecklistService.GetTemplate(uint(templateID))
+	if err != nil {
+		writeChecklistError(w, err, "Error retrieving checklist template by ID")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(template); err != nil {
+		log.Warning(err)
+	}
+}
+
+// PostChecklistTemplate - adds a new checklist template
+func (h *Handler) PostChecklistTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var template checklist.Template
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.ChecklistService.PostTemplate(template)
+	if err != nil {
+		writeChecklistError(w, err, "Failed to post new checklist template")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(template); err != nil {
+		log.Warning(err)
+	}
+}
+
+// UpdateChecklistTemplate - updates a checklist template by ID, replacing its fields when given
+func (h *Handler) UpdateChecklistTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var template checklist.Template
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	templateID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	template, err = h.ChecklistService.UpdateTemplate(uint(templateID), template)
+	if err != nil {
+		writeChecklistError(w, err, "Failed to update checklist template")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(template); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeleteChecklistTemplate - deletes a checklist template by ID
+func (h *Handler) DeleteChecklistTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	templateID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.ChecklistService.DeleteTemplate(uint(templateID)); err != nil {
+		writeChecklistError(w, err, "Failed to delete checklist template")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully deleted checklist template"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetJobChecklists - fetch a job's checklists, making any from templates that now apply to it
+func (h *Handler) GetJobChecklists(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	jobID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+	h.writeChecklists(w, uint(jobID))
+}
+
+// GetBookingChecklists - fetch the checklists of a booking's job
+func (h *Handler) GetBookingChecklists(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	jobID, ok := h.bookingJobID(w, r)
+	if !ok {
+		return
+	}
+	h.writeChecklists(w, jobID)
+}
+
+// AnswerJobChecklistItem - records the answer to an item of one of a job's checklists
+func (h *Handler) AnswerJobChecklistItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	jobID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+	h.answerChecklistItem(w, r, uint(jobID))
+}
+
+// AnswerBookingChecklistItem - records the answer to an item of one of the checklists of a
+// booking's job
+func (h *Handler) AnswerBookingChecklistItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	jobID, ok := h.bookingJobID(w, r)
+	if !ok {
+		return
+	}
+	h.answerChecklistItem(w, r, jobID)
+}
+
+func (h *Handler) writeChecklists(w http.ResponseWriter, jobID uint) {
+	checklists, err := h.ChecklistService.GetChecklists(jobID)
+	if err != nil {
+		writeChecklistError(w, err, "Failed to retrieve checklists")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(checklists); err != nil {
+		log.Warning(err)
+	}
+}
+
+func (h *Handler) answerChecklistItem(w http.ResponseWriter, r *http.Request, jobID uint) {
+	var answer checklist.Item
+	if err := json.NewDecoder(r.Body).Decode(&answer); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	checklistID, err := strconv.ParseUint(vars["checklistId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from checklist ID", http.StatusBadRequest)
+		return
+	}
+	itemID, err := strconv.ParseUint(vars["itemId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from item ID", http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.ChecklistService.AnswerItem(jobID, uint(checklistID), uint(itemID), answer)
+	if err != nil {
+		writeChecklistError(w, err, "Failed to answer checklist item")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(item); err != nil {
+		log.Warning(err)
+	}
+}
+
+// writeChecklistError - maps checklist service errors onto HTTP responses
+func writeChecklistError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, checklist.ErrInvalidTemplate), errors.Is(err, checklist.ErrInvalidField),
+		errors.Is(err, checklist.ErrInvalidAnswer), errors.Is(err, checklist.ErrPhotoNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, checklist.ErrLocked), errors.Is(err, booking.ErrBookingSigned):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	log.Error(err)
+	http.Error(w, message, http.StatusInternalServerError)
+}
@@ -0,0 +1,246 @@
+package http
+
+// Define endpoints for customers' service contracts and the SLA clocks of bookings made under them.
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/contract"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetContracts - fetch a customer's service contracts
+func (h *Handler) GetContracts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	customerID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	contracts, err := h.ContractService.GetContracts(uint(customerID))
+	if err != nil {
+		writeContractError(w, err, "Failed to retrieve contracts")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(contracts); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetContract - fetch one of a customer's service contracts
+func (h *Handler) GetContract(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	customerID, contractID, err := parseContractIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, err := h.ContractService.GetContract(customerID, contractID)
+	if err != nil {
+		writeContractError(w, err, "Failed to retrieve contract")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(c); err != nil {
+		log.Warning(err)
+	}
+}
+
+// PostContract - adds a service contract for a customer
+func (h *Handler) PostContract(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var c contract.Contract
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	customerID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	c, err = h.ContractService.PostContract(uint(customerID), c)
+	if err != nil {
+		writeContractError(w, err, "Failed to post new contract")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(c); err != nil {
+		log.Warning(err)
+	}
+}
+
+// UpdateContract - updates one of a customer's service contracts
+func (h *Handler) UpdateContract(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var c contract.Contract
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	customerID, contractID, err := parseContractIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, err = h.ContractService.UpdateContract(customerID, contractID, c)
+	if err != nil {
+		writeContractError(w, err, "Failed to update contract")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(c); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeleteContract - deletes one of a customer's service contracts
+func (h *Handler) DeleteContract(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	customerID, contractID, err := parseContractIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.ContractService.DeleteContract(customerID, contractID); err != nil {
+		writeContractError(w, err, "Failed to delete contract")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully deleted contract"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetCustomerSLAReport - fetch the SLA compliance report for the bookings a customer requested in a
+// ?from=&to= window, the last 30 days by default
+func (h *Handler) GetCustomerSLAReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	customerID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+	from, to, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if to.IsZero() {
+		to = time.Now().UTC()
+	}
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -30)
+	}
+
+	report, err := h.ContractService.GetReport(uint(customerID), from, to)
+	if err != nil {
+		writeContractError(w, err, "Failed to build SLA report")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetBookingSLA - fetch the SLA clock of a booking made under contract
+func (h *Handler) GetBookingSLA(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	bookingID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	clock, err := h.ContractService.GetClock(uint(bookingID))
+	if err != nil {
+		writeContractError(w, err, "Failed to retrieve SLA clock")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(clock); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetSLAWarnings - fetch the bookings at risk of breaching their SLA or breaching it now
+func (h *Handler) GetSLAWarnings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	clocks, err := h.ContractService.GetWarnings(time.Now().UTC())
+	if err != nil {
+		writeContractError(w, err, "Failed to retrieve SLA warnings")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(clocks); err != nil {
+		log.Warning(err)
+	}
+}
+
+// parseContractIDs - reads the customer {id} and {contractId} route variables
+func parseContractIDs(r *http.Request) (uint, uint, error) {
+	vars := mux.Vars(r)
+	customerID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("Unable to parse UINT from ID")
+	}
+	contractID, err := strconv.ParseUint(vars["contractId"], 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("Unable to parse UINT from contract ID")
+	}
+	return uint(customerID), uint(contractID), nil
+}
+
+// writeContractError - maps contract service errors onto HTTP status codes
+func writeContractError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, contract.ErrInvalidContract), errors.Is(err, customer.ErrCustomerNotFound),
+		errors.Is(err, customer.ErrInstrumentNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, contract.ErrContractNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	log.Error(err)
+	http.Error(w, message, http.StatusInternalServerError)
+}
@@ -0,0 +1,604 @@
+package http
+
+// Define endpoints and map them to the customer service, covering customers, their sites and jobs.
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetCustomer - retrieve a single customer and their sites by ID
+func (h *Handler) GetCustomer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+	customerID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	customer, err := h.CustomerService.GetCustomer(uint(customerID))
+	if err != nil {
+		writeCustomerError(w, err, "Error retrieving Customer by ID")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(customer); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetAllCustomers - fetch all customers from the customer service
+func (h *Handler) GetAllCustomers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	customers, err := h.CustomerService.GetAllCustomers()
+	if err != nil {
+		http.Error(w, "Failed to retrieve customers", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(customers); err != nil {
+		log.Warning(err)
+	}
+}
+
+// PostCustomer - adds a new customer, along with any sites in the body
+func (h *Handler) PostCustomer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var customer customer.Customer
+	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	customer, err := h.CustomerService.PostCustomer(customer)
+	if err != nil {
+		writeCustomerError(w, err, "Failed to post new customer")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(customer); err != nil {
+		log.Warning(err)
+	}
+}
+
+// UpdateCustomer - update an existing customer by ID
+func (h *Handler) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var customer customer.Customer
+	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	customerID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	customer, err = h.CustomerService.UpdateCustomer(uint(customerID), customer)
+	if err != nil {
+		writeCustomerError(w, err, "Failed to update customer")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(customer); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeleteCustomer - delete a customer by ID
+func (h *Handler) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+	customerID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.CustomerService.DeleteCustomer(uint(customerID)); err != nil {
+		http.Error(w, "Failed to delete customer", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully deleted customer"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetCustomerBookings - fetch a customer's bookings, optionally limited to a ?from=&to= RFC 3339 window
+func (h *Handler) GetCustomerBookings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+	customerID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	inZone, err := responseZone(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.CustomerService.GetCustomer(uint(customerID)); err != nil {
+		writeCustomerError(w, err, "Error retrieving Customer by ID")
+		return
+	}
+
+	bookings, err := h.BookService.GetBookingsByCustomer(uint(customerID), from, to)
+	if err != nil {
+		http.Error(w, "Failed to retrieve bookings", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(inZoneAll(inZone, bookings)); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetCustomerSites - fetch a customer's sites
+func (h *Handler) GetCustomerSites(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+	customerID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	sites, err := h.CustomerService.GetSites(uint(customerID))
+	if err != nil {
+		http.Error(w, "Failed to retrieve sites", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(sites); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetCustomerSite - retrieve one of a customer's sites by ID
+func (h *Handler) GetCustomerSite(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	customerID, siteID, err := parseSiteIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	site, err := h.CustomerService.GetSite(customerID, siteID)
+	if err != nil {
+		writeCustomerError(w, err, "Error retrieving Site by ID")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(site); err != nil {
+		log.Warning(err)
+	}
+}
+
+// PostCustomerSite - adds a new site for a customer
+func (h *Handler) PostCustomerSite(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var site customer.Site
+	if err := json.NewDecoder(r.Body).Decode(&site); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	customerID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	site, err = h.CustomerService.PostSite(uint(customerID), site)
+	if err != nil {
+		writeCustomerError(w, err, "Failed to post new site")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(site); err != nil {
+		log.Warning(err)
+	}
+}
+
+// UpdateCustomerSite - update one of a customer's sites by ID
+func (h *Handler) UpdateCustomerSite(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var site customer.Site
+	if err := json.NewDecoder(r.Body).Decode(&site); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	customerID, siteID, err := parseSiteIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	site, err = h.CustomerService.UpdateSite(customerID, siteID, site)
+	if err != nil {
+		writeCustomerError(w, err, "Failed to update site")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(site); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeleteCustomerSite - delete one of a customer's sites by ID
+func (h *Handler) DeleteCustomerSite(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	customerID, siteID, err := parseSiteIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.CustomerService.DeleteSite(customerID, siteID); err != nil {
+		http.Error(w, "Failed to delete site", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully deleted site"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetCustomerContacts - fetch a customer's contacts
+func (h *Handler) GetCustomerContacts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+	customerID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	contacts, err := h.CustomerService.GetContacts(uint(customerID))
+	if err != nil {
+		http.Error(w, "Failed to retrieve contacts", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(contacts); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetCustomerContact - retrieve one of a customer's contacts by ID
+func (h *Handler) GetCustomerContact(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	customerID, contactID, err := parseContactIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contact, err := h.CustomerService.GetContact(customerID, contactID)
+	if err != nil {
+		writeCustomerError(w, err, "Error retrieving Contact by ID")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(contact); err != nil {
+		log.Warning(err)
+	}
+}
+
+// PostCustomerContact - adds a new contact for a customer
+func (h *Handler) PostCustomerContact(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var contact customer.Contact
+	if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	customerID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	contact, err = h.CustomerService.PostContact(uint(customerID), contact)
+	if err != nil {
+		writeCustomerError(w, err, "Failed to post new contact")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(contact); err != nil {
+		log.Warning(err)
+	}
+}
+
+// UpdateCustomerContact - update one of a customer's contacts by ID
+func (h *Handler) UpdateCustomerContact(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var contact customer.Contact
+	if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	customerID, contactID, err := parseContactIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contact, err = h.CustomerService.UpdateContact(customerID, contactID, contact)
+	if err != nil {
+		writeCustomerError(w, err, "Failed to update contact")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(contact); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeleteCustomerContact - delete one of a customer's contacts by ID
+func (h *Handler) DeleteCustomerContact(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	customerID, contactID, err := parseContactIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.CustomerService.DeleteContact(customerID, contactID); err != nil {
+		http.Error(w, "Failed to delete contact", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully deleted contact"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetJob - retrieve a single job by ID
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+	jobID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.CustomerService.GetJob(uint(jobID))
+	if err != nil {
+		writeCustomerError(w, err, "Error retrieving Job by ID")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetAllJobs - fetch all jobs, or only one customer's with ?customer=
+func (h *Handler) GetAllJobs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var customerID uint64
+	if value := r.URL.Query().Get("customer"); value != "" {
+		var err error
+		if customerID, err = strconv.ParseUint(value, 10, 64); err != nil {
+			http.Error(w, "Unable to parse UINT from customer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	jobs, err := h.CustomerService.GetJobs(uint(customerID))
+	if err != nil {
+		http.Error(w, "Failed to retrieve jobs", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(jobs); err != nil {
+		log.Warning(err)
+	}
+}
+
+// PostJob - adds a new job for an existing customer
+func (h *Handler) PostJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var job customer.Job
+	if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.CustomerService.PostJob(job)
+	if err != nil {
+		writeCustomerError(w, err, "Failed to post new job")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Warning(err)
+	}
+}
+
+// UpdateJob - update an existing job by ID
+func (h *Handler) UpdateJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var job customer.Job
+	if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	jobID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err = h.CustomerService.UpdateJob(uint(jobID), job)
+	if err != nil {
+		writeCustomerError(w, err, "Failed to update job")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeleteJob - delete a job by ID
+func (h *Handler) DeleteJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+	jobID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.CustomerService.DeleteJob(uint(jobID)); err != nil {
+		http.Error(w, "Failed to delete job", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully deleted job"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+// parseSiteIDs - reads the customer {id} and {siteId} route variables
+func parseSiteIDs(r *http.Request) (uint, uint, error) {
+	vars := mux.Vars(r)
+	customerID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("Unable to parse UINT from ID")
+	}
+	siteID, err := strconv.ParseUint(vars["siteId"], 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("Unable to parse UINT from site ID")
+	}
+	return uint(customerID), uint(siteID), nil
+}
+
+// parseContactIDs - reads the customer {id} and {contactId} route variables
+func parseContactIDs(r *http.Request) (uint, uint, error) {
+	vars := mux.Vars(r)
+	customerID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("Unable to parse UINT from ID")
+	}
+	contactID, err := strconv.ParseUint(vars["contactId"], 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("Unable to parse UINT from contact ID")
+	}
+	return uint(customerID), uint(contactID), nil
+}
+
+// writeCustomerError - maps customer service errors onto HTTP status codes
+func writeCustomerError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, customer.ErrInvalidCustomer), errors.Is(err, customer.ErrCustomerNotFound),
+		errors.Is(err, customer.ErrSiteNotFound), errors.Is(err, customer.ErrInvalidJob),
+		errors.Is(err, customer.ErrInvalidSite), errors.Is(err, customer.ErrInvalidLocation),
+		errors.Is(err, customer.ErrInvalidTimeZone), errors.Is(err, customer.ErrInvalidContact),
+		errors.Is(err, customer.ErrContactNotFound), errors.Is(err, customer.ErrInvalidInstrument),
+		errors.Is(err, customer.ErrInstrumentNotFound), errors.Is(err, customer.ErrInvalidEquipment),
+		errors.Is(err, customer.ErrInvalidMeasurement), errors.Is(err, customer.ErrInvalidInterval):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, customer.ErrSerialInUse), errors.Is(err, customer.ErrBookingSigned):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	log.Error(err)
+	http.Error(w, message, http.StatusInternalServerError)
+}
@@ -0,0 +1,69 @@
+package http
+
+// Define endpoints for proposing and applying engineer assignments for unassigned bookings.
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Open-FiSE/go-rest-api/internal/dispatch"
+	log "github.com/sirupsen/logrus"
+)
+
+// PreviewDispatch - propose engineers for the unassigned bookings in a window without assigning any
+func (h *Handler) PreviewDispatch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var request dispatch.Request
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	proposal, err := h.DispatchService.Propose(request)
+	if err != nil {
+		writeDispatchError(w, err, "Failed to propose assignments")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(proposal); err != nil {
+		log.Warning(err)
+	}
+}
+
+// ApplyDispatch - assign the engineers of a previewed proposal, reporting those that could not be
+func (h *Handler) ApplyDispatch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var body struct {
+		Assignments []dispatch.Assignment `json:"assignments"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.DispatchService.Apply(body.Assignments)
+	if err != nil {
+		writeDispatchError(w, err, "Failed to apply assignments")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Warning(err)
+	}
+}
+
+// writeDispatchError - maps dispatch service errors onto HTTP status codes
+func writeDispatchError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, dispatch.ErrInvalidRequest) || errors.Is(err, dispatch.ErrNoAssignments) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Error(err)
+	http.Error(w, message, http.StatusInternalServerError)
+}
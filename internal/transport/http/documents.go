@@ -97,6 +97,7 @@ func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
 		document.Version = docVer
 		document.Author = ""                       //TBD when auth is implemented
 		document.Hash = hex.EncodeToString(sum[:]) // `:` is needed because byte arrays cannot be directly turned to a string while slices can
+		document.BookingID, document.InstrumentID = formID(r, "bookingId"), formID(r, "instrumentId")
 
 		document, err = h.Service.PostDocument(document)
 		if err != nil {
@@ -104,7 +105,8 @@ func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
 		}
 	} else {
 		document.Version = docVer + 1.0
-		log.Infof("updating document version to:", document.Version)
+		log.Infof("updating document version to: %v", document.Version)
+		document.BookingID, document.InstrumentID = formID(r, "bookingId"), formID(r, "instrumentId")
 
 		document, err = h.Service.UpdateDocument(document.ID, document)
 		if err != nil {
@@ -131,6 +133,17 @@ func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
 	log.Infof("Successfully uploaded file: %s\n", document.Title)
 }
 
+// formID - reads an optional ID from a form field, such as the booking or instrument an uploaded
+// document belongs to
+func formID(r *http.Request, key string) *uint {
+	value, err := strconv.ParseUint(r.FormValue(key), 10, 64)
+	if err != nil {
+		return nil
+	}
+	id := uint(value)
+	return &id
+}
+
 // GetDocument - retrieve a single document by ID
 func (h *Handler) GetDocument(w http.ResponseWriter, r *http.Request) {
 	enableCors(&w)
@@ -0,0 +1,307 @@
+package http
+
+// Define endpoints and map them to the engineer service.
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/engineer"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetEngineer - retrieve a single engineer by ID
+func (h *Handler) GetEngineer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	engineerID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	engineer, err := h.EngineerService.GetEngineer(uint(engineerID))
+	if err != nil {
+		http.Error(w, "Error retrieving Engineer by ID", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(engineer); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetAllEngineers - fetch all engineers from the engineer service
+func (h *Handler) GetAllEngineers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	engineers, err := h.EngineerService.GetAllEngineers()
+	if err != nil {
+		http.Error(w, "Failed to retrieve engineers", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(engineers); err != nil {
+		log.Warning(err)
+	}
+}
+
+// PostEngineer - adds a new engineer
+func (h *Handler) PostEngineer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var engineer engineer.Engineer
+	if err := json.NewDecoder(r.Body).Decode(&engineer); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	engineer, err := h.EngineerService.PostEngineer(engineer)
+	if err != nil {
+		writeEngineerError(w, err, "Failed to post new engineer")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(engineer); err != nil {
+		log.Warning(err)
+	}
+}
+
+// UpdateEngineer - update an exisiting engineer by ID
+func (h *Handler) UpdateEngineer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var engineer engineer.Engineer
+	if err := json.NewDecoder(r.Body).Decode(&engineer); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	engineerID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	engineer, err = h.EngineerService.UpdateEngineer(uint(engineerID), engineer)
+	if err != nil {
+		writeEngineerError(w, err, "Failed to update engineer")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(engineer); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeleteEngineer - delete an engineer by ID
+func (h *Handler) DeleteEngineer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	engineerID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.EngineerService.DeleteEngineer(uint(engineerID)); err != nil {
+		http.Error(w, "Failed to delete engineer", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully deleted engineer"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetEngineerBookings - fetch an engineer's schedule, optionally limited to a ?from=&to= RFC 3339 window
+func (h *Handler) GetEngineerBookings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	engineerID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.EngineerService.GetEngineer(uint(engineerID)); err != nil {
+		http.Error(w, "Error retrieving Engineer by ID", http.StatusNotFound)
+		return
+	}
+
+	inZone, err := responseZone(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bookings, err := h.BookService.GetBookingsByEngineer(uint(engineerID), from, to)
+	if err != nil {
+		http.Error(w, "Failed to retrieve bookings", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(inZoneAll(inZone, bookings)); err != nil {
+		log.Warning(err)
+	}
+}
+
+// parseWindow - reads the optional ?from= and ?to= RFC 3339 query parameters
+func parseWindow(r *http.Request) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			return from, to, errors.New("unable to parse from, expected RFC 3339")
+		}
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			return from, to, errors.New("unable to parse to, expected RFC 3339")
+		}
+	}
+	return from, to, nil
+}
+
+// writeEngineerError - maps engineer service errors onto HTTP status codes
+func writeEngineerError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, engineer.ErrInvalidWorkingHours), errors.Is(err, engineer.ErrInvalidWorkDays),
+		errors.Is(err, engineer.ErrInvalidTimeZone), errors.Is(err, engineer.ErrInvalidAbsence),
+		errors.Is(err, engineer.ErrInvalidBase):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Engineer not found", http.StatusNotFound)
+		return
+	}
+	log.Error(err)
+	http.Error(w, message, http.StatusInternalServerError)
+}
+
+// GetEngineerAbsences - fetch an engineer's leave and absences, optionally limited to a ?from=&to= window
+func (h *Handler) GetEngineerAbsences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	engineerID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	absences, err := h.EngineerService.GetAbsences(uint(engineerID), from, to)
+	if err != nil {
+		http.Error(w, "Failed to retrieve absences", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(absences); err != nil {
+		log.Warning(err)
+	}
+}
+
+// PostEngineerAbsence - record leave or an absence for an engineer
+func (h *Handler) PostEngineerAbsence(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var absence engineer.Absence
+	if err := json.NewDecoder(r.Body).Decode(&absence); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	engineerID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	absence, err = h.EngineerService.PostAbsence(uint(engineerID), absence)
+	if err != nil {
+		writeEngineerError(w, err, "Failed to post new absence")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(absence); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeleteEngineerAbsence - delete one of an engineer's absences by ID
+func (h *Handler) DeleteEngineerAbsence(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+
+	engineerID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+	absenceID, err := strconv.ParseUint(vars["absenceId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from absence ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.EngineerService.DeleteAbsence(uint(engineerID), uint(absenceID)); err != nil {
+		http.Error(w, "Failed to delete absence", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully deleted absence"}); err != nil {
+		log.Warning(err)
+	}
+}
@@ -0,0 +1,394 @@
+package http
+
+// Define the endpoints for a booking's job, the equipment line items it is made up of, their
+// measurements and calibration certificates, nested under /booking/{id}/job.
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/certificate"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetBookingJob - retrieve the job, with its equipment, a booking is carrying out
+func (h *Handler) GetBookingJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	jobID, ok := h.bookingJobID(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.CustomerService.GetJob(jobID)
+	if err != nil {
+		writeCustomerError(w, err, "Error retrieving Job by ID")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Warning(err)
+	}
+}
+
+// PostBookingJob - raise a job, with any equipment in the body, for a booking that has none. The job
+// is raised for the booking's customer and site.
+func (h *Handler) PostBookingJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var job customer.Job
+	if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bookingID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+	b, err := h.BookService.GetBooking(uint(bookingID))
+	if err != nil {
+		writeBookingError(w, err, "Error retrieving Booking by ID")
+		return
+	}
+	if b.JobID != nil {
+		http.Error(w, "Booking already has a job", http.StatusConflict)
+		return
+	}
+	if b.CustomerID == nil {
+		http.Error(w, "Booking has no customer to raise a job for", http.StatusBadRequest)
+		return
+	}
+
+	job.CustomerID = *b.CustomerID
+	if job.SiteID == nil {
+		job.SiteID = b.SiteID
+	}
+	job, err = h.CustomerService.PostJob(job)
+	if err != nil {
+		writeCustomerError(w, err, "Failed to post new job")
+		return
+	}
+	// the booking's window is unchanged, so there is nothing new to conflict with
+	if _, err := h.BookService.UpdateBooking(b.ID, booking.Booking{JobID: &job.ID}, true); err != nil {
+		writeBookingError(w, err, "Failed to add job to booking")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Warning(err)
+	}
+}
+
+// UpdateBookingJob - update the job a booking is carrying out
+func (h *Handler) UpdateBookingJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var job customer.Job
+	if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	jobID, ok := h.bookingJobID(w, r)
+	if !ok {
+		return
+	}
+	// the job stays with the booking's customer
+	job.CustomerID = 0
+
+	job, err := h.CustomerService.UpdateJob(jobID, job)
+	if err != nil {
+		writeCustomerError(w, err, "Failed to update job")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetBookingEquipment - fetch the equipment line items of a booking's job
+func (h *Handler) GetBookingEquipment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	jobID, ok := h.bookingJobID(w, r)
+	if !ok {
+		return
+	}
+
+	equipment, err := h.CustomerService.GetEquipment(jobID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve equipment", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(equipment); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetBookingEquipmentItem - retrieve one equipment line item of a booking's job by ID
+func (h *Handler) GetBookingEquipmentItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	jobID, ok := h.bookingJobID(w, r)
+	if !ok {
+		return
+	}
+	itemID, err := strconv.ParseUint(mux.Vars(r)["itemId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from item ID", http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.CustomerService.GetEquipmentItem(jobID, uint(itemID))
+	if err != nil {
+		writeCustomerError(w, err, "Error retrieving Equipment by ID")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(item); err != nil {
+		log.Warning(err)
+	}
+}
+
+// PostBookingEquipment - add an equipment line item to a booking's job
+func (h *Handler) PostBookingEquipment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var item customer.Equipment
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	jobID, ok := h.bookingJobID(w, r)
+	if !ok {
+		return
+	}
+
+	item, err := h.CustomerService.PostEquipment(jobID, item)
+	if err != nil {
+		writeCustomerError(w, err, "Failed to post new equipment")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(item); err != nil {
+		log.Warning(err)
+	}
+}
+
+// UpdateBookingEquipment - update an equipment line item's work type, status, findings or time spent
+func (h *Handler) UpdateBookingEquipment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var item customer.Equipment
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	jobID, ok := h.bookingJobID(w, r)
+	if !ok {
+		return
+	}
+	itemID, err := strconv.ParseUint(mux.Vars(r)["itemId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from item ID", http.StatusBadRequest)
+		return
+	}
+
+	item, err = h.CustomerService.UpdateEquipment(jobID, uint(itemID), item)
+	if err != nil {
+		writeCustomerError(w, err, "Failed to update equipment")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(item); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeleteBookingEquipment - delete an equipment line item from a booking's job
+func (h *Handler) DeleteBookingEquipment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	jobID, ok := h.bookingJobID(w, r)
+	if !ok {
+		return
+	}
+	itemID, err := strconv.ParseUint(mux.Vars(r)["itemId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from item ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.CustomerService.DeleteEquipment(jobID, uint(itemID)); err != nil {
+		http.Error(w, "Failed to delete equipment", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully deleted equipment"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+// bookingJobID - finds the job of the booking in the {id} route variable, writing the error response
+// and returning false when there is none, or when changing the job of a signed off booking
+func (h *Handler) bookingJobID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	bookingID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return 0, false
+	}
+	b, err := h.BookService.GetBooking(uint(bookingID))
+	if err != nil {
+		writeBookingError(w, err, "Error retrieving Booking by ID")
+		return 0, false
+	}
+	if b.JobID == nil {
+		http.Error(w, "Booking has no job", http.StatusNotFound)
+		return 0, false
+	}
+	// the work recorded against a signed off booking is part of what the customer signed
+	if r.Method != http.MethodGet && b.SignedAt != nil {
+		http.Error(w, booking.ErrBookingSigned.Error(), http.StatusConflict)
+		return 0, false
+	}
+	return *b.JobID, true
+}
+
+// GetBookingMeasurements - fetch the measurements taken on an equipment line item of a booking's job
+func (h *Handler) GetBookingMeasurements(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	jobID, ok := h.bookingJobID(w, r)
+	if !ok {
+		return
+	}
+	itemID, err := strconv.ParseUint(mux.Vars(r)["itemId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from item ID", http.StatusBadRequest)
+		return
+	}
+
+	measurements, err := h.CustomerService.GetMeasurements(jobID, uint(itemID))
+	if err != nil {
+		writeCustomerError(w, err, "Failed to retrieve measurements")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(measurements); err != nil {
+		log.Warning(err)
+	}
+}
+
+// SetBookingMeasurements - replace the measurements taken on an equipment line item with the list in
+// the body, working out which readings passed
+func (h *Handler) SetBookingMeasurements(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var measurements []customer.Measurement
+	if err := json.NewDecoder(r.Body).Decode(&measurements); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	jobID, ok := h.bookingJobID(w, r)
+	if !ok {
+		return
+	}
+	itemID, err := strconv.ParseUint(mux.Vars(r)["itemId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from item ID", http.StatusBadRequest)
+		return
+	}
+
+	measurements, err = h.CustomerService.SetMeasurements(jobID, uint(itemID), measurements)
+	if err != nil {
+		writeCustomerError(w, err, "Failed to save measurements")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(measurements); err != nil {
+		log.Warning(err)
+	}
+}
+
+// CertificateRequest - the body of a request generating a calibration certificate
+type CertificateRequest struct {
+	IssuedBy string `json:"issuedBy"`
+}
+
+// PostBookingCertificate - generate the calibration certificate for a completed equipment line item,
+// returning the document it is filed as
+func (h *Handler) PostBookingCertificate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var request CertificateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	bookingID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+	itemID, err := strconv.ParseUint(vars["itemId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from item ID", http.StatusBadRequest)
+		return
+	}
+
+	document, err := h.CertificateService.Generate(uint(bookingID), uint(itemID), request.IssuedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, certificate.ErrNoMeasurements), errors.Is(err, certificate.ErrNotCompleted),
+			errors.Is(err, certificate.ErrInvalidIssuedBy):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, certificate.ErrNoJob), errors.Is(err, gorm.ErrRecordNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			log.Error(err)
+			http.Error(w, "Failed to generate certificate", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(document); err != nil {
+		log.Warning(err)
+	}
+}
@@ -0,0 +1,93 @@
+package http
+
+// Define the endpoint streaming changes to bookings and documents as Server-Sent Events.
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/events"
+	log "github.com/sirupsen/logrus"
+)
+
+// heartbeat - how often an idle stream sends a comment, so proxies keep the connection open
+const heartbeat = 20 * time.Second
+
+// GetEvents - stream booking and document created, updated and deleted events as Server-Sent Events,
+// only those of ?types=booking,document when given. A client reconnecting with the Last-Event-ID
+// header (or ?lastEventId=) is sent the events it missed first, or a reset event telling it to reload
+// when they are no longer kept.
+func (h *Handler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	resources, err := events.ParseResources(r.URL.Query().Get("types"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("lastEventId")
+	}
+	var lastEventID uint64
+	if lastID != "" {
+		if lastEventID, err = strconv.ParseUint(lastID, 10, 64); err != nil {
+			http.Error(w, "Unable to parse UINT from Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	sub, backlog, complete := h.Events.Subscribe(resources, lastEventID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if complete {
+		for _, event := range backlog {
+			writeEvent(w, event)
+		}
+	} else {
+		// the missed events are gone, the client reloads and carries on from the latest
+		fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {}\n\n", sub.Latest)
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			// dropped for falling behind, the client reconnects and resumes from Last-Event-ID
+			if !ok {
+				return
+			}
+			writeEvent(w, event)
+			flusher.Flush()
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent - writes an event in the Server-Sent Events format, named resource.action
+func writeEvent(w http.ResponseWriter, event events.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Warning(err)
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Name(), data)
+}
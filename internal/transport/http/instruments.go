@@ -0,0 +1,193 @@
+package http
+
+// Define endpoints for the instrument asset register and each instrument's service history.
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/Open-FiSE/go-rest-api/internal/document"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// InstrumentHistory - everything done to an instrument: the jobs raised on it, every visit and the
+// reports and documents filed against it or its visits
+type InstrumentHistory struct {
+	Instrument customer.Instrument `json:"instrument"`
+	Jobs       []customer.Job      `json:"jobs"`
+	Visits     []booking.Booking   `json:"visits"`
+	Documents  []document.Document `json:"documents"`
+}
+
+// GetInstrument - retrieve a single instrument by ID
+func (h *Handler) GetInstrument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+	instrumentID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	instrument, err := h.CustomerService.GetInstrument(uint(instrumentID))
+	if err != nil {
+		writeCustomerError(w, err, "Error retrieving Instrument by ID")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(instrument); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetAllInstruments - fetch the asset register, optionally only a ?customer= or ?site='s instruments
+func (h *Handler) GetAllInstruments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var filters [2]uint64
+	for i, key := range []string{"customer", "site"} {
+		if value := r.URL.Query().Get(key); value != "" {
+			var err error
+			if filters[i], err = strconv.ParseUint(value, 10, 64); err != nil {
+				http.Error(w, "Unable to parse UINT from "+key, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	instruments, err := h.CustomerService.GetInstruments(uint(filters[0]), uint(filters[1]))
+	if err != nil {
+		http.Error(w, "Failed to retrieve instruments", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(instruments); err != nil {
+		log.Warning(err)
+	}
+}
+
+// PostInstrument - registers a new instrument
+func (h *Handler) PostInstrument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var instrument customer.Instrument
+	if err := json.NewDecoder(r.Body).Decode(&instrument); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	instrument, err := h.CustomerService.PostInstrument(instrument)
+	if err != nil {
+		writeCustomerError(w, err, "Failed to post new instrument")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(instrument); err != nil {
+		log.Warning(err)
+	}
+}
+
+// UpdateInstrument - update a registered instrument by ID
+func (h *Handler) UpdateInstrument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var instrument customer.Instrument
+	if err := json.NewDecoder(r.Body).Decode(&instrument); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	instrumentID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	instrument, err = h.CustomerService.UpdateInstrument(uint(instrumentID), instrument)
+	if err != nil {
+		writeCustomerError(w, err, "Failed to update instrument")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(instrument); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeleteInstrument - remove an instrument from the register by ID
+func (h *Handler) DeleteInstrument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+	instrumentID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.CustomerService.DeleteInstrument(uint(instrumentID)); err != nil {
+		http.Error(w, "Failed to delete instrument", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully deleted instrument"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetInstrumentHistory - the full service history of the instrument with serial number {serial}
+func (h *Handler) GetInstrumentHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	vars := mux.Vars(r)
+	instrument, err := h.CustomerService.GetInstrumentBySerial(vars["serial"])
+	if err != nil {
+		writeCustomerError(w, err, "Error retrieving Instrument by serial number")
+		return
+	}
+	inZone, err := responseZone(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	history := InstrumentHistory{Instrument: instrument}
+	if history.Jobs, err = h.CustomerService.GetJobsByInstrument(instrument.ID); err != nil {
+		http.Error(w, "Failed to retrieve jobs", http.StatusInternalServerError)
+		return
+	}
+	if history.Visits, err = h.BookService.GetBookingsByInstrument(instrument.ID); err != nil {
+		http.Error(w, "Failed to retrieve bookings", http.StatusInternalServerError)
+		return
+	}
+	bookingIDs := make([]uint, len(history.Visits))
+	for i, visit := range history.Visits {
+		bookingIDs[i] = visit.ID
+	}
+	if history.Documents, err = h.Service.GetLinkedDocuments(instrument.ID, bookingIDs); err != nil {
+		http.Error(w, "Failed to retrieve documents", http.StatusInternalServerError)
+		return
+	}
+	history.Visits = inZoneAll(inZone, history.Visits)
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		log.Warning(err)
+	}
+}
@@ -0,0 +1,465 @@
+package http
+
+// Define endpoints for the parts catalogue, stock held in warehouses and vans, and the parts used on
+// each job.
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/Open-FiSE/go-rest-api/internal/inventory"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+// TransferRequest - the body of a request moving stock between locations
+type TransferRequest struct {
+	PartID         uint `json:"partId"`
+	FromLocationID uint `json:"fromLocationId"`
+	ToLocationID   uint `json:"toLocationId"`
+	Quantity       int  `json:"quantity"`
+}
+
+// GetAllParts - fetch the parts catalogue
+func (h *Handler) GetAllParts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	parts, err := h.InventoryService.GetAllParts()
+	if err != nil {
+		writeInventoryError(w, err, "Failed to retrieve parts")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(parts); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetPart - retrieve a part from the catalogue by ID
+func (h *Handler) GetPart(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	partID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	part, err := h.InventoryService.GetPart(uint(partID))
+	if err != nil {
+		writeInventoryError(w, err, "Error retrieving Part by ID")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(part); err != nil {
+		log.Warning(err)
+	}
+}
+
+// PostPart - adds a part to the catalogue
+func (h *Handler) PostPart(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var part inventory.Part
+	if err := json.NewDecoder(r.Body).Decode(&part); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	part, err := h.InventoryService.PostPart(part)
+	if err != nil {
+		writeInventoryError(w, err, "Failed to post new part")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(part); err != nil {
+		log.Warning(err)
+	}
+}
+
+// UpdatePart - updates a part in the catalogue by ID
+func (h *Handler) UpdatePart(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var part inventory.Part
+	if err := json.NewDecoder(r.Body).Decode(&part); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	partID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	part, err = h.InventoryService.UpdatePart(uint(partID), part)
+	if err != nil {
+		writeInventoryError(w, err, "Failed to update part")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(part); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeletePart - removes a part from the catalogue by ID
+func (h *Handler) DeletePart(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	partID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.InventoryService.DeletePart(uint(partID)); err != nil {
+		writeInventoryError(w, err, "Failed to delete part")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully deleted part"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetAllStockLocations - fetch every warehouse and van
+func (h *Handler) GetAllStockLocations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	locations, err := h.InventoryService.GetAllLocations()
+	if err != nil {
+		writeInventoryError(w, err, "Failed to retrieve stock locations")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(locations); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetStockLocation - retrieve a stock location by ID
+func (h *Handler) GetStockLocation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	locationID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	location, err := h.InventoryService.GetLocation(uint(locationID))
+	if err != nil {
+		writeInventoryError(w, err, "Error retrieving stock location by ID")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(location); err != nil {
+		log.Warning(err)
+	}
+}
+
+// PostStockLocation - adds a new warehouse or van
+func (h *Handler) PostStockLocation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var location inventory.Location
+	if err := json.NewDecoder(r.Body).Decode(&location); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	location, err := h.InventoryService.PostLocation(location)
+	if err != nil {
+		writeInventoryError(w, err, "Failed to post new stock location")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(location); err != nil {
+		log.Warning(err)
+	}
+}
+
+// UpdateStockLocation - updates a stock location by ID
+func (h *Handler) UpdateStockLocation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var location inventory.Location
+	if err := json.NewDecoder(r.Body).Decode(&location); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	locationID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	location, err = h.InventoryService.UpdateLocation(uint(locationID), location)
+	if err != nil {
+		writeInventoryError(w, err, "Failed to update stock location")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(location); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeleteStockLocation - removes a stock location by ID
+func (h *Handler) DeleteStockLocation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	locationID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.InventoryService.DeleteLocation(uint(locationID)); err != nil {
+		writeInventoryError(w, err, "Failed to delete stock location")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully deleted stock location"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetLocationStock - fetch the stock held at a location
+func (h *Handler) GetLocationStock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	locationID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	levels, err := h.InventoryService.GetStock(uint(locationID))
+	if err != nil {
+		writeInventoryError(w, err, "Failed to retrieve stock")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(levels); err != nil {
+		log.Warning(err)
+	}
+}
+
+// SetLocationStock - sets how many of a part are held at a location and its reorder level
+func (h *Handler) SetLocationStock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var level inventory.StockLevel
+	if err := json.NewDecoder(r.Body).Decode(&level); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	locationID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+	partID, err := strconv.ParseUint(vars["partId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from part ID", http.StatusBadRequest)
+		return
+	}
+
+	level, err = h.InventoryService.SetStock(uint(locationID), uint(partID), level)
+	if err != nil {
+		writeInventoryError(w, err, "Failed to set stock level")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(level); err != nil {
+		log.Warning(err)
+	}
+}
+
+// TransferStock - moves stock of a part between two locations
+func (h *Handler) TransferStock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var request TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.InventoryService.TransferStock(request.PartID, request.FromLocationID, request.ToLocationID, request.Quantity); err != nil {
+		writeInventoryError(w, err, "Failed to transfer stock")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully transferred stock"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetLowStock - fetch the parts at or below their reorder level at each location
+func (h *Handler) GetLowStock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	levels, err := h.InventoryService.GetLowStock()
+	if err != nil {
+		writeInventoryError(w, err, "Failed to retrieve low stock")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(levels); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetJobParts - fetch the parts used on a job
+func (h *Handler) GetJobParts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	jobID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+	h.writeJobParts(w, uint(jobID))
+}
+
+// GetBookingParts - fetch the parts used on a booking's job
+func (h *Handler) GetBookingParts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	jobID, ok := h.bookingJobID(w, r)
+	if !ok {
+		return
+	}
+	h.writeJobParts(w, jobID)
+}
+
+// PostBookingParts - records parts used during a booking on its job, taking them out of stock
+func (h *Handler) PostBookingParts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var usage inventory.Usage
+	if err := json.NewDecoder(r.Body).Decode(&usage); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	jobID, ok := h.bookingJobID(w, r)
+	if !ok {
+		return
+	}
+	bookingID, _ := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	booked := uint(bookingID)
+
+	usage, err := h.InventoryService.UseParts(jobID, &booked, usage)
+	if err != nil {
+		writeInventoryError(w, err, "Failed to record parts used")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(usage); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeleteBookingParts - removes parts recorded in error from a booking's job, returning them to stock
+func (h *Handler) DeleteBookingParts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	jobID, ok := h.bookingJobID(w, r)
+	if !ok {
+		return
+	}
+	usageID, err := strconv.ParseUint(mux.Vars(r)["usageId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from usage ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.InventoryService.ReturnParts(jobID, uint(usageID)); err != nil {
+		writeInventoryError(w, err, "Failed to return parts")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully returned parts to stock"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+func (h *Handler) writeJobParts(w http.ResponseWriter, jobID uint) {
+	usage, err := h.InventoryService.GetUsage(jobID)
+	if err != nil {
+		writeInventoryError(w, err, "Failed to retrieve parts used")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(usage); err != nil {
+		log.Warning(err)
+	}
+}
+
+// writeInventoryError - maps inventory service errors onto HTTP responses
+func writeInventoryError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, inventory.ErrInvalidPart), errors.Is(err, inventory.ErrInvalidLocation),
+		errors.Is(err, inventory.ErrInvalidQuantity), errors.Is(err, inventory.ErrPartNotFound),
+		errors.Is(err, inventory.ErrLocationNotFound), errors.Is(err, inventory.ErrSameLocation),
+		errors.Is(err, inventory.ErrInvalidStockLevel), errors.Is(err, inventory.ErrEngineerNotFound),
+		errors.Is(err, inventory.ErrJobNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, inventory.ErrPartNoInUse), errors.Is(err, inventory.ErrInsufficientStock),
+		errors.Is(err, customer.ErrBookingSigned):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	log.Error(err)
+	http.Error(w, message, http.StatusInternalServerError)
+}
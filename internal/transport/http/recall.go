@@ -0,0 +1,67 @@
+package http
+
+// Define endpoints for instruments coming due for calibration and the recall bookings made for them.
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/recall"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetCalibrationDue - fetch the instruments due for calibration within ?within= days (30 by default),
+// overdue ones included, optionally only a ?customer='s
+func (h *Handler) GetCalibrationDue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	within := recall.DefaultLeadDays
+	if value := r.URL.Query().Get("within"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			http.Error(w, "within must be a number of days", http.StatusBadRequest)
+			return
+		}
+		within = days
+	}
+	var customerID uint64
+	if value := r.URL.Query().Get("customer"); value != "" {
+		var err error
+		if customerID, err = strconv.ParseUint(value, 10, 64); err != nil {
+			http.Error(w, "Unable to parse UINT from customer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	due, err := h.RecallService.GetDueList(within, uint(customerID))
+	if err != nil {
+		writeCustomerError(w, err, "Failed to retrieve due list")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(due); err != nil {
+		log.Warning(err)
+	}
+}
+
+// PostCalibrationRecall - run the recall now rather than waiting for the scheduler, returning the
+// bookings it made
+func (h *Handler) PostCalibrationRecall(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	booked, err := h.RecallService.Run(time.Now().UTC())
+	if err != nil {
+		log.Error(err)
+		http.Error(w, "Failed to run calibration recall", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(booked); err != nil {
+		log.Warning(err)
+	}
+}
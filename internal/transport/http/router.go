@@ -4,17 +4,49 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/Open-FiSE/go-rest-api/internal/availability"
+	"github.com/Open-FiSE/go-rest-api/internal/billing"
 	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/calendar"
+	"github.com/Open-FiSE/go-rest-api/internal/certificate"
+	"github.com/Open-FiSE/go-rest-api/internal/checklist"
+	"github.com/Open-FiSE/go-rest-api/internal/contract"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
+	"github.com/Open-FiSE/go-rest-api/internal/dispatch"
 	"github.com/Open-FiSE/go-rest-api/internal/document"
+	"github.com/Open-FiSE/go-rest-api/internal/engineer"
+	"github.com/Open-FiSE/go-rest-api/internal/events"
+	"github.com/Open-FiSE/go-rest-api/internal/fieldsync"
+	"github.com/Open-FiSE/go-rest-api/internal/inventory"
+	"github.com/Open-FiSE/go-rest-api/internal/recall"
+	"github.com/Open-FiSE/go-rest-api/internal/routing"
+	"github.com/Open-FiSE/go-rest-api/internal/signoff"
+	"github.com/Open-FiSE/go-rest-api/internal/timesheet"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 )
 
 // Handler - store a pointer to the router and document service that the app uses
 type Handler struct {
-	Router      *mux.Router
-	Service     *document.Service
-	BookService *booking.BookService
+	Router              *mux.Router
+	Service             *document.Service
+	BookService         *booking.BookService
+	EngineerService     *engineer.Service
+	AvailabilityService *availability.Service
+	CalendarService     *calendar.Service
+	CustomerService     *customer.Service
+	CertificateService  *certificate.Service
+	RecallService       *recall.Service
+	ChecklistService    *checklist.Service
+	SignOffService      *signoff.Service
+	InventoryService    *inventory.Service
+	TimesheetService    *timesheet.Service
+	BillingService      *billing.Service
+	ContractService     *contract.Service
+	RoutingService      *routing.Service
+	DispatchService     *dispatch.Service
+	SyncService         *fieldsync.Service
+	Events              *events.Broker
 }
 
 // Response - an object to store repsonses from the API
@@ -23,10 +55,32 @@ type Response struct {
 }
 
 // NewHandler - returns a pointer to a Handler
-func NewHandler(service *document.Service, bookservice *booking.BookService) *Handler {
+func NewHandler(service *document.Service, bookservice *booking.BookService, engineerService *engineer.Service,
+	availabilityService *availability.Service, calendarService *calendar.Service,
+	customerService *customer.Service, certificateService *certificate.Service, recallService *recall.Service,
+	checklistService *checklist.Service, signOffService *signoff.Service, inventoryService *inventory.Service,
+	timesheetService *timesheet.Service, billingService *billing.Service, contractService *contract.Service,
+	routingService *routing.Service, dispatchService *dispatch.Service,
+	syncService *fieldsync.Service, broker *events.Broker) *Handler {
 	return &Handler{
-		Service:     service,
-		BookService: bookservice,
+		Service:             service,
+		BookService:         bookservice,
+		EngineerService:     engineerService,
+		AvailabilityService: availabilityService,
+		CalendarService:     calendarService,
+		CustomerService:     customerService,
+		CertificateService:  certificateService,
+		RecallService:       recallService,
+		ChecklistService:    checklistService,
+		SignOffService:      signOffService,
+		InventoryService:    inventoryService,
+		TimesheetService:    timesheetService,
+		BillingService:      billingService,
+		ContractService:     contractService,
+		RoutingService:      routingService,
+		DispatchService:     dispatchService,
+		SyncService:         syncService,
+		Events:              broker,
 	}
 }
 
@@ -66,9 +120,179 @@ func (h *Handler) SetupRoutes() {
 	// Booking Service Routes
 	h.Router.HandleFunc(apiPrefix+"booking", h.GetAllBookings).Methods("GET")
 	h.Router.HandleFunc(apiPrefix+"booking", h.PostBooking).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"booking/occurrences", h.GetBookingOccurrences).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"booking/import", h.ImportBookings).Methods("POST")
 	h.Router.HandleFunc(apiPrefix+"booking/{id}", h.UpdateBooking).Methods("PUT")
 	h.Router.HandleFunc(apiPrefix+"booking/{id}", h.GetBooking).Methods("GET")
 	h.Router.HandleFunc(apiPrefix+"booking/{id}", h.DeleteBooking).Methods("DELETE")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/engineers", h.AssignEngineers).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/instruments", h.AssignInstruments).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/job", h.GetBookingJob).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/job", h.PostBookingJob).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/job", h.UpdateBookingJob).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/job/equipment", h.GetBookingEquipment).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/job/equipment", h.PostBookingEquipment).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/job/equipment/{itemId}", h.UpdateBookingEquipment).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/job/equipment/{itemId}", h.GetBookingEquipmentItem).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/job/equipment/{itemId}", h.DeleteBookingEquipment).Methods("DELETE")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/job/equipment/{itemId}/measurements", h.GetBookingMeasurements).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/job/equipment/{itemId}/measurements", h.SetBookingMeasurements).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/job/equipment/{itemId}/certificate", h.PostBookingCertificate).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/job/checklist", h.GetBookingChecklists).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/job/checklist/{checklistId}/item/{itemId}", h.AnswerBookingChecklistItem).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/job/parts", h.GetBookingParts).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/job/parts", h.PostBookingParts).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/job/parts/{usageId}", h.DeleteBookingParts).Methods("DELETE")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/occurrence", h.UpdateBookingOccurrence).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/occurrence", h.DeleteBookingOccurrence).Methods("DELETE")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/status", h.TransitionBookingStatus).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/status", h.GetBookingStatusHistory).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/complete", h.CompleteBooking).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/time", h.GetBookingTime).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/time", h.PostBookingTime).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/time/start", h.StartBookingTimer).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/time/stop", h.StopBookingTimer).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/time/{entryId:[0-9]+}", h.UpdateBookingTime).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/time/{entryId:[0-9]+}", h.DeleteBookingTime).Methods("DELETE")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/signoff", h.GetBookingSignOff).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/invoice", h.GetBookingInvoices).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/invoice", h.InvoiceBooking).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/quote", h.QuoteBooking).Methods("POST")
+
+	// Engineer Service Routes
+	h.Router.HandleFunc(apiPrefix+"engineer", h.GetAllEngineers).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"engineer", h.PostEngineer).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"engineer/{id}", h.UpdateEngineer).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"engineer/{id}", h.GetEngineer).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"engineer/{id}", h.DeleteEngineer).Methods("DELETE")
+	h.Router.HandleFunc(apiPrefix+"engineer/{id}/bookings", h.GetEngineerBookings).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"engineer/{id}/absence", h.GetEngineerAbsences).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"engineer/{id}/absence", h.PostEngineerAbsence).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"engineer/{id}/absence/{absenceId}", h.DeleteEngineerAbsence).Methods("DELETE")
+	h.Router.HandleFunc(apiPrefix+"engineer/{id}/route", h.GetEngineerRoute).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"engineer/{id}/sync", h.GetSyncChanges).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"engineer/{id}/sync", h.PushSyncMutations).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"engineer/{id}/timesheet", h.GetEngineerTimesheet).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"engineer/{id}/timesheet/submit", h.SubmitEngineerTimesheet).Methods("POST")
+
+	// Timesheet Approval Routes
+	h.Router.HandleFunc(apiPrefix+"timesheet", h.GetTimesheets).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"timesheet/{id}/approve", h.ApproveTimesheet).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"timesheet/{id}/reject", h.RejectTimesheet).Methods("POST")
+
+	// Customer Service Routes
+	h.Router.HandleFunc(apiPrefix+"customer", h.GetAllCustomers).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"customer", h.PostCustomer).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}", h.UpdateCustomer).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}", h.GetCustomer).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}", h.DeleteCustomer).Methods("DELETE")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/bookings", h.GetCustomerBookings).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/site", h.GetCustomerSites).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/site", h.PostCustomerSite).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/site/{siteId}", h.UpdateCustomerSite).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/site/{siteId}", h.GetCustomerSite).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/site/{siteId}", h.DeleteCustomerSite).Methods("DELETE")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/contact", h.GetCustomerContacts).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/contact", h.PostCustomerContact).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/contact/{contactId}", h.UpdateCustomerContact).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/contact/{contactId}", h.GetCustomerContact).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/contact/{contactId}", h.DeleteCustomerContact).Methods("DELETE")
+
+	// Contract and SLA Routes
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/contract", h.GetContracts).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/contract", h.PostContract).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/contract/{contractId}", h.GetContract).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/contract/{contractId}", h.UpdateContract).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/contract/{contractId}", h.DeleteContract).Methods("DELETE")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/sla", h.GetCustomerSLAReport).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"booking/{id}/sla", h.GetBookingSLA).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"sla/warnings", h.GetSLAWarnings).Methods("GET")
+
+	// Instrument Register Routes
+	h.Router.HandleFunc(apiPrefix+"instrument", h.GetAllInstruments).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"instrument", h.PostInstrument).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"instrument/{id:[0-9]+}", h.UpdateInstrument).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"instrument/{id:[0-9]+}", h.GetInstrument).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"instrument/{id:[0-9]+}", h.DeleteInstrument).Methods("DELETE")
+	h.Router.HandleFunc(apiPrefix+"instrument/{serial}/history", h.GetInstrumentHistory).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"calibration/due", h.GetCalibrationDue).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"calibration/recall", h.PostCalibrationRecall).Methods("POST")
+
+	// Job Service Routes
+	h.Router.HandleFunc(apiPrefix+"job", h.GetAllJobs).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"job", h.PostJob).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"job/{id}", h.UpdateJob).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"job/{id}", h.GetJob).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"job/{id}", h.DeleteJob).Methods("DELETE")
+	h.Router.HandleFunc(apiPrefix+"job/{id}/checklist", h.GetJobChecklists).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"job/{id}/parts", h.GetJobParts).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"job/{id}/checklist/{checklistId}/item/{itemId}", h.AnswerJobChecklistItem).Methods("PUT")
+
+	// Checklist Template Routes
+	h.Router.HandleFunc(apiPrefix+"checklisttemplate", h.GetAllChecklistTemplates).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"checklisttemplate", h.PostChecklistTemplate).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"checklisttemplate/{id}", h.UpdateChecklistTemplate).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"checklisttemplate/{id}", h.GetChecklistTemplate).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"checklisttemplate/{id}", h.DeleteChecklistTemplate).Methods("DELETE")
+
+	// Inventory Routes
+	h.Router.HandleFunc(apiPrefix+"part", h.GetAllParts).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"part", h.PostPart).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"part/{id}", h.UpdatePart).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"part/{id}", h.GetPart).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"part/{id}", h.DeletePart).Methods("DELETE")
+	h.Router.HandleFunc(apiPrefix+"stocklocation", h.GetAllStockLocations).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"stocklocation", h.PostStockLocation).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"stocklocation/{id}", h.UpdateStockLocation).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"stocklocation/{id}", h.GetStockLocation).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"stocklocation/{id}", h.DeleteStockLocation).Methods("DELETE")
+	h.Router.HandleFunc(apiPrefix+"stocklocation/{id}/stock", h.GetLocationStock).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"stocklocation/{id}/stock/{partId}", h.SetLocationStock).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"stock/transfer", h.TransferStock).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"stock/low", h.GetLowStock).Methods("GET")
+
+	// Billing Routes
+	h.Router.HandleFunc(apiPrefix+"billing/rates", h.GetRates).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"billing/rates/labour/{type}", h.SetLabourRate).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"billing/rates/work/{workType}", h.SetWorkPrice).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"billing/rates/tax", h.SetTaxRule).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"billing/rates/tax/{id}", h.DeleteTaxRule).Methods("DELETE")
+	h.Router.HandleFunc(apiPrefix+"billing/sequence", h.GetSequences).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"billing/sequence/{kind}", h.SetSequence).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"invoice", h.GetInvoices).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"invoice/export", h.ExportInvoices).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"invoice/{id:[0-9]+}", h.GetInvoice).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"invoice/{id:[0-9]+}/ubl", h.GetInvoiceUBL).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"invoice/{id:[0-9]+}/void", h.VoidInvoice).Methods("POST")
+
+	// Dispatch Routes
+	h.Router.HandleFunc(apiPrefix+"dispatch/preview", h.PreviewDispatch).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"dispatch/apply", h.ApplyDispatch).Methods("POST")
+
+	// Change Feed Routes
+	h.Router.HandleFunc(apiPrefix+"events", h.GetEvents).Methods("GET")
+
+	// Calendar Feed Routes
+	h.Router.HandleFunc(apiPrefix+"booking.ics", h.GetBookingFeed).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"engineer/{id}/bookings.ics", h.GetEngineerFeed).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"customer/{id}/bookings.ics", h.GetCustomerFeed).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"feedtoken", h.PostFeedToken).Methods("POST")
+	h.Router.HandleFunc(apiPrefix+"feedtoken/{id}", h.DeleteFeedToken).Methods("DELETE")
+
+	// CalDAV Routes, one calendar collection per engineer
+	for _, collection := range []string{apiPrefix + "caldav/engineer/{id}", apiPrefix + "caldav/engineer/{id}/"} {
+		h.Router.HandleFunc(collection, h.CalDAVOptions).Methods("OPTIONS")
+		h.Router.HandleFunc(collection, h.CalDAVPropfind).Methods("PROPFIND")
+		h.Router.HandleFunc(collection, h.CalDAVReport).Methods("REPORT")
+	}
+	h.Router.HandleFunc(apiPrefix+"caldav/engineer/{id}/{resource}", h.CalDAVOptions).Methods("OPTIONS")
+	h.Router.HandleFunc(apiPrefix+"caldav/engineer/{id}/{resource}", h.CalDAVPropfind).Methods("PROPFIND")
+	h.Router.HandleFunc(apiPrefix+"caldav/engineer/{id}/{resource}", h.CalDAVGet).Methods("GET")
+	h.Router.HandleFunc(apiPrefix+"caldav/engineer/{id}/{resource}", h.CalDAVPut).Methods("PUT")
+	h.Router.HandleFunc(apiPrefix+"caldav/engineer/{id}/{resource}", h.CalDAVDelete).Methods("DELETE")
+
+	// Availability Routes
+	h.Router.HandleFunc(apiPrefix+"availability", h.GetAvailability).Methods("GET")
 
 	h.Router.HandleFunc(apiPrefix+"health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
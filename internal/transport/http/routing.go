@@ -0,0 +1,69 @@
+package http
+
+// Define endpoints for planning the order of an engineer's visits.
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/routing"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetEngineerRoute - fetch an engineer's visits on a ?date= (today by default) ordered to minimise
+// travel, starting from ?lat=&lng= when given and the engineer's base otherwise, with warnings where
+// the planned times cannot be kept
+func (h *Handler) GetEngineerRoute(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	engineerID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+	values := r.URL.Query()
+	date := values.Get("date")
+	if date == "" {
+		date = time.Now().UTC().Format("2006-01-02")
+	}
+	var start *routing.Point
+	if values.Get("lat") != "" || values.Get("lng") != "" {
+		lat, latErr := strconv.ParseFloat(values.Get("lat"), 64)
+		lng, lngErr := strconv.ParseFloat(values.Get("lng"), 64)
+		if latErr != nil || lngErr != nil {
+			http.Error(w, routing.ErrInvalidStart.Error(), http.StatusBadRequest)
+			return
+		}
+		start = &routing.Point{Latitude: lat, Longitude: lng}
+	}
+
+	plan, err := h.RoutingService.PlanDay(uint(engineerID), date, start)
+	if err != nil {
+		writeRoutingError(w, err, "Failed to plan route")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(plan); err != nil {
+		log.Warning(err)
+	}
+}
+
+// writeRoutingError - maps routing service errors onto HTTP status codes
+func writeRoutingError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, routing.ErrInvalidDate), errors.Is(err, routing.ErrInvalidStart):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Engineer not found", http.StatusNotFound)
+		return
+	}
+	log.Error(err)
+	http.Error(w, message, http.StatusInternalServerError)
+}
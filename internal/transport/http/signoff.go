@@ -0,0 +1,70 @@
+package http
+
+// Define endpoints for completing a booking with the customer's sign-off.
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Open-FiSE/go-rest-api/internal/signoff"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// CompleteBooking - completes a booking with the customer's signature, filing the signature and
+// the signed service report against it
+func (h *Handler) CompleteBooking(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var request signoff.Request
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	bookingID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	signOff, err := h.SignOffService.SignOff(uint(bookingID), request)
+	if err != nil {
+		if errors.Is(err, signoff.ErrInvalidSignerName) || errors.Is(err, signoff.ErrInvalidSignature) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeBookingError(w, err, "Failed to complete booking")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(signOff); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetBookingSignOff - fetch the customer's sign-off of a booking
+func (h *Handler) GetBookingSignOff(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	bookingID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	signOff, err := h.BookService.GetSignOff(uint(bookingID))
+	if err != nil {
+		writeBookingError(w, err, "Failed to retrieve booking sign-off")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(signOff); err != nil {
+		log.Warning(err)
+	}
+}
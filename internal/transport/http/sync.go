@@ -0,0 +1,83 @@
+package http
+
+// Define endpoints for the field engineer app to sync its offline copy of an engineer's work.
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Open-FiSE/go-rest-api/internal/fieldsync"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetSyncChanges - fetch an engineer's bookings, jobs and documents changed since ?cursor=, and
+// those deleted, everything when no cursor is given
+func (h *Handler) GetSyncChanges(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	engineerID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := h.SyncService.Changes(uint(engineerID), r.URL.Query().Get("cursor"))
+	if err != nil {
+		writeSyncError(w, err, "Failed to retrieve changes")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(changes); err != nil {
+		log.Warning(err)
+	}
+}
+
+// PushSyncMutations - apply a batch of changes an engineer made offline, returning the outcome of each
+func (h *Handler) PushSyncMutations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	engineerID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		Mutations []fieldsync.Mutation `json:"mutations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.SyncService.Push(uint(engineerID), body.Mutations)
+	if err != nil {
+		writeSyncError(w, err, "Failed to apply mutations")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		log.Warning(err)
+	}
+}
+
+// writeSyncError - maps sync service errors onto HTTP status codes
+func writeSyncError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, fieldsync.ErrInvalidCursor), errors.Is(err, fieldsync.ErrNoMutations),
+		errors.Is(err, fieldsync.ErrInvalidMutation):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Engineer not found", http.StatusNotFound)
+		return
+	}
+	log.Error(err)
+	http.Error(w, message, http.StatusInternalServerError)
+}
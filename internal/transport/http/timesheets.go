@@ -0,0 +1,341 @@
+package http
+
+// Define endpoints for the time engineers record against bookings and their weekly timesheets.
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Open-FiSE/go-rest-api/internal/timesheet"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+// TimerRequest - the body of a request stopping an engineer's timer
+type TimerRequest struct {
+	EngineerID uint `json:"engineerId"`
+}
+
+// ReviewRequest - the body of a request approving or rejecting a timesheet
+type ReviewRequest struct {
+	ReviewedBy string `json:"reviewedBy"`
+	Reason     string `json:"reason"`
+}
+
+// GetBookingTime - fetch the time recorded against a booking
+func (h *Handler) GetBookingTime(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	bookingID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.TimesheetService.GetEntries(uint(bookingID))
+	if err != nil {
+		writeTimesheetError(w, err, "Failed to retrieve time entries")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		log.Warning(err)
+	}
+}
+
+// PostBookingTime - records time entered by hand against a booking
+func (h *Handler) PostBookingTime(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var entry timesheet.Entry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	bookingID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	entry, err = h.TimesheetService.PostEntry(uint(bookingID), entry)
+	if err != nil {
+		writeTimesheetError(w, err, "Failed to record time")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(entry); err != nil {
+		log.Warning(err)
+	}
+}
+
+// StartBookingTimer - starts timing an engineer's travel, work or wait on a booking
+func (h *Handler) StartBookingTimer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var entry timesheet.Entry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	bookingID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	entry, err = h.TimesheetService.StartTimer(uint(bookingID), entry)
+	if err != nil {
+		writeTimesheetError(w, err, "Failed to start timer")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(entry); err != nil {
+		log.Warning(err)
+	}
+}
+
+// StopBookingTimer - stops an engineer's timer running on a booking
+func (h *Handler) StopBookingTimer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var request TimerRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	bookingID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.TimesheetService.StopTimer(uint(bookingID), request.EngineerID)
+	if err != nil {
+		writeTimesheetError(w, err, "Failed to stop timer")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(entry); err != nil {
+		log.Warning(err)
+	}
+}
+
+// UpdateBookingTime - corrects a time entry on a booking
+func (h *Handler) UpdateBookingTime(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var entry timesheet.Entry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	bookingID, entryID, ok := parseEntryIDs(w, r)
+	if !ok {
+		return
+	}
+
+	entry, err := h.TimesheetService.UpdateEntry(bookingID, entryID, entry)
+	if err != nil {
+		writeTimesheetError(w, err, "Failed to update time entry")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(entry); err != nil {
+		log.Warning(err)
+	}
+}
+
+// DeleteBookingTime - removes a time entry recorded in error from a booking
+func (h *Handler) DeleteBookingTime(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	bookingID, entryID, ok := parseEntryIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.TimesheetService.DeleteEntry(bookingID, entryID); err != nil {
+		writeTimesheetError(w, err, "Failed to delete time entry")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(Response{Message: "Successfully deleted time entry"}); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetEngineerTimesheet - fetch an engineer's timesheet for the ?week= containing a date, this week
+// when none is given
+func (h *Handler) GetEngineerTimesheet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	engineerID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	sheet, err := h.TimesheetService.GetTimesheet(uint(engineerID), week(r))
+	if err != nil {
+		writeTimesheetError(w, err, "Failed to retrieve timesheet")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(sheet); err != nil {
+		log.Warning(err)
+	}
+}
+
+// SubmitEngineerTimesheet - submits an engineer's timesheet for the ?week= for approval
+func (h *Handler) SubmitEngineerTimesheet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	engineerID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	sheet, err := h.TimesheetService.SubmitTimesheet(uint(engineerID), week(r))
+	if err != nil {
+		writeTimesheetError(w, err, "Failed to submit timesheet")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(sheet); err != nil {
+		log.Warning(err)
+	}
+}
+
+// GetTimesheets - fetch the timesheets in a ?status=, those submitted and awaiting approval by default
+func (h *Handler) GetTimesheets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	status := timesheet.StatusSubmitted
+	if value := r.URL.Query().Get("status"); value != "" {
+		status = timesheet.Status(value)
+	}
+
+	sheets, err := h.TimesheetService.GetTimesheets(status)
+	if err != nil {
+		writeTimesheetError(w, err, "Failed to retrieve timesheets")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(sheets); err != nil {
+		log.Warning(err)
+	}
+}
+
+// ApproveTimesheet - approves a submitted timesheet so its time can be billed
+func (h *Handler) ApproveTimesheet(w http.ResponseWriter, r *http.Request) {
+	h.reviewTimesheet(w, r, true)
+}
+
+// RejectTimesheet - sends a submitted timesheet back to the engineer with a reason
+func (h *Handler) RejectTimesheet(w http.ResponseWriter, r *http.Request) {
+	h.reviewTimesheet(w, r, false)
+}
+
+func (h *Handler) reviewTimesheet(w http.ResponseWriter, r *http.Request, approve bool) {
+	w.Header().Set("Content-Type", "application/json; charcet=UTF-8")
+	enableCors(&w)
+
+	var request ReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Failed to decode JSON Body", http.StatusBadRequest)
+		return
+	}
+
+	timesheetID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return
+	}
+
+	var sheet timesheet.Timesheet
+	if approve {
+		sheet, err = h.TimesheetService.ApproveTimesheet(uint(timesheetID), request.ReviewedBy)
+	} else {
+		sheet, err = h.TimesheetService.RejectTimesheet(uint(timesheetID), request.ReviewedBy, request.Reason)
+	}
+	if err != nil {
+		writeTimesheetError(w, err, "Failed to review timesheet")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(sheet); err != nil {
+		log.Warning(err)
+	}
+}
+
+// parseEntryIDs - parses the {id} and {entryId} route variables, writing the error response and
+// returning false when either is not a number
+func parseEntryIDs(w http.ResponseWriter, r *http.Request) (uint, uint, bool) {
+	vars := mux.Vars(r)
+	bookingID, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	entryID, err := strconv.ParseUint(vars["entryId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Unable to parse UINT from entry ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return uint(bookingID), uint(entryID), true
+}
+
+// week - the ?week= query parameter, today when it is not given
+func week(r *http.Request) string {
+	if value := r.URL.Query().Get("week"); value != "" {
+		return value
+	}
+	return time.Now().UTC().Format("2006-01-02")
+}
+
+// writeTimesheetError - maps timesheet service errors onto HTTP responses
+func writeTimesheetError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, timesheet.ErrInvalidEntry), errors.Is(err, timesheet.ErrEngineerNotAssigned),
+		errors.Is(err, timesheet.ErrInvalidWeek), errors.Is(err, timesheet.ErrInvalidReview):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, timesheet.ErrTimerRunning), errors.Is(err, timesheet.ErrNoTimerRunning),
+		errors.Is(err, timesheet.ErrTimesheetLocked):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	log.Error(err)
+	http.Error(w, message, http.StatusInternalServerError)
+}
@@ -3,10 +3,27 @@ package main
 import (
 	"net/http"
 	"os"
+	"time"
 
+	"github.com/Open-FiSE/go-rest-api/internal/availability"
+	"github.com/Open-FiSE/go-rest-api/internal/billing"
 	"github.com/Open-FiSE/go-rest-api/internal/booking"
+	"github.com/Open-FiSE/go-rest-api/internal/calendar"
+	"github.com/Open-FiSE/go-rest-api/internal/certificate"
+	"github.com/Open-FiSE/go-rest-api/internal/checklist"
+	"github.com/Open-FiSE/go-rest-api/internal/contract"
+	"github.com/Open-FiSE/go-rest-api/internal/customer"
 	"github.com/Open-FiSE/go-rest-api/internal/database"
+	"github.com/Open-FiSE/go-rest-api/internal/dispatch"
 	"github.com/Open-FiSE/go-rest-api/internal/document"
+	"github.com/Open-FiSE/go-rest-api/internal/engineer"
+	"github.com/Open-FiSE/go-rest-api/internal/events"
+	"github.com/Open-FiSE/go-rest-api/internal/fieldsync"
+	"github.com/Open-FiSE/go-rest-api/internal/inventory"
+	"github.com/Open-FiSE/go-rest-api/internal/recall"
+	"github.com/Open-FiSE/go-rest-api/internal/routing"
+	"github.com/Open-FiSE/go-rest-api/internal/signoff"
+	"github.com/Open-FiSE/go-rest-api/internal/timesheet"
 
 	// using alias 'transportHTTP' to prevent conflict with net/http pkg
 	transportHTTP "github.com/Open-FiSE/go-rest-api/internal/transport/http"
@@ -48,10 +65,41 @@ func (app *App) Run() error {
 		log.Error("Error: Failed to migrate database")
 	}
 
-	documentService := document.NewService(db)
-	bookingService := booking.NewService(db)
+	// changes to bookings and documents are streamed to the dispatcher web client
+	broker := events.NewBroker(events.DefaultHistory)
+	documentService := document.NewService(db, broker)
+	bookingService := booking.NewService(db, broker)
+	engineerService := engineer.NewService(db)
+	availabilityService := availability.NewService(bookingService, engineerService)
+	calendarService := calendar.NewService(db, bookingService)
+	customerService := customer.NewService(db)
+	certificateService := certificate.NewService(db, bookingService, customerService, documentService,
+		"/app/docs/certificates")
+	recallService := recall.NewService(bookingService, customerService)
+	checklistService := checklist.NewService(db, customerService)
+	inventoryService := inventory.NewService(db, customerService)
+	timesheetService := timesheet.NewService(db, bookingService, engineerService)
+	signOffService := signoff.NewService(bookingService, customerService, checklistService, inventoryService,
+		documentService, "/app/docs/signoff")
+	billingService := billing.NewService(db, bookingService, customerService, inventoryService, timesheetService,
+		documentService, "/app/docs/invoices")
+	contractService := contract.NewService(db, bookingService, customerService)
+	routingService := routing.NewService(bookingService, engineerService)
+	dispatchService := dispatch.NewService(bookingService, engineerService, customerService, availabilityService,
+		contractService)
+	syncService := fieldsync.NewService(db, bookingService, customerService, documentService, engineerService)
 
-	handler := transportHTTP.NewHandler(documentService, bookingService)
+	// book tentative visits for instruments coming due for calibration once a day
+	stopRecall := recallService.Start(24 * time.Hour)
+	defer stopRecall()
+	// start missed SLA clocks and warn about bookings close to breaching their SLA every 15 minutes
+	stopSLA := contractService.Start(15 * time.Minute)
+	defer stopSLA()
+
+	handler := transportHTTP.NewHandler(documentService, bookingService, engineerService, availabilityService,
+		calendarService, customerService, certificateService, recallService,
+		checklistService, signOffService, inventoryService, timesheetService, billingService, contractService, routingService,
+		dispatchService, syncService, broker)
 	handler.SetupRoutes()
 
 	if err := http.ListenAndServe(port, handler.Router); err != nil {